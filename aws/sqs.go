@@ -7,6 +7,8 @@ package aws
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/sqs"
@@ -14,8 +16,16 @@ import (
 	"github.com/pkg/errors"
 )
 
-// Max size of a sqs body in bytes.
-const sqsMaxMessageSize = 262144
+const (
+	// Max size of a sqs body in bytes.
+	sqsMaxMessageSize = 262144
+
+	// Max delay SQS accept on a message.
+	sqsMaxDelay = 15 * time.Minute
+
+	// Max quantity of messages SQS accept on a batch.
+	sqsMaxBatchEntries = 10
+)
 
 // SQS returns a new client to interact with a SQS queue.
 type SQS struct {
@@ -42,6 +52,90 @@ func (s *SQS) Push(ctx context.Context, content []byte) error {
 	return nil
 }
 
+// PushBatch send the contents to SQS queue with SendMessageBatch. The contents are split in batches
+// that respect the SQS limits of entries and payload size.
+func (s *SQS) PushBatch(ctx context.Context, contents [][]byte) []error {
+	errs := make([]error, len(contents))
+	batch := make([]int, 0, sqsMaxBatchEntries)
+	size := 0
+
+	for i, content := range contents {
+		if len(content) > sqsMaxMessageSize {
+			errs[i] = errors.New("document too big")
+			continue
+		}
+
+		if len(batch) == sqsMaxBatchEntries || size+len(content) > sqsMaxMessageSize {
+			s.pushBatch(ctx, contents, batch, errs)
+			batch, size = batch[:0], 0
+		}
+		batch = append(batch, i)
+		size += len(content)
+	}
+
+	if len(batch) > 0 {
+		s.pushBatch(ctx, contents, batch, errs)
+	}
+	return errs
+}
+
+// pushBatch send the contents at the indexes and set the errors of the entries that failed. The
+// entry id is the content index.
+func (s *SQS) pushBatch(ctx context.Context, contents [][]byte, indexes []int, errs []error) {
+	entries := make([]*sqs.SendMessageBatchRequestEntry, len(indexes))
+	for i, index := range indexes {
+		entries[i] = &sqs.SendMessageBatchRequestEntry{
+			Id:          aws.String(strconv.Itoa(index)),
+			MessageBody: aws.String(string(contents[index])),
+		}
+	}
+
+	output, err := s.client.SendMessageBatchWithContext(ctx, &sqs.SendMessageBatchInput{
+		Entries:  entries,
+		QueueUrl: aws.String(s.endpoint),
+	})
+	if err != nil {
+		for _, index := range indexes {
+			errs[index] = errors.Wrap(err, "error during SQS message batch enqueue")
+		}
+		return
+	}
+
+	for _, failed := range output.Failed {
+		index, err := strconv.Atoi(aws.StringValue(failed.Id))
+		if err != nil || index < 0 || index >= len(errs) {
+			continue
+		}
+		errs[index] = fmt.Errorf(
+			"error during SQS message enqueue, code '%s': %s",
+			aws.StringValue(failed.Code), aws.StringValue(failed.Message),
+		)
+	}
+}
+
+// PushDelay send the content to SQS queue to be delivered after the delay. SQS has a limit of 15
+// minutes, bigger delays are truncated, so the consumer should check if the message can be processed.
+func (s *SQS) PushDelay(ctx context.Context, content []byte, delay time.Duration) error {
+	if len(content) > sqsMaxMessageSize {
+		return errors.New("document too big")
+	}
+
+	if delay > sqsMaxDelay {
+		delay = sqsMaxDelay
+	}
+
+	params := &sqs.SendMessageInput{
+		DelaySeconds: aws.Int64((int64)(delay / time.Second)),
+		MessageBody:  aws.String(string(content)),
+		QueueUrl:     aws.String(s.endpoint),
+	}
+
+	if _, err := s.client.SendMessageWithContext(ctx, params); err != nil {
+		return errors.Wrap(err, "error during SQS message enqueue")
+	}
+	return nil
+}
+
 // Pull a message from SQS and send it to be processed.
 func (s *SQS) Pull(ctx context.Context, fn func(context.Context, []byte) error) error {
 	output, err := s.client.ReceiveMessageWithContext(
@@ -76,6 +170,35 @@ func (s *SQS) Pull(ctx context.Context, fn func(context.Context, []byte) error)
 	return nil
 }
 
+// Depth returns the approximate quantity of messages available to be pulled.
+func (s *SQS) Depth(ctx context.Context) (int, error) {
+	attribute := sqs.QueueAttributeNameApproximateNumberOfMessages
+	output, err := s.client.GetQueueAttributesWithContext(ctx, &sqs.GetQueueAttributesInput{
+		AttributeNames: []*string{aws.String(attribute)},
+		QueueUrl:       aws.String(s.endpoint),
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "error during SQS queue attributes fetch")
+	}
+
+	value, ok := output.Attributes[attribute]
+	if !ok || value == nil {
+		return 0, fmt.Errorf("SQS attribute '%s' not found", attribute)
+	}
+
+	depth, err := strconv.Atoi(*value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "error during parse of SQS attribute '%s'", attribute)
+	}
+	return depth, nil
+}
+
+// Ping check if the queue is reachable.
+func (s *SQS) Ping(ctx context.Context) error {
+	_, err := s.Depth(ctx)
+	return err
+}
+
 func (s *SQS) sqsEndpoint() error {
 	result, err := s.client.GetQueueUrl(&sqs.GetQueueUrlInput{QueueName: aws.String(s.name)})
 	if err != nil {
@@ -0,0 +1,173 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package aws
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/pkg/errors"
+
+	"github.com/diegobernardes/flare"
+)
+
+// Max quantity of message attributes accepted by SQS and SNS.
+const targetMaxAttributes = 10
+
+// SQSTarget implements flare.SubscriptionTarget sending the notifications to the SQS queue at the
+// subscription endpoint. The headers are sent as message attributes.
+type SQSTarget struct {
+	session *Session
+	client  sqsiface.SQSAPI
+}
+
+// Deliver send the notification to the endpoint queue.
+func (s *SQSTarget) Deliver(
+	ctx context.Context,
+	sub flare.Subscription,
+	content []byte,
+	header http.Header,
+	delivery *flare.Delivery,
+) error {
+	if len(content) > sqsMaxMessageSize {
+		return errors.New("document too big")
+	}
+
+	if len(header) > targetMaxAttributes {
+		return errors.Errorf("too many headers, SQS accept at most %d", targetMaxAttributes)
+	}
+
+	attributes := make(map[string]*sqs.MessageAttributeValue, len(header))
+	for key, values := range header {
+		attributes[key] = &sqs.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(strings.Join(values, ",")),
+		}
+	}
+
+	output, err := s.client.SendMessageWithContext(ctx, &sqs.SendMessageInput{
+		MessageAttributes: attributes,
+		MessageBody:       aws.String(string(content)),
+		QueueUrl:          aws.String(sub.Endpoint.Queue),
+	})
+	if err != nil {
+		return errors.Wrap(err, "error during SQS message send")
+	}
+	delivery.MessageID = aws.StringValue(output.MessageId)
+	return nil
+}
+
+// NewSQSTarget returns a configured SQS target.
+func NewSQSTarget(options ...func(*SQSTarget)) (*SQSTarget, error) {
+	s := &SQSTarget{}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	if s.client == nil {
+		if s.session == nil {
+			return nil, errors.New("session not found")
+		}
+		s.client = sqs.New(s.session.base)
+	}
+
+	return s, nil
+}
+
+// SQSTargetSession set the AWS Session.
+func SQSTargetSession(session *Session) func(*SQSTarget) {
+	return func(s *SQSTarget) {
+		s.session = session
+	}
+}
+
+// SQSTargetClient set the SQS client, it's used on tests to replace the AWS access.
+func SQSTargetClient(client sqsiface.SQSAPI) func(*SQSTarget) {
+	return func(s *SQSTarget) {
+		s.client = client
+	}
+}
+
+// SNSTarget implements flare.SubscriptionTarget publishing the notifications at the SNS topic at
+// the subscription endpoint. The headers are sent as message attributes.
+type SNSTarget struct {
+	session *Session
+	client  snsiface.SNSAPI
+}
+
+// Deliver publish the notification at the endpoint topic.
+func (s *SNSTarget) Deliver(
+	ctx context.Context,
+	sub flare.Subscription,
+	content []byte,
+	header http.Header,
+	delivery *flare.Delivery,
+) error {
+	if len(content) > sqsMaxMessageSize {
+		return errors.New("document too big")
+	}
+
+	if len(header) > targetMaxAttributes {
+		return errors.Errorf("too many headers, SNS accept at most %d", targetMaxAttributes)
+	}
+
+	attributes := make(map[string]*sns.MessageAttributeValue, len(header))
+	for key, values := range header {
+		attributes[key] = &sns.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(strings.Join(values, ",")),
+		}
+	}
+
+	output, err := s.client.PublishWithContext(ctx, &sns.PublishInput{
+		Message:           aws.String(string(content)),
+		MessageAttributes: attributes,
+		TopicArn:          aws.String(sub.Endpoint.Topic),
+	})
+	if err != nil {
+		return errors.Wrap(err, "error during SNS message publish")
+	}
+	delivery.MessageID = aws.StringValue(output.MessageId)
+	return nil
+}
+
+// NewSNSTarget returns a configured SNS target.
+func NewSNSTarget(options ...func(*SNSTarget)) (*SNSTarget, error) {
+	s := &SNSTarget{}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	if s.client == nil {
+		if s.session == nil {
+			return nil, errors.New("session not found")
+		}
+		s.client = sns.New(s.session.base)
+	}
+
+	return s, nil
+}
+
+// SNSTargetSession set the AWS Session.
+func SNSTargetSession(session *Session) func(*SNSTarget) {
+	return func(s *SNSTarget) {
+		s.session = session
+	}
+}
+
+// SNSTargetClient set the SNS client, it's used on tests to replace the AWS access.
+func SNSTargetClient(client snsiface.SNSAPI) func(*SNSTarget) {
+	return func(s *SNSTarget) {
+		s.client = client
+	}
+}
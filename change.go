@@ -0,0 +1,42 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flare
+
+import (
+	"context"
+	"time"
+)
+
+// Change is a entry at the change feed of a resource. The Sequence is assigned by the repository
+// and is unique and ordered per resource. The Document has the content on create and update.
+type Change struct {
+	Sequence  int64
+	Resource  Resource
+	Document  Document
+	Action    string
+	CreatedAt time.Time
+}
+
+// ChangeRepositorier is used to interact with the change feed data storage.
+type ChangeRepositorier interface {
+	// FindAll returns the changes of the resource with the sequence greater than the given one, in
+	// ascending order.
+	FindAll(ctx context.Context, resourceId string, sequence int64, limit int) ([]Change, error)
+
+	// FindLatest returns the newest change of a document.
+	FindLatest(ctx context.Context, resourceId, documentId string) (*Change, error)
+
+	// Create a change and set the sequence. The change is only created if the newest change of the
+	// document still is the one with the latest sequence, zero when the document has no change,
+	// otherwise a error with Conflict is returned. This way, the concurrent updates of a document
+	// can't both be written at the feed.
+	Create(ctx context.Context, change *Change, latest int64) error
+}
+
+// ChangeRepositoryError implements all the errrors the repository can return.
+type ChangeRepositoryError interface {
+	NotFound() bool
+	Conflict() bool
+}
@@ -0,0 +1,37 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flare
+
+import (
+	"context"
+	"time"
+)
+
+// DeadLetter is a delivery that could not be done after all the retry attempts.
+type DeadLetter struct {
+	ID           string
+	Subscription Subscription
+	Document     Document
+	Action       string
+	Attempts     int
+	Error        string
+	CreatedAt    time.Time
+}
+
+// DeadLetterRepositorier is used to interact with the DeadLetter data storage.
+type DeadLetterRepositorier interface {
+	FindAll(
+		ctx context.Context, pagination *Pagination, resourceId, subscriptionId string,
+	) ([]DeadLetter, *Pagination, error)
+	FindOne(ctx context.Context, resourceId, subscriptionId, id string) (*DeadLetter, error)
+	Create(context.Context, *DeadLetter) error
+	Delete(ctx context.Context, resourceId, subscriptionId, id string) error
+	DeleteAll(ctx context.Context, resourceId, subscriptionId string) error
+}
+
+// DeadLetterRepositoryError implements all the errrors the repository can return.
+type DeadLetterRepositoryError interface {
+	NotFound() bool
+}
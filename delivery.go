@@ -0,0 +1,70 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flare
+
+import (
+	"context"
+	"time"
+)
+
+// Delivery is a attempt to notify a subscription about a document change. The Status, the
+// ResponseBody and the RetryAfter, asked by the subscriber with the status 429, are set by the HTTP
+// targets and the MessageID by the targets that publish messages.
+type Delivery struct {
+	ID           string
+	Subscription Subscription
+	Document     Document
+	Action       string
+	Target       string
+	Attempt      int
+	Status       int
+	Latency      time.Duration
+	Error        string
+	ResponseBody string
+	RetryAfter   time.Duration
+	MessageID    string
+	CreatedAt    time.Time
+}
+
+// DeliveryFilter is used to restrict the deliveries returned by the repository. The zero value
+// of each field means no restriction.
+type DeliveryFilter struct {
+	Status []int
+	From   time.Time
+	To     time.Time
+}
+
+// Match indicates if the delivery match the filter.
+func (df *DeliveryFilter) Match(delivery *Delivery) bool {
+	if !df.From.IsZero() && delivery.CreatedAt.Before(df.From) {
+		return false
+	}
+
+	if !df.To.IsZero() && delivery.CreatedAt.After(df.To) {
+		return false
+	}
+
+	if len(df.Status) == 0 {
+		return true
+	}
+
+	for _, status := range df.Status {
+		if status == delivery.Status {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliveryRepositorier is used to interact with the Delivery data storage.
+type DeliveryRepositorier interface {
+	FindAll(
+		ctx context.Context,
+		pagination *Pagination,
+		resourceId, subscriptionId string,
+		filter *DeliveryFilter,
+	) ([]Delivery, *Pagination, error)
+	Create(context.Context, *Delivery) error
+}
@@ -0,0 +1,67 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flare
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDeliveryFilterMatch(t *testing.T) {
+	Convey("Given a Delivery", t, func() {
+		delivery := &Delivery{
+			Status:    500,
+			CreatedAt: time.Date(2017, time.November, 10, 23, 0, 0, 0, time.UTC),
+		}
+
+		tests := []struct {
+			title  string
+			filter DeliveryFilter
+			match  bool
+		}{
+			{
+				"It should match a empty filter",
+				DeliveryFilter{},
+				true,
+			},
+			{
+				"It should match the status",
+				DeliveryFilter{Status: []int{200, 500}},
+				true,
+			},
+			{
+				"It should not match the status",
+				DeliveryFilter{Status: []int{200}},
+				false,
+			},
+			{
+				"It should match the time range",
+				DeliveryFilter{
+					From: time.Date(2017, time.November, 10, 0, 0, 0, 0, time.UTC),
+					To:   time.Date(2017, time.November, 11, 0, 0, 0, 0, time.UTC),
+				},
+				true,
+			},
+			{
+				"It should not match the time range start",
+				DeliveryFilter{From: time.Date(2017, time.November, 11, 0, 0, 0, 0, time.UTC)},
+				false,
+			},
+			{
+				"It should not match the time range end",
+				DeliveryFilter{To: time.Date(2017, time.November, 10, 0, 0, 0, 0, time.UTC)},
+				false,
+			},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				So(tt.filter.Match(delivery), ShouldEqual, tt.match)
+			})
+		}
+	})
+}
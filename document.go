@@ -6,7 +6,9 @@ package flare
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
 	"strconv"
 	"time"
@@ -18,6 +20,7 @@ import (
 type Document struct {
 	Id               string
 	ChangeFieldValue interface{}
+	Content          map[string]interface{}
 	Resource         Resource
 	UpdatedAt        time.Time
 }
@@ -35,10 +38,26 @@ func (doc *Document) TransformRevision() error {
 		if err := doc.transformRevisionInt(); err != nil {
 			return errors.Wrap(err, "error during resource change integer transformation")
 		}
-	case ResourceChangeString:
+	case ResourceChangeString, ResourceChangeHash:
 		if err := doc.transformRevisionString(); err != nil {
 			return errors.Wrap(err, "error during resource change string transformation")
 		}
+	case ResourceChangeNumeric:
+		value, err := revisionInt64(doc.ChangeFieldValue)
+		if err != nil {
+			return errors.Wrap(err, "error during resource change numeric transformation")
+		}
+		doc.ChangeFieldValue = value
+	case ResourceChangeSemver:
+		if err := doc.transformRevisionSemver(); err != nil {
+			return errors.Wrap(err, "error during resource change semver transformation")
+		}
+	case ResourceChangeComposite:
+		values, err := revisionComposite(doc.ChangeFieldValue)
+		if err != nil {
+			return errors.Wrap(err, "error during resource change composite transformation")
+		}
+		doc.ChangeFieldValue = formatRevisionComposite(values)
 	}
 
 	return nil
@@ -68,8 +87,26 @@ func (doc *Document) transformRevisionInt() error {
 	switch v := doc.ChangeFieldValue.(type) {
 	case int:
 		return nil
+	case int64:
+		doc.ChangeFieldValue = int(v)
+		return nil
+	case json.Number:
+		value, err := revisionInt64(v)
+		if err != nil {
+			return err
+		}
+		doc.ChangeFieldValue = int(value)
+		return nil
+	case float64:
+		// Numbers decoded from JSON are float64, only integral values without precision loss are
+		// accepted.
+		if v != math.Trunc(v) || math.Abs(v) > revisionMaxSafeFloat {
+			return fmt.Errorf("invalid revision '%v', expected a integer", v)
+		}
+		doc.ChangeFieldValue = int(v)
+		return nil
 	case string:
-		value, err := strconv.ParseInt(v, 10, 64)
+		value, err := strconv.Atoi(v)
 		if err != nil {
 			return errors.Wrapf(err, "error during parse '%s' to int", v)
 		}
@@ -87,6 +124,18 @@ func (doc *Document) transformRevisionString() error {
 	return fmt.Errorf("invalid revision type '%s'", reflect.TypeOf(doc.validChangeFieldValue()).Name())
 }
 
+func (doc *Document) transformRevisionSemver() error {
+	value, ok := doc.ChangeFieldValue.(string)
+	if !ok {
+		return fmt.Errorf("invalid revision type '%T'", doc.ChangeFieldValue)
+	}
+
+	if _, err := parseSemver(value); err != nil {
+		return err
+	}
+	return nil
+}
+
 // Valid indicates if the current document is valid.
 func (doc *Document) Valid() error {
 	if doc.Id == "" {
@@ -110,7 +159,7 @@ func (doc *Document) validChangeFieldValue() error {
 		if _, ok := doc.ChangeFieldValue.(time.Time); !ok {
 			return errors.New("invalid ChangeFieldValue, could not cast it to time.Time")
 		}
-	case ResourceChangeString:
+	case ResourceChangeString, ResourceChangeHash:
 		if _, ok := doc.ChangeFieldValue.(string); !ok {
 			return errors.New("invalid ChangeFieldValue, could not cast it to string")
 		}
@@ -118,12 +167,21 @@ func (doc *Document) validChangeFieldValue() error {
 		if _, ok := doc.ChangeFieldValue.(int); !ok {
 			return errors.New("invalid ChangeFieldValue, could not cast it to integer")
 		}
+	case ResourceChangeNumeric:
+		if _, ok := doc.ChangeFieldValue.(int64); !ok {
+			return errors.New("invalid ChangeFieldValue, could not cast it to int64")
+		}
+	case ResourceChangeSemver, ResourceChangeComposite:
+		if _, ok := doc.ChangeFieldValue.(string); !ok {
+			return errors.New("invalid ChangeFieldValue, could not cast it to string")
+		}
 	}
 
 	return nil
 }
 
-// Newer indicates if the current document is newer then the one passed as parameter.
+// Newer indicates if the current document is newer then the one passed as parameter. On the hash
+// kind, any different document is newer.
 func (doc *Document) Newer(reference *Document) (bool, error) {
 	if reference == nil {
 		return true, nil
@@ -136,6 +194,14 @@ func (doc *Document) Newer(reference *Document) (bool, error) {
 		return doc.newerInteger(reference.ChangeFieldValue)
 	case ResourceChangeString:
 		return doc.newerString(reference.ChangeFieldValue)
+	case ResourceChangeHash:
+		return doc.newerHash(reference.ChangeFieldValue)
+	case ResourceChangeNumeric:
+		return doc.newerNumeric(reference.ChangeFieldValue)
+	case ResourceChangeSemver:
+		return doc.newerSemver(reference.ChangeFieldValue)
+	case ResourceChangeComposite:
+		return doc.newerComposite(reference.ChangeFieldValue)
 	default:
 		return false, errors.New("invalid change kind")
 	}
@@ -183,6 +249,104 @@ func (doc *Document) newerString(rawReferenceValue interface{}) (bool, error) {
 	return docValue > referenceValue, nil
 }
 
+// newerHash indicates if the hash is different, the hashes can't be ordered, so, any change is
+// considered newer.
+func (doc *Document) newerHash(rawReferenceValue interface{}) (bool, error) {
+	docValue, ok := doc.ChangeFieldValue.(string)
+	if !ok {
+		return false, fmt.Errorf("could not cast rawDocValue(%v) to string", doc.ChangeFieldValue)
+	}
+
+	referenceValue, ok := rawReferenceValue.(string)
+	if !ok {
+		return false, fmt.Errorf("could not cast rawReferenceValue(%v) to string", rawReferenceValue)
+	}
+
+	return docValue != referenceValue, nil
+}
+
+func (doc *Document) newerNumeric(rawReferenceValue interface{}) (bool, error) {
+	docValue, err := revisionInt64(doc.ChangeFieldValue)
+	if err != nil {
+		return false, errors.Wrap(err, "invalid document revision")
+	}
+
+	referenceValue, err := revisionInt64(rawReferenceValue)
+	if err != nil {
+		return false, errors.Wrap(err, "invalid reference revision")
+	}
+
+	return docValue > referenceValue, nil
+}
+
+func (doc *Document) newerSemver(rawReferenceValue interface{}) (bool, error) {
+	rawDocValue, ok := doc.ChangeFieldValue.(string)
+	if !ok {
+		return false, fmt.Errorf("could not cast rawDocValue(%v) to string", doc.ChangeFieldValue)
+	}
+
+	rawReference, ok := rawReferenceValue.(string)
+	if !ok {
+		return false, fmt.Errorf("could not cast rawReferenceValue(%v) to string", rawReferenceValue)
+	}
+
+	docValue, err := parseSemver(rawDocValue)
+	if err != nil {
+		return false, errors.Wrap(err, "invalid document revision")
+	}
+
+	referenceValue, err := parseSemver(rawReference)
+	if err != nil {
+		return false, errors.Wrap(err, "invalid reference revision")
+	}
+
+	return docValue.compare(referenceValue) > 0, nil
+}
+
+func (doc *Document) newerComposite(rawReferenceValue interface{}) (bool, error) {
+	docValue, err := revisionComposite(doc.ChangeFieldValue)
+	if err != nil {
+		return false, errors.Wrap(err, "invalid document revision")
+	}
+
+	referenceValue, err := revisionComposite(rawReferenceValue)
+	if err != nil {
+		return false, errors.Wrap(err, "invalid reference revision")
+	}
+
+	result, err := compareRevisionComposite(docValue, referenceValue)
+	if err != nil {
+		return false, err
+	}
+	return result > 0, nil
+}
+
+// RevisionSortKey returns a string that keep the order of the semver and composite revisions when
+// compared byte by byte. It's used by the repositories that sort the revisions at the database.
+func (doc *Document) RevisionSortKey() (string, error) {
+	switch doc.Resource.Change.Kind {
+	case ResourceChangeSemver:
+		rawValue, ok := doc.ChangeFieldValue.(string)
+		if !ok {
+			return "", fmt.Errorf("invalid revision type '%T'", doc.ChangeFieldValue)
+		}
+
+		value, err := parseSemver(rawValue)
+		if err != nil {
+			return "", err
+		}
+		return value.sortKey(), nil
+	case ResourceChangeComposite:
+		values, err := revisionComposite(doc.ChangeFieldValue)
+		if err != nil {
+			return "", err
+		}
+		return revisionCompositeSortKey(values), nil
+	}
+
+	return "", fmt.Errorf("change kind '%s' don't have a sort key", doc.Resource.Change.Kind)
+}
+
 // DocumentRepositorier used to interact with Document data storage.
 type DocumentRepositorier interface {
 	FindOne(ctx context.Context, id string) (*Document, error)
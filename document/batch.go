@@ -0,0 +1,336 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package document
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+
+	"github.com/diegobernardes/flare"
+)
+
+const (
+	// Default max quantity of entries per batch.
+	serviceDefaultMaxBatchSize = 1000
+
+	// Default max size, in bytes, of the batch body.
+	serviceDefaultMaxBatchBodySize = 16 * 1024 * 1024
+
+	// Quantity of entries sent at once to the worker queue.
+	serviceBatchPushSize = 100
+)
+
+type batchEntry struct {
+	Id     string          `json:"id"`
+	Body   json.RawMessage `json:"body"`
+	Delete bool            `json:"delete"`
+}
+
+type batchResult struct {
+	Index  int    `json:"index"`
+	Id     string `json:"id,omitempty"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Results []batchResult
+}
+
+func (r *batchResponse) MarshalJSON() ([]byte, error) {
+	var accepted int
+	for _, result := range r.Results {
+		if result.Status == http.StatusAccepted {
+			accepted++
+		}
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"accepted": accepted,
+		"rejected": len(r.Results) - accepted,
+		"results":  r.Results,
+	})
+}
+
+// errBatchTooLarge is returned when the batch is above the max body size or quantity of entries.
+type errBatchTooLarge struct {
+	message string
+}
+
+func (e errBatchTooLarge) Error() string { return e.message }
+
+// batchReader returns a error when the body is bigger than the limit.
+type batchReader struct {
+	reader    io.Reader
+	limit     int64
+	remaining int64
+}
+
+func (br *batchReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > br.remaining+1 {
+		p = p[:br.remaining+1]
+	}
+
+	n, err := br.reader.Read(p)
+	br.remaining -= int64(n)
+	if br.remaining < 0 {
+		return 0, errBatchTooLarge{
+			message: fmt.Sprintf("batch body should have at most %d bytes", br.limit),
+		}
+	}
+	return n, err
+}
+
+// batchDecoder decode the entries from a JSON array or from NDJSON, one entry per line, as they
+// are read from the body.
+type batchDecoder struct {
+	decoder *json.Decoder
+	isArray bool
+	count   int
+	done    bool
+}
+
+func newBatchDecoder(r io.Reader) (*batchDecoder, error) {
+	reader := bufio.NewReader(r)
+	for {
+		c, err := reader.ReadByte()
+		if err == io.EOF {
+			return &batchDecoder{done: true}, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "error during batch parse")
+		}
+
+		if !unicode.IsSpace(rune(c)) {
+			if err = reader.UnreadByte(); err != nil {
+				return nil, errors.Wrap(err, "error during batch parse")
+			}
+
+			d := &batchDecoder{decoder: json.NewDecoder(reader), isArray: c == '['}
+			if d.isArray {
+				if _, err := d.decoder.Token(); err != nil {
+					return nil, errors.Wrap(err, "error during batch parse")
+				}
+			}
+			return d, nil
+		}
+	}
+}
+
+// next returns the next entry, the bool is false at the end of the batch.
+func (d *batchDecoder) next() (*batchEntry, bool, error) {
+	if d.done {
+		return nil, false, nil
+	}
+
+	if d.decoder.More() {
+		var entry batchEntry
+		if err := d.decoder.Decode(&entry); err != nil {
+			return nil, false, errors.Wrapf(err, "error during batch entry %d parse", d.count)
+		}
+		d.count++
+		return &entry, true, nil
+	}
+	d.done = true
+
+	if d.isArray {
+		if _, err := d.decoder.Token(); err != nil {
+			return nil, false, errors.Wrap(err, "error during batch parse")
+		}
+	}
+
+	if _, err := d.decoder.Token(); err != io.EOF {
+		if _, ok := errors.Cause(err).(errBatchTooLarge); ok {
+			return nil, false, err
+		}
+		return nil, false, errors.New("unexpected content after the batch entries")
+	}
+	return nil, false, nil
+}
+
+// HandleBatch receive the request to update and delete many documents at once. The entries are
+// sent as a JSON array or as NDJSON and each one has a result with the status, 202 when the entry
+// was accepted to be processed. The entries above the namespace ingestion rate have the status 429
+// and the response has the Retry-After header.
+//
+// The entries are decoded and pushed to the worker in groups while the body is read. When the
+// batch is invalid or too large before the first push, the whole batch is rejected, after it, the
+// error is the last result.
+func (s *Service) HandleBatch(w http.ResponseWriter, r *http.Request) {
+	decoder, err := newBatchDecoder(&batchReader{
+		reader: r.Body, limit: s.maxBatchBodySize, remaining: s.maxBatchBodySize,
+	})
+	if err != nil {
+		s.batchError(w, err)
+		return
+	}
+
+	var (
+		wait           time.Duration
+		pushed         bool
+		results        = make([]batchResult, 0)
+		pending        = make([]int, 0, serviceBatchPushSize)
+		pendingEntries = make([]batchEntry, 0, serviceBatchPushSize)
+	)
+
+	for {
+		entry, ok, err := decoder.next()
+		if err == nil && ok && len(results) == s.maxBatchSize {
+			err = errBatchTooLarge{
+				message: fmt.Sprintf("batch should have at most %d entries", s.maxBatchSize),
+			}
+		}
+
+		if err != nil {
+			if !pushed {
+				s.batchError(w, err)
+				return
+			}
+
+			result := batchResult{Index: len(results), Status: http.StatusBadRequest, Error: err.Error()}
+			if _, ok := errors.Cause(err).(errBatchTooLarge); ok {
+				result.Status = http.StatusRequestEntityTooLarge
+			}
+			results = append(results, result)
+			break
+		}
+
+		if !ok {
+			break
+		}
+
+		index := len(results)
+		results = append(results, batchResult{Index: index, Id: entry.Id})
+		if status, err := s.validBatchEntry(entry); err != nil {
+			results[index].Status, results[index].Error = status, err.Error()
+			continue
+		}
+
+		if status, err := s.authorizeBatchEntry(r, entry); err != nil {
+			results[index].Status, results[index].Error = status, err.Error()
+			continue
+		}
+
+		if s.limiter != nil {
+			if granted, entryWait := s.limiter.Take(r.Context(), 1); granted == 0 {
+				results[index].Status = http.StatusTooManyRequests
+				results[index].Error = "ingestion quota exceeded"
+				wait = entryWait
+				continue
+			}
+		}
+
+		pending = append(pending, index)
+		pendingEntries = append(pendingEntries, *entry)
+		if len(pending) == serviceBatchPushSize {
+			s.pushBatch(r, pendingEntries, pending, results)
+			pending, pendingEntries = pending[:0], pendingEntries[:0]
+			pushed = true
+		}
+	}
+
+	if len(results) == 0 {
+		s.writer.Error(w, "missing batch entries", nil, http.StatusBadRequest)
+		return
+	}
+
+	if len(pending) > 0 {
+		s.pushBatch(r, pendingEntries, pending, results)
+	}
+
+	var headers http.Header
+	if wait > 0 {
+		headers = http.Header{"Retry-After": []string{retryAfter(wait)}}
+	}
+	s.writer.Response(w, &batchResponse{Results: results}, http.StatusOK, headers)
+}
+
+func (s *Service) batchError(w http.ResponseWriter, err error) {
+	if _, ok := errors.Cause(err).(errBatchTooLarge); ok {
+		s.writer.Error(w, "batch too large", err, http.StatusRequestEntityTooLarge)
+		return
+	}
+	s.writer.Error(w, "invalid batch", err, http.StatusBadRequest)
+}
+
+// pushBatch push the entries, the indexes are the position of each entry at the results.
+func (s *Service) pushBatch(
+	r *http.Request, entries []batchEntry, indexes []int, results []batchResult,
+) {
+	pushEntries := make([]pushEntry, len(indexes))
+	for i, entry := range entries {
+		if entry.Delete {
+			pushEntries[i] = pushEntry{id: entry.Id, action: flare.SubscriptionTriggerDelete}
+		} else {
+			pushEntries[i] = pushEntry{
+				id: entry.Id, action: flare.SubscriptionTriggerUpdate, body: entry.Body,
+			}
+		}
+	}
+
+	for i, err := range s.pusher.pushBatch(r.Context(), pushEntries) {
+		result := &results[indexes[i]]
+		if err != nil {
+			result.Status = http.StatusInternalServerError
+			result.Error = errors.Wrap(err, "could not push the document to worker").Error()
+			continue
+		}
+		result.Status = http.StatusAccepted
+	}
+}
+
+func (s *Service) authorizeBatchEntry(r *http.Request, entry *batchEntry) (int, error) {
+	if s.authorize == nil {
+		return 0, nil
+	}
+
+	allowed, err := s.authorize(r.Context(), entry.Id)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(err, "error during authorization")
+	}
+
+	if !allowed {
+		return http.StatusForbidden, errors.New("document not allowed")
+	}
+	return 0, nil
+}
+
+func (s *Service) validBatchEntry(entry *batchEntry) (int, error) {
+	if entry.Id == "" {
+		return http.StatusBadRequest, errors.New("missing id")
+	}
+
+	hasBody := len(entry.Body) > 0 && !bytes.Equal(entry.Body, []byte("null"))
+	if entry.Delete {
+		if hasBody {
+			return http.StatusBadRequest, errors.New("body not allowed on delete")
+		}
+		return 0, nil
+	}
+
+	if !hasBody {
+		return http.StatusBadRequest, errors.New("missing body")
+	}
+
+	if entry.Body[0] != '{' {
+		return http.StatusBadRequest, errors.New("body should be a object")
+	}
+
+	if int64(len(entry.Body)) > s.maxSize {
+		return http.StatusRequestEntityTooLarge, fmt.Errorf(
+			"document body should have at most %d bytes", s.maxSize,
+		)
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,305 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package document
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+
+	infraHTTP "github.com/diegobernardes/flare/infra/http"
+	repoTest "github.com/diegobernardes/flare/repository/test"
+)
+
+type batchPushMock struct {
+	batches [][]pushEntry
+}
+
+func (bpm *batchPushMock) push(context.Context, string, string, []byte) error { return nil }
+
+func (bpm *batchPushMock) pushBatch(_ context.Context, entries []pushEntry) []error {
+	bpm.batches = append(bpm.batches, entries)
+	errs := make([]error, len(entries))
+	for i, entry := range entries {
+		if entry.id == "http://app.com/users/fail" {
+			errs[i] = errors.New("error during push")
+		}
+	}
+	return errs
+}
+
+type limiterMock struct {
+	available int
+}
+
+func (lm *limiterMock) Take(_ context.Context, n int) (int, time.Duration) {
+	if lm.available < n {
+		granted := lm.available
+		lm.available = 0
+		return granted, 1500 * time.Millisecond
+	}
+	lm.available -= n
+	return n, 0
+}
+
+func TestServiceHandleBatch(t *testing.T) {
+	Convey("Given a Service", t, func() {
+		writer, err := infraHTTP.NewWriter(log.NewNopLogger())
+		So(err, ShouldBeNil)
+
+		pusher := &batchPushMock{}
+		service, err := NewService(
+			ServiceDocumentRepository(repoTest.NewDocument()),
+			ServiceResourceRepository(repoTest.NewResource()),
+			ServiceGetDocumentId(func(*http.Request) string { return "" }),
+			ServicePusher(pusher),
+			ServiceMaxSize(32),
+			ServiceMaxBatchSize(150),
+			ServiceWriter(writer),
+		)
+		So(err, ShouldBeNil)
+
+		handle := func(body string) (int, map[string]interface{}) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/documents:batch", strings.NewReader(body))
+			service.HandleBatch(w, r)
+
+			result := make(map[string]interface{})
+			So(json.Unmarshal(w.Body.Bytes(), &result), ShouldBeNil)
+			return w.Code, result
+		}
+
+		status := func(result map[string]interface{}) []float64 {
+			var statuses []float64
+			for _, r := range result["results"].([]interface{}) {
+				statuses = append(statuses, r.(map[string]interface{})["status"].(float64))
+			}
+			return statuses
+		}
+
+		tests := []struct {
+			title string
+			body  string
+		}{
+			{
+				"It should accept a JSON array",
+				`[
+					{"id": "http://app.com/users/1", "body": {"revision": 1}},
+					{"id": "http://app.com/users/2", "delete": true},
+					{"body": {"revision": 1}},
+					{"id": "http://app.com/users/3", "body": [1]},
+					{"id": "http://app.com/users/4"},
+					{"id": "http://app.com/users/5", "delete": true, "body": {}},
+					{"id": "http://app.com/users/6", "body": {"name": "a very long name to exceed"}},
+					{"id": "http://app.com/users/fail", "body": {"revision": 1}}
+				]`,
+			},
+			{
+				"It should accept NDJSON",
+				strings.Join([]string{
+					`{"id": "http://app.com/users/1", "body": {"revision": 1}}`,
+					`{"id": "http://app.com/users/2", "delete": true}`,
+					`{"body": {"revision": 1}}`,
+					`{"id": "http://app.com/users/3", "body": [1]}`,
+					`{"id": "http://app.com/users/4"}`,
+					`{"id": "http://app.com/users/5", "delete": true, "body": {}}`,
+					`{"id": "http://app.com/users/6", "body": {"name": "a very long name to exceed"}}`,
+					`{"id": "http://app.com/users/fail", "body": {"revision": 1}}`,
+				}, "\n"),
+			},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				code, result := handle(tt.body)
+				So(code, ShouldEqual, http.StatusOK)
+				So(result["accepted"], ShouldEqual, 2)
+				So(result["rejected"], ShouldEqual, 6)
+				So(status(result), ShouldResemble, []float64{202, 202, 400, 400, 400, 400, 413, 500})
+
+				So(pusher.batches, ShouldHaveLength, 1)
+				So(pusher.batches[0], ShouldHaveLength, 3)
+				So(pusher.batches[0][0].action, ShouldEqual, "update")
+				So(string(pusher.batches[0][0].body), ShouldEqual, `{"revision": 1}`)
+				So(pusher.batches[0][1].action, ShouldEqual, "delete")
+			})
+		}
+
+		Convey("It should push the entries in batches", func() {
+			entries := make([]string, 150)
+			for i := range entries {
+				entries[i] = fmt.Sprintf(`{"id": "http://app.com/users/%d", "delete": true}`, i)
+			}
+
+			code, result := handle(strings.Join(entries, "\n"))
+			So(code, ShouldEqual, http.StatusOK)
+			So(result["accepted"], ShouldEqual, 150)
+			So(pusher.batches, ShouldHaveLength, 2)
+			So(pusher.batches[0], ShouldHaveLength, 100)
+			So(pusher.batches[1], ShouldHaveLength, 50)
+		})
+
+		Convey("It should reject the invalid batches", func() {
+			tests := []struct {
+				body   string
+				status int
+			}{
+				{"", http.StatusBadRequest},
+				{"[]", http.StatusBadRequest},
+				{`[{"id": "1", "delete": true}`, http.StatusBadRequest},
+				{`{"id": "1", "delete": true}]`, http.StatusBadRequest},
+				{`{"id": "1", "delete": true} invalid`, http.StatusBadRequest},
+			}
+
+			for _, tt := range tests {
+				code, _ := handle(tt.body)
+				So(code, ShouldEqual, tt.status)
+			}
+			So(pusher.batches, ShouldBeEmpty)
+		})
+
+		Convey("It should end the batch with the error after the first push", func() {
+			entries := make([]string, 151)
+			for i := range entries {
+				entries[i] = `{"id": "1", "delete": true}`
+			}
+
+			code, result := handle(strings.Join(entries, "\n"))
+			So(code, ShouldEqual, http.StatusOK)
+			So(result["accepted"], ShouldEqual, 150)
+			So(status(result)[150], ShouldEqual, http.StatusRequestEntityTooLarge)
+			So(pusher.batches, ShouldHaveLength, 2)
+
+			code, result = handle("[" + strings.Join(entries[:120], ",") + ",invalid]")
+			So(code, ShouldEqual, http.StatusOK)
+			So(result["accepted"], ShouldEqual, 120)
+			So(status(result)[120], ShouldEqual, http.StatusBadRequest)
+		})
+	})
+
+	Convey("Given a Service with a small batch body size", t, func() {
+		writer, err := infraHTTP.NewWriter(log.NewNopLogger())
+		So(err, ShouldBeNil)
+
+		pusher := &batchPushMock{}
+		service, err := NewService(
+			ServiceDocumentRepository(repoTest.NewDocument()),
+			ServiceResourceRepository(repoTest.NewResource()),
+			ServiceGetDocumentId(func(*http.Request) string { return "" }),
+			ServicePusher(pusher),
+			ServiceMaxBatchBodySize(64),
+			ServiceWriter(writer),
+		)
+		So(err, ShouldBeNil)
+
+		handle := func(body string) int {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/documents:batch", strings.NewReader(body))
+			service.HandleBatch(w, r)
+			return w.Code
+		}
+
+		Convey("It should reject the batches above the body size", func() {
+			entry := `{"id": "http://app.com/users/1", "delete": true}`
+			So(handle(entry), ShouldEqual, http.StatusOK)
+			So(handle(entry+"\n"+entry), ShouldEqual, http.StatusRequestEntityTooLarge)
+			So(pusher.batches, ShouldHaveLength, 1)
+		})
+	})
+
+	Convey("Given a Service with a authorizer", t, func() {
+		writer, err := infraHTTP.NewWriter(log.NewNopLogger())
+		So(err, ShouldBeNil)
+
+		pusher := &batchPushMock{}
+		service, err := NewService(
+			ServiceDocumentRepository(repoTest.NewDocument()),
+			ServiceResourceRepository(repoTest.NewResource()),
+			ServiceGetDocumentId(func(*http.Request) string { return "" }),
+			ServicePusher(pusher),
+			ServiceWriter(writer),
+			ServiceAuthorizer(func(_ context.Context, id string) (bool, error) {
+				if id == "http://other.com/error" {
+					return false, errors.New("error at repository")
+				}
+				return strings.HasPrefix(id, "http://app.com/"), nil
+			}),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("It should only push the allowed entries", func() {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/documents:batch", strings.NewReader(`[
+				{"id": "http://app.com/users/1", "delete": true},
+				{"id": "http://other.com/users/1", "delete": true},
+				{"id": "http://other.com/error", "delete": true}
+			]`))
+			service.HandleBatch(w, r)
+			So(w.Code, ShouldEqual, http.StatusOK)
+
+			var result struct {
+				Results []struct {
+					Status int `json:"status"`
+				} `json:"results"`
+			}
+			So(json.Unmarshal(w.Body.Bytes(), &result), ShouldBeNil)
+			So(result.Results, ShouldHaveLength, 3)
+			So(result.Results[0].Status, ShouldEqual, http.StatusAccepted)
+			So(result.Results[1].Status, ShouldEqual, http.StatusForbidden)
+			So(result.Results[2].Status, ShouldEqual, http.StatusInternalServerError)
+
+			So(pusher.batches, ShouldHaveLength, 1)
+			So(pusher.batches[0], ShouldHaveLength, 1)
+		})
+	})
+
+	Convey("Given a Service with a limiter", t, func() {
+		writer, err := infraHTTP.NewWriter(log.NewNopLogger())
+		So(err, ShouldBeNil)
+
+		pusher := &batchPushMock{}
+		service, err := NewService(
+			ServiceDocumentRepository(repoTest.NewDocument()),
+			ServiceResourceRepository(repoTest.NewResource()),
+			ServiceGetDocumentId(func(*http.Request) string { return "" }),
+			ServicePusher(pusher),
+			ServiceWriter(writer),
+			ServiceLimiter(&limiterMock{available: 2}),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("It should reject the entries above the ingestion rate", func() {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/documents:batch", strings.NewReader(`[
+				{"id": "http://app.com/users/1", "delete": true},
+				{"id": "http://app.com/users/2", "delete": true},
+				{"id": "http://app.com/users/3", "delete": true}
+			]`))
+			service.HandleBatch(w, r)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Header().Get("Retry-After"), ShouldEqual, "2")
+
+			var result struct {
+				Results []struct {
+					Status int `json:"status"`
+				} `json:"results"`
+			}
+			So(json.Unmarshal(w.Body.Bytes(), &result), ShouldBeNil)
+			So(result.Results, ShouldHaveLength, 3)
+			So(result.Results[0].Status, ShouldEqual, http.StatusAccepted)
+			So(result.Results[1].Status, ShouldEqual, http.StatusAccepted)
+			So(result.Results[2].Status, ShouldEqual, http.StatusTooManyRequests)
+		})
+	})
+}
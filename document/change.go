@@ -0,0 +1,219 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package document
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/diegobernardes/flare"
+	infraHTTP "github.com/diegobernardes/flare/infra/http"
+)
+
+// Max quantity of changes returned per request.
+const changeMaxLimit = 1000
+
+type change flare.Change
+
+func (c *change) MarshalJSON() ([]byte, error) {
+	type document struct {
+		Id               string                 `json:"id"`
+		ChangeFieldValue interface{}            `json:"changeFieldValue"`
+		Content          map[string]interface{} `json:"content,omitempty"`
+	}
+
+	return json.Marshal(&struct {
+		Action    string   `json:"action"`
+		Document  document `json:"document"`
+		CreatedAt string   `json:"createdAt"`
+	}{
+		Action: c.Action,
+		Document: document{
+			Id:               c.Document.Id,
+			ChangeFieldValue: c.Document.ChangeFieldValue,
+			Content:          c.Document.Content,
+		},
+		CreatedAt: c.CreatedAt.Format(time.RFC3339),
+	})
+}
+
+type changeResponse struct {
+	Changes []change
+	Cursor  string
+}
+
+func (r *changeResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"changes": r.Changes,
+		"cursor":  r.Cursor,
+	})
+}
+
+// ChangeService implements the HTTP handler to poll the change feed of a resource.
+type ChangeService struct {
+	resourceRepository flare.ResourceRepositorier
+	changeRepository   flare.ChangeRepositorier
+	getResourceID      func(*http.Request) string
+	writer             *infraHTTP.Writer
+	parsePagination    func(r *http.Request) (*flare.Pagination, error)
+}
+
+// HandleIndex receive the request to list the changes of a resource after the cursor. The
+// response has the cursor to be used at the next request, even if there are no changes.
+func (s *ChangeService) HandleIndex(w http.ResponseWriter, r *http.Request) {
+	pag, err := s.parsePagination(r)
+	if err != nil {
+		s.writer.Error(w, "error during pagination parse", err, http.StatusBadRequest)
+		return
+	}
+
+	if err = pag.Valid(); err != nil {
+		s.writer.Error(w, "invalid pagination", err, http.StatusBadRequest)
+		return
+	}
+
+	if pag.Limit < 1 || pag.Limit > changeMaxLimit {
+		s.writer.Error(
+			w, "invalid pagination",
+			fmt.Errorf("limit should be between 1 and %d", changeMaxLimit), http.StatusBadRequest,
+		)
+		return
+	}
+
+	if pag.Offset != 0 {
+		s.writer.Error(
+			w, "invalid pagination", errors.New("offset not supported, use the cursor"),
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	resourceID := s.getResourceID(r)
+	sequence, err := s.decodeCursor(resourceID, r.URL.Query().Get("cursor"))
+	if err != nil {
+		s.writer.Error(w, "invalid cursor", err, http.StatusBadRequest)
+		return
+	}
+
+	if _, err = s.resourceRepository.FindOne(r.Context(), resourceID); err != nil {
+		status := http.StatusInternalServerError
+		if errRepo, ok := err.(flare.ResourceRepositoryError); ok && errRepo.NotFound() {
+			status = http.StatusNotFound
+		}
+
+		s.writer.Error(w, "error during resource search", err, status)
+		return
+	}
+
+	changes, err := s.changeRepository.FindAll(r.Context(), resourceID, sequence, pag.Limit)
+	if err != nil {
+		s.writer.Error(w, "error during changes search", err, http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]change, len(changes))
+	for i := range changes {
+		result[i] = (change)(changes[i])
+		sequence = changes[i].Sequence
+	}
+
+	s.writer.Response(w, &changeResponse{
+		Changes: result,
+		Cursor:  s.encodeCursor(resourceID, sequence),
+	}, http.StatusOK, nil)
+}
+
+// The cursor is opaque to the clients, internally it's the resource id and the sequence of the last
+// change returned.
+func (s *ChangeService) encodeCursor(resourceID string, sequence int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%d", resourceID, sequence)))
+}
+
+func (s *ChangeService) decodeCursor(resourceID, cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	content, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.Wrapf(err, "error during cursor '%s' decode", cursor)
+	}
+
+	i := strings.LastIndex(string(content), ":")
+	if i < 0 || string(content[:i]) != resourceID {
+		return 0, fmt.Errorf("cursor '%s' don't belong to the resource", cursor)
+	}
+
+	sequence, err := strconv.ParseInt(string(content[i+1:]), 10, 64)
+	if err != nil || sequence < 0 {
+		return 0, fmt.Errorf("cursor '%s' has a invalid sequence", cursor)
+	}
+	return sequence, nil
+}
+
+// NewChangeService initialize the service to handle HTTP requests.
+func NewChangeService(options ...func(*ChangeService)) (*ChangeService, error) {
+	service := &ChangeService{}
+
+	for _, option := range options {
+		option(service)
+	}
+
+	if service.resourceRepository == nil {
+		return nil, errors.New("resourceRepository not found")
+	}
+
+	if service.changeRepository == nil {
+		return nil, errors.New("changeRepository not found")
+	}
+
+	if service.getResourceID == nil {
+		return nil, errors.New("getResourceID not found")
+	}
+
+	if service.parsePagination == nil {
+		return nil, errors.New("parsePagination not found")
+	}
+
+	if service.writer == nil {
+		return nil, errors.New("writer not found")
+	}
+
+	return service, nil
+}
+
+// ChangeServiceResourceRepository set the repository to access the resources.
+func ChangeServiceResourceRepository(repo flare.ResourceRepositorier) func(*ChangeService) {
+	return func(s *ChangeService) { s.resourceRepository = repo }
+}
+
+// ChangeServiceChangeRepository set the repository to access the change feed.
+func ChangeServiceChangeRepository(repo flare.ChangeRepositorier) func(*ChangeService) {
+	return func(s *ChangeService) { s.changeRepository = repo }
+}
+
+// ChangeServiceGetResourceID set the function to fetch the resourceId from the URL.
+func ChangeServiceGetResourceID(fn func(*http.Request) string) func(*ChangeService) {
+	return func(s *ChangeService) { s.getResourceID = fn }
+}
+
+// ChangeServiceParsePagination set the function used to parse the limit.
+func ChangeServiceParsePagination(
+	fn func(r *http.Request) (*flare.Pagination, error),
+) func(*ChangeService) {
+	return func(s *ChangeService) { s.parsePagination = fn }
+}
+
+// ChangeServiceWriter set the function that return the content to client.
+func ChangeServiceWriter(writer *infraHTTP.Writer) func(*ChangeService) {
+	return func(s *ChangeService) { s.writer = writer }
+}
@@ -0,0 +1,244 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package document
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/diegobernardes/flare"
+	infraHTTP "github.com/diegobernardes/flare/infra/http"
+	"github.com/diegobernardes/flare/repository/memory"
+)
+
+type triggerMock struct{}
+
+func (triggerMock) Update(context.Context, *flare.Document) error { return nil }
+func (triggerMock) Delete(context.Context, *flare.Document) error { return nil }
+
+type taskPusherMock struct{}
+
+func (taskPusherMock) Push(context.Context, []byte) error { return nil }
+
+// changeRepositoryBarrier hold the first two FindLatest until both are done, this way, the workers
+// always race to create the change.
+type changeRepositoryBarrier struct {
+	flare.ChangeRepositorier
+	mutex   sync.Mutex
+	calls   int
+	barrier sync.WaitGroup
+}
+
+func (c *changeRepositoryBarrier) FindLatest(
+	ctx context.Context, resourceId, documentId string,
+) (*flare.Change, error) {
+	change, err := c.ChangeRepositorier.FindLatest(ctx, resourceId, documentId)
+
+	c.mutex.Lock()
+	c.calls++
+	calls := c.calls
+	c.mutex.Unlock()
+
+	if calls <= 2 {
+		c.barrier.Done()
+		c.barrier.Wait()
+	}
+	return change, err
+}
+
+type changeConflictError struct{}
+
+func (changeConflictError) Error() string  { return "conflict" }
+func (changeConflictError) NotFound() bool { return false }
+func (changeConflictError) Conflict() bool { return true }
+
+// changeRepositoryConflict always fail the creates with a conflict.
+type changeRepositoryConflict struct {
+	flare.ChangeRepositorier
+	mutex sync.Mutex
+	calls int
+}
+
+func (c *changeRepositoryConflict) Create(context.Context, *flare.Change, int64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.calls++
+	return changeConflictError{}
+}
+
+func TestChangeFeed(t *testing.T) {
+	Convey("Given a Worker and a ChangeService with the memory repositories", t, func() {
+		resourceRepository := memory.NewResource()
+		So(resourceRepository.Create(context.Background(), &flare.Resource{
+			ID:        "123",
+			Addresses: []string{"http://app.com"},
+			Path:      "/users/{id}",
+			Change:    flare.ResourceChange{Field: "revision", Kind: flare.ResourceChangeInteger},
+		}), ShouldBeNil)
+
+		changeRepository := memory.NewChange()
+
+		worker := &Worker{}
+		So(worker.Init(
+			WorkerPusher(taskPusherMock{}),
+			WorkerResourceRepository(resourceRepository),
+			WorkerDocumentRepository(memory.NewDocument()),
+			WorkerSubscriptionRepository(memory.NewSubscription()),
+			WorkerSubscriptionTrigger(triggerMock{}),
+			WorkerChangeRepository(changeRepository),
+		), ShouldBeNil)
+
+		process := func(action string, revision int) {
+			body, err := json.Marshal(map[string]interface{}{"revision": revision})
+			So(err, ShouldBeNil)
+
+			content, err := worker.marshal(context.Background(), "http://app.com/users/1", action, body)
+			So(err, ShouldBeNil)
+			So(worker.Process(context.Background(), content), ShouldBeNil)
+		}
+
+		writer, err := infraHTTP.NewWriter(log.NewNopLogger())
+		So(err, ShouldBeNil)
+
+		service, err := NewChangeService(
+			ChangeServiceResourceRepository(resourceRepository),
+			ChangeServiceChangeRepository(changeRepository),
+			ChangeServiceGetResourceID(func(*http.Request) string { return "123" }),
+			ChangeServiceParsePagination(infraHTTP.ParsePagination(2)),
+			ChangeServiceWriter(writer),
+		)
+		So(err, ShouldBeNil)
+
+		index := func(query string) (int, map[string]interface{}) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/resources/123/changes?"+query, nil)
+			service.HandleIndex(w, r)
+
+			result := make(map[string]interface{})
+			So(json.Unmarshal(w.Body.Bytes(), &result), ShouldBeNil)
+			return w.Code, result
+		}
+
+		action := func(change interface{}) interface{} {
+			return change.(map[string]interface{})["action"]
+		}
+
+		Convey("It should record the changes without subscriptions", func() {
+			process(flare.SubscriptionTriggerUpdate, 1)
+			process(flare.SubscriptionTriggerUpdate, 2)
+			process(flare.SubscriptionTriggerUpdate, 1)
+			process(flare.SubscriptionTriggerDelete, 0)
+			process(flare.SubscriptionTriggerDelete, 0)
+
+			status, result := index("")
+			So(status, ShouldEqual, http.StatusOK)
+			changes := result["changes"].([]interface{})
+			So(changes, ShouldHaveLength, 2)
+			So(action(changes[0]), ShouldEqual, flare.SubscriptionTriggerCreate)
+			So(action(changes[1]), ShouldEqual, flare.SubscriptionTriggerUpdate)
+
+			status, result = index("cursor=" + result["cursor"].(string))
+			So(status, ShouldEqual, http.StatusOK)
+			changes = result["changes"].([]interface{})
+			So(changes, ShouldHaveLength, 1)
+			So(action(changes[0]), ShouldEqual, flare.SubscriptionTriggerDelete)
+
+			cursor := result["cursor"].(string)
+			status, result = index("cursor=" + cursor)
+			So(status, ShouldEqual, http.StatusOK)
+			So(result["changes"], ShouldBeEmpty)
+			So(result["cursor"], ShouldEqual, cursor)
+
+			Convey("It should detect a create after the delete", func() {
+				process(flare.SubscriptionTriggerUpdate, 1)
+				_, result = index("cursor=" + cursor)
+				changes = result["changes"].([]interface{})
+				So(changes, ShouldHaveLength, 1)
+				So(action(changes[0]), ShouldEqual, flare.SubscriptionTriggerCreate)
+			})
+		})
+
+		Convey("It should record only the newer revisions with concurrent updates", func() {
+			repository := &changeRepositoryBarrier{ChangeRepositorier: changeRepository}
+			repository.barrier.Add(2)
+			So(worker.Init(WorkerChangeRepository(repository)), ShouldBeNil)
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 2)
+			for _, revision := range []int{1, 2} {
+				wg.Add(1)
+				go func(revision int) {
+					defer wg.Done()
+					body, _ := json.Marshal(map[string]interface{}{"revision": revision})
+					content, err := worker.marshal(
+						context.Background(), "http://app.com/users/1", flare.SubscriptionTriggerUpdate, body,
+					)
+					if err == nil {
+						err = worker.Process(context.Background(), content)
+					}
+					errs <- err
+				}(revision)
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				So(err, ShouldBeNil)
+			}
+
+			changes, err := changeRepository.FindAll(context.Background(), "123", 0, 10)
+			So(err, ShouldBeNil)
+			So(changes, ShouldNotBeEmpty)
+			So(changes[0].Action, ShouldEqual, flare.SubscriptionTriggerCreate)
+			for i := 1; i < len(changes); i++ {
+				So(changes[i].Action, ShouldEqual, flare.SubscriptionTriggerUpdate)
+				previous := changes[i-1].Document.ChangeFieldValue
+				So(changes[i].Document.ChangeFieldValue, ShouldBeGreaterThan, previous)
+			}
+		})
+
+		Convey("It should give up after the max attempts when the conflicts persist", func() {
+			repository := &changeRepositoryConflict{ChangeRepositorier: changeRepository}
+			So(worker.Init(WorkerChangeRepository(repository)), ShouldBeNil)
+
+			content, err := worker.marshal(
+				context.Background(),
+				"http://app.com/users/1",
+				flare.SubscriptionTriggerUpdate,
+				[]byte(`{"revision": 1}`),
+			)
+			So(err, ShouldBeNil)
+			So(worker.Process(context.Background(), content), ShouldNotBeNil)
+			So(repository.calls, ShouldEqual, workerFeedAttempts)
+		})
+
+		Convey("It should not retry the conflicts after the context is done", func() {
+			repository := &changeRepositoryConflict{ChangeRepositorier: changeRepository}
+			So(worker.Init(WorkerChangeRepository(repository)), ShouldBeNil)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			err := worker.feed(ctx, &flare.Document{
+				Id:       "http://app.com/users/1",
+				Resource: flare.Resource{ID: "123"},
+			}, flare.SubscriptionTriggerUpdate)
+			So(err, ShouldNotBeNil)
+			So(repository.calls, ShouldEqual, 0)
+		})
+
+		Convey("It should reject the invalid requests", func() {
+			for _, query := range []string{"cursor=invalid", "cursor=MTI0OjE", "offset=1", "limit=0"} {
+				status, _ := index(query)
+				So(status, ShouldEqual, http.StatusBadRequest)
+			}
+		})
+	})
+}
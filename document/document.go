@@ -14,6 +14,13 @@ import (
 
 type pusher interface {
 	push(ctx context.Context, id, action string, body []byte) error
+	pushBatch(ctx context.Context, entries []pushEntry) []error
+}
+
+type pushEntry struct {
+	id     string
+	action string
+	body   []byte
 }
 
 type document flare.Document
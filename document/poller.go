@@ -0,0 +1,583 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package document
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+
+	"github.com/diegobernardes/flare"
+)
+
+const (
+	// Interval between the checks for resources that should be polled.
+	pollerDefaultScanInterval = 5 * time.Second
+
+	// Max size, in bytes, of the listing responses.
+	pollerMaxListingSize = 10 * 1024 * 1024
+)
+
+// Poller fetch the documents of the resources that can't send the changes to Flare. Each resource
+// with the poll enabled is fetched at its interval and the documents with a new revision are pushed
+// to the worker, the same way as the documents received by the service.
+//
+// The ETag and Last-Modified of the responses are kept to do conditional requests and the last
+// revision of each document is kept to push only the changes. The state is kept in memory, so,
+// after a restart, all the documents are pushed again and the worker discard the old revisions.
+type Poller struct {
+	resourceRepository flare.ResourceRepositorier
+	pusher             pusher
+	httpClient         *http.Client
+	logger             log.Logger
+	maxSize            int64
+	scanInterval       time.Duration
+
+	mutex     sync.Mutex
+	states    map[string]*pollerState
+	ctx       context.Context
+	ctxCancel func()
+	wg        sync.WaitGroup
+}
+
+type pollerState struct {
+	running    bool
+	lastRun    time.Time
+	validators map[string]pollerValidator
+	revisions  map[string]interface{}
+}
+
+type pollerValidator struct {
+	etag         string
+	lastModified string
+}
+
+// pollerLimiter wait between the requests to respect the resource rate limit.
+type pollerLimiter struct {
+	interval time.Duration
+	last     time.Time
+}
+
+func (l *pollerLimiter) wait(ctx context.Context) error {
+	if l.interval == 0 {
+		return nil
+	}
+
+	if delay := time.Until(l.last.Add(l.interval)); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	l.last = time.Now()
+	return nil
+}
+
+// Start the poller.
+func (p *Poller) Start() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(p.scanInterval)
+		defer ticker.Stop()
+
+		for {
+			p.scan(time.Now())
+
+			select {
+			case <-ticker.C:
+			case <-p.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop the poller and wait the running polls.
+func (p *Poller) Stop() {
+	p.ctxCancel()
+	p.wg.Wait()
+}
+
+// scan start the poll of the resources that reached the interval.
+func (p *Poller) scan(now time.Time) {
+	resources, err := p.resources()
+	if err != nil {
+		level.Error(p.logger).Log("error", err.Error(), "message", "error during resources search")
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	enabled := make(map[string]struct{})
+	for _, resource := range resources {
+		if !resource.Poll.Enabled() {
+			continue
+		}
+		enabled[resource.ID] = struct{}{}
+
+		state, ok := p.states[resource.ID]
+		if !ok {
+			state = &pollerState{
+				validators: make(map[string]pollerValidator),
+				revisions:  make(map[string]interface{}),
+			}
+			p.states[resource.ID] = state
+		}
+
+		if state.running || now.Sub(state.lastRun) < resource.Poll.Interval {
+			continue
+		}
+		state.running, state.lastRun = true, now
+
+		p.wg.Add(1)
+		go func(resource flare.Resource, state *pollerState) {
+			defer p.wg.Done()
+			p.poll(flare.ContextWithNamespace(p.ctx, resource.Namespace), &resource, state)
+
+			p.mutex.Lock()
+			state.running = false
+			p.mutex.Unlock()
+		}(resource, state)
+	}
+
+	for id, state := range p.states {
+		if _, ok := enabled[id]; !ok && !state.running {
+			delete(p.states, id)
+		}
+	}
+}
+
+// resources returns the resources of all the namespaces.
+func (p *Poller) resources() ([]flare.Resource, error) {
+	var (
+		result     []flare.Resource
+		pagination = &flare.Pagination{Limit: 100}
+		ctx        = flare.ContextWithNamespace(p.ctx, flare.NamespaceAll)
+	)
+
+	for {
+		resources, resourcesPag, err := p.resourceRepository.FindAll(ctx, pagination)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, resources...)
+
+		pagination.Offset += pagination.Limit
+		if len(resources) == 0 || pagination.Offset >= resourcesPag.Total {
+			return result, nil
+		}
+	}
+}
+
+// poll fetch the resource documents. Only one poll per resource runs at time, so the state don't
+// need to be protected.
+func (p *Poller) poll(ctx context.Context, resource *flare.Resource, state *pollerState) {
+	limiter := &pollerLimiter{}
+	if resource.Poll.RateLimit > 0 {
+		limiter.interval = time.Duration(float64(time.Second) / resource.Poll.RateLimit)
+	}
+
+	var err error
+	if resource.Poll.Listing != "" {
+		err = p.pollListing(ctx, resource, state)
+	} else {
+		err = p.pollDocuments(ctx, resource, state, limiter)
+	}
+
+	if err != nil {
+		level.Error(p.logger).Log(
+			"error", err.Error(), "resource", resource.ID, "message", "error during resource poll",
+		)
+	}
+}
+
+func (p *Poller) pollListing(
+	ctx context.Context, resource *flare.Resource, state *pollerState,
+) error {
+	address := resource.Addresses[0]
+	endpoint := address + resource.Poll.Listing
+	body, validator, status, err := p.fetch(ctx, state, endpoint, pollerMaxListingSize)
+	if err != nil {
+		return err
+	}
+
+	switch status {
+	case http.StatusNotModified:
+		return nil
+	case http.StatusOK:
+	default:
+		return fmt.Errorf("unexpected status code %d from '%s'", status, endpoint)
+	}
+
+	var content interface{}
+	if err = json.Unmarshal(body, &content); err != nil {
+		return errors.Wrapf(err, "error during listing '%s' parse", endpoint)
+	}
+
+	rawItems, _ := pollerLookup(content, resource.Poll.Items)
+	items, ok := rawItems.([]interface{})
+	if !ok {
+		return fmt.Errorf("listing '%s' don't have a list at '%s'", endpoint, resource.Poll.Items)
+	}
+
+	var (
+		entries   []pushEntry
+		revisions []interface{}
+	)
+	for _, rawItem := range items {
+		item, ok := rawItem.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		id, err := p.documentID(resource, address, item)
+		if err != nil {
+			level.Error(p.logger).Log(
+				"error", err.Error(), "resource", resource.ID, "message", "error during document id build",
+			)
+			continue
+		}
+
+		body, revision, changed, err := p.document(resource, state, id, item)
+		if err != nil {
+			level.Error(p.logger).Log(
+				"error", err.Error(), "document", id, "message", "error during document check",
+			)
+			continue
+		}
+
+		if changed {
+			entries = append(entries, pushEntry{id: id, action: flare.SubscriptionTriggerUpdate, body: body})
+			revisions = append(revisions, revision)
+		}
+	}
+
+	var pushErr error
+	for start := 0; start < len(entries); start += serviceBatchPushSize {
+		end := start + serviceBatchPushSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		for i, err := range p.pusher.pushBatch(ctx, entries[start:end]) {
+			if err != nil {
+				pushErr = errors.Wrap(err, "error during document push")
+				continue
+			}
+			state.revisions[entries[start+i].id] = revisions[start+i]
+		}
+	}
+
+	// The validator is only kept when all the documents were pushed, otherwise, the next request
+	// could return not modified and the documents would be lost.
+	if pushErr != nil {
+		return pushErr
+	}
+	state.validators[endpoint] = validator
+	return nil
+}
+
+func (p *Poller) pollDocuments(
+	ctx context.Context, resource *flare.Resource, state *pollerState, limiter *pollerLimiter,
+) error {
+	for _, endpoint := range resource.Poll.Documents {
+		if err := limiter.wait(ctx); err != nil {
+			return err
+		}
+
+		body, validator, status, err := p.fetch(ctx, state, endpoint, p.maxSize)
+		if err != nil {
+			level.Error(p.logger).Log(
+				"error", err.Error(), "document", endpoint, "message", "error during document fetch",
+			)
+			continue
+		}
+
+		switch status {
+		case http.StatusNotModified:
+			continue
+		case http.StatusNotFound, http.StatusGone:
+			if err = p.pollDelete(ctx, state, endpoint); err != nil {
+				return err
+			}
+			continue
+		case http.StatusOK:
+		default:
+			level.Error(p.logger).Log(
+				"document", endpoint,
+				"message", fmt.Sprintf("unexpected status code %d during document fetch", status),
+			)
+			continue
+		}
+
+		content := make(map[string]interface{})
+		if err = json.Unmarshal(body, &content); err != nil {
+			level.Error(p.logger).Log(
+				"error", err.Error(), "document", endpoint, "message", "error during document parse",
+			)
+			continue
+		}
+
+		body, revision, changed, err := p.document(resource, state, endpoint, content)
+		if err != nil {
+			level.Error(p.logger).Log(
+				"error", err.Error(), "document", endpoint, "message", "error during document check",
+			)
+			continue
+		}
+
+		if changed {
+			err = p.pusher.push(ctx, endpoint, flare.SubscriptionTriggerUpdate, body)
+			if err != nil {
+				return errors.Wrap(err, "error during document push")
+			}
+			state.revisions[endpoint] = revision
+		}
+		state.validators[endpoint] = validator
+	}
+	return nil
+}
+
+// pollDelete push the delete of the documents that were seen before.
+func (p *Poller) pollDelete(ctx context.Context, state *pollerState, endpoint string) error {
+	if _, ok := state.revisions[endpoint]; !ok {
+		return nil
+	}
+
+	if err := p.pusher.push(ctx, endpoint, flare.SubscriptionTriggerDelete, nil); err != nil {
+		return errors.Wrap(err, "error during document push")
+	}
+	delete(state.revisions, endpoint)
+	delete(state.validators, endpoint)
+	return nil
+}
+
+// document returns the body and the revision of the document and if it has changed since the last
+// poll.
+func (p *Poller) document(
+	resource *flare.Resource, state *pollerState, id string, content map[string]interface{},
+) ([]byte, interface{}, bool, error) {
+	revision, ok := resource.Change.Value(content)
+	if !ok || revision == nil {
+		return nil, nil, false, fmt.Errorf("missing the change field '%s'", resource.Change.Field)
+	}
+
+	if last, ok := state.revisions[id]; ok && reflect.DeepEqual(last, revision) {
+		return nil, nil, false, nil
+	}
+
+	body, err := json.Marshal(content)
+	if err != nil {
+		return nil, nil, false, errors.Wrap(err, "error during document marshal")
+	}
+
+	if int64(len(body)) > p.maxSize {
+		return nil, nil, false, fmt.Errorf("document body should have at most %d bytes", p.maxSize)
+	}
+	return body, revision, true, nil
+}
+
+// documentID build the document id replacing the resource path wildcards by the item fields.
+func (p *Poller) documentID(
+	resource *flare.Resource, address string, item map[string]interface{},
+) (string, error) {
+	segments := strings.Split(resource.Path, "/")
+	for i, segment := range segments {
+		if len(segment) < 2 || segment[0] != '{' || segment[len(segment)-1] != '}' {
+			continue
+		}
+
+		wildcard := strings.TrimSpace(segment[1 : len(segment)-1])
+		field := resource.Poll.Wildcards[wildcard]
+		if field == "" {
+			field = wildcard
+		}
+
+		rawValue, ok := pollerLookup(item, field)
+		if !ok {
+			return "", fmt.Errorf("missing the field '%s' of the wildcard '%s'", field, wildcard)
+		}
+
+		var value string
+		switch v := rawValue.(type) {
+		case string:
+			value = v
+		case float64:
+			value = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return "", fmt.Errorf("invalid value '%v' at the field '%s'", rawValue, field)
+		}
+
+		if value == "" {
+			return "", fmt.Errorf("blank value at the field '%s'", field)
+		}
+		segments[i] = url.PathEscape(value)
+	}
+
+	return address + strings.Join(segments, "/"), nil
+}
+
+// fetch do a conditional request to the endpoint. The validator should be kept only after the
+// response is processed.
+func (p *Poller) fetch(
+	ctx context.Context, state *pollerState, endpoint string, maxSize int64,
+) ([]byte, pollerValidator, int, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, pollerValidator{}, 0, errors.Wrapf(err, "error during request '%s' create", endpoint)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+
+	if validator, ok := state.validators[endpoint]; ok {
+		if validator.etag != "" {
+			req.Header.Set("If-None-Match", validator.etag)
+		}
+
+		if validator.lastModified != "" {
+			req.Header.Set("If-Modified-Since", validator.lastModified)
+		}
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, pollerValidator{}, 0, errors.Wrapf(err, "error during request to '%s'", endpoint)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, pollerValidator{}, resp.StatusCode, nil
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, pollerValidator{}, 0, errors.Wrapf(
+			err, "error during response read from '%s'", endpoint,
+		)
+	}
+
+	if int64(len(body)) > maxSize {
+		return nil, pollerValidator{}, 0, fmt.Errorf(
+			"response from '%s' should have at most %d bytes", endpoint, maxSize,
+		)
+	}
+
+	validator := pollerValidator{
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+	return body, validator, resp.StatusCode, nil
+}
+
+// pollerLookup returns the value at the path, with the fields separated by dots. A empty path
+// returns the content.
+func pollerLookup(content interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return content, true
+	}
+
+	for _, field := range strings.Split(path, ".") {
+		object, ok := content.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		if content, ok = object[field]; !ok {
+			return nil, false
+		}
+	}
+	return content, true
+}
+
+// NewPoller returns a configured poller.
+func NewPoller(options ...func(*Poller)) (*Poller, error) {
+	p := &Poller{
+		scanInterval: pollerDefaultScanInterval,
+		states:       make(map[string]*pollerState),
+	}
+
+	for _, option := range options {
+		option(p)
+	}
+
+	if p.resourceRepository == nil {
+		return nil, errors.New("resourceRepository not found")
+	}
+
+	if p.pusher == nil {
+		return nil, errors.New("pusher not found")
+	}
+
+	if p.httpClient == nil {
+		return nil, errors.New("httpClient not found")
+	}
+
+	if p.logger == nil {
+		return nil, errors.New("logger not found")
+	}
+
+	if p.maxSize == 0 {
+		p.maxSize = serviceDefaultMaxSize
+	} else if p.maxSize < 0 {
+		return nil, errors.New("invalid maxSize")
+	}
+
+	if p.scanInterval <= 0 {
+		return nil, errors.New("invalid scanInterval")
+	}
+
+	p.ctx, p.ctxCancel = context.WithCancel(context.Background())
+	return p, nil
+}
+
+// PollerResourceRepository set the repository to access the resources.
+func PollerResourceRepository(repo flare.ResourceRepositorier) func(*Poller) {
+	return func(p *Poller) { p.resourceRepository = repo }
+}
+
+// PollerPusher set the pusher to enqueue the documents to be processed async.
+func PollerPusher(pu pusher) func(*Poller) {
+	return func(p *Poller) { p.pusher = pu }
+}
+
+// PollerHTTPClient set the client used to fetch the documents.
+func PollerHTTPClient(client *http.Client) func(*Poller) {
+	return func(p *Poller) { p.httpClient = client }
+}
+
+// PollerLogger set the logger.
+func PollerLogger(logger log.Logger) func(*Poller) {
+	return func(p *Poller) { p.logger = logger }
+}
+
+// PollerMaxSize set the max size, in bytes, of the document body. Default value: 128KB.
+func PollerMaxSize(size int64) func(*Poller) {
+	return func(p *Poller) { p.maxSize = size }
+}
+
+// PollerScanInterval set the interval between the checks for resources that should be polled.
+func PollerScanInterval(interval time.Duration) func(*Poller) {
+	return func(p *Poller) { p.scanInterval = interval }
+}
@@ -0,0 +1,195 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package document
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/diegobernardes/flare"
+	"github.com/diegobernardes/flare/repository/memory"
+)
+
+type pollerPushMock struct {
+	mutex   sync.Mutex
+	entries []pushEntry
+}
+
+func (ppm *pollerPushMock) push(_ context.Context, id, action string, body []byte) error {
+	ppm.mutex.Lock()
+	defer ppm.mutex.Unlock()
+	ppm.entries = append(ppm.entries, pushEntry{id: id, action: action, body: body})
+	return nil
+}
+
+func (ppm *pollerPushMock) pushBatch(ctx context.Context, entries []pushEntry) []error {
+	errs := make([]error, len(entries))
+	for i, entry := range entries {
+		errs[i] = ppm.push(ctx, entry.id, entry.action, entry.body)
+	}
+	return errs
+}
+
+func (ppm *pollerPushMock) take() []pushEntry {
+	ppm.mutex.Lock()
+	defer ppm.mutex.Unlock()
+	entries := ppm.entries
+	ppm.entries = nil
+	return entries
+}
+
+func TestPoller(t *testing.T) {
+	Convey("Given a Poller and a API", t, func() {
+		var (
+			mutex    sync.Mutex
+			listing  = `{"data": [{"userId": 1, "revision": 1}, {"userId": 2, "revision": 1}]}`
+			etag     = `"1"`
+			status   = http.StatusOK
+			requests []time.Time
+		)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			requests = append(requests, time.Now())
+
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+
+			switch r.URL.Path {
+			case "/users":
+				fmt.Fprint(w, listing)
+			default:
+				w.WriteHeader(status)
+				fmt.Fprint(w, `{"revision": 1}`)
+			}
+		}))
+		defer server.Close()
+
+		pusher := &pollerPushMock{}
+		poller, err := NewPoller(
+			PollerResourceRepository(memory.NewResource()),
+			PollerPusher(pusher),
+			PollerHTTPClient(server.Client()),
+			PollerLogger(log.NewNopLogger()),
+		)
+		So(err, ShouldBeNil)
+
+		state := &pollerState{
+			validators: make(map[string]pollerValidator),
+			revisions:  make(map[string]interface{}),
+		}
+
+		resource := &flare.Resource{
+			ID:        "123",
+			Addresses: []string{server.URL},
+			Path:      "/users/{id}",
+			Change:    flare.ResourceChange{Field: "revision", Kind: flare.ResourceChangeInteger},
+		}
+
+		Convey("It should push the changed documents from the listing", func() {
+			resource.Poll = flare.ResourcePoll{
+				Interval:  time.Minute,
+				Listing:   "/users",
+				Items:     "data",
+				Wildcards: map[string]string{"id": "userId"},
+			}
+
+			poller.poll(context.Background(), resource, state)
+			entries := pusher.take()
+			So(entries, ShouldHaveLength, 2)
+			So(entries[0].id, ShouldEqual, server.URL+"/users/1")
+			So(entries[0].action, ShouldEqual, flare.SubscriptionTriggerUpdate)
+			So(string(entries[0].body), ShouldEqual, `{"revision":1,"userId":1}`)
+			So(entries[1].id, ShouldEqual, server.URL+"/users/2")
+
+			poller.poll(context.Background(), resource, state)
+			So(pusher.take(), ShouldBeEmpty)
+
+			mutex.Lock()
+			listing = `{"data": [{"userId": 1, "revision": 1}, {"userId": 2, "revision": 2}]}`
+			etag = `"2"`
+			mutex.Unlock()
+
+			poller.poll(context.Background(), resource, state)
+			entries = pusher.take()
+			So(entries, ShouldHaveLength, 1)
+			So(entries[0].id, ShouldEqual, server.URL+"/users/2")
+		})
+
+		Convey("It should push the documents and the deletes from the document list", func() {
+			resource.Poll = flare.ResourcePoll{
+				Interval:  time.Minute,
+				Documents: []string{server.URL + "/users/1", server.URL + "/users/2"},
+				RateLimit: 20,
+			}
+
+			poller.poll(context.Background(), resource, state)
+			entries := pusher.take()
+			So(entries, ShouldHaveLength, 2)
+			So(entries[0].id, ShouldEqual, server.URL+"/users/1")
+			So(string(entries[0].body), ShouldEqual, `{"revision":1}`)
+
+			mutex.Lock()
+			So(requests, ShouldHaveLength, 2)
+			So(requests[1].Sub(requests[0]), ShouldBeGreaterThanOrEqualTo, 45*time.Millisecond)
+			mutex.Unlock()
+
+			poller.poll(context.Background(), resource, state)
+			So(pusher.take(), ShouldBeEmpty)
+
+			mutex.Lock()
+			etag, status = `"2"`, http.StatusNotFound
+			mutex.Unlock()
+
+			poller.poll(context.Background(), resource, state)
+			entries = pusher.take()
+			So(entries, ShouldHaveLength, 2)
+			So(entries[0].action, ShouldEqual, flare.SubscriptionTriggerDelete)
+			So(entries[1].action, ShouldEqual, flare.SubscriptionTriggerDelete)
+
+			poller.poll(context.Background(), resource, state)
+			So(pusher.take(), ShouldBeEmpty)
+		})
+	})
+
+	Convey("Given a list of invalid options", t, func() {
+		tests := [][]func(*Poller){
+			{},
+			{PollerResourceRepository(memory.NewResource())},
+			{PollerResourceRepository(memory.NewResource()), PollerPusher(&pollerPushMock{})},
+			{
+				PollerResourceRepository(memory.NewResource()),
+				PollerPusher(&pollerPushMock{}),
+				PollerHTTPClient(http.DefaultClient),
+			},
+			{
+				PollerResourceRepository(memory.NewResource()),
+				PollerPusher(&pollerPushMock{}),
+				PollerHTTPClient(http.DefaultClient),
+				PollerLogger(log.NewNopLogger()),
+				PollerScanInterval(0),
+			},
+		}
+
+		Convey("It should return a error", func() {
+			for _, tt := range tests {
+				_, err := NewPoller(tt...)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
@@ -5,9 +5,14 @@
 package document
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -22,8 +27,22 @@ type Service struct {
 	getDocumentId      func(*http.Request) string
 	pusher             pusher
 	writer             *infraHTTP.Writer
+	maxSize            int64
+	maxBatchSize       int
+	maxBatchBodySize   int64
+	authorize          func(ctx context.Context, id string) (bool, error)
+	limiter            limiter
 }
 
+// limiter control the ingestion rate of the namespaces. It returns how many of the n documents can
+// be ingested and, if not all, the time until the next one can.
+type limiter interface {
+	Take(ctx context.Context, n int) (int, time.Duration)
+}
+
+// Default max size, in bytes, of the document body.
+const serviceDefaultMaxSize = 128 * 1024
+
 // HandleShow receive the request to show a given document.
 func (s *Service) HandleShow(w http.ResponseWriter, r *http.Request) {
 	d, err := s.documentRepository.FindOne(r.Context(), s.getDocumentId(r))
@@ -52,7 +71,7 @@ func (s *Service) HandleUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	content, err := ioutil.ReadAll(r.Body)
+	content, err := ioutil.ReadAll(io.LimitReader(r.Body, s.maxSize+1))
 	if err != nil {
 		s.writer.Error(
 			w,
@@ -63,11 +82,25 @@ func (s *Service) HandleUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if int64(len(content)) > s.maxSize {
+		s.writer.Error(
+			w,
+			"document too large",
+			fmt.Errorf("document body should have at most %d bytes", s.maxSize),
+			http.StatusRequestEntityTooLarge,
+		)
+		return
+	}
+
 	if len(content) == 0 {
 		s.writer.Error(w, "missing document body", nil, http.StatusBadRequest)
 		return
 	}
 
+	if !s.take(w, r) {
+		return
+	}
+
 	err = s.pusher.push(r.Context(), s.getDocumentId(r), flare.SubscriptionTriggerUpdate, content)
 	if err != nil {
 		s.writer.Error(
@@ -94,6 +127,10 @@ func (s *Service) HandleDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.take(w, r) {
+		return
+	}
+
 	err := s.pusher.push(r.Context(), s.getDocumentId(r), flare.SubscriptionTriggerDelete, nil)
 	if err != nil {
 		s.writer.Error(
@@ -108,9 +145,36 @@ func (s *Service) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	s.writer.Response(w, nil, http.StatusAccepted, nil)
 }
 
+// take consume the ingestion rate of the namespace. When the rate is exceeded, the client receive a
+// 429 with the time to retry.
+func (s *Service) take(w http.ResponseWriter, r *http.Request) bool {
+	if s.limiter == nil {
+		return true
+	}
+
+	granted, wait := s.limiter.Take(r.Context(), 1)
+	if granted == 1 {
+		return true
+	}
+
+	w.Header().Set("Retry-After", retryAfter(wait))
+	s.writer.Error(
+		w,
+		"ingestion quota exceeded",
+		fmt.Errorf("namespace '%s' exceeded the ingestion rate", flare.NamespaceFromContext(r.Context())),
+		http.StatusTooManyRequests,
+	)
+	return false
+}
+
+// retryAfter format the wait at the Retry-After header, in seconds.
+func retryAfter(wait time.Duration) string {
+	return strconv.Itoa(int(math.Max(1, math.Ceil(wait.Seconds()))))
+}
+
 // NewService initialize the service to handle HTTP requests.
 func NewService(options ...func(*Service)) (*Service, error) {
-	s := &Service{}
+	s := &Service{maxBatchSize: serviceDefaultMaxBatchSize}
 
 	for _, option := range options {
 		option(s)
@@ -136,6 +200,22 @@ func NewService(options ...func(*Service)) (*Service, error) {
 		return nil, errors.New("writer not Found")
 	}
 
+	if s.maxSize == 0 {
+		s.maxSize = serviceDefaultMaxSize
+	} else if s.maxSize < 0 {
+		return nil, errors.New("invalid maxSize")
+	}
+
+	if s.maxBatchSize <= 0 {
+		return nil, errors.New("invalid maxBatchSize")
+	}
+
+	if s.maxBatchBodySize == 0 {
+		s.maxBatchBodySize = serviceDefaultMaxBatchBodySize
+	} else if s.maxBatchBodySize < 0 {
+		return nil, errors.New("invalid maxBatchBodySize")
+	}
+
 	return s, nil
 }
 
@@ -159,6 +239,32 @@ func ServicePusher(p pusher) func(*Service) {
 	return func(s *Service) { s.pusher = p }
 }
 
+// ServiceMaxSize set the max size, in bytes, of the document body. Default value: 128KB.
+func ServiceMaxSize(size int64) func(*Service) {
+	return func(s *Service) { s.maxSize = size }
+}
+
+// ServiceMaxBatchSize set the max quantity of entries per batch.
+func ServiceMaxBatchSize(size int) func(*Service) {
+	return func(s *Service) { s.maxBatchSize = size }
+}
+
+// ServiceMaxBatchBodySize set the max size, in bytes, of the batch body. Default value: 16MB.
+func ServiceMaxBatchBodySize(size int64) func(*Service) {
+	return func(s *Service) { s.maxBatchBodySize = size }
+}
+
+// ServiceAuthorizer set the function to check if the client can change the document. It's used at
+// the batch, where each entry can be of a different document. It's optional.
+func ServiceAuthorizer(fn func(ctx context.Context, id string) (bool, error)) func(*Service) {
+	return func(s *Service) { s.authorize = fn }
+}
+
+// ServiceLimiter set the limiter of the namespaces ingestion rate. It's optional.
+func ServiceLimiter(l limiter) func(*Service) {
+	return func(s *Service) { s.limiter = l }
+}
+
 // ServiceWriter set the writer to send the content to client.
 func ServiceWriter(writer *infraHTTP.Writer) func(*Service) {
 	return func(s *Service) { s.writer = writer }
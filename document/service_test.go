@@ -217,6 +217,63 @@ func TestServiceHandleUpdate(t *testing.T) {
 			})
 		}
 	})
+
+	Convey("Given a Service with a max size", t, func() {
+		writer, err := infraHTTP.NewWriter(log.NewNopLogger())
+		So(err, ShouldBeNil)
+
+		service, err := NewService(
+			ServiceDocumentRepository(repoTest.NewDocument()),
+			ServiceResourceRepository(repoTest.NewResource()),
+			ServiceGetDocumentId(func(r *http.Request) string { return "123" }),
+			ServicePusher(newPushMock(nil)),
+			ServiceMaxSize(10),
+			ServiceWriter(writer),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("The request should be invalid because the body is too large", func() {
+			req := httptest.NewRequest(
+				http.MethodPut,
+				"http://documents/http://app.com/123",
+				bytes.NewBuffer(infraTest.Load("serviceHandleUpdate.input.json")),
+			)
+
+			test.Runner(
+				http.StatusRequestEntityTooLarge,
+				http.Header{"Content-Type": []string{"application/json"}},
+				service.HandleUpdate,
+				req,
+				infraTest.Load("serviceHandleUpdate.invalid.4.json"),
+			)
+		})
+	})
+
+	Convey("Given a Service with a exhausted limiter", t, func() {
+		writer, err := infraHTTP.NewWriter(log.NewNopLogger())
+		So(err, ShouldBeNil)
+
+		service, err := NewService(
+			ServiceDocumentRepository(repoTest.NewDocument()),
+			ServiceResourceRepository(repoTest.NewResource()),
+			ServiceGetDocumentId(func(r *http.Request) string { return "123" }),
+			ServicePusher(newPushMock(nil)),
+			ServiceLimiter(&limiterMock{}),
+			ServiceWriter(writer),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("The request should be rejected with the time to retry", func() {
+			w := httptest.NewRecorder()
+			service.HandleUpdate(w, httptest.NewRequest(
+				http.MethodPut,
+				"http://documents/http://app.com/123",
+				bytes.NewBuffer(infraTest.Load("serviceHandleUpdate.input.json")),
+			))
+			So(w.Code, ShouldEqual, http.StatusTooManyRequests)
+			So(w.Header().Get("Retry-After"), ShouldEqual, "2")
+		})
+	})
 }
 
 func TestServiceHandleDelete(t *testing.T) {
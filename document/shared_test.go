@@ -17,6 +17,14 @@ func (pm *pushMock) push(ctx context.Context, id, action string, body []byte) er
 	return nil
 }
 
+func (pm *pushMock) pushBatch(ctx context.Context, entries []pushEntry) []error {
+	errs := make([]error, len(entries))
+	for i := range entries {
+		errs[i] = pm.err
+	}
+	return errs
+}
+
 func newPushMock(err error) *pushMock {
 	return &pushMock{err}
 }
@@ -5,14 +5,24 @@
 package document
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/diegobernardes/flare"
 	"github.com/diegobernardes/flare/infra/task"
+	"github.com/diegobernardes/flare/infra/trace"
+)
+
+// The max attempts to write a document change at the feed when other workers keep writing changes
+// of the same document. Between the attempts, the worker wait the attempt times the backoff.
+const (
+	workerFeedAttempts = 5
+	workerFeedBackoff  = 10 * time.Millisecond
 )
 
 // Worker is used to async process all the create, update and delete operations on documents.
@@ -22,15 +32,36 @@ type Worker struct {
 	documentRepository     flare.DocumentRepositorier
 	subscriptionRepository flare.SubscriptionRepositorier
 	subscriptionTrigger    flare.SubscriptionTrigger
+	changeRepository       flare.ChangeRepositorier
+	listener               listener
+	tracer                 *trace.Tracer
 }
 
-// Process process the enqueued documents.
-func (w *Worker) Process(ctx context.Context, rawContent []byte) error {
+// listener is used to know if there are clients, besides the subscriptions, waiting for changes.
+type listener interface {
+	Listening(resourceID string) bool
+}
+
+// Process process the enqueued documents. The span continues the trace received with the
+// document, if any. The messages without namespace are from the default one.
+func (w *Worker) Process(ctx context.Context, rawContent []byte) (err error) {
 	content, id, action, err := w.extractContent(rawContent)
 	if err != nil {
 		return errors.Wrap(err, "error during message uncompress")
 	}
 
+	namespace, _ := content["namespace"].(string)
+	ctx = flare.ContextWithNamespace(ctx, namespace)
+
+	ctx, span := w.tracer.StartSpan(
+		trace.ExtractMap(ctx, content), "document "+action, trace.SpanKindConsumer,
+	)
+	span.SetAttribute("document.id", id)
+	defer func() {
+		span.SetError(err)
+		span.Finish()
+	}()
+
 	switch action {
 	case flare.SubscriptionTriggerCreate, flare.SubscriptionTriggerUpdate:
 		rawBody, ok := content["body"].(string)
@@ -60,7 +91,7 @@ func (w *Worker) Process(ctx context.Context, rawContent []byte) error {
 }
 
 func (w *Worker) push(ctx context.Context, id, action string, body []byte) error {
-	content, err := w.marshal(id, action, body)
+	content, err := w.marshal(ctx, id, action, body)
 	if err != nil {
 		return errors.Wrap(err, "error during message compress")
 	}
@@ -71,6 +102,40 @@ func (w *Worker) push(ctx context.Context, id, action string, body []byte) error
 	return nil
 }
 
+// pushBatch enqueue the entries at once when the pusher support batches. The result has one error
+// per entry.
+func (w *Worker) pushBatch(ctx context.Context, entries []pushEntry) []error {
+	errs := make([]error, len(entries))
+	contents := make([][]byte, 0, len(entries))
+	indexes := make([]int, 0, len(entries))
+	for i, entry := range entries {
+		content, err := w.marshal(ctx, entry.id, entry.action, entry.body)
+		if err != nil {
+			errs[i] = errors.Wrap(err, "error during message compress")
+			continue
+		}
+		contents = append(contents, content)
+		indexes = append(indexes, i)
+	}
+
+	pusher, ok := w.pusher.(task.BatchPusher)
+	if !ok {
+		for i, content := range contents {
+			if err := w.pusher.Push(ctx, content); err != nil {
+				errs[indexes[i]] = errors.Wrap(err, "error during job enqueue")
+			}
+		}
+		return errs
+	}
+
+	for i, err := range pusher.PushBatch(ctx, contents) {
+		if err != nil {
+			errs[indexes[i]] = errors.Wrap(err, "error during job enqueue")
+		}
+	}
+	return errs
+}
+
 func (w *Worker) extractContent(rawContent []byte) (map[string]interface{}, string, string, error) {
 	content, err := w.unmarshal(rawContent)
 	if err != nil {
@@ -105,6 +170,10 @@ func (w *Worker) processDelete(ctx context.Context, id string) error {
 	}
 	document.Resource = *resource
 
+	if err = w.feed(ctx, document, flare.SubscriptionTriggerDelete); err != nil {
+		return errors.Wrap(err, "error during change feed write")
+	}
+
 	if err = w.subscriptionTrigger.Delete(ctx, document); err != nil {
 		return errors.Wrap(err, "error during document change trigger")
 	}
@@ -121,7 +190,8 @@ func (w *Worker) processUpdate(ctx context.Context, id, action string, body []by
 	if err != nil {
 		return errors.Wrap(err, "error during check if the document resource has subscriptions")
 	}
-	if !hasSubscr {
+	if !hasSubscr && w.changeRepository == nil &&
+		(w.listener == nil || !w.listener.Listening(document.Resource.ID)) {
 		return nil
 	}
 
@@ -129,18 +199,114 @@ func (w *Worker) processUpdate(ctx context.Context, id, action string, body []by
 		return errors.Wrap(err, "error during document persistence")
 	}
 
+	if err = w.feed(ctx, document, action); err != nil {
+		return errors.Wrap(err, "error during change feed write")
+	}
+
 	if err := w.subscriptionTrigger.Update(ctx, document); err != nil {
 		return errors.Wrap(err, "error during document change trigger")
 	}
 	return nil
 }
 
-func (w *Worker) marshal(id, action string, body []byte) ([]byte, error) {
-	content, err := json.Marshal(map[string]interface{}{
+// feed write the document change at the resource change feed. The action is detected from the
+// newest change of the document, this way, the older revisions and the repeated deletes are
+// discarded. When other worker wrote a change of the document in between, the detection is done
+// again, until the max attempts. After that, the error is returned and the message is processed
+// again later.
+func (w *Worker) feed(ctx context.Context, document *flare.Document, action string) error {
+	if w.changeRepository == nil {
+		return nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return errors.Wrap(err, "error during change feed write")
+		}
+
+		err := w.feedChange(ctx, document, action)
+		errRepo, ok := errors.Cause(err).(flare.ChangeRepositoryError)
+		if !ok || !errRepo.Conflict() {
+			return err
+		}
+
+		if attempt == workerFeedAttempts {
+			return errors.Wrap(err, fmt.Sprintf(
+				"could not write the document change after %d attempts", workerFeedAttempts,
+			))
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Duration(attempt) * workerFeedBackoff):
+		}
+	}
+}
+
+func (w *Worker) feedChange(ctx context.Context, document *flare.Document, action string) error {
+	latest, err := w.changeRepository.FindLatest(ctx, document.Resource.ID, document.Id)
+	if err != nil {
+		if errRepo, ok := err.(flare.ChangeRepositoryError); !ok || !errRepo.NotFound() {
+			return errors.Wrap(err, "error during the search of the document newest change")
+		}
+		latest = nil
+	}
+	removed := latest == nil || latest.Action == flare.SubscriptionTriggerDelete
+
+	switch {
+	case action == flare.SubscriptionTriggerDelete:
+		if removed {
+			return nil
+		}
+	case removed:
+		action = flare.SubscriptionTriggerCreate
+	default:
+		reference := latest.Document
+		reference.Resource = document.Resource
+		if err = reference.TransformRevision(); err != nil {
+			return errors.Wrap(err, "error during the newest change revision parse")
+		}
+
+		newer, err := document.Newer(&reference)
+		if err != nil {
+			return errors.Wrap(err, "error during check if document is newer")
+		}
+		if !newer {
+			return nil
+		}
+		action = flare.SubscriptionTriggerUpdate
+	}
+
+	change := &flare.Change{Resource: document.Resource, Document: *document, Action: action}
+	if action == flare.SubscriptionTriggerDelete {
+		change.Document.Content = nil
+	}
+
+	var sequence int64
+	if latest != nil {
+		sequence = latest.Sequence
+	}
+
+	if err = w.changeRepository.Create(ctx, change, sequence); err != nil {
+		return errors.Wrap(err, "error during change create")
+	}
+	return nil
+}
+
+// marshal build the message envelope. The trace context and the namespace at ctx are sent with
+// the message to be continued by the Process.
+func (w *Worker) marshal(ctx context.Context, id, action string, body []byte) ([]byte, error) {
+	rawContent := map[string]interface{}{
 		"id":     id,
 		"action": action,
 		"body":   string(body),
-	})
+	}
+	if namespace := flare.NamespaceFromContext(ctx); namespace != flare.NamespaceDefault {
+		rawContent["namespace"] = namespace
+	}
+	trace.InjectMap(ctx, rawContent)
+
+	content, err := json.Marshal(rawContent)
 	if err != nil {
 		return nil, errors.Wrap(err, "error during message marshal")
 	}
@@ -155,11 +321,34 @@ func (w *Worker) unmarshal(rawContent []byte) (map[string]interface{}, error) {
 	return content, nil
 }
 
+// contentFloat convert the json.Number to float64, the type the filters and the repositories
+// expect from the content. The conversion is done in place.
+func contentFloat(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if result, err := v.Float64(); err == nil {
+			return result
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = contentFloat(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = contentFloat(item)
+		}
+	}
+	return value
+}
+
 func (w *Worker) parseHandleUpdateDocument(
 	ctx context.Context, rawContent []byte, id string,
 ) (*flare.Document, error) {
+	// The numbers are kept as json.Number to not lose the precision of the numeric revisions.
 	content := make(map[string]interface{})
-	if err := json.Unmarshal(rawContent, &content); err != nil {
+	decoder := json.NewDecoder(bytes.NewReader(rawContent))
+	decoder.UseNumber()
+	if err := decoder.Decode(&content); err != nil {
 		return nil, errors.Wrap(err, "invalid body content")
 	}
 
@@ -168,10 +357,12 @@ func (w *Worker) parseHandleUpdateDocument(
 		return nil, errors.Wrap(err, "error during resource search")
 	}
 
+	revision, _ := resource.Change.Value(content)
 	document := &flare.Document{
 		Id:               id,
 		Resource:         *resource,
-		ChangeFieldValue: content[resource.Change.Field],
+		ChangeFieldValue: revision,
+		Content:          contentFloat(content).(map[string]interface{}),
 	}
 
 	if err = document.TransformRevision(); err != nil {
@@ -234,6 +425,23 @@ func WorkerSubscriptionRepository(repo flare.SubscriptionRepositorier) func(*Wor
 	return func(w *Worker) { w.subscriptionRepository = repo }
 }
 
+// WorkerChangeRepository set the repository of the change feed. It's optional, without it the
+// changes are not recorded.
+func WorkerChangeRepository(repo flare.ChangeRepositorier) func(*Worker) {
+	return func(w *Worker) { w.changeRepository = repo }
+}
+
+// WorkerListener set the listener checked when the resource don't have subscriptions.
+func WorkerListener(l listener) func(*Worker) {
+	return func(w *Worker) { w.listener = l }
+}
+
+// WorkerTracer set the tracer of the documents processing. It's optional, without it the trace
+// context is propagated but the spans are not exported.
+func WorkerTracer(tracer *trace.Tracer) func(*Worker) {
+	return func(w *Worker) { w.tracer = tracer }
+}
+
 // WorkerSubscriptionTrigger set the subscription trigger processor.
 func WorkerSubscriptionTrigger(trigger flare.SubscriptionTrigger) func(*Worker) {
 	return func(w *Worker) { w.subscriptionTrigger = trigger }
@@ -5,10 +5,15 @@
 package document
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/diegobernardes/flare"
+	"github.com/diegobernardes/flare/infra/trace"
+	"github.com/diegobernardes/flare/repository/memory"
 )
 
 func TestWorkerMarshal(t *testing.T) {
@@ -36,7 +41,7 @@ func TestWorkerMarshal(t *testing.T) {
 		Convey("The output should be valid", func() {
 			for _, tt := range tests {
 				w := &Worker{}
-				content, err := w.marshal(tt.id, tt.action, tt.body)
+				content, err := w.marshal(context.Background(), tt.id, tt.action, tt.body)
 				So(err, ShouldBeNil)
 
 				b1, b2 := make(map[string]interface{}), make(map[string]interface{})
@@ -51,3 +56,70 @@ func TestWorkerMarshal(t *testing.T) {
 		})
 	})
 }
+
+func TestWorkerMarshalTrace(t *testing.T) {
+	Convey("Given a context with a trace", t, func() {
+		traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		sc, err := trace.Parse(traceparent, "vendor=value")
+		So(err, ShouldBeNil)
+		ctx := trace.ContextWithRemote(context.Background(), sc)
+
+		Convey("The trace context should be at the message", func() {
+			w := &Worker{}
+			content, err := w.marshal(ctx, "123", "update", []byte("{}"))
+			So(err, ShouldBeNil)
+
+			raw, err := w.unmarshal(content)
+			So(err, ShouldBeNil)
+			So(raw["traceparent"], ShouldEqual, traceparent)
+			So(raw["tracestate"], ShouldEqual, "vendor=value")
+
+			extracted, ok := trace.FromContext(trace.ExtractMap(context.Background(), raw))
+			So(ok, ShouldBeTrue)
+			So(extracted, ShouldResemble, sc)
+		})
+	})
+}
+
+func TestWorkerMarshalNamespace(t *testing.T) {
+	Convey("Given a context with a namespace", t, func() {
+		ctx := flare.ContextWithNamespace(context.Background(), "team-a")
+
+		Convey("The namespace should be at the message", func() {
+			w := &Worker{}
+			content, err := w.marshal(ctx, "123", "update", []byte("{}"))
+			So(err, ShouldBeNil)
+
+			raw, err := w.unmarshal(content)
+			So(err, ShouldBeNil)
+			So(raw["namespace"], ShouldEqual, "team-a")
+		})
+	})
+}
+
+func TestWorkerParseHandleUpdateDocument(t *testing.T) {
+	Convey("Given a Worker with a numeric resource", t, func() {
+		resourceRepository := memory.NewResource()
+		So(resourceRepository.Create(context.Background(), &flare.Resource{
+			ID:        "123",
+			Addresses: []string{"http://app.com"},
+			Path:      "/users/{id}",
+			Change:    flare.ResourceChange{Field: "revision", Kind: flare.ResourceChangeNumeric},
+		}), ShouldBeNil)
+		w := &Worker{resourceRepository: resourceRepository}
+
+		Convey("The revision should not lose the precision", func() {
+			document, err := w.parseHandleUpdateDocument(
+				context.Background(),
+				[]byte(`{"revision":9007199254740993,"total":1.5,"items":[{"price":2}]}`),
+				"http://app.com/users/1",
+			)
+			So(err, ShouldBeNil)
+			So(document.ChangeFieldValue, ShouldEqual, int64(9007199254740993))
+			So(document.Content["total"], ShouldEqual, float64(1.5))
+			So(document.Content["items"], ShouldResemble, []interface{}{
+				map[string]interface{}{"price": float64(2)},
+			})
+		})
+	})
+}
@@ -637,6 +637,45 @@ func TestDocumentNewerString(t *testing.T) {
 	})
 }
 
+func TestDocumentNewerHash(t *testing.T) {
+	Convey("Given a list of documents", t, func() {
+		tests := []struct {
+			document Document
+			value    interface{}
+			newer    bool
+		}{
+			{Document{ChangeFieldValue: "a1"}, "b2", true},
+			{Document{ChangeFieldValue: "b2"}, "a1", true},
+			{Document{ChangeFieldValue: "a1"}, "a1", false},
+		}
+
+		Convey("Any different hash should be newer", func() {
+			for _, tt := range tests {
+				newer, err := tt.document.newerHash(tt.value)
+				So(err, ShouldBeNil)
+				So(newer, ShouldEqual, tt.newer)
+			}
+		})
+	})
+
+	Convey("Given a list of invalid documents", t, func() {
+		tests := []struct {
+			document Document
+			value    interface{}
+		}{
+			{Document{}, "a1"},
+			{Document{ChangeFieldValue: "a1"}, nil},
+		}
+
+		Convey("The output should be a error", func() {
+			for _, tt := range tests {
+				_, err := tt.document.newerHash(tt.value)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
+
 func TestDocumentTransformRevisionDate(t *testing.T) {
 	Convey("Given a list of documents", t, func() {
 		tests := []struct {
@@ -744,6 +783,17 @@ func TestDocumentTransformRevisionInt(t *testing.T) {
 				},
 				2,
 			},
+			{
+				Document{
+					ChangeFieldValue: float64(3),
+					Resource: Resource{
+						Change: ResourceChange{
+							Kind: ResourceChangeInteger,
+						},
+					},
+				},
+				3,
+			},
 		}
 
 		Convey("The output should be valid", func() {
@@ -783,7 +833,18 @@ func TestDocumentTransformRevisionInt(t *testing.T) {
 			},
 			{
 				Document{
-					ChangeFieldValue: float64(0),
+					ChangeFieldValue: float64(1.5),
+					Resource: Resource{
+						Change: ResourceChange{
+							Kind: ResourceChangeInteger,
+						},
+					},
+				},
+				nil,
+			},
+			{
+				Document{
+					ChangeFieldValue: true,
 					Resource: Resource{
 						Change: ResourceChange{
 							Kind: ResourceChangeInteger,
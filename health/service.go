@@ -0,0 +1,210 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package health implements the liveness, readiness and status endpoints used to probe Flare.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	infraHTTP "github.com/diegobernardes/flare/infra/http"
+)
+
+// Checker verify if a dependency is available.
+type Checker interface {
+	Check(context.Context) error
+}
+
+// CheckerFunc is a adapter to use functions as checkers.
+type CheckerFunc func(context.Context) error
+
+// Check call the function.
+func (fn CheckerFunc) Check(ctx context.Context) error { return fn(ctx) }
+
+// The status of the service and of the dependencies.
+const (
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+)
+
+// Default time each check has to finish.
+const serviceDefaultTimeout = 5 * time.Second
+
+type check struct {
+	name    string
+	checker Checker
+
+	mutex       sync.Mutex
+	err         error
+	latency     time.Duration
+	checkedAt   time.Time
+	lastError   string
+	lastErrorAt time.Time
+}
+
+func (c *check) run(ctx context.Context, timeout time.Duration) {
+	ctx, ctxCancel := context.WithTimeout(ctx, timeout)
+	defer ctxCancel()
+
+	t1 := time.Now()
+	errs := make(chan error, 1)
+	go func() { errs <- c.checker.Check(ctx) }()
+
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+		err = errors.Wrap(ctx.Err(), "check not finished")
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.err = err
+	c.latency = time.Since(t1)
+	c.checkedAt = t1
+	if err != nil {
+		c.lastError = err.Error()
+		c.lastErrorAt = t1
+	}
+}
+
+func (c *check) MarshalJSON() ([]byte, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	status := statusOK
+	if c.err != nil {
+		status = statusUnavailable
+	}
+
+	var lastErrorAt string
+	if !c.lastErrorAt.IsZero() {
+		lastErrorAt = c.lastErrorAt.Format(time.RFC3339)
+	}
+
+	return json.Marshal(&struct {
+		Status      string  `json:"status"`
+		Latency     float64 `json:"latency"`
+		CheckedAt   string  `json:"checkedAt"`
+		LastError   string  `json:"lastError,omitempty"`
+		LastErrorAt string  `json:"lastErrorAt,omitempty"`
+	}{
+		Status:      status,
+		Latency:     c.latency.Seconds(),
+		CheckedAt:   c.checkedAt.Format(time.RFC3339),
+		LastError:   c.lastError,
+		LastErrorAt: lastErrorAt,
+	})
+}
+
+// Service implements the HTTP handlers to probe the service.
+type Service struct {
+	writer    *infraHTTP.Writer
+	timeout   time.Duration
+	checks    []*check
+	startedAt time.Time
+}
+
+// HandleLive answer if the process is alive. It don't check the dependencies, a failure at the
+// dependencies should not restart the process.
+func (s *Service) HandleLive(w http.ResponseWriter, r *http.Request) {
+	s.writer.Response(w, map[string]interface{}{"status": statusOK}, http.StatusOK, nil)
+}
+
+// HandleReady answer if the service is ready to receive traffic, all the checks should pass.
+func (s *Service) HandleReady(w http.ResponseWriter, r *http.Request) {
+	status, code := s.run(r.Context())
+	s.writer.Response(w, map[string]interface{}{"status": status}, code, nil)
+}
+
+// HandleStatus show the status of each dependency with the latency and the last error.
+func (s *Service) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	status, code := s.run(r.Context())
+
+	dependencies := make(map[string]*check, len(s.checks))
+	for _, c := range s.checks {
+		dependencies[c.name] = c
+	}
+
+	s.writer.Response(w, map[string]interface{}{
+		"status":       status,
+		"startedAt":    s.startedAt.Format(time.RFC3339),
+		"uptime":       time.Since(s.startedAt).Seconds(),
+		"dependencies": dependencies,
+	}, code, nil)
+}
+
+// run execute the checks concurrently and return the status of the service.
+func (s *Service) run(ctx context.Context) (string, int) {
+	var wg sync.WaitGroup
+	wg.Add(len(s.checks))
+	for _, c := range s.checks {
+		go func(c *check) {
+			defer wg.Done()
+			c.run(ctx, s.timeout)
+		}(c)
+	}
+	wg.Wait()
+
+	for _, c := range s.checks {
+		c.mutex.Lock()
+		err := c.err
+		c.mutex.Unlock()
+
+		if err != nil {
+			return statusUnavailable, http.StatusServiceUnavailable
+		}
+	}
+	return statusOK, http.StatusOK
+}
+
+// NewService returns a configured health service.
+func NewService(options ...func(*Service)) (*Service, error) {
+	s := &Service{timeout: serviceDefaultTimeout, startedAt: time.Now()}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	if s.writer == nil {
+		return nil, errors.New("writer not found")
+	}
+
+	if s.timeout <= 0 {
+		return nil, errors.New("invalid timeout")
+	}
+
+	names := make(map[string]struct{}, len(s.checks))
+	for _, c := range s.checks {
+		if _, ok := names[c.name]; ok {
+			return nil, errors.Errorf("duplicated check '%s'", c.name)
+		}
+		names[c.name] = struct{}{}
+	}
+
+	sort.Slice(s.checks, func(i, j int) bool { return s.checks[i].name < s.checks[j].name })
+	return s, nil
+}
+
+// ServiceWriter set the writer to send the responses.
+func ServiceWriter(writer *infraHTTP.Writer) func(*Service) {
+	return func(s *Service) { s.writer = writer }
+}
+
+// ServiceTimeout set the max time each check has to finish.
+func ServiceTimeout(timeout time.Duration) func(*Service) {
+	return func(s *Service) { s.timeout = timeout }
+}
+
+// ServiceChecker add a dependency to be checked at the readiness and status endpoints.
+func ServiceChecker(name string, checker Checker) func(*Service) {
+	return func(s *Service) { s.checks = append(s.checks, &check{name: name, checker: checker}) }
+}
@@ -0,0 +1,195 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+
+	infraHTTP "github.com/diegobernardes/flare/infra/http"
+)
+
+func TestNewService(t *testing.T) {
+	Convey("Given a list of valid service options", t, func() {
+		writer, err := infraHTTP.NewWriter(log.NewNopLogger())
+		So(err, ShouldBeNil)
+
+		tests := [][]func(*Service){
+			{ServiceWriter(writer)},
+			{
+				ServiceWriter(writer),
+				ServiceTimeout(time.Second),
+				ServiceChecker("mongodb", CheckerFunc(func(context.Context) error { return nil })),
+			},
+		}
+
+		Convey("The service initialization should not return error", func() {
+			for _, tt := range tests {
+				_, err := NewService(tt...)
+				So(err, ShouldBeNil)
+			}
+		})
+	})
+
+	Convey("Given a list of invalid service options", t, func() {
+		writer, err := infraHTTP.NewWriter(log.NewNopLogger())
+		So(err, ShouldBeNil)
+
+		checker := CheckerFunc(func(context.Context) error { return nil })
+		tests := [][]func(*Service){
+			{},
+			{ServiceWriter(writer), ServiceTimeout(-1)},
+			{ServiceWriter(writer), ServiceChecker("queue", checker), ServiceChecker("queue", checker)},
+		}
+
+		Convey("The service initialization should return error", func() {
+			for _, tt := range tests {
+				_, err := NewService(tt...)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestServiceHandleLive(t *testing.T) {
+	Convey("Given a Service with a failing check", t, func() {
+		writer, err := infraHTTP.NewWriter(log.NewNopLogger())
+		So(err, ShouldBeNil)
+
+		service, err := NewService(
+			ServiceWriter(writer),
+			ServiceChecker("mongodb", CheckerFunc(func(context.Context) error {
+				return errors.New("no reachable servers")
+			})),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("The process should still be alive", func() {
+			w := httptest.NewRecorder()
+			service.HandleLive(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldEqual, `{"status":"ok"}`)
+		})
+	})
+}
+
+func TestServiceHandleReady(t *testing.T) {
+	Convey("Given a list of checkers", t, func() {
+		tests := []struct {
+			title   string
+			checker Checker
+			status  int
+			body    string
+		}{
+			{
+				"The service should be ready",
+				CheckerFunc(func(context.Context) error { return nil }),
+				http.StatusOK,
+				`{"status":"ok"}`,
+			},
+			{
+				"The service should not be ready because the check failed",
+				CheckerFunc(func(context.Context) error { return errors.New("queue is closed") }),
+				http.StatusServiceUnavailable,
+				`{"status":"unavailable"}`,
+			},
+			{
+				"The service should not be ready because the check timed out",
+				CheckerFunc(func(context.Context) error {
+					time.Sleep(time.Second)
+					return nil
+				}),
+				http.StatusServiceUnavailable,
+				`{"status":"unavailable"}`,
+			},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				writer, err := infraHTTP.NewWriter(log.NewNopLogger())
+				So(err, ShouldBeNil)
+
+				service, err := NewService(
+					ServiceWriter(writer),
+					ServiceTimeout(10*time.Millisecond),
+					ServiceChecker("worker", CheckerFunc(func(context.Context) error { return nil })),
+					ServiceChecker("queue", tt.checker),
+				)
+				So(err, ShouldBeNil)
+
+				w := httptest.NewRecorder()
+				service.HandleReady(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+				So(w.Code, ShouldEqual, tt.status)
+				So(w.Body.String(), ShouldEqual, tt.body)
+			})
+		}
+	})
+}
+
+func TestServiceHandleStatus(t *testing.T) {
+	Convey("Given a Service with a check that fail once", t, func() {
+		writer, err := infraHTTP.NewWriter(log.NewNopLogger())
+		So(err, ShouldBeNil)
+
+		fail := true
+		service, err := NewService(
+			ServiceWriter(writer),
+			ServiceChecker("mongodb", CheckerFunc(func(context.Context) error {
+				if fail {
+					return errors.New("no reachable servers")
+				}
+				return nil
+			})),
+		)
+		So(err, ShouldBeNil)
+
+		type dependency struct {
+			Status      string  `json:"status"`
+			Latency     float64 `json:"latency"`
+			CheckedAt   string  `json:"checkedAt"`
+			LastError   string  `json:"lastError"`
+			LastErrorAt string  `json:"lastErrorAt"`
+		}
+
+		status := func() (int, string, dependency) {
+			w := httptest.NewRecorder()
+			service.HandleStatus(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+			var resp struct {
+				Status       string                `json:"status"`
+				Dependencies map[string]dependency `json:"dependencies"`
+			}
+			So(json.Unmarshal(w.Body.Bytes(), &resp), ShouldBeNil)
+			return w.Code, resp.Status, resp.Dependencies["mongodb"]
+		}
+
+		Convey("The dependency should be unavailable", func() {
+			code, s, d := status()
+			So(code, ShouldEqual, http.StatusServiceUnavailable)
+			So(s, ShouldEqual, "unavailable")
+			So(d.Status, ShouldEqual, "unavailable")
+			So(d.LastError, ShouldEqual, "no reachable servers")
+			So(d.LastErrorAt, ShouldNotBeEmpty)
+
+			Convey("After the recover, the last error should be kept", func() {
+				fail = false
+				code, s, d := status()
+				So(code, ShouldEqual, http.StatusOK)
+				So(s, ShouldEqual, "ok")
+				So(d.Status, ShouldEqual, "ok")
+				So(d.LastError, ShouldEqual, "no reachable servers")
+				So(d.CheckedAt, ShouldNotBeEmpty)
+			})
+		})
+	})
+}
@@ -0,0 +1,280 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	infraHTTP "github.com/diegobernardes/flare/infra/http"
+)
+
+// The scopes of the API. Each one allow the read and the write at the area.
+const (
+	ScopeResources     = "resources"
+	ScopeSubscriptions = "subscriptions"
+	ScopeDocuments     = "documents"
+)
+
+type authContextKey int
+
+const authContextKeyPrincipal authContextKey = iota
+
+// Authenticator identify the principal of a request. When the request don't have the credentials
+// handled by the authenticator, it should return nil as the principal and the error, this way, the
+// next authenticator is tried.
+type Authenticator interface {
+	Authenticate(*http.Request) (*Principal, error)
+}
+
+// Grant is a scope given to a principal. The grant can be restricted to a namespace, to a
+// resource, to a address host, or to any combination of them.
+type Grant struct {
+	Scope     string
+	Namespace string
+	Resource  string
+	Host      string
+}
+
+func (g Grant) restricted() bool { return g.Namespace != "" || g.Resource != "" || g.Host != "" }
+
+func (g Grant) match(target *AuthTarget) bool {
+	if !g.restricted() {
+		return true
+	}
+
+	if target == nil {
+		return false
+	}
+
+	if g.Namespace != "" && g.Namespace != target.Namespace {
+		return false
+	}
+
+	if g.Resource != "" && g.Resource != target.ResourceID {
+		return false
+	}
+
+	if g.Host == "" {
+		return true
+	}
+
+	for _, host := range target.Hosts {
+		if strings.EqualFold(host, g.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseGrant parse the grant at the format "scope[:key=value,...]", like "documents",
+// "subscriptions:resource=123", "documents:host=api.app.com" and "resources:namespace=team-a".
+func ParseGrant(raw string) (Grant, error) {
+	var g Grant
+
+	fields := strings.SplitN(strings.TrimSpace(raw), ":", 2)
+	switch fields[0] {
+	case ScopeResources, ScopeSubscriptions, ScopeDocuments:
+		g.Scope = fields[0]
+	default:
+		return g, fmt.Errorf("invalid scope '%s'", fields[0])
+	}
+
+	if len(fields) == 1 {
+		return g, nil
+	}
+
+	for _, restriction := range strings.Split(fields[1], ",") {
+		kv := strings.SplitN(restriction, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return g, fmt.Errorf("invalid restriction '%s' at grant '%s'", restriction, raw)
+		}
+
+		switch kv[0] {
+		case "namespace":
+			g.Namespace = kv[1]
+		case "resource":
+			g.Resource = kv[1]
+		case "host":
+			g.Host = strings.ToLower(kv[1])
+		default:
+			return g, fmt.Errorf("invalid restriction '%s' at grant '%s'", restriction, raw)
+		}
+	}
+	return g, nil
+}
+
+// ParseGrants parse a list of grants.
+func ParseGrants(raw []string) ([]Grant, error) {
+	grants := make([]Grant, 0, len(raw))
+	for _, r := range raw {
+		g, err := ParseGrant(r)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, g)
+	}
+	return grants, nil
+}
+
+// Principal is the authenticated client.
+type Principal struct {
+	Subject string
+	Grants  []Grant
+}
+
+// AuthTarget is what the request access. It's used to check the restricted grants.
+type AuthTarget struct {
+	Namespace  string
+	ResourceID string
+	Hosts      []string
+}
+
+// AuthTargetFunc resolve the target of a request.
+type AuthTargetFunc func(*http.Request) (*AuthTarget, error)
+
+// PrincipalFromContext returns the principal authenticated at the request.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(authContextKeyPrincipal).(*Principal)
+	return p, ok
+}
+
+// Authorize check if the principal at the context has the scope to access the target. The target
+// is only resolved when the principal has just restricted grants of the scope. A nil target
+// function allow any principal that has a grant of the scope, restricted or not, it's used when
+// the check is done later, like at the batch entries.
+func Authorize(
+	ctx context.Context, scope string, target func() (*AuthTarget, error),
+) (bool, error) {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return false, nil
+	}
+
+	grants := make([]Grant, 0, len(principal.Grants))
+	for _, g := range principal.Grants {
+		if g.Scope != scope {
+			continue
+		}
+
+		if !g.restricted() || target == nil {
+			return true, nil
+		}
+		grants = append(grants, g)
+	}
+
+	if len(grants) == 0 {
+		return false, nil
+	}
+
+	t, err := target()
+	if err != nil {
+		return false, errors.Wrap(err, "error during authorization target resolve")
+	}
+
+	for _, g := range grants {
+		if g.match(t) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Auth is a middleware to authenticate the requests and authorize them by scope.
+type Auth struct {
+	authenticators []Authenticator
+	writer         *infraHTTP.Writer
+}
+
+// Handler authenticate the requests. The requests without valid credentials are rejected.
+func (a *Auth) Handler(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		for _, authenticator := range a.authenticators {
+			principal, err := authenticator.Authenticate(r)
+			if err != nil {
+				a.unauthorized(w, err)
+				return
+			}
+
+			if principal != nil {
+				ctx := context.WithValue(r.Context(), authContextKeyPrincipal, principal)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+		}
+
+		a.unauthorized(w, errors.New("missing credentials"))
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// Require returns a middleware that only allow the principals with the scope to the target of the
+// request.
+func (a *Auth) Require(scope string, target AuthTargetFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			var resolve func() (*AuthTarget, error)
+			if target != nil {
+				resolve = func() (*AuthTarget, error) { return target(r) }
+			}
+
+			allowed, err := Authorize(r.Context(), scope, resolve)
+			if err != nil {
+				a.writer.Error(w, "error during authorization", err, http.StatusInternalServerError)
+				return
+			}
+
+			if !allowed {
+				a.writer.Error(
+					w, "forbidden", fmt.Errorf("missing scope '%s'", scope), http.StatusForbidden,
+				)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func (a *Auth) unauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	a.writer.Error(w, "unauthorized", err, http.StatusUnauthorized)
+}
+
+// NewAuth returns a configured middleware to authenticate and authorize the requests.
+func NewAuth(options ...func(*Auth)) (*Auth, error) {
+	a := &Auth{}
+
+	for _, option := range options {
+		option(a)
+	}
+
+	if len(a.authenticators) == 0 {
+		return nil, errors.New("authenticators not found")
+	}
+
+	if a.writer == nil {
+		return nil, errors.New("writer not found")
+	}
+
+	return a, nil
+}
+
+// AuthAuthenticator add a authenticator. They are tried in the same order they are added.
+func AuthAuthenticator(authenticator Authenticator) func(*Auth) {
+	return func(a *Auth) { a.authenticators = append(a.authenticators, authenticator) }
+}
+
+// AuthWriter set the writer to send the errors.
+func AuthWriter(writer *infraHTTP.Writer) func(*Auth) {
+	return func(a *Auth) { a.writer = writer }
+}
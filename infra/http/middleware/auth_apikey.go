@@ -0,0 +1,65 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"crypto/sha256"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// HeaderAPIKey is the header with the API key.
+const HeaderAPIKey = "X-API-Key"
+
+// APIKey authenticate the requests by static keys.
+type APIKey struct {
+	principals map[string]*Principal
+	keys       map[[sha256.Size]byte]*Principal
+}
+
+// Authenticate the request by the key at the X-API-Key header. The keys are compared by the hash,
+// this way, the lookup time don't leak the key content.
+func (a *APIKey) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get(HeaderAPIKey)
+	if key == "" {
+		return nil, nil
+	}
+
+	principal, ok := a.keys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return nil, errors.New("invalid api key")
+	}
+	return principal, nil
+}
+
+// NewAPIKey returns a configured APIKey authenticator.
+func NewAPIKey(options ...func(*APIKey)) (*APIKey, error) {
+	a := &APIKey{principals: make(map[string]*Principal)}
+
+	for _, option := range options {
+		option(a)
+	}
+
+	if len(a.principals) == 0 {
+		return nil, errors.New("keys not found")
+	}
+
+	a.keys = make(map[[sha256.Size]byte]*Principal, len(a.principals))
+	for key, principal := range a.principals {
+		if key == "" {
+			return nil, errors.Errorf("empty api key of '%s'", principal.Subject)
+		}
+		a.keys[sha256.Sum256([]byte(key))] = principal
+	}
+	a.principals = nil
+
+	return a, nil
+}
+
+// APIKeyPrincipal add a key and the principal it authenticate.
+func APIKeyPrincipal(key string, principal *Principal) func(*APIKey) {
+	return func(a *APIKey) { a.principals[key] = principal }
+}
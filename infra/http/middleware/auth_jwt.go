@@ -0,0 +1,352 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// JWK is a public key of a JSON Web Key Set.
+type JWK struct {
+	ID  string
+	Key crypto.PublicKey
+}
+
+// ParseJWKS parse the RSA and EC signature keys of a JSON Web Key Set. The other keys are ignored.
+func ParseJWKS(content []byte) ([]JWK, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &set); err != nil {
+		return nil, errors.Wrap(err, "error during JWKS unmarshal")
+	}
+
+	keys := make([]JWK, 0, len(set.Keys))
+	for i, raw := range set.Keys {
+		if raw.Use != "" && raw.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		switch raw.Kty {
+		case "RSA":
+			n, err := jwtDecodeInt(raw.N)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid modulus at key %d", i)
+			}
+
+			e, err := jwtDecodeInt(raw.E)
+			if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+				return nil, fmt.Errorf("invalid exponent at key %d", i)
+			}
+			key = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch raw.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				return nil, fmt.Errorf("invalid curve '%s' at key %d", raw.Crv, i)
+			}
+
+			x, err := jwtDecodeInt(raw.X)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid x at key %d", i)
+			}
+
+			y, err := jwtDecodeInt(raw.Y)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid y at key %d", i)
+			}
+
+			if !curve.IsOnCurve(x, y) {
+				return nil, fmt.Errorf("invalid point at key %d", i)
+			}
+			key = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		default:
+			continue
+		}
+
+		keys = append(keys, JWK{ID: raw.Kid, Key: key})
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS without signature keys")
+	}
+	return keys, nil
+}
+
+// JWT authenticate the requests by a bearer JSON Web Token signed by one of the keys. The RS and
+// ES algorithms are accepted. The grants are taken from the "scope" claim, a list separated by
+// spaces, and from the "scp" claim.
+type JWT struct {
+	keys     []JWK
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *json.Number    `json:"exp"`
+	NotBefore *json.Number    `json:"nbf"`
+	Scope     string          `json:"scope"`
+	Scp       json.RawMessage `json:"scp"`
+}
+
+// Authenticate the request by the bearer token at the Authorization header.
+func (j *JWT) Authenticate(r *http.Request) (*Principal, error) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return nil, nil
+	}
+
+	claims, err := j.verify(strings.TrimSpace(header[7:]))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid token")
+	}
+
+	scopes := append(strings.Fields(claims.Scope), jwtStrings(claims.Scp)...)
+	grants := make([]Grant, 0, len(scopes))
+	for _, scope := range scopes {
+		// The tokens can have scopes of other services, they are ignored.
+		if g, err := ParseGrant(scope); err == nil {
+			grants = append(grants, g)
+		}
+	}
+
+	return &Principal{Subject: claims.Subject, Grants: grants}, nil
+}
+
+func (j *JWT) verify(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := jwtDecodeJSON(parts[0], &header); err != nil {
+		return nil, errors.Wrap(err, "invalid header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid signature encoding")
+	}
+
+	if err = j.verifySignature(header.Alg, header.Kid, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err = jwtDecodeJSON(parts[1], &claims); err != nil {
+		return nil, errors.Wrap(err, "invalid claims")
+	}
+
+	if err = j.verifyClaims(&claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func (j *JWT) verifySignature(alg, kid, input string, signature []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("algorithm '%s' not supported", alg)
+	}
+
+	h := hash.New()
+	h.Write([]byte(input))
+	digest := h.Sum(nil)
+
+	for _, k := range j.keys {
+		if kid != "" && k.ID != "" && k.ID != kid {
+			continue
+		}
+
+		switch key := k.Key.(type) {
+		case *rsa.PublicKey:
+			if alg[0] == 'R' && rsa.VerifyPKCS1v15(key, hash, digest, signature) == nil {
+				return nil
+			}
+		case *ecdsa.PublicKey:
+			size := (key.Curve.Params().BitSize + 7) / 8
+			if alg[0] != 'E' || len(signature) != 2*size {
+				continue
+			}
+
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			if ecdsa.Verify(key, digest, r, s) {
+				return nil
+			}
+		}
+	}
+	return errors.New("invalid signature")
+}
+
+func (j *JWT) verifyClaims(claims *jwtClaims) error {
+	now := j.now()
+
+	if claims.ExpiresAt == nil {
+		return errors.New("missing exp claim")
+	}
+
+	exp, err := claims.ExpiresAt.Float64()
+	if err != nil {
+		return errors.Wrap(err, "invalid exp claim")
+	}
+	if now.After(jwtTime(exp).Add(j.leeway)) {
+		return errors.New("token expired")
+	}
+
+	if claims.NotBefore != nil {
+		nbf, err := claims.NotBefore.Float64()
+		if err != nil {
+			return errors.Wrap(err, "invalid nbf claim")
+		}
+		if now.Add(j.leeway).Before(jwtTime(nbf)) {
+			return errors.New("token not valid yet")
+		}
+	}
+
+	if j.issuer != "" && claims.Issuer != j.issuer {
+		return fmt.Errorf("invalid issuer '%s'", claims.Issuer)
+	}
+
+	if j.audience != "" && !jwtHasAudience(claims.Audience, j.audience) {
+		return errors.New("invalid audience")
+	}
+	return nil
+}
+
+// NewJWT returns a configured JWT authenticator.
+func NewJWT(options ...func(*JWT)) (*JWT, error) {
+	j := &JWT{leeway: time.Minute, now: time.Now}
+
+	for _, option := range options {
+		option(j)
+	}
+
+	if len(j.keys) == 0 {
+		return nil, errors.New("keys not found")
+	}
+
+	if j.leeway < 0 {
+		return nil, errors.New("invalid leeway")
+	}
+
+	return j, nil
+}
+
+// JWTKeys set the keys used to verify the tokens signature.
+func JWTKeys(keys []JWK) func(*JWT) {
+	return func(j *JWT) { j.keys = keys }
+}
+
+// JWTIssuer set the expected "iss" claim. When unset, the issuer is not checked.
+func JWTIssuer(issuer string) func(*JWT) {
+	return func(j *JWT) { j.issuer = issuer }
+}
+
+// JWTAudience set the expected "aud" claim. When unset, the audience is not checked.
+func JWTAudience(audience string) func(*JWT) {
+	return func(j *JWT) { j.audience = audience }
+}
+
+// JWTLeeway set the clock skew tolerated at the "exp" and "nbf" claims.
+func JWTLeeway(leeway time.Duration) func(*JWT) {
+	return func(j *JWT) { j.leeway = leeway }
+}
+
+func jwtDecodeJSON(segment string, v interface{}) error {
+	content, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.Wrap(err, "error during base64 decode")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	return errors.Wrap(decoder.Decode(v), "error during json decode")
+}
+
+func jwtDecodeInt(value string) (*big.Int, error) {
+	content, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "error during base64 decode")
+	}
+
+	if len(content) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(content), nil
+}
+
+func jwtTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+func jwtHasAudience(raw json.RawMessage, audience string) bool {
+	for _, a := range jwtStrings(raw) {
+		if a == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// jwtStrings decode the claims that can be a string or a array of strings. The string is split by
+// the spaces.
+func jwtStrings(raw json.RawMessage) []string {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return strings.Fields(single)
+	}
+
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil
+	}
+	return list
+}
@@ -0,0 +1,319 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-kit/kit/log"
+	. "github.com/smartystreets/goconvey/convey"
+
+	infraHTTP "github.com/diegobernardes/flare/infra/http"
+)
+
+func TestParseGrant(t *testing.T) {
+	Convey("Given a list of valid grants", t, func() {
+		tests := []struct {
+			raw      string
+			expected Grant
+		}{
+			{"resources", Grant{Scope: ScopeResources}},
+			{"subscriptions:resource=123", Grant{Scope: ScopeSubscriptions, Resource: "123"}},
+			{"documents:host=API.app.com", Grant{Scope: ScopeDocuments, Host: "api.app.com"}},
+			{"resources:namespace=team-a", Grant{Scope: ScopeResources, Namespace: "team-a"}},
+			{
+				"documents:resource=123,host=app.com",
+				Grant{Scope: ScopeDocuments, Resource: "123", Host: "app.com"},
+			},
+		}
+
+		Convey("The grants should be parsed", func() {
+			for _, tt := range tests {
+				g, err := ParseGrant(tt.raw)
+				So(err, ShouldBeNil)
+				So(g, ShouldResemble, tt.expected)
+			}
+		})
+	})
+
+	Convey("Given a list of invalid grants", t, func() {
+		tests := []string{"", "admin", "documents:", "documents:host", "documents:user=1"}
+
+		Convey("The parse should return error", func() {
+			for _, tt := range tests {
+				_, err := ParseGrant(tt)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestAuthorize(t *testing.T) {
+	Convey("Given a principal with restricted grants", t, func() {
+		grants, err := ParseGrants([]string{
+			"subscriptions", "documents:host=api.app.com", "resources:resource=123",
+		})
+		So(err, ShouldBeNil)
+
+		ctx := context.WithValue(
+			context.Background(),
+			authContextKeyPrincipal,
+			&Principal{Subject: "producer", Grants: grants},
+		)
+
+		target := func(t *AuthTarget) func() (*AuthTarget, error) {
+			return func() (*AuthTarget, error) { return t, nil }
+		}
+
+		tests := []struct {
+			title   string
+			scope   string
+			target  func() (*AuthTarget, error)
+			allowed bool
+		}{
+			{"Unrestricted grant", ScopeSubscriptions, target(&AuthTarget{ResourceID: "1"}), true},
+			{"Matching host", ScopeDocuments, target(&AuthTarget{Hosts: []string{"API.app.com"}}), true},
+			{"Other host", ScopeDocuments, target(&AuthTarget{Hosts: []string{"app.com"}}), false},
+			{"Matching resource", ScopeResources, target(&AuthTarget{ResourceID: "123"}), true},
+			{"Other resource", ScopeResources, target(&AuthTarget{ResourceID: "456"}), false},
+			{"Without a target", ScopeResources, target(&AuthTarget{}), false},
+			{"Deferred check", ScopeDocuments, nil, true},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				allowed, err := Authorize(ctx, tt.scope, tt.target)
+				So(err, ShouldBeNil)
+				So(allowed, ShouldEqual, tt.allowed)
+			})
+		}
+
+		Convey("Without a principal, the access should be denied", func() {
+			allowed, err := Authorize(context.Background(), ScopeDocuments, nil)
+			So(err, ShouldBeNil)
+			So(allowed, ShouldBeFalse)
+		})
+	})
+
+	Convey("Given a principal with a namespace grant", t, func() {
+		grants, err := ParseGrants([]string{"documents:namespace=team-a,host=api.app.com"})
+		So(err, ShouldBeNil)
+
+		ctx := context.WithValue(
+			context.Background(),
+			authContextKeyPrincipal,
+			&Principal{Subject: "producer", Grants: grants},
+		)
+
+		tests := []struct {
+			title   string
+			target  *AuthTarget
+			allowed bool
+		}{
+			{"Matching namespace", &AuthTarget{Namespace: "team-a", Hosts: []string{"api.app.com"}}, true},
+			{"Other namespace", &AuthTarget{Namespace: "team-b", Hosts: []string{"api.app.com"}}, false},
+			{"Other host", &AuthTarget{Namespace: "team-a", Hosts: []string{"app.com"}}, false},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				allowed, err := Authorize(ctx, ScopeDocuments, func() (*AuthTarget, error) {
+					return tt.target, nil
+				})
+				So(err, ShouldBeNil)
+				So(allowed, ShouldEqual, tt.allowed)
+			})
+		}
+	})
+}
+
+func TestAuthHandler(t *testing.T) {
+	Convey("Given a router with the Auth middleware and a api key", t, func() {
+		grants, err := ParseGrants([]string{"documents:host=api.app.com"})
+		So(err, ShouldBeNil)
+
+		apiKey, err := NewAPIKey(APIKeyPrincipal("secret", &Principal{"producer", grants}))
+		So(err, ShouldBeNil)
+
+		writer, err := infraHTTP.NewWriter(log.NewNopLogger())
+		So(err, ShouldBeNil)
+
+		auth, err := NewAuth(AuthAuthenticator(apiKey), AuthWriter(writer))
+		So(err, ShouldBeNil)
+
+		r := chi.NewRouter()
+		r.Use(auth.Handler)
+		r.With(auth.Require(ScopeDocuments, func(r *http.Request) (*AuthTarget, error) {
+			return &AuthTarget{Hosts: []string{chi.URLParam(r, "host")}}, nil
+		})).Put("/documents/{host}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		})
+
+		tests := []struct {
+			title  string
+			key    string
+			path   string
+			status int
+		}{
+			{"Missing key", "", "/documents/api.app.com", http.StatusUnauthorized},
+			{"Invalid key", "other", "/documents/api.app.com", http.StatusUnauthorized},
+			{"Other host", "secret", "/documents/app.com", http.StatusForbidden},
+			{"Allowed", "secret", "/documents/api.app.com", http.StatusAccepted},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				req := httptest.NewRequest(http.MethodPut, tt.path, nil)
+				if tt.key != "" {
+					req.Header.Set(HeaderAPIKey, tt.key)
+				}
+
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				So(w.Code, ShouldEqual, tt.status)
+			})
+		}
+	})
+}
+
+func TestJWTAuthenticate(t *testing.T) {
+	Convey("Given a JWT authenticator with a RSA and a EC key", t, func() {
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		So(err, ShouldBeNil)
+
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		So(err, ShouldBeNil)
+
+		encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+		jwks, err := json.Marshal(map[string]interface{}{
+			"keys": []map[string]string{
+				{
+					"kty": "RSA",
+					"kid": "rsa",
+					"n":   encode(rsaKey.N.Bytes()),
+					"e":   encode(big.NewInt(int64(rsaKey.E)).Bytes()),
+				},
+				{
+					"kty": "EC",
+					"kid": "ec",
+					"crv": "P-256",
+					"x":   encode(ecKey.X.Bytes()),
+					"y":   encode(ecKey.Y.Bytes()),
+				},
+				{"kty": "oct", "kid": "hmac", "k": encode([]byte("secret"))},
+			},
+		})
+		So(err, ShouldBeNil)
+
+		keys, err := ParseJWKS(jwks)
+		So(err, ShouldBeNil)
+		So(keys, ShouldHaveLength, 2)
+
+		now := time.Date(2017, time.November, 10, 23, 0, 0, 0, time.UTC)
+		authenticator, err := NewJWT(JWTKeys(keys), JWTIssuer("issuer"), JWTAudience("flare"))
+		So(err, ShouldBeNil)
+		authenticator.now = func() time.Time { return now }
+
+		sign := func(alg, kid string, claims map[string]interface{}) string {
+			header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+			payload, _ := json.Marshal(claims)
+			input := encode(header) + "." + encode(payload)
+
+			digest := crypto.SHA256.New()
+			digest.Write([]byte(input))
+
+			var signature []byte
+			switch alg {
+			case "RS256":
+				signature, err = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest.Sum(nil))
+				So(err, ShouldBeNil)
+			case "ES256":
+				r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest.Sum(nil))
+				So(err, ShouldBeNil)
+				signature = make([]byte, 64)
+				r.FillBytes(signature[:32])
+				s.FillBytes(signature[32:])
+			}
+			return input + "." + encode(signature)
+		}
+
+		claims := func(changes map[string]interface{}) map[string]interface{} {
+			c := map[string]interface{}{
+				"sub":   "producer",
+				"iss":   "issuer",
+				"aud":   []string{"flare", "other"},
+				"exp":   now.Add(time.Hour).Unix(),
+				"scope": "documents:host=api.app.com openid",
+			}
+			for key, value := range changes {
+				c[key] = value
+			}
+			return c
+		}
+
+		authenticate := func(token string) (*Principal, error) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			return authenticator.Authenticate(req)
+		}
+
+		Convey("The valid tokens should be authenticated", func() {
+			for _, token := range []string{
+				sign("RS256", "rsa", claims(nil)),
+				sign("ES256", "ec", claims(nil)),
+				sign("ES256", "", claims(nil)),
+			} {
+				principal, err := authenticate(token)
+				So(err, ShouldBeNil)
+				So(principal, ShouldResemble, &Principal{
+					Subject: "producer",
+					Grants:  []Grant{{Scope: ScopeDocuments, Host: "api.app.com"}},
+				})
+			}
+		})
+
+		Convey("The request without a token should be ignored", func() {
+			principal, err := authenticate("")
+			So(err, ShouldBeNil)
+			So(principal, ShouldBeNil)
+		})
+
+		Convey("The invalid tokens should be rejected", func() {
+			tampered := sign("RS256", "rsa", claims(nil))
+			tampered = tampered[:len(tampered)-4] + "AAAA"
+
+			for _, token := range []string{
+				"invalid",
+				tampered,
+				sign("RS256", "ec", claims(nil)),
+				sign("HS256", "hmac", claims(nil)),
+				sign("RS256", "rsa", claims(map[string]interface{}{"exp": now.Add(-time.Hour).Unix()})),
+				sign("RS256", "rsa", claims(map[string]interface{}{"exp": nil})),
+				sign("RS256", "rsa", claims(map[string]interface{}{"nbf": now.Add(time.Hour).Unix()})),
+				sign("RS256", "rsa", claims(map[string]interface{}{"iss": "other"})),
+				sign("RS256", "rsa", claims(map[string]interface{}{"aud": "other"})),
+			} {
+				principal, err := authenticate(token)
+				So(err, ShouldNotBeNil)
+				So(principal, ShouldBeNil)
+			}
+		})
+	})
+}
@@ -0,0 +1,63 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-kit/kit/metrics"
+
+	infraMetrics "github.com/diegobernardes/flare/infra/metrics"
+)
+
+// Label used at the requests that don't match any route.
+const metricsUnmatchedRoute = "unmatched"
+
+// Metrics is a middleware to record the quantity and the latency of the requests per route.
+type Metrics struct {
+	requests metrics.Counter
+	duration metrics.Histogram
+}
+
+// Handler process and record the requests. The route is the chi pattern, like
+// "/resources/{id}", so, the requests are grouped by the route and not by the URL.
+func (m *Metrics) Handler(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		t1 := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := metricsUnmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		m.requests.With("method", r.Method, "route", route, "status", strconv.Itoa(status)).Add(1)
+		m.duration.With("method", r.Method, "route", route).Observe(time.Since(t1).Seconds())
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// NewMetrics return a configured middleware to record the requests metrics.
+func NewMetrics(registry *infraMetrics.Registry) Metrics {
+	return Metrics{
+		requests: registry.NewCounter(
+			"http_requests_total", "Quantity of HTTP requests.", "method", "route", "status",
+		),
+		duration: registry.NewHistogram(
+			"http_request_duration_seconds", "Latency of the HTTP requests.", nil, "method", "route",
+		),
+	}
+}
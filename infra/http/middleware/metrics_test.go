@@ -0,0 +1,55 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	. "github.com/smartystreets/goconvey/convey"
+
+	infraMetrics "github.com/diegobernardes/flare/infra/metrics"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	Convey("Given a router with the Metrics middleware", t, func() {
+		registry := infraMetrics.NewRegistry()
+		metrics := NewMetrics(registry)
+
+		r := chi.NewRouter()
+		r.Use(metrics.Handler)
+		r.Route("/resources", func(r chi.Router) {
+			r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})
+		})
+
+		Convey("It should record the requests by the route pattern", func() {
+			for _, path := range []string{"/resources/1", "/resources/2", "/unknown"} {
+				r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+			}
+
+			w := httptest.NewRecorder()
+			registry.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			So(
+				w.Body.String(),
+				ShouldContainSubstring,
+				`http_requests_total{method="GET",route="/resources/{id}",status="204"} 2`,
+			)
+			So(
+				w.Body.String(),
+				ShouldContainSubstring,
+				`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+			)
+			So(
+				w.Body.String(),
+				ShouldContainSubstring,
+				`http_request_duration_seconds_count{method="GET",route="/resources/{id}"} 2`,
+			)
+		})
+	})
+}
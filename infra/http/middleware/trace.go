@@ -0,0 +1,62 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+
+	"github.com/diegobernardes/flare/infra/trace"
+)
+
+// Trace is a middleware to accept the W3C Trace Context and start a span per request. The span is
+// at the request context, so, it can be propagated to the queues.
+type Trace struct {
+	tracer *trace.Tracer
+}
+
+// Handler process and trace the requests.
+func (t *Trace) Handler(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		ctx := trace.Extract(r.Context(), r.Header)
+		ctx, span := t.tracer.StartSpan(ctx, "HTTP "+r.Method, trace.SpanKindServer)
+		defer span.Finish()
+
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.target", r.URL.Path)
+		if reqID := middleware.GetReqID(ctx); reqID != "" {
+			span.SetAttribute("http.request_id", reqID)
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			span.Name = r.Method + " " + rctx.RoutePattern()
+			span.SetAttribute("http.route", rctx.RoutePattern())
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttribute("http.status_code", strconv.Itoa(status))
+		if status >= http.StatusInternalServerError {
+			span.SetError(fmt.Errorf("response status '%d'", status))
+		}
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// NewTrace return a configured middleware to trace the requests. Without the tracer, the trace
+// context is still propagated, but the spans are not exported.
+func NewTrace(tracer *trace.Tracer) Trace {
+	return Trace{tracer}
+}
@@ -0,0 +1,306 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package metrics implements the go-kit metrics with the Prometheus text format exposition.
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+// DefaultBuckets are the histogram buckets, in seconds, used to measure latencies.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Time the collectors have to update the metrics during a scrape.
+const registryCollectTimeout = 5 * time.Second
+
+const (
+	kindCounter   = "counter"
+	kindGauge     = "gauge"
+	kindHistogram = "histogram"
+)
+
+// Registry holds the metrics and write them at the Prometheus text format. The metrics are created
+// once by name, so, many components can share the same metric with different label values.
+type Registry struct {
+	mutex      sync.Mutex
+	families   map[string]*family
+	collectors []func(context.Context)
+}
+
+// NewCounter returns the counter with the given name.
+func (r *Registry) NewCounter(name, help string, labels ...string) metrics.Counter {
+	return &counter{serie: serie{family: r.family(name, help, kindCounter, nil, labels)}}
+}
+
+// NewGauge returns the gauge with the given name.
+func (r *Registry) NewGauge(name, help string, labels ...string) metrics.Gauge {
+	return &gauge{serie: serie{family: r.family(name, help, kindGauge, nil, labels)}}
+}
+
+// NewHistogram returns the histogram with the given name. The buckets are the upper bounds, when
+// nil, the DefaultBuckets are used.
+func (r *Registry) NewHistogram(
+	name, help string, buckets []float64, labels ...string,
+) metrics.Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	return &histogram{serie: serie{family: r.family(name, help, kindHistogram, buckets, labels)}}
+}
+
+// OnCollect register a function to be called before the metrics are written. It's used to update
+// the values that are only known at the time of the scrape, like the queue depth.
+func (r *Registry) OnCollect(fn func(context.Context)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.collectors = append(r.collectors, fn)
+}
+
+// ServeHTTP write the metrics at the Prometheus text format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx, ctxCancel := context.WithTimeout(req.Context(), registryCollectTimeout)
+	defer ctxCancel()
+
+	r.mutex.Lock()
+	collectors := append([]func(context.Context){}, r.collectors...)
+	r.mutex.Unlock()
+
+	for _, collector := range collectors {
+		collector(ctx)
+	}
+
+	buf := &bytes.Buffer{}
+	r.write(buf)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func (r *Registry) write(buf *bytes.Buffer) {
+	r.mutex.Lock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mutex.Unlock()
+
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+	for _, f := range families {
+		f.write(buf)
+	}
+}
+
+func (r *Registry) family(
+	name, help, kind string, buckets []float64, labels []string,
+) *family {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if f, ok := r.families[name]; ok {
+		if f.kind != kind {
+			panic(fmt.Sprintf("metric '%s' already registered as a %s", name, f.kind))
+		}
+		return f
+	}
+
+	f := &family{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		buckets: buckets,
+		labels:  labels,
+		values:  make(map[string]*value),
+	}
+	r.families[name] = f
+	return f
+}
+
+// NewRegistry returns a empty registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+type family struct {
+	mutex   sync.Mutex
+	name    string
+	help    string
+	kind    string
+	buckets []float64
+	labels  []string
+	values  map[string]*value
+}
+
+type value struct {
+	labels  []string
+	value   float64
+	buckets []uint64
+	count   uint64
+}
+
+// value returns the value of the label values, the pairs of label name and value. The labels not
+// declared at the family are discarded and the missing ones are blank.
+func (f *family) value(labelValues []string) *value {
+	labels := make([]string, len(f.labels))
+	for i := 0; i+1 < len(labelValues); i += 2 {
+		for j, label := range f.labels {
+			if label == labelValues[i] {
+				labels[j] = labelValues[i+1]
+			}
+		}
+	}
+	key := strings.Join(labels, "\xff")
+
+	v, ok := f.values[key]
+	if !ok {
+		v = &value{labels: labels, buckets: make([]uint64, len(f.buckets))}
+		f.values[key] = v
+	}
+	return v
+}
+
+func (f *family) write(buf *bytes.Buffer) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(f.values) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(f.values))
+	for key := range f.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(buf, "# HELP %s %s\n", f.name, escape(f.help, false))
+	fmt.Fprintf(buf, "# TYPE %s %s\n", f.name, f.kind)
+	for _, key := range keys {
+		v := f.values[key]
+		if f.kind != kindHistogram {
+			f.writeSample(buf, f.name, v.labels, "", v.value)
+			continue
+		}
+
+		for i, bucket := range f.buckets {
+			f.writeSample(buf, f.name+"_bucket", v.labels, formatFloat(bucket), float64(v.buckets[i]))
+		}
+		f.writeSample(buf, f.name+"_bucket", v.labels, "+Inf", float64(v.count))
+		f.writeSample(buf, f.name+"_sum", v.labels, "", v.value)
+		f.writeSample(buf, f.name+"_count", v.labels, "", float64(v.count))
+	}
+}
+
+func (f *family) writeSample(
+	buf *bytes.Buffer, name string, labels []string, le string, value float64,
+) {
+	pairs := make([]string, 0, len(labels)+1)
+	for i, label := range labels {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, f.labels[i], escape(label, true)))
+	}
+
+	if le != "" {
+		pairs = append(pairs, fmt.Sprintf(`le="%s"`, le))
+	}
+
+	buf.WriteString(name)
+	if len(pairs) > 0 {
+		buf.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	buf.WriteString(" " + formatFloat(value) + "\n")
+}
+
+func escape(value string, quote bool) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, "\n", `\n`, -1)
+	if quote {
+		value = strings.Replace(value, `"`, `\"`, -1)
+	}
+	return value
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+type serie struct {
+	family      *family
+	labelValues []string
+}
+
+func (s serie) with(labelValues []string) serie {
+	return serie{
+		family:      s.family,
+		labelValues: append(append([]string{}, s.labelValues...), labelValues...),
+	}
+}
+
+type counter struct{ serie }
+
+func (c *counter) With(labelValues ...string) metrics.Counter {
+	return &counter{serie: c.with(labelValues)}
+}
+
+func (c *counter) Add(delta float64) {
+	c.family.mutex.Lock()
+	defer c.family.mutex.Unlock()
+	c.family.value(c.labelValues).value += delta
+}
+
+type gauge struct{ serie }
+
+func (g *gauge) With(labelValues ...string) metrics.Gauge {
+	return &gauge{serie: g.with(labelValues)}
+}
+
+func (g *gauge) Set(value float64) {
+	g.family.mutex.Lock()
+	defer g.family.mutex.Unlock()
+	g.family.value(g.labelValues).value = value
+}
+
+func (g *gauge) Add(delta float64) {
+	g.family.mutex.Lock()
+	defer g.family.mutex.Unlock()
+	g.family.value(g.labelValues).value += delta
+}
+
+type histogram struct{ serie }
+
+func (h *histogram) With(labelValues ...string) metrics.Histogram {
+	return &histogram{serie: h.with(labelValues)}
+}
+
+func (h *histogram) Observe(observation float64) {
+	h.family.mutex.Lock()
+	defer h.family.mutex.Unlock()
+
+	v := h.family.value(h.labelValues)
+	for i, bucket := range h.family.buckets {
+		if observation <= bucket {
+			v.buckets[i]++
+		}
+	}
+	v.value += observation
+	v.count++
+}
@@ -0,0 +1,70 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRegistryServeHTTP(t *testing.T) {
+	Convey("Given a Registry", t, func() {
+		registry := NewRegistry()
+
+		Convey("It should output the metrics at the Prometheus text format", func() {
+			counter := registry.NewCounter("requests_total", "Requests.", "method", "route")
+			counter.With("method", "GET", "route", "/resources").Add(2)
+			counter.With("route", `/say "hi"`, "method", "POST").Add(1)
+
+			gauge := registry.NewGauge("queue_depth", "Depth.", "queue")
+			registry.OnCollect(func(context.Context) { gauge.With("queue", "document").Set(5) })
+
+			histogram := registry.NewHistogram("duration_seconds", "Duration.", []float64{.1, 1})
+			histogram.Observe(.05)
+			histogram.Observe(.5)
+			histogram.Observe(2)
+
+			registry.NewCounter("unused_total", "Unused.")
+
+			w := httptest.NewRecorder()
+			registry.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Header().Get("Content-Type"), ShouldEqual, "text/plain; version=0.0.4")
+			So(w.Body.String(), ShouldEqual, `# HELP duration_seconds Duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{le="0.1"} 1
+duration_seconds_bucket{le="1"} 2
+duration_seconds_bucket{le="+Inf"} 3
+duration_seconds_sum 2.55
+duration_seconds_count 3
+# HELP queue_depth Depth.
+# TYPE queue_depth gauge
+queue_depth{queue="document"} 5
+# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{method="GET",route="/resources"} 2
+requests_total{method="POST",route="/say \"hi\""} 1
+`)
+		})
+
+		Convey("It should return the same metric by name", func() {
+			registry.NewCounter("requests_total", "Requests.").Add(1)
+			registry.NewCounter("requests_total", "Requests.").Add(1)
+
+			w := httptest.NewRecorder()
+			registry.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			So(w.Body.String(), ShouldContainSubstring, "requests_total 2\n")
+		})
+
+		Convey("It should panic when the name is used by other kind of metric", func() {
+			registry.NewCounter("requests_total", "Requests.")
+			So(func() { registry.NewGauge("requests_total", "Requests.") }, ShouldPanic)
+		})
+	})
+}
@@ -4,13 +4,27 @@
 
 package task
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Pusher is used to send a task to be processed.
 type Pusher interface {
 	Push(context.Context, []byte) error
 }
 
+// BatchPusher is used to send many tasks at once. The result has one error per task, nil when the
+// task was sent.
+type BatchPusher interface {
+	PushBatch(context.Context, [][]byte) []error
+}
+
+// DelayPusher is used to send a task to be processed after a given delay.
+type DelayPusher interface {
+	PushDelay(context.Context, []byte, time.Duration) error
+}
+
 // Puller is used to fetch a task to process.
 type Puller interface {
 	Pull(context.Context, func(context.Context, []byte) error) error
@@ -20,3 +34,19 @@ type Puller interface {
 type Processor interface {
 	Process(context.Context, []byte) error
 }
+
+// Drainer is implemented by the pullers that can process the remaining tasks before stop.
+type Drainer interface {
+	Drain(context.Context) error
+}
+
+// Depther is implemented by the queues that can report the quantity of tasks waiting to be
+// processed.
+type Depther interface {
+	Depth(context.Context) (int, error)
+}
+
+// Pinger is implemented by the queues that can check if they are reachable.
+type Pinger interface {
+	Ping(context.Context) error
+}
@@ -6,12 +6,18 @@ package task
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
-
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
 	"github.com/pkg/errors"
+
+	infraMetrics "github.com/diegobernardes/flare/infra/metrics"
 )
 
 // Worker implements the logic to process tasks.
@@ -22,9 +28,21 @@ type Worker struct {
 	timeoutProcess time.Duration
 	timeoutPush    time.Duration
 	goroutines     int
+	running        int32
 	ctx            context.Context
 	ctxCancel      func()
 	logger         log.Logger
+	wg             sync.WaitGroup
+	queue          string
+	registry       *infraMetrics.Registry
+	metrics        workerMetrics
+}
+
+// workerMetrics holds the worker metrics, all labeled by the queue name.
+type workerMetrics struct {
+	pulls     metrics.Counter
+	processes metrics.Counter
+	duration  metrics.Histogram
 }
 
 // Push the task to be processed.
@@ -32,10 +50,47 @@ func (w *Worker) Push(ctx context.Context, content []byte) error {
 	return errors.Wrap(w.pusher.Push(ctx, content), "error during task push")
 }
 
+// PushBatch send the tasks to be processed. If the pusher don't support batches, the tasks are sent
+// one by one.
+func (w *Worker) PushBatch(ctx context.Context, contents [][]byte) []error {
+	pusher, ok := w.pusher.(BatchPusher)
+	if !ok {
+		errs := make([]error, len(contents))
+		for i, content := range contents {
+			errs[i] = w.Push(ctx, content)
+		}
+		return errs
+	}
+
+	errs := pusher.PushBatch(ctx, contents)
+	for i, err := range errs {
+		if err != nil {
+			errs[i] = errors.Wrap(err, "error during task push")
+		}
+	}
+	return errs
+}
+
+// PushDelay send the task to be processed after the delay.
+func (w *Worker) PushDelay(ctx context.Context, content []byte, delay time.Duration) error {
+	pusher, ok := w.pusher.(DelayPusher)
+	if !ok {
+		return errors.New("pusher does not support delayed tasks")
+	}
+	return errors.Wrap(pusher.PushDelay(ctx, content, delay), "error during task push")
+}
+
 // Start the worker to process tasks.
 func (w *Worker) Start() {
+	w.wg.Add(w.goroutines)
 	for i := 0; i < w.goroutines; i++ {
 		go func() {
+			atomic.AddInt32(&w.running, 1)
+			defer func() {
+				atomic.AddInt32(&w.running, -1)
+				w.wg.Done()
+			}()
+
 			for {
 				w.process()
 
@@ -47,6 +102,38 @@ func (w *Worker) Start() {
 	}
 }
 
+// Stop the worker. If the puller is a Drainer, the pending tasks are processed before the worker
+// goroutines are released.
+func (w *Worker) Stop(ctx context.Context) error {
+	var err error
+	if drainer, ok := w.puller.(Drainer); ok {
+		err = errors.Wrap(drainer.Drain(ctx), "error during task drain")
+	}
+
+	w.ctxCancel()
+	w.wg.Wait()
+	return err
+}
+
+// Check returns a error when the worker is not processing tasks, because it was not started, it's
+// stopped or some goroutines are not running.
+func (w *Worker) Check(context.Context) error {
+	if running := int(atomic.LoadInt32(&w.running)); running != w.goroutines {
+		return fmt.Errorf("%d of %d goroutines running", running, w.goroutines)
+	}
+	return nil
+}
+
+// Ping check if the queue of the worker is reachable. Pullers that don't implement Pinger are
+// assumed to be reachable.
+func (w *Worker) Ping(ctx context.Context) error {
+	pinger, ok := w.puller.(Pinger)
+	if !ok {
+		return nil
+	}
+	return errors.Wrap(pinger.Ping(ctx), "error during queue ping")
+}
+
 func (w *Worker) process() {
 	defer func() { recover() }()
 
@@ -55,18 +142,65 @@ func (w *Worker) process() {
 
 	err := w.puller.Pull(ctx, func(ctx context.Context, content []byte) error {
 		level.Info(w.logger).Log("message", "message received to be processed")
+		w.metrics.pulls.With("result", "success").Add(1)
 
-		if err := w.processor.Process(ctx, content); err != nil {
+		start := time.Now()
+		err := w.processor.Process(ctx, content)
+		w.metrics.duration.Observe(time.Since(start).Seconds())
+		if err != nil {
+			w.metrics.processes.With("result", "error").Add(1)
 			level.Error(w.logger).Log("error", err.Error(), "message", "error during message process")
 			return err
 		}
+		w.metrics.processes.With("result", "success").Add(1)
 		return nil
 	})
 	if err != nil {
+		w.metrics.pulls.With("result", "error").Add(1)
 		level.Error(w.logger).Log("error", err.Error(), "message", "error during message pull")
 	}
 }
 
+func (w *Worker) initMetrics() {
+	if w.registry == nil {
+		w.metrics = workerMetrics{
+			pulls:     discard.NewCounter(),
+			processes: discard.NewCounter(),
+			duration:  discard.NewHistogram(),
+		}
+		return
+	}
+
+	w.metrics = workerMetrics{
+		pulls: w.registry.NewCounter(
+			"task_pulls_total", "Quantity of tasks pulled from the queue.", "queue", "result",
+		).With("queue", w.queue),
+		processes: w.registry.NewCounter(
+			"task_processes_total", "Quantity of tasks processed.", "queue", "result",
+		).With("queue", w.queue),
+		duration: w.registry.NewHistogram(
+			"task_process_duration_seconds", "Time spent to process the tasks.", nil, "queue",
+		).With("queue", w.queue),
+	}
+
+	depther, ok := w.puller.(Depther)
+	if !ok {
+		return
+	}
+
+	depth := w.registry.NewGauge(
+		"task_queue_depth", "Quantity of tasks waiting to be processed.", "queue",
+	).With("queue", w.queue)
+	w.registry.OnCollect(func(ctx context.Context) {
+		value, err := depther.Depth(ctx)
+		if err != nil {
+			level.Error(w.logger).Log("error", err.Error(), "message", "error during queue depth fetch")
+			return
+		}
+		depth.Set(float64(value))
+	})
+}
+
 // NewWorker returns a configured worker.
 func NewWorker(options ...func(*Worker)) (*Worker, error) {
 	w := &Worker{}
@@ -103,6 +237,8 @@ func NewWorker(options ...func(*Worker)) (*Worker, error) {
 		return nil, errors.New("logger not found")
 	}
 
+	w.initMetrics()
+
 	ctx, ctxCancel := context.WithCancel(context.Background())
 	w.ctx = ctx
 	w.ctxCancel = ctxCancel
@@ -158,3 +294,13 @@ func WorkerLogger(logger log.Logger) func(*Worker) {
 		w.logger = log.With(logger, "package", "infra/task")
 	}
 }
+
+// WorkerMetrics set the registry used to record the pulls, the processes and its duration, labeled
+// by the queue name. If the puller is a Depther, the queue depth is updated on each collect.
+// Without the registry, the metrics are discarded.
+func WorkerMetrics(queue string, registry *infraMetrics.Registry) func(*Worker) {
+	return func(w *Worker) {
+		w.queue = queue
+		w.registry = registry
+	}
+}
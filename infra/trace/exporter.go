@@ -0,0 +1,249 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Name of the instrumentation scope at the exported spans.
+const exporterScope = "github.com/diegobernardes/flare"
+
+// OTLP status code of the failed spans.
+const otlpStatusError = 2
+
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	TraceState        string          `json:"traceState,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status,omitempty"`
+}
+
+// encodeOTLP encode the spans as a OTLP ExportTraceServiceRequest with the JSON encoding.
+func encodeOTLP(serviceName string, spans []*Span) ([]byte, error) {
+	attribute := func(key, value string) otlpAttribute {
+		var a otlpAttribute
+		a.Key = key
+		a.Value.StringValue = value
+		return a
+	}
+
+	result := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		span.mutex.Lock()
+		s := otlpSpan{
+			TraceID:           hex.EncodeToString(span.context.TraceID[:]),
+			SpanID:            hex.EncodeToString(span.context.SpanID[:]),
+			TraceState:        span.context.State,
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		}
+
+		if span.Parent != [8]byte{} {
+			s.ParentSpanID = hex.EncodeToString(span.Parent[:])
+		}
+
+		keys := make([]string, 0, len(span.Attributes))
+		for key := range span.Attributes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s.Attributes = append(s.Attributes, attribute(key, span.Attributes[key]))
+		}
+
+		if span.Error != "" {
+			s.Status = &struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			}{Code: otlpStatusError, Message: span.Error}
+		}
+		span.mutex.Unlock()
+
+		result = append(result, s)
+	}
+
+	content, err := json.Marshal(map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": []otlpAttribute{attribute("service.name", serviceName)},
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": exporterScope},
+						"spans": result,
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error during spans marshal")
+	}
+	return content, nil
+}
+
+// OTLPExporter send the spans to a OpenTelemetry collector with the OTLP/HTTP protocol and the
+// JSON encoding.
+type OTLPExporter struct {
+	endpoint    string
+	serviceName string
+	httpClient  *http.Client
+	header      http.Header
+}
+
+// Export the spans.
+func (e *OTLPExporter) Export(ctx context.Context, spans []*Span) error {
+	content, err := encodeOTLP(e.serviceName, spans)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(content))
+	if err != nil {
+		return errors.Wrap(err, "error during request create")
+	}
+	req = req.WithContext(ctx)
+	for key, values := range e.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error during spans export")
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status '%d' during spans export", resp.StatusCode)
+	}
+	return nil
+}
+
+// NewOTLPExporter returns a configured OTLPExporter.
+func NewOTLPExporter(options ...func(*OTLPExporter)) (*OTLPExporter, error) {
+	e := &OTLPExporter{}
+
+	for _, option := range options {
+		option(e)
+	}
+
+	if e.endpoint == "" {
+		return nil, errors.New("endpoint not found")
+	}
+
+	if e.httpClient == nil {
+		return nil, errors.New("httpClient not found")
+	}
+
+	if e.serviceName == "" {
+		e.serviceName = "flare"
+	}
+	return e, nil
+}
+
+// OTLPExporterEndpoint set the collector traces endpoint, like "http://localhost:4318/v1/traces".
+func OTLPExporterEndpoint(endpoint string) func(*OTLPExporter) {
+	return func(e *OTLPExporter) { e.endpoint = endpoint }
+}
+
+// OTLPExporterServiceName set the service name of the spans.
+func OTLPExporterServiceName(name string) func(*OTLPExporter) {
+	return func(e *OTLPExporter) { e.serviceName = name }
+}
+
+// OTLPExporterHTTPClient set the client used to send the spans.
+func OTLPExporterHTTPClient(client *http.Client) func(*OTLPExporter) {
+	return func(e *OTLPExporter) { e.httpClient = client }
+}
+
+// OTLPExporterHeader set the headers sent to the collector, like the authentication.
+func OTLPExporterHeader(header http.Header) func(*OTLPExporter) {
+	return func(e *OTLPExporter) { e.header = header }
+}
+
+// FileExporter write the spans to a file, one OTLP JSON request per line, the same format of the
+// OpenTelemetry collector file exporter.
+type FileExporter struct {
+	serviceName string
+	writer      io.Writer
+	mutex       sync.Mutex
+}
+
+// Export the spans.
+func (e *FileExporter) Export(_ context.Context, spans []*Span) error {
+	content, err := encodeOTLP(e.serviceName, spans)
+	if err != nil {
+		return err
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if _, err = e.writer.Write(append(content, '\n')); err != nil {
+		return errors.Wrap(err, "error during spans write")
+	}
+	return nil
+}
+
+// NewFileExporter returns a configured FileExporter.
+func NewFileExporter(options ...func(*FileExporter)) (*FileExporter, error) {
+	e := &FileExporter{}
+
+	for _, option := range options {
+		option(e)
+	}
+
+	if e.writer == nil {
+		return nil, errors.New("writer not found")
+	}
+
+	if e.serviceName == "" {
+		e.serviceName = "flare"
+	}
+	return e, nil
+}
+
+// FileExporterWriter set where the spans are written.
+func FileExporterWriter(writer io.Writer) func(*FileExporter) {
+	return func(e *FileExporter) { e.writer = writer }
+}
+
+// FileExporterServiceName set the service name of the spans.
+func FileExporterServiceName(name string) func(*FileExporter) {
+	return func(e *FileExporter) { e.serviceName = name }
+}
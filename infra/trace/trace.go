@@ -0,0 +1,195 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package trace implements the W3C Trace Context propagation and a minimal tracer that export the
+// spans with the OTLP JSON encoding.
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The W3C Trace Context headers, https://www.w3.org/TR/trace-context. The same names are used as
+// keys at the queue messages.
+const (
+	HeaderTraceparent = "traceparent"
+	HeaderTracestate  = "tracestate"
+)
+
+// Version of the traceparent generated by Flare.
+const traceparentVersion = "00"
+
+// Flag set when the trace is sampled.
+const flagSampled = 0x01
+
+type contextKey int
+
+const (
+	contextKeySpan contextKey = iota
+	contextKeyRemote
+)
+
+// SpanContext identify a span across the process boundaries.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+	State   string
+}
+
+// Valid indicates if the trace and the span ids are set.
+func (sc SpanContext) Valid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Sampled indicates if the span should be recorded.
+func (sc SpanContext) Sampled() bool { return sc.Flags&flagSampled != 0 }
+
+// Traceparent returns the value of the traceparent header.
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf(
+		"%s-%s-%s-%02x",
+		traceparentVersion,
+		hex.EncodeToString(sc.TraceID[:]),
+		hex.EncodeToString(sc.SpanID[:]),
+		sc.Flags,
+	)
+}
+
+// Parse the traceparent and the tracestate. The versions after 00 are accepted as long the first
+// fields follow the 00 format.
+func Parse(traceparent, tracestate string) (SpanContext, error) {
+	var sc SpanContext
+
+	fields := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(fields) < 4 {
+		return sc, fmt.Errorf("invalid traceparent '%s'", traceparent)
+	}
+
+	version := fields[0]
+	if len(version) != 2 || version == "ff" || (version == traceparentVersion && len(fields) != 4) {
+		return sc, fmt.Errorf("invalid traceparent version '%s'", version)
+	}
+
+	if _, err := hex.DecodeString(version); err != nil {
+		return sc, errors.Wrapf(err, "invalid traceparent version '%s'", version)
+	}
+
+	for _, field := range []struct {
+		name  string
+		value string
+		dst   []byte
+	}{
+		{"trace id", fields[1], sc.TraceID[:]},
+		{"span id", fields[2], sc.SpanID[:]},
+	} {
+		size := hex.EncodedLen(len(field.dst))
+		if len(field.value) != size || field.value != strings.ToLower(field.value) {
+			return sc, fmt.Errorf("invalid traceparent %s '%s'", field.name, field.value)
+		}
+
+		if _, err := hex.Decode(field.dst, []byte(field.value)); err != nil {
+			return sc, errors.Wrapf(err, "invalid traceparent %s '%s'", field.name, field.value)
+		}
+	}
+
+	flags, err := hex.DecodeString(fields[3])
+	if err != nil || len(flags) != 1 {
+		return sc, fmt.Errorf("invalid traceparent flags '%s'", fields[3])
+	}
+	sc.Flags = flags[0]
+
+	if !sc.Valid() {
+		return sc, errors.New("invalid traceparent, the trace and span ids can't be zero")
+	}
+
+	sc.State = strings.TrimSpace(tracestate)
+	return sc, nil
+}
+
+// FromContext returns the span context of the current span or, when there is no span, the remote
+// span context extracted from a request or a message.
+func FromContext(ctx context.Context) (SpanContext, bool) {
+	if span, ok := ctx.Value(contextKeySpan).(*Span); ok {
+		return span.context, true
+	}
+
+	sc, ok := ctx.Value(contextKeyRemote).(SpanContext)
+	return sc, ok
+}
+
+// ContextWithRemote returns a context with the span context received from other process. It's
+// used as the parent of the next span.
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, contextKeyRemote, sc)
+}
+
+// Extract the span context from the headers. If the headers don't have a valid traceparent, the
+// context is returned as is.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	sc, err := Parse(header.Get(HeaderTraceparent), header.Get(HeaderTracestate))
+	if err != nil {
+		return ctx
+	}
+	return ContextWithRemote(ctx, sc)
+}
+
+// Inject the span context at the headers.
+func Inject(ctx context.Context, header http.Header) {
+	sc, ok := FromContext(ctx)
+	if !ok {
+		return
+	}
+
+	header.Set(HeaderTraceparent, sc.Traceparent())
+	if sc.State != "" {
+		header.Set(HeaderTracestate, sc.State)
+	}
+}
+
+// InjectMap set the span context at the content of a message.
+func InjectMap(ctx context.Context, content map[string]interface{}) {
+	sc, ok := FromContext(ctx)
+	if !ok {
+		return
+	}
+
+	content[HeaderTraceparent] = sc.Traceparent()
+	if sc.State != "" {
+		content[HeaderTracestate] = sc.State
+	}
+}
+
+// ExtractMap returns a context with the span context from the content of a message.
+func ExtractMap(ctx context.Context, content map[string]interface{}) context.Context {
+	traceparent, _ := content[HeaderTraceparent].(string)
+	tracestate, _ := content[HeaderTracestate].(string)
+
+	sc, err := Parse(traceparent, tracestate)
+	if err != nil {
+		return ctx
+	}
+	return ContextWithRemote(ctx, sc)
+}
+
+func randomID(dst []byte) {
+	for {
+		if _, err := rand.Read(dst); err != nil {
+			panic(errors.Wrap(err, "error during random id generation"))
+		}
+
+		for _, b := range dst {
+			if b != 0 {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParse(t *testing.T) {
+	Convey("Given a list of valid traceparents", t, func() {
+		tests := []struct {
+			traceparent string
+			sampled     bool
+		}{
+			{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+			{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", false},
+			{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", true},
+		}
+
+		Convey("The output should be valid", func() {
+			for _, tt := range tests {
+				sc, err := Parse(tt.traceparent, "")
+				So(err, ShouldBeNil)
+				So(sc.Valid(), ShouldBeTrue)
+				So(sc.Sampled(), ShouldEqual, tt.sampled)
+			}
+		})
+
+		Convey("The traceparent should be generated back", func() {
+			sc, err := Parse(tests[0].traceparent, "")
+			So(err, ShouldBeNil)
+			So(sc.Traceparent(), ShouldEqual, tests[0].traceparent)
+		})
+	})
+
+	Convey("Given a list of invalid traceparents", t, func() {
+		tests := []string{
+			"",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+			"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902bz-01",
+		}
+
+		Convey("The output should be a error", func() {
+			for _, tt := range tests {
+				_, err := Parse(tt, "")
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestPropagation(t *testing.T) {
+	Convey("Given a request with the trace context", t, func() {
+		header := make(http.Header)
+		header.Set(HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		header.Set(HeaderTracestate, "vendor=value")
+		ctx := Extract(context.Background(), header)
+
+		Convey("The span should be a child of the remote span", func() {
+			var tracer *Tracer
+			ctx, span := tracer.StartSpan(ctx, "process", SpanKindConsumer)
+			remote, _ := Parse(header.Get(HeaderTraceparent), "")
+			So(span.Context().TraceID, ShouldEqual, remote.TraceID)
+			So(span.Parent, ShouldEqual, remote.SpanID)
+			So(span.Context().SpanID, ShouldNotEqual, remote.SpanID)
+
+			Convey("The span should be propagated to the headers and the messages", func() {
+				output := make(http.Header)
+				Inject(ctx, output)
+				So(output.Get(HeaderTraceparent), ShouldEqual, span.Context().Traceparent())
+				So(output.Get(HeaderTracestate), ShouldEqual, "vendor=value")
+
+				content := make(map[string]interface{})
+				InjectMap(ctx, content)
+				sc, ok := FromContext(ExtractMap(context.Background(), content))
+				So(ok, ShouldBeTrue)
+				So(sc, ShouldResemble, span.Context())
+			})
+		})
+	})
+
+	Convey("Given a request without the trace context", t, func() {
+		ctx := Extract(context.Background(), make(http.Header))
+
+		Convey("A new trace should be started", func() {
+			_, ok := FromContext(ctx)
+			So(ok, ShouldBeFalse)
+
+			var tracer *Tracer
+			_, span := tracer.StartSpan(ctx, "request", SpanKindServer)
+			So(span.Context().Valid(), ShouldBeTrue)
+			So(span.Context().Sampled(), ShouldBeTrue)
+			So(span.Parent, ShouldEqual, [8]byte{})
+		})
+	})
+}
+
+func TestTracerExport(t *testing.T) {
+	Convey("Given a Tracer with the file exporter", t, func() {
+		buf := &bytes.Buffer{}
+		exporter, err := NewFileExporter(FileExporterWriter(buf), FileExporterServiceName("test"))
+		So(err, ShouldBeNil)
+
+		tracer, err := NewTracer(TracerExporter(exporter), TracerLogger(log.NewNopLogger()))
+		So(err, ShouldBeNil)
+		tracer.Start()
+
+		Convey("The finished spans should be exported on stop", func() {
+			ctx, parent := tracer.StartSpan(context.Background(), "parent", SpanKindServer)
+			_, child := tracer.StartSpan(ctx, "child", SpanKindClient)
+			child.SetAttribute("subscription.id", "456")
+			child.SetError(errors.New("failure"))
+			child.Finish()
+			parent.Finish()
+			parent.Finish()
+			tracer.Stop()
+
+			var content struct {
+				ResourceSpans []struct {
+					Resource struct {
+						Attributes []otlpAttribute `json:"attributes"`
+					} `json:"resource"`
+					ScopeSpans []struct {
+						Spans []otlpSpan `json:"spans"`
+					} `json:"scopeSpans"`
+				} `json:"resourceSpans"`
+			}
+			So(json.Unmarshal(buf.Bytes(), &content), ShouldBeNil)
+			So(content.ResourceSpans, ShouldHaveLength, 1)
+			So(content.ResourceSpans[0].Resource.Attributes[0].Value.StringValue, ShouldEqual, "test")
+
+			spans := content.ResourceSpans[0].ScopeSpans[0].Spans
+			So(spans, ShouldHaveLength, 2)
+			So(spans[0].Name, ShouldEqual, "child")
+			So(spans[0].TraceID, ShouldEqual, spans[1].TraceID)
+			So(spans[0].ParentSpanID, ShouldEqual, spans[1].SpanID)
+			So(spans[0].Attributes[0].Key, ShouldEqual, "subscription.id")
+			So(spans[0].Status.Code, ShouldEqual, otlpStatusError)
+			So(spans[1].Name, ShouldEqual, "parent")
+			So(spans[1].ParentSpanID, ShouldBeEmpty)
+			So(spans[1].Status, ShouldBeNil)
+		})
+	})
+}
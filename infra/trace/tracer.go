@@ -0,0 +1,240 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// The kinds of span, the values follow the OTLP enumeration.
+const (
+	SpanKindInternal = 1
+	SpanKindServer   = 2
+	SpanKindClient   = 3
+	SpanKindProducer = 4
+	SpanKindConsumer = 5
+)
+
+// Exporter send the finished spans to a backend.
+type Exporter interface {
+	Export(context.Context, []*Span) error
+}
+
+// Span is a operation of a trace.
+type Span struct {
+	Name       string
+	Kind       int
+	Parent     [8]byte
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]string
+	Error      string
+
+	context SpanContext
+	tracer  *Tracer
+	mutex   sync.Mutex
+	ended   bool
+}
+
+// Context returns the span context, used to propagate the span.
+func (s *Span) Context() SpanContext { return s.context }
+
+// SetAttribute set a attribute at the span.
+func (s *Span) SetAttribute(key, value string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Attributes[key] = value
+}
+
+// SetError mark the span as failed. Nil errors are ignored.
+func (s *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Error = err.Error()
+}
+
+// Finish end the span and send it to be exported. The next calls are ignored.
+func (s *Span) Finish() {
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.End = time.Now()
+	s.mutex.Unlock()
+
+	if s.tracer != nil && s.context.Sampled() {
+		s.tracer.enqueue(s)
+	}
+}
+
+// Tracer create the spans and export them in batches. A nil Tracer is valid, the spans are created
+// and propagated but never exported.
+type Tracer struct {
+	exporter      Exporter
+	logger        log.Logger
+	bufferSize    int
+	batchSize     int
+	flushInterval time.Duration
+	timeout       time.Duration
+
+	spans     chan *Span
+	ctx       context.Context
+	ctxCancel func()
+	wg        sync.WaitGroup
+}
+
+// StartSpan start a span as a child of the span, or the remote span context, at the context. When
+// there is no parent, a new trace is started.
+func (t *Tracer) StartSpan(
+	ctx context.Context, name string, kind int,
+) (context.Context, *Span) {
+	span := &Span{
+		Name:       name,
+		Kind:       kind,
+		Start:      time.Now(),
+		Attributes: make(map[string]string),
+		tracer:     t,
+	}
+
+	if parent, ok := FromContext(ctx); ok && parent.Valid() {
+		span.context = SpanContext{TraceID: parent.TraceID, Flags: parent.Flags, State: parent.State}
+		span.Parent = parent.SpanID
+	} else {
+		randomID(span.context.TraceID[:])
+		span.context.Flags = flagSampled
+	}
+	randomID(span.context.SpanID[:])
+
+	return context.WithValue(ctx, contextKeySpan, span), span
+}
+
+// Start the goroutine that export the spans.
+func (t *Tracer) Start() {
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+
+		ticker := time.NewTicker(t.flushInterval)
+		defer ticker.Stop()
+
+		batch := make([]*Span, 0, t.batchSize)
+		for {
+			select {
+			case span := <-t.spans:
+				if batch = append(batch, span); len(batch) < t.batchSize {
+					continue
+				}
+			case <-ticker.C:
+			case <-t.ctx.Done():
+				for len(t.spans) > 0 {
+					batch = append(batch, <-t.spans)
+				}
+				t.export(batch)
+				return
+			}
+
+			t.export(batch)
+			batch = make([]*Span, 0, t.batchSize)
+		}
+	}()
+}
+
+// Stop the tracer and export the pending spans.
+func (t *Tracer) Stop() {
+	t.ctxCancel()
+	t.wg.Wait()
+}
+
+func (t *Tracer) export(spans []*Span) {
+	if len(spans) == 0 {
+		return
+	}
+
+	ctx, ctxCancel := context.WithTimeout(context.Background(), t.timeout)
+	defer ctxCancel()
+
+	if err := t.exporter.Export(ctx, spans); err != nil {
+		level.Error(t.logger).Log("error", err.Error(), "message", "error during spans export")
+	}
+}
+
+// enqueue send the span to be exported. If the buffer is full the span is discarded, the tracing
+// should never slow down the processing.
+func (t *Tracer) enqueue(span *Span) {
+	select {
+	case t.spans <- span:
+	default:
+		level.Warn(t.logger).Log("message", "span discarded, the export buffer is full")
+	}
+}
+
+// NewTracer returns a configured tracer.
+func NewTracer(options ...func(*Tracer)) (*Tracer, error) {
+	t := &Tracer{}
+
+	for _, option := range options {
+		option(t)
+	}
+
+	if t.exporter == nil {
+		return nil, errors.New("exporter not found")
+	}
+
+	if t.logger == nil {
+		return nil, errors.New("logger not found")
+	}
+
+	if t.bufferSize == 0 {
+		t.bufferSize = 4096
+	}
+
+	if t.batchSize == 0 {
+		t.batchSize = 512
+	}
+
+	if t.flushInterval == 0 {
+		t.flushInterval = 5 * time.Second
+	}
+
+	if t.timeout == 0 {
+		t.timeout = 10 * time.Second
+	}
+
+	t.spans = make(chan *Span, t.bufferSize)
+	t.ctx, t.ctxCancel = context.WithCancel(context.Background())
+	return t, nil
+}
+
+// TracerExporter set the exporter of the spans.
+func TracerExporter(exporter Exporter) func(*Tracer) {
+	return func(t *Tracer) { t.exporter = exporter }
+}
+
+// TracerLogger set the logger.
+func TracerLogger(logger log.Logger) func(*Tracer) {
+	return func(t *Tracer) { t.logger = log.With(logger, "package", "infra/trace") }
+}
+
+// TracerFlushInterval set the max time the spans wait to be exported.
+func TracerFlushInterval(interval time.Duration) func(*Tracer) {
+	return func(t *Tracer) { t.flushInterval = interval }
+}
+
+// TracerBatchSize set the quantity of spans exported at once.
+func TracerBatchSize(size int) func(*Tracer) {
+	return func(t *Tracer) { t.batchSize = size }
+}
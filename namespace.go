@@ -0,0 +1,89 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flare
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+const (
+	// NamespaceDefault is the namespace of the requests without one and of the entities created
+	// before the namespaces.
+	NamespaceDefault = "default"
+
+	// NamespaceAll is used at the context to query the entities of all the namespaces, like at the
+	// poller. It's not a valid namespace name.
+	NamespaceAll = "*"
+)
+
+type namespaceContextKey int
+
+const (
+	namespaceContextKeyName namespaceContextKey = iota
+	namespaceContextKeyCreateLimit
+)
+
+var namespaceName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// ValidNamespace check if the namespace name is valid. The name has lower case letters, digits and
+// dashes, at most 63 chars.
+func ValidNamespace(namespace string) error {
+	if !namespaceName.MatchString(namespace) {
+		return fmt.Errorf("invalid namespace '%s'", namespace)
+	}
+	return nil
+}
+
+// ContextWithNamespace returns a context scoped to the namespace. All the repositories queries
+// are restricted to the namespace at the context.
+func ContextWithNamespace(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, namespaceContextKeyName, namespace)
+}
+
+// NamespaceFromContext returns the namespace at the context, or the default one if not set.
+func NamespaceFromContext(ctx context.Context) string {
+	if namespace, ok := ctx.Value(namespaceContextKeyName).(string); ok && namespace != "" {
+		return namespace
+	}
+	return NamespaceDefault
+}
+
+// ContextWithCreateLimit returns a context that limit the quantity of entities the namespace can
+// have. The repositories check the limit at the create, this way, the concurrent creates can't go
+// above it. The zero value is unlimited.
+func ContextWithCreateLimit(ctx context.Context, limit int) context.Context {
+	return context.WithValue(ctx, namespaceContextKeyCreateLimit, limit)
+}
+
+// CreateLimitFromContext returns the create limit at the context, or zero if not set.
+func CreateLimitFromContext(ctx context.Context) int {
+	limit, _ := ctx.Value(namespaceContextKeyCreateLimit).(int)
+	return limit
+}
+
+// NamespaceMatch indicates if a entity at the namespace is visible from the context. The entities
+// without namespace belong to the default one.
+func NamespaceMatch(ctx context.Context, namespace string) bool {
+	current := NamespaceFromContext(ctx)
+	if current == NamespaceAll {
+		return true
+	}
+
+	if namespace == "" {
+		namespace = NamespaceDefault
+	}
+	return current == namespace
+}
+
+// NamespaceQuota limit the usage of a namespace. The zero values are unlimited. The ingestion is
+// the rate of documents per second, with the burst of documents accepted at once.
+type NamespaceQuota struct {
+	Resources      int
+	Subscriptions  int
+	IngestionRate  float64
+	IngestionBurst int
+}
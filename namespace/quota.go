@@ -0,0 +1,148 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package namespace implements the quotas that limit the usage of each namespace, this way, a
+// namespace can't exhaust the workers of the others.
+package namespace
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/diegobernardes/flare"
+)
+
+// Quota hold the quotas of the namespaces. The namespaces without a specific quota use the default
+// one. The ingestion rate is controlled by a token bucket per namespace, the buckets are kept in
+// memory, so, each instance has its own rate.
+type Quota struct {
+	base      flare.NamespaceQuota
+	overrides map[string]flare.NamespaceQuota
+	now       func() time.Time
+
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Get returns the quota of the namespace.
+func (q *Quota) Get(namespace string) flare.NamespaceQuota {
+	if quota, ok := q.overrides[namespace]; ok {
+		return quota
+	}
+	return q.base
+}
+
+// Take try to consume n documents from the ingestion rate of the namespace at the context. It
+// returns the quantity of documents allowed and, when not all of them were, the time until the next
+// one is available.
+func (q *Quota) Take(ctx context.Context, n int) (int, time.Duration) {
+	namespace := flare.NamespaceFromContext(ctx)
+	quota := q.Get(namespace)
+	if quota.IngestionRate == 0 {
+		return n, 0
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := q.now()
+	burst := float64(quota.IngestionBurst)
+	b, ok := q.buckets[namespace]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		q.buckets[namespace] = b
+	}
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed.Seconds()*quota.IngestionRate)
+	}
+	b.last = now
+
+	granted := n
+	if available := int(b.tokens); available < n {
+		granted = available
+	}
+	b.tokens -= float64(granted)
+
+	if granted == n {
+		return granted, 0
+	}
+
+	wait := (1 - b.tokens) / quota.IngestionRate
+	return granted, time.Duration(wait * float64(time.Second))
+}
+
+// NewQuota returns a configured quota.
+func NewQuota(options ...func(*Quota)) (*Quota, error) {
+	q := &Quota{
+		overrides: make(map[string]flare.NamespaceQuota),
+		buckets:   make(map[string]*bucket),
+		now:       time.Now,
+	}
+
+	for _, option := range options {
+		option(q)
+	}
+
+	var err error
+	if q.base, err = q.normalize(q.base); err != nil {
+		return nil, errors.Wrap(err, "invalid default quota")
+	}
+
+	for namespace, quota := range q.overrides {
+		if err := flare.ValidNamespace(namespace); err != nil {
+			return nil, err
+		}
+
+		if q.overrides[namespace], err = q.normalize(quota); err != nil {
+			return nil, errors.Wrapf(err, "invalid quota of namespace '%s'", namespace)
+		}
+	}
+
+	return q, nil
+}
+
+// normalize validate the quota and set the burst, when missing, to the quantity of documents
+// ingested in one second.
+func (q *Quota) normalize(quota flare.NamespaceQuota) (flare.NamespaceQuota, error) {
+	if quota.Resources < 0 {
+		return quota, errors.New("invalid resources")
+	}
+
+	if quota.Subscriptions < 0 {
+		return quota, errors.New("invalid subscriptions")
+	}
+
+	if quota.IngestionRate < 0 {
+		return quota, errors.New("invalid ingestion rate")
+	}
+
+	if quota.IngestionBurst < 0 {
+		return quota, errors.New("invalid ingestion burst")
+	}
+
+	if quota.IngestionRate > 0 && quota.IngestionBurst == 0 {
+		quota.IngestionBurst = int(math.Max(1, math.Ceil(quota.IngestionRate)))
+	}
+	return quota, nil
+}
+
+// QuotaDefault set the quota of the namespaces without a specific one.
+func QuotaDefault(quota flare.NamespaceQuota) func(*Quota) {
+	return func(q *Quota) { q.base = quota }
+}
+
+// QuotaNamespace set the quota of a namespace.
+func QuotaNamespace(namespace string, quota flare.NamespaceQuota) func(*Quota) {
+	return func(q *Quota) { q.overrides[namespace] = quota }
+}
@@ -0,0 +1,105 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package namespace
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/diegobernardes/flare"
+)
+
+func TestNewQuota(t *testing.T) {
+	Convey("Given a list of invalid options", t, func() {
+		tests := []struct {
+			title   string
+			options []func(*Quota)
+		}{
+			{
+				"Negative resources",
+				[]func(*Quota){QuotaDefault(flare.NamespaceQuota{Resources: -1})},
+			},
+			{
+				"Negative ingestion rate",
+				[]func(*Quota){QuotaNamespace("team-a", flare.NamespaceQuota{IngestionRate: -1})},
+			},
+			{
+				"Invalid namespace",
+				[]func(*Quota){QuotaNamespace("Team A", flare.NamespaceQuota{})},
+			},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				_, err := NewQuota(tt.options...)
+				So(err, ShouldNotBeNil)
+			})
+		}
+	})
+
+	Convey("Given a quota with a namespace override", t, func() {
+		q, err := NewQuota(
+			QuotaDefault(flare.NamespaceQuota{Resources: 10, IngestionRate: 2.5}),
+			QuotaNamespace("team-a", flare.NamespaceQuota{Resources: 1}),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("The namespaces should have the right quota", func() {
+			So(q.Get("team-a"), ShouldResemble, flare.NamespaceQuota{Resources: 1})
+			So(q.Get("team-b"), ShouldResemble, flare.NamespaceQuota{
+				Resources: 10, IngestionRate: 2.5, IngestionBurst: 3,
+			})
+		})
+	})
+}
+
+func TestQuotaTake(t *testing.T) {
+	Convey("Given a quota with a ingestion rate", t, func() {
+		now := time.Date(2017, time.November, 10, 23, 0, 0, 0, time.UTC)
+		q, err := NewQuota(
+			QuotaDefault(flare.NamespaceQuota{IngestionRate: 2, IngestionBurst: 4}),
+			QuotaNamespace("unlimited", flare.NamespaceQuota{}),
+		)
+		So(err, ShouldBeNil)
+		q.now = func() time.Time { return now }
+
+		ctxA := flare.ContextWithNamespace(context.Background(), "team-a")
+		ctxB := flare.ContextWithNamespace(context.Background(), "team-b")
+
+		Convey("The burst should be allowed at once", func() {
+			granted, wait := q.Take(ctxA, 3)
+			So(granted, ShouldEqual, 3)
+			So(wait, ShouldEqual, 0)
+
+			Convey("The documents above the burst should wait", func() {
+				granted, wait := q.Take(ctxA, 3)
+				So(granted, ShouldEqual, 1)
+				So(wait, ShouldEqual, 500*time.Millisecond)
+
+				Convey("The other namespaces should not be affected", func() {
+					granted, _ := q.Take(ctxB, 4)
+					So(granted, ShouldEqual, 4)
+				})
+
+				Convey("The tokens should be refilled with the time", func() {
+					now = now.Add(time.Second)
+					granted, wait := q.Take(ctxA, 3)
+					So(granted, ShouldEqual, 2)
+					So(wait, ShouldEqual, 500*time.Millisecond)
+				})
+			})
+		})
+
+		Convey("The namespace without rate should not be limited", func() {
+			ctx := flare.ContextWithNamespace(context.Background(), "unlimited")
+			granted, wait := q.Take(ctx, 1000)
+			So(granted, ShouldEqual, 1000)
+			So(wait, ShouldEqual, 0)
+		})
+	})
+}
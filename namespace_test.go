@@ -0,0 +1,62 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flare
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValidNamespace(t *testing.T) {
+	Convey("Given a list of valid namespaces", t, func() {
+		tests := []string{"default", "team-a", "a", "42", "a-b-c"}
+
+		Convey("The validation should not return a error", func() {
+			for _, tt := range tests {
+				So(ValidNamespace(tt), ShouldBeNil)
+			}
+		})
+	})
+
+	Convey("Given a list of invalid namespaces", t, func() {
+		tests := []string{
+			"", "*", "Team", "team_a", "-team", "team-", "team/a",
+			"a123456789a123456789a123456789a123456789a123456789a123456789abcd",
+		}
+
+		Convey("The validation should return a error", func() {
+			for _, tt := range tests {
+				So(ValidNamespace(tt), ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestNamespaceMatch(t *testing.T) {
+	Convey("Given a list of contexts and namespaces", t, func() {
+		tests := []struct {
+			title     string
+			ctx       context.Context
+			namespace string
+			expected  bool
+		}{
+			{"Both without namespace", context.Background(), "", true},
+			{"Context without namespace", context.Background(), "default", true},
+			{"Context without namespace and other entity", context.Background(), "team-a", false},
+			{"Same namespace", ContextWithNamespace(context.Background(), "team-a"), "team-a", true},
+			{"Other namespace", ContextWithNamespace(context.Background(), "team-a"), "team-b", false},
+			{"Entity without namespace", ContextWithNamespace(context.Background(), "team-a"), "", false},
+			{"All namespaces", ContextWithNamespace(context.Background(), NamespaceAll), "team-b", true},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				So(NamespaceMatch(tt.ctx, tt.namespace), ShouldEqual, tt.expected)
+			})
+		}
+	})
+}
@@ -0,0 +1,184 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Queue implements task.Pusher and task.Puller using a bounded in-memory buffer. When a message
+// fails to be processed it become visible again after the visibility timeout.
+type Queue struct {
+	mutex             sync.Mutex
+	messages          chan []byte
+	bufferSize        int
+	visibilityTimeout time.Duration
+	pending           int
+	closed            bool
+	drained           chan struct{}
+	quit              chan struct{}
+}
+
+// Push content to the queue. If the buffer is full, it gonna block until there is space or the
+// context is done.
+func (q *Queue) Push(ctx context.Context, content []byte) error {
+	q.mutex.Lock()
+	if q.closed {
+		q.mutex.Unlock()
+		return errors.New("queue is closed")
+	}
+	q.pending++
+	q.mutex.Unlock()
+
+	select {
+	case q.messages <- content:
+		return nil
+	case <-ctx.Done():
+		q.done()
+		return errors.Wrap(ctx.Err(), "error during message enqueue")
+	}
+}
+
+// PushBatch send the contents to the queue, one by one.
+func (q *Queue) PushBatch(ctx context.Context, contents [][]byte) []error {
+	errs := make([]error, len(contents))
+	for i, content := range contents {
+		errs[i] = q.Push(ctx, content)
+	}
+	return errs
+}
+
+// PushDelay send the content to the queue after the delay.
+func (q *Queue) PushDelay(_ context.Context, content []byte, delay time.Duration) error {
+	q.mutex.Lock()
+	if q.closed {
+		q.mutex.Unlock()
+		return errors.New("queue is closed")
+	}
+	q.pending++
+	q.mutex.Unlock()
+
+	q.schedule(content, delay)
+	return nil
+}
+
+// Pull a message from the queue and send it to be processed.
+func (q *Queue) Pull(ctx context.Context, fn func(context.Context, []byte) error) error {
+	var content []byte
+	select {
+	case content = <-q.messages:
+	case <-ctx.Done():
+		return nil
+	}
+
+	if err := fn(ctx, content); err != nil {
+		q.schedule(content, q.visibilityTimeout)
+		return errors.Wrap(err, "error during message process")
+	}
+
+	q.done()
+	return nil
+}
+
+// Depth returns the quantity of messages waiting to be pulled. The messages waiting to be
+// redelivered are not counted.
+func (q *Queue) Depth(context.Context) (int, error) { return len(q.messages), nil }
+
+// Ping returns a error when the queue is closed.
+func (q *Queue) Ping(context.Context) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return errors.New("queue is closed")
+	}
+	return nil
+}
+
+// Drain stop the queue from receiving new messages and wait until all the messages, including the
+// ones waiting to be redelivered, are processed.
+func (q *Queue) Drain(ctx context.Context) error {
+	q.mutex.Lock()
+	if !q.closed {
+		q.closed = true
+		if q.pending == 0 {
+			close(q.drained)
+		}
+	}
+	q.mutex.Unlock()
+
+	select {
+	case <-q.drained:
+		return nil
+	case <-ctx.Done():
+		q.mutex.Lock()
+		select {
+		case <-q.quit:
+		default:
+			close(q.quit)
+		}
+		q.mutex.Unlock()
+		return errors.Wrap(ctx.Err(), "error during queue drain")
+	}
+}
+
+func (q *Queue) schedule(content []byte, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		select {
+		case q.messages <- content:
+		case <-q.quit:
+		}
+	})
+}
+
+func (q *Queue) done() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.pending--
+	if q.closed && q.pending == 0 {
+		close(q.drained)
+	}
+}
+
+// NewQueue returns a configured queue.
+func NewQueue(options ...func(*Queue)) (*Queue, error) {
+	q := &Queue{}
+
+	for _, option := range options {
+		option(q)
+	}
+
+	if q.bufferSize == 0 {
+		q.bufferSize = 1000
+	} else if q.bufferSize < 0 {
+		return nil, errors.New("invalid bufferSize")
+	}
+
+	if q.visibilityTimeout == 0 {
+		q.visibilityTimeout = 30 * time.Second
+	} else if q.visibilityTimeout < 0 {
+		return nil, errors.New("invalid visibilityTimeout")
+	}
+
+	q.messages = make(chan []byte, q.bufferSize)
+	q.drained = make(chan struct{})
+	q.quit = make(chan struct{})
+	return q, nil
+}
+
+// QueueBufferSize set the maximum quantity of messages waiting to be processed.
+func QueueBufferSize(size int) func(*Queue) {
+	return func(q *Queue) { q.bufferSize = size }
+}
+
+// QueueVisibilityTimeout set the time a message wait to be redelivered after a process error.
+func QueueVisibilityTimeout(timeout time.Duration) func(*Queue) {
+	return func(q *Queue) { q.visibilityTimeout = timeout }
+}
@@ -0,0 +1,190 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewQueue(t *testing.T) {
+	Convey("Given a list of valid queue options", t, func() {
+		tests := [][]func(*Queue){
+			{},
+			{QueueBufferSize(1)},
+			{QueueBufferSize(10), QueueVisibilityTimeout(time.Second)},
+		}
+
+		Convey("The output should be valid", func() {
+			for _, tt := range tests {
+				_, err := NewQueue(tt...)
+				So(err, ShouldBeNil)
+			}
+		})
+	})
+
+	Convey("Given a list of invalid queue options", t, func() {
+		tests := [][]func(*Queue){
+			{QueueBufferSize(-1)},
+			{QueueVisibilityTimeout(-time.Second)},
+		}
+
+		Convey("The output should be invalid", func() {
+			for _, tt := range tests {
+				_, err := NewQueue(tt...)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestQueuePushPull(t *testing.T) {
+	Convey("Given a Queue", t, func() {
+		q, err := NewQueue(QueueBufferSize(1), QueueVisibilityTimeout(time.Millisecond))
+		So(err, ShouldBeNil)
+
+		Convey("It should be possible to push and pull a message", func() {
+			So(q.Push(context.Background(), []byte("message")), ShouldBeNil)
+
+			var content []byte
+			err := q.Pull(context.Background(), func(_ context.Context, c []byte) error {
+				content = c
+				return nil
+			})
+			So(err, ShouldBeNil)
+			So(string(content), ShouldEqual, "message")
+		})
+
+		Convey("It should block the push when the buffer is full", func() {
+			So(q.Push(context.Background(), []byte("message")), ShouldBeNil)
+
+			ctx, ctxCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer ctxCancel()
+			So(q.Push(ctx, []byte("message")), ShouldNotBeNil)
+		})
+
+		Convey("It should not block the pull when the context is done", func() {
+			ctx, ctxCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer ctxCancel()
+
+			err := q.Pull(ctx, func(context.Context, []byte) error { return nil })
+			So(err, ShouldBeNil)
+		})
+
+		Convey("It should redeliver the message after a process error", func() {
+			So(q.Push(context.Background(), []byte("message")), ShouldBeNil)
+
+			err := q.Pull(context.Background(), func(context.Context, []byte) error {
+				return errors.New("error during process")
+			})
+			So(err, ShouldNotBeNil)
+
+			var content []byte
+			err = q.Pull(context.Background(), func(_ context.Context, c []byte) error {
+				content = c
+				return nil
+			})
+			So(err, ShouldBeNil)
+			So(string(content), ShouldEqual, "message")
+		})
+	})
+}
+
+func TestQueueDrain(t *testing.T) {
+	Convey("Given a Queue with pending messages", t, func() {
+		q, err := NewQueue(QueueBufferSize(10), QueueVisibilityTimeout(time.Millisecond))
+		So(err, ShouldBeNil)
+
+		for i := 0; i < 5; i++ {
+			So(q.Push(context.Background(), []byte("message")), ShouldBeNil)
+		}
+
+		Convey("It should not finish the drain while the messages are not processed", func() {
+			ctx, ctxCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer ctxCancel()
+			So(q.Drain(ctx), ShouldNotBeNil)
+			So(q.Push(context.Background(), []byte("message")), ShouldNotBeNil)
+		})
+
+		Convey("It should finish the drain after all the messages are processed", func() {
+			go func() {
+				var failed bool
+				for i := 0; i < 6; i++ {
+					q.Pull(context.Background(), func(context.Context, []byte) error {
+						if !failed {
+							failed = true
+							return errors.New("error during process")
+						}
+						return nil
+					})
+				}
+			}()
+
+			ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second)
+			defer ctxCancel()
+			So(q.Drain(ctx), ShouldBeNil)
+		})
+	})
+}
+
+func TestQueuePushBatch(t *testing.T) {
+	Convey("Given a Queue", t, func() {
+		q, err := NewQueue(QueueBufferSize(2))
+		So(err, ShouldBeNil)
+
+		Convey("It should push the messages until the buffer is full", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			errs := q.PushBatch(ctx, [][]byte{[]byte("1"), []byte("2"), []byte("3")})
+			So(errs, ShouldHaveLength, 3)
+			So(errs[0], ShouldBeNil)
+			So(errs[1], ShouldBeNil)
+			So(errs[2], ShouldNotBeNil)
+		})
+	})
+}
+
+func TestQueueDepth(t *testing.T) {
+	Convey("Given a Queue", t, func() {
+		q, err := NewQueue(QueueBufferSize(2))
+		So(err, ShouldBeNil)
+
+		Convey("The depth should follow the messages waiting to be pulled", func() {
+			depth, err := q.Depth(context.Background())
+			So(err, ShouldBeNil)
+			So(depth, ShouldEqual, 0)
+
+			So(q.Push(context.Background(), []byte("a")), ShouldBeNil)
+			So(q.Push(context.Background(), []byte("b")), ShouldBeNil)
+			depth, err = q.Depth(context.Background())
+			So(err, ShouldBeNil)
+			So(depth, ShouldEqual, 2)
+
+			err = q.Pull(context.Background(), func(context.Context, []byte) error { return nil })
+			So(err, ShouldBeNil)
+			depth, err = q.Depth(context.Background())
+			So(err, ShouldBeNil)
+			So(depth, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestQueuePing(t *testing.T) {
+	Convey("Given a Queue", t, func() {
+		q, err := NewQueue()
+		So(err, ShouldBeNil)
+
+		Convey("The ping should fail only after the drain", func() {
+			So(q.Ping(context.Background()), ShouldBeNil)
+			So(q.Drain(context.Background()), ShouldBeNil)
+			So(q.Ping(context.Background()), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,124 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/diegobernardes/flare"
+)
+
+// Change implements the data layer for the change feed. The changes older than the retention are
+// discarded on each create.
+type Change struct {
+	mutex     sync.RWMutex
+	retention time.Duration
+	sequences map[string]int64
+	changes   map[string][]flare.Change
+}
+
+// FindAll returns the changes of the resource after the sequence.
+func (c *Change) FindAll(
+	_ context.Context, resourceId string, sequence int64, limit int,
+) ([]flare.Change, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	result := make([]flare.Change, 0)
+	for _, change := range c.changes[resourceId] {
+		if len(result) == limit {
+			break
+		}
+
+		if change.Sequence > sequence {
+			result = append(result, change)
+		}
+	}
+	return result, nil
+}
+
+// FindLatest returns the newest change of a document.
+func (c *Change) FindLatest(
+	_ context.Context, resourceId, documentId string,
+) (*flare.Change, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if change := c.latest(resourceId, documentId); change != nil {
+		return change, nil
+	}
+
+	return nil, &errMemory{
+		message:  fmt.Sprintf("change of document '%s' not found", documentId),
+		notFound: true,
+	}
+}
+
+// Create a change if the newest change of the document still has the latest sequence.
+func (c *Change) Create(_ context.Context, change *flare.Change, latest int64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	resourceId := change.Resource.ID
+	var sequence int64
+	if current := c.latest(resourceId, change.Document.Id); current != nil {
+		sequence = current.Sequence
+	}
+	if sequence != latest {
+		return &errMemory{
+			message:  fmt.Sprintf("change of document '%s' is outdated", change.Document.Id),
+			conflict: true,
+		}
+	}
+
+	c.sequences[resourceId]++
+	change.Sequence = c.sequences[resourceId]
+	change.CreatedAt = time.Now()
+
+	changes := c.changes[resourceId]
+	if c.retention > 0 {
+		limit := change.CreatedAt.Add(-c.retention)
+		i := 0
+		for i < len(changes) && changes[i].CreatedAt.Before(limit) {
+			i++
+		}
+		changes = changes[i:]
+	}
+	c.changes[resourceId] = append(changes, *change)
+	return nil
+}
+
+func (c *Change) latest(resourceId, documentId string) *flare.Change {
+	changes := c.changes[resourceId]
+	for i := len(changes) - 1; i >= 0; i-- {
+		if changes[i].Document.Id == documentId {
+			change := changes[i]
+			return &change
+		}
+	}
+	return nil
+}
+
+// NewChange returns a configured change repository.
+func NewChange(options ...func(*Change)) *Change {
+	c := &Change{
+		sequences: make(map[string]int64),
+		changes:   make(map[string][]flare.Change),
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+// ChangeRetention set the time the changes are kept. The zero value keep the changes forever.
+func ChangeRetention(retention time.Duration) func(*Change) {
+	return func(c *Change) { c.retention = retention }
+}
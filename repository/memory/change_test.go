@@ -0,0 +1,89 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/diegobernardes/flare"
+)
+
+func TestChange(t *testing.T) {
+	Convey("Given a Change with changes from two resources", t, func() {
+		c := NewChange()
+		latest := make(map[string]int64)
+		for i, id := range []string{"1", "2", "1", "1"} {
+			change := &flare.Change{
+				Resource: flare.Resource{ID: id},
+				Document: flare.Document{Id: "http://app.com/users/1", ChangeFieldValue: i},
+				Action:   flare.SubscriptionTriggerUpdate,
+			}
+			So(c.Create(context.Background(), change, latest[id]), ShouldBeNil)
+			latest[id] = change.Sequence
+		}
+
+		Convey("It should return the changes after the sequence", func() {
+			changes, err := c.FindAll(context.Background(), "1", 0, 10)
+			So(err, ShouldBeNil)
+			So(changes, ShouldHaveLength, 3)
+			for i, change := range changes {
+				So(change.Sequence, ShouldEqual, i+1)
+			}
+
+			changes, err = c.FindAll(context.Background(), "1", 1, 1)
+			So(err, ShouldBeNil)
+			So(changes, ShouldHaveLength, 1)
+			So(changes[0].Sequence, ShouldEqual, 2)
+			So(changes[0].Document.ChangeFieldValue, ShouldEqual, 2)
+		})
+
+		Convey("It should not create a change over a outdated one", func() {
+			err := c.Create(context.Background(), &flare.Change{
+				Resource: flare.Resource{ID: "1"},
+				Document: flare.Document{Id: "http://app.com/users/1"},
+			}, 2)
+			So(err, ShouldNotBeNil)
+			So(err.(flare.ChangeRepositoryError).Conflict(), ShouldBeTrue)
+
+			changes, err := c.FindAll(context.Background(), "1", 0, 10)
+			So(err, ShouldBeNil)
+			So(changes, ShouldHaveLength, 3)
+		})
+
+		Convey("It should return the newest change of the document", func() {
+			change, err := c.FindLatest(context.Background(), "1", "http://app.com/users/1")
+			So(err, ShouldBeNil)
+			So(change.Sequence, ShouldEqual, 3)
+
+			_, err = c.FindLatest(context.Background(), "1", "http://app.com/users/2")
+			So(err, ShouldNotBeNil)
+			So(err.(flare.ChangeRepositoryError).NotFound(), ShouldBeTrue)
+		})
+	})
+
+	Convey("Given a Change with retention", t, func() {
+		c := NewChange(ChangeRetention(time.Hour))
+		c.changes["1"] = []flare.Change{
+			{Sequence: 1, CreatedAt: time.Now().Add(-2 * time.Hour)},
+			{Sequence: 2, CreatedAt: time.Now()},
+		}
+		c.sequences["1"] = 2
+
+		Convey("It should discard the old changes", func() {
+			err := c.Create(context.Background(), &flare.Change{Resource: flare.Resource{ID: "1"}}, 2)
+			So(err, ShouldBeNil)
+
+			changes, err := c.FindAll(context.Background(), "1", 0, 10)
+			So(err, ShouldBeNil)
+			So(changes, ShouldHaveLength, 2)
+			So(changes[0].Sequence, ShouldEqual, 2)
+			So(changes[1].Sequence, ShouldEqual, 3)
+		})
+	})
+}
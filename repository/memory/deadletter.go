@@ -0,0 +1,119 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/diegobernardes/flare"
+)
+
+// DeadLetter implements the data layer for the dead letters.
+type DeadLetter struct {
+	mutex       sync.RWMutex
+	deadLetters map[string][]flare.DeadLetter
+}
+
+// FindAll returns a list of dead letters from a subscription.
+func (dl *DeadLetter) FindAll(
+	_ context.Context, pagination *flare.Pagination, resourceId, subscriptionId string,
+) ([]flare.DeadLetter, *flare.Pagination, error) {
+	dl.mutex.RLock()
+	defer dl.mutex.RUnlock()
+
+	deadLetters := dl.filter(resourceId, subscriptionId)
+
+	var resp []flare.DeadLetter
+	if pagination.Offset > len(deadLetters) {
+		resp = deadLetters
+	} else if pagination.Limit+pagination.Offset > len(deadLetters) {
+		resp = deadLetters[pagination.Offset:]
+	} else {
+		resp = deadLetters[pagination.Offset : pagination.Offset+pagination.Limit]
+	}
+
+	return resp, &flare.Pagination{
+		Total:  len(deadLetters),
+		Limit:  pagination.Limit,
+		Offset: pagination.Offset,
+	}, nil
+}
+
+// FindOne return the dead letter that match the id.
+func (dl *DeadLetter) FindOne(
+	_ context.Context, resourceId, subscriptionId, id string,
+) (*flare.DeadLetter, error) {
+	dl.mutex.RLock()
+	defer dl.mutex.RUnlock()
+
+	for _, deadLetter := range dl.filter(resourceId, subscriptionId) {
+		if deadLetter.ID == id {
+			return &deadLetter, nil
+		}
+	}
+
+	return nil, &errMemory{message: fmt.Sprintf("dead letter '%s' not found", id), notFound: true}
+}
+
+// Create a dead letter.
+func (dl *DeadLetter) Create(_ context.Context, deadLetter *flare.DeadLetter) error {
+	dl.mutex.Lock()
+	defer dl.mutex.Unlock()
+
+	deadLetter.CreatedAt = time.Now()
+	dl.deadLetters[deadLetter.Subscription.ID] = append(
+		dl.deadLetters[deadLetter.Subscription.ID], *deadLetter,
+	)
+	return nil
+}
+
+// Delete a given dead letter.
+func (dl *DeadLetter) Delete(_ context.Context, resourceId, subscriptionId, id string) error {
+	dl.mutex.Lock()
+	defer dl.mutex.Unlock()
+
+	deadLetters := dl.deadLetters[subscriptionId]
+	for i, deadLetter := range deadLetters {
+		if deadLetter.ID == id && deadLetter.Subscription.Resource.ID == resourceId {
+			dl.deadLetters[subscriptionId] = append(deadLetters[:i], deadLetters[i+1:]...)
+			return nil
+		}
+	}
+
+	return &errMemory{message: fmt.Sprintf("dead letter '%s' not found", id), notFound: true}
+}
+
+// DeleteAll remove all the dead letters from a subscription.
+func (dl *DeadLetter) DeleteAll(_ context.Context, resourceId, subscriptionId string) error {
+	dl.mutex.Lock()
+	defer dl.mutex.Unlock()
+
+	deadLetters := make([]flare.DeadLetter, 0)
+	for _, deadLetter := range dl.deadLetters[subscriptionId] {
+		if deadLetter.Subscription.Resource.ID != resourceId {
+			deadLetters = append(deadLetters, deadLetter)
+		}
+	}
+	dl.deadLetters[subscriptionId] = deadLetters
+	return nil
+}
+
+func (dl *DeadLetter) filter(resourceId, subscriptionId string) []flare.DeadLetter {
+	result := make([]flare.DeadLetter, 0)
+	for _, deadLetter := range dl.deadLetters[subscriptionId] {
+		if deadLetter.Subscription.Resource.ID == resourceId {
+			result = append(result, deadLetter)
+		}
+	}
+	return result
+}
+
+// NewDeadLetter returns a configured dead letter repository.
+func NewDeadLetter() *DeadLetter {
+	return &DeadLetter{deadLetters: make(map[string][]flare.DeadLetter)}
+}
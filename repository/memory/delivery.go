@@ -0,0 +1,80 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/diegobernardes/flare"
+)
+
+// Max quantity of deliveries kept by subscription.
+const deliveryMaxEntries = 1000
+
+// Delivery implements the data layer for the delivery attempts.
+type Delivery struct {
+	mutex      sync.RWMutex
+	deliveries map[string][]flare.Delivery
+}
+
+// FindAll returns a list of deliveries from a subscription, the newest first.
+func (d *Delivery) FindAll(
+	_ context.Context,
+	pagination *flare.Pagination,
+	resourceId, subscriptionId string,
+	filter *flare.DeliveryFilter,
+) ([]flare.Delivery, *flare.Pagination, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	entries := d.deliveries[subscriptionId]
+	deliveries := make([]flare.Delivery, 0)
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Subscription.Resource.ID != resourceId {
+			continue
+		}
+
+		if filter != nil && !filter.Match(&entries[i]) {
+			continue
+		}
+		deliveries = append(deliveries, entries[i])
+	}
+
+	var resp []flare.Delivery
+	if pagination.Offset > len(deliveries) {
+		resp = deliveries
+	} else if pagination.Limit+pagination.Offset > len(deliveries) {
+		resp = deliveries[pagination.Offset:]
+	} else {
+		resp = deliveries[pagination.Offset : pagination.Offset+pagination.Limit]
+	}
+
+	return resp, &flare.Pagination{
+		Total:  len(deliveries),
+		Limit:  pagination.Limit,
+		Offset: pagination.Offset,
+	}, nil
+}
+
+// Create a delivery. Only the last deliveries of each subscription are kept.
+func (d *Delivery) Create(_ context.Context, delivery *flare.Delivery) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	delivery.CreatedAt = time.Now()
+	deliveries := append(d.deliveries[delivery.Subscription.ID], *delivery)
+	if len(deliveries) > deliveryMaxEntries {
+		deliveries = deliveries[len(deliveries)-deliveryMaxEntries:]
+	}
+	d.deliveries[delivery.Subscription.ID] = deliveries
+	return nil
+}
+
+// NewDelivery returns a configured delivery repository.
+func NewDelivery() *Delivery {
+	return &Delivery{deliveries: make(map[string][]flare.Delivery)}
+}
@@ -7,59 +7,129 @@ package memory
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"sync"
 	"time"
 
-	"github.com/pkg/errors"
-
 	"github.com/diegobernardes/flare"
 )
 
-// Document implements the data layer for the document service.
+// Max quantity of revisions kept per document.
+const documentMaxRevisions = 100
+
+// Document implements the data layer for the document service. Each document has a list of
+// revisions ordered by the time they were persisted.
 type Document struct {
 	mutex     sync.RWMutex
-	documents map[string]flare.Document
+	documents map[string][]flare.Document
 }
 
-// FindOne return the document that match the id.
+// FindOne return the latest revision of the document that match the id.
 func (d *Document) FindOne(ctx context.Context, id string) (*flare.Document, error) {
 	d.mutex.RLock()
 	defer d.mutex.RUnlock()
 
-	document, ok := d.documents[id]
-	if !ok {
+	revisions, ok := d.documents[d.key(ctx, id)]
+	if !ok || len(revisions) == 0 {
 		return nil, &errMemory{message: fmt.Sprintf("document '%s' not found", id), notFound: true}
 	}
+
+	document := d.latest(revisions)
 	return &document, nil
 }
 
 // FindOneWithRevision return the document that match the id and the revision.
 func (d *Document) FindOneWithRevision(
-	context.Context, string, interface{},
+	ctx context.Context, id string, revision interface{},
 ) (*flare.Document, error) {
-	return nil, errors.New("not implemented")
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	for _, document := range d.documents[d.key(ctx, id)] {
+		if d.equalRevision(document.ChangeFieldValue, revision) {
+			return &document, nil
+		}
+	}
+
+	return nil, &errMemory{
+		message:  fmt.Sprintf("document '%s' with revision '%v' not found", id, revision),
+		notFound: true,
+	}
 }
 
-// Update a document.
+// Update a document. If the revision already exists it's replaced, otherwise a new revision is
+// added to the document history.
 func (d *Document) Update(ctx context.Context, doc *flare.Document) error {
-	d.mutex.RLock()
-	defer d.mutex.RUnlock()
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
 
 	doc.UpdatedAt = time.Now()
-	d.documents[doc.Id] = *doc
+	key := d.key(ctx, doc.Id)
+	revisions := d.documents[key]
+	for i, document := range revisions {
+		if d.equalRevision(document.ChangeFieldValue, doc.ChangeFieldValue) {
+			// The revision is moved to the end to keep the history ordered by the persistence time.
+			revisions = append(revisions[:i], revisions[i+1:]...)
+			break
+		}
+	}
+
+	revisions = append(revisions, *doc)
+	if len(revisions) > documentMaxRevisions {
+		revisions = revisions[len(revisions)-documentMaxRevisions:]
+	}
+	d.documents[key] = revisions
 	return nil
 }
 
-// Delete a given document.
+// Delete a given document with all the revisions.
 func (d *Document) Delete(ctx context.Context, id string) error {
-	d.mutex.RLock()
-	defer d.mutex.RUnlock()
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
 
-	delete(d.documents, id)
+	delete(d.documents, d.key(ctx, id))
 	return nil
 }
 
+// latest return the newest revision. When the revisions can't be compared, like the hashes, the
+// last persisted is used.
+func (d *Document) latest(revisions []flare.Document) flare.Document {
+	result := revisions[len(revisions)-1]
+	if result.Resource.Change.Kind == flare.ResourceChangeHash {
+		return result
+	}
+
+	for _, document := range revisions {
+		newer, err := document.Newer(&result)
+		if err != nil {
+			return revisions[len(revisions)-1]
+		}
+
+		if newer {
+			result = document
+		}
+	}
+	return result
+}
+
+// key scope the document id to the namespace of the context, the same document can be at more then
+// one namespace. The ids are URIs and don't have spaces.
+func (d *Document) key(ctx context.Context, id string) string {
+	if namespace := flare.NamespaceFromContext(ctx); namespace != flare.NamespaceDefault {
+		return namespace + " " + id
+	}
+	return id
+}
+
+func (d *Document) equalRevision(a, b interface{}) bool {
+	if aTime, ok := a.(time.Time); ok {
+		bTime, ok := b.(time.Time)
+		return ok && aTime.Equal(bTime)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
 // NewDocument returns a configured document repository.
 func NewDocument() *Document {
-	return &Document{documents: make(map[string]flare.Document)}
+	return &Document{documents: make(map[string][]flare.Document)}
 }
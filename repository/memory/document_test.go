@@ -0,0 +1,131 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/diegobernardes/flare"
+)
+
+func TestDocumentFindOneWithRevision(t *testing.T) {
+	Convey("Given a Document", t, func() {
+		d := NewDocument()
+		resource := flare.Resource{
+			ID:     "1",
+			Change: flare.ResourceChange{Field: "revision", Kind: flare.ResourceChangeInteger},
+		}
+
+		for _, revision := range []int{1, 3, 2} {
+			err := d.Update(context.Background(), &flare.Document{
+				Id: "http://app.com/users/1", ChangeFieldValue: revision, Resource: resource,
+			})
+			So(err, ShouldBeNil)
+		}
+
+		Convey("It should find all the revisions", func() {
+			for _, revision := range []int{1, 2, 3} {
+				doc, err := d.FindOneWithRevision(context.Background(), "http://app.com/users/1", revision)
+				So(err, ShouldBeNil)
+				So(doc.ChangeFieldValue, ShouldEqual, revision)
+			}
+		})
+
+		Convey("It should not find a missing revision", func() {
+			_, err := d.FindOneWithRevision(context.Background(), "http://app.com/users/1", 4)
+			So(err, ShouldNotBeNil)
+
+			nErr, ok := err.(flare.DocumentRepositoryError)
+			So(ok, ShouldBeTrue)
+			So(nErr.NotFound(), ShouldBeTrue)
+		})
+
+		Convey("The FindOne should return the newest revision", func() {
+			doc, err := d.FindOne(context.Background(), "http://app.com/users/1")
+			So(err, ShouldBeNil)
+			So(doc.ChangeFieldValue, ShouldEqual, 3)
+		})
+
+		Convey("It should not find the revisions after the delete", func() {
+			So(d.Delete(context.Background(), "http://app.com/users/1"), ShouldBeNil)
+
+			_, err := d.FindOneWithRevision(context.Background(), "http://app.com/users/1", 1)
+			So(err, ShouldNotBeNil)
+
+			_, err = d.FindOne(context.Background(), "http://app.com/users/1")
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given a Document with date revisions", t, func() {
+		d := NewDocument()
+		date := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+		err := d.Update(context.Background(), &flare.Document{
+			Id: "http://app.com/users/1", ChangeFieldValue: date,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("It should find the revision in another location", func() {
+			doc, err := d.FindOneWithRevision(
+				context.Background(), "http://app.com/users/1", date.In(time.FixedZone("", 3600)),
+			)
+			So(err, ShouldBeNil)
+			So(doc.Id, ShouldEqual, "http://app.com/users/1")
+		})
+	})
+}
+
+func TestDocumentFindOneHash(t *testing.T) {
+	Convey("Given a Document with hash revisions", t, func() {
+		d := NewDocument()
+		resource := flare.Resource{ID: "1", Change: flare.ResourceChange{Kind: flare.ResourceChangeHash}}
+
+		for _, revision := range []string{"b", "a", "b"} {
+			err := d.Update(context.Background(), &flare.Document{
+				Id: "http://app.com/users/1", ChangeFieldValue: revision, Resource: resource,
+			})
+			So(err, ShouldBeNil)
+		}
+
+		Convey("The FindOne should return the last persisted revision", func() {
+			doc, err := d.FindOne(context.Background(), "http://app.com/users/1")
+			So(err, ShouldBeNil)
+			So(doc.ChangeFieldValue, ShouldEqual, "b")
+			So(d.documents["http://app.com/users/1"], ShouldHaveLength, 2)
+		})
+	})
+}
+
+func TestDocumentConcurrency(t *testing.T) {
+	Convey("Given a Document", t, func() {
+		d := NewDocument()
+
+		Convey("It should be possible to update and search concurrently", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					id := fmt.Sprintf("http://app.com/users/%d", i%3)
+					for revision := 0; revision < 50; revision++ {
+						_ = d.Update(context.Background(), &flare.Document{Id: id, ChangeFieldValue: revision})
+						_, _ = d.FindOneWithRevision(context.Background(), id, revision)
+						if revision%10 == 0 {
+							_ = d.Delete(context.Background(), id)
+						}
+					}
+				}(i)
+			}
+			wg.Wait()
+		})
+	})
+}
@@ -9,9 +9,13 @@ type errMemory struct {
 	alreadyExists bool
 	pathConflict  bool
 	notFound      bool
+	conflict      bool
+	limitReached  bool
 }
 
 func (e *errMemory) Error() string       { return e.message }
 func (e *errMemory) AlreadyExists() bool { return e.alreadyExists }
 func (e *errMemory) PathConflict() bool  { return e.pathConflict }
 func (e *errMemory) NotFound() bool      { return e.notFound }
+func (e *errMemory) Conflict() bool      { return e.conflict }
+func (e *errMemory) LimitReached() bool  { return e.limitReached }
@@ -27,34 +27,36 @@ type Resource struct {
 
 // FindAll returns a list of resources.
 func (r *Resource) FindAll(
-	_ context.Context,
+	ctx context.Context,
 	pagination *flare.Pagination,
 ) ([]flare.Resource, *flare.Pagination, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
+	resources := r.namespaced(ctx)
+
 	var resp []flare.Resource
-	if pagination.Offset > len(r.resources) {
-		resp = r.resources
-	} else if pagination.Limit+pagination.Offset > len(r.resources) {
-		resp = r.resources[pagination.Offset:]
+	if pagination.Offset > len(resources) {
+		resp = resources
+	} else if pagination.Limit+pagination.Offset > len(resources) {
+		resp = resources[pagination.Offset:]
 	} else {
-		resp = r.resources[pagination.Offset : pagination.Offset+pagination.Limit]
+		resp = resources[pagination.Offset : pagination.Offset+pagination.Limit]
 	}
 
 	return resp, &flare.Pagination{
-		Total:  len(r.resources),
+		Total:  len(resources),
 		Limit:  pagination.Limit,
 		Offset: pagination.Offset,
 	}, nil
 }
 
 // FindOne return the resource that match the id.
-func (r *Resource) FindOne(_ context.Context, id string) (*flare.Resource, error) {
+func (r *Resource) FindOne(ctx context.Context, id string) (*flare.Resource, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	for _, resource := range r.resources {
+	for _, resource := range r.namespaced(ctx) {
 		if resource.ID == id {
 			return &resource, nil
 		}
@@ -63,10 +65,19 @@ func (r *Resource) FindOne(_ context.Context, id string) (*flare.Resource, error
 }
 
 // Create a resource.
-func (r *Resource) Create(_ context.Context, res *flare.Resource) error {
+func (r *Resource) Create(ctx context.Context, res *flare.Resource) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
+	if limit := flare.CreateLimitFromContext(ctx); limit > 0 && len(r.namespaced(ctx)) >= limit {
+		return &errMemory{
+			limitReached: true,
+			message: fmt.Sprintf(
+				"namespace '%s' reached the limit of %d resources", flare.NamespaceFromContext(ctx), limit,
+			),
+		}
+	}
+
 	for _, resource := range r.resources {
 		if resource.ID == res.ID {
 			return &errMemory{
@@ -74,6 +85,10 @@ func (r *Resource) Create(_ context.Context, res *flare.Resource) error {
 			}
 		}
 
+		if !flare.NamespaceMatch(ctx, resource.Namespace) {
+			continue
+		}
+
 		if sliceIntersection(resource.Addresses, res.Addresses, resource.Path, res.Path) {
 			return &errMemory{
 				message: fmt.Sprintf(
@@ -89,6 +104,41 @@ func (r *Resource) Create(_ context.Context, res *flare.Resource) error {
 	return nil
 }
 
+// Update a resource.
+func (r *Resource) Update(ctx context.Context, res *flare.Resource) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	index := -1
+	for i, resource := range r.resources {
+		if !flare.NamespaceMatch(ctx, resource.Namespace) {
+			continue
+		}
+
+		if resource.ID == res.ID {
+			index = i
+			continue
+		}
+
+		if sliceIntersection(resource.Addresses, res.Addresses, resource.Path, res.Path) {
+			return &errMemory{
+				message: fmt.Sprintf(
+					"address+path already associated to another resource '%s'", resource.ID,
+				),
+				pathConflict: true,
+			}
+		}
+	}
+
+	if index == -1 {
+		return &errMemory{message: fmt.Sprintf("resource '%s' not found", res.ID), notFound: true}
+	}
+
+	res.CreatedAt = r.resources[index].CreatedAt
+	r.resources[index] = *res
+	return nil
+}
+
 // Delete a given resource.
 func (r *Resource) Delete(ctx context.Context, id string) error {
 	r.mutex.Lock()
@@ -106,7 +156,7 @@ func (r *Resource) Delete(ctx context.Context, id string) error {
 	}
 
 	for i, res := range r.resources {
-		if res.ID == id {
+		if res.ID == id && flare.NamespaceMatch(ctx, res.Namespace) {
 			r.resources = append(r.resources[:i], r.resources[i+1:]...)
 			return nil
 		}
@@ -116,7 +166,7 @@ func (r *Resource) Delete(ctx context.Context, id string) error {
 }
 
 // FindByURI take a URI and find the resource that match.
-func (r *Resource) FindByURI(_ context.Context, rawURI string) (*flare.Resource, error) {
+func (r *Resource) FindByURI(ctx context.Context, rawURI string) (*flare.Resource, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
@@ -129,7 +179,7 @@ func (r *Resource) FindByURI(_ context.Context, rawURI string) (*flare.Resource,
 		return nil, errors.Wrap(err, fmt.Sprintf("error during url.Parse with '%s'", rawURI))
 	}
 
-	resources, err := r.findResourcesByHost(uri)
+	resources, err := r.findResourcesByHost(r.namespaced(ctx), uri)
 	if err != nil {
 		return nil, errors.Wrap(err, "error during resource search")
 	}
@@ -146,9 +196,11 @@ func (r *Resource) FindByURI(_ context.Context, rawURI string) (*flare.Resource,
 	}
 }
 
-func (r *Resource) findResourcesByHost(uri *url.URL) ([]flare.Resource, error) {
+func (r *Resource) findResourcesByHost(
+	candidates []flare.Resource, uri *url.URL,
+) ([]flare.Resource, error) {
 	var resources []flare.Resource
-	for _, resource := range r.resources {
+	for _, resource := range candidates {
 		for _, rawAddress := range resource.Addresses {
 			address, err := url.Parse(rawAddress)
 			if err != nil {
@@ -208,6 +260,17 @@ func (r *Resource) genResourceSegments(resources []flare.Resource, qtySegments i
 	return result
 }
 
+// namespaced returns the resources visible at the namespace of the context.
+func (r *Resource) namespaced(ctx context.Context) []flare.Resource {
+	resources := make([]flare.Resource, 0, len(r.resources))
+	for _, resource := range r.resources {
+		if flare.NamespaceMatch(ctx, resource.Namespace) {
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
+
 func sliceIntersection(a, b []string, a1, b1 string) bool {
 	for _, aValue := range a {
 		for _, bValue := range b {
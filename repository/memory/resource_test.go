@@ -130,6 +130,75 @@ func TestResourceCreate(t *testing.T) {
 				So(nErr.PathConflict(), ShouldBeTrue)
 			})
 		})
+
+		Convey("It should not be possible to insert above the create limit", func() {
+			ctx := flare.ContextWithCreateLimit(context.Background(), 1)
+			So(r.Create(ctx, &flare.Resource{ID: "1"}), ShouldBeNil)
+
+			err := r.Create(ctx, &flare.Resource{ID: "2"})
+			So(err, ShouldNotBeNil)
+
+			nErr, ok := err.(flare.ResourceRepositoryError)
+			So(ok, ShouldBeTrue)
+			So(nErr.LimitReached(), ShouldBeTrue)
+		})
+	})
+}
+
+func TestResourceUpdate(t *testing.T) {
+	Convey("Given a Resource", t, func() {
+		r := NewResource()
+
+		Convey("It should not be possible to update a missing flare.Resource", func() {
+			err := r.Update(context.Background(), &flare.Resource{ID: "1"})
+			So(err, ShouldNotBeNil)
+
+			nErr, ok := err.(flare.ResourceRepositoryError)
+			So(ok, ShouldBeTrue)
+			So(nErr.NotFound(), ShouldBeTrue)
+		})
+
+		Convey("With two flare.Resource at different addresses", func() {
+			So(r.Create(context.Background(), &flare.Resource{
+				ID: "1", Addresses: []string{"http://app.com"},
+			}), ShouldBeNil)
+			So(r.Create(context.Background(), &flare.Resource{
+				ID: "2", Addresses: []string{"http://app.io"},
+			}), ShouldBeNil)
+
+			Convey("It should be possible to add a address to a flare.Resource", func() {
+				err := r.Update(context.Background(), &flare.Resource{
+					ID: "1", Addresses: []string{"http://app.com", "http://app.net"},
+				})
+				So(err, ShouldBeNil)
+
+				resource, err := r.FindOne(context.Background(), "1")
+				So(err, ShouldBeNil)
+				So(resource.Addresses, ShouldResemble, []string{"http://app.com", "http://app.net"})
+			})
+
+			Convey("It should not be possible to use the address of another flare.Resource", func() {
+				err := r.Update(context.Background(), &flare.Resource{
+					ID: "1", Addresses: []string{"http://app.io"},
+				})
+				So(err, ShouldNotBeNil)
+
+				nErr, ok := err.(flare.ResourceRepositoryError)
+				So(ok, ShouldBeTrue)
+				So(nErr.PathConflict(), ShouldBeTrue)
+			})
+
+			Convey("It should detect the conflict when the addresses overlap the current ones", func() {
+				err := r.Update(context.Background(), &flare.Resource{
+					ID: "2", Addresses: []string{"http://app.io", "http://app.com"},
+				})
+				So(err, ShouldNotBeNil)
+
+				nErr, ok := err.(flare.ResourceRepositoryError)
+				So(ok, ShouldBeTrue)
+				So(nErr.PathConflict(), ShouldBeTrue)
+			})
+		})
 	})
 }
 
@@ -183,3 +252,57 @@ func (r *subscriptionRepositorier) FindAll(
 ) ([]flare.Subscription, *flare.Pagination, error) {
 	return r.base.FindAll(ctx, pagination, id)
 }
+
+func TestResourceNamespace(t *testing.T) {
+	Convey("Given a Resource repository with two namespaces", t, func() {
+		r := NewResource()
+		ctxA := flare.ContextWithNamespace(context.Background(), "team-a")
+		ctxB := flare.ContextWithNamespace(context.Background(), "team-b")
+
+		resource := func(id, namespace string) *flare.Resource {
+			return &flare.Resource{
+				ID:        id,
+				Namespace: namespace,
+				Addresses: []string{"http://app.com"},
+				Path:      "/users/{*}",
+			}
+		}
+		So(r.Create(ctxA, resource("1", "team-a")), ShouldBeNil)
+
+		Convey("The same address+path should be allowed at another namespace", func() {
+			So(r.Create(ctxB, resource("2", "team-b")), ShouldBeNil)
+
+			Convey("The queries should only return the resources of the namespace", func() {
+				resources, pagination, err := r.FindAll(ctxB, &flare.Pagination{Limit: 10})
+				So(err, ShouldBeNil)
+				So(pagination.Total, ShouldEqual, 1)
+				So(resources[0].ID, ShouldEqual, "2")
+
+				found, err := r.FindByURI(ctxA, "http://app.com/users/123")
+				So(err, ShouldBeNil)
+				So(found.ID, ShouldEqual, "1")
+
+				_, err = r.FindOne(ctxB, "1")
+				So(err, ShouldNotBeNil)
+
+				So(r.Delete(ctxB, "1"), ShouldNotBeNil)
+			})
+
+			Convey("The poller context should see all the namespaces", func() {
+				ctx := flare.ContextWithNamespace(context.Background(), flare.NamespaceAll)
+				_, pagination, err := r.FindAll(ctx, &flare.Pagination{Limit: 10})
+				So(err, ShouldBeNil)
+				So(pagination.Total, ShouldEqual, 2)
+			})
+		})
+
+		Convey("The same address+path should conflict at the same namespace", func() {
+			err := r.Create(ctxA, resource("2", "team-a"))
+			So(err, ShouldNotBeNil)
+
+			errRepo, ok := err.(flare.ResourceRepositoryError)
+			So(ok, ShouldBeTrue)
+			So(errRepo.PathConflict(), ShouldBeTrue)
+		})
+	})
+}
@@ -25,13 +25,13 @@ type Subscription struct {
 
 // FindAll returns a list of subscriptions.
 func (s *Subscription) FindAll(
-	_ context.Context, pagination *flare.Pagination, id string,
+	ctx context.Context, pagination *flare.Pagination, id string,
 ) ([]flare.Subscription, *flare.Pagination, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	subscriptions, ok := s.subscriptions[id]
-	if !ok {
+	subscriptions := s.namespaced(ctx, id)
+	if len(subscriptions) == 0 {
 		return []flare.Subscription{}, &flare.Pagination{
 			Total:  0,
 			Limit:  pagination.Limit,
@@ -57,7 +57,7 @@ func (s *Subscription) FindAll(
 
 // FindOne return the Subscription that match the id.
 func (s *Subscription) FindOne(
-	_ context.Context, resourceId, id string,
+	ctx context.Context, resourceId, id string,
 ) (*flare.Subscription, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
@@ -66,12 +66,7 @@ func (s *Subscription) FindOne(
 		message:  fmt.Sprintf("subscription '%s' at resource '%s', not found", id, resourceId),
 		notFound: true,
 	}
-	subscriptions, ok := s.subscriptions[resourceId]
-	if !ok {
-		return nil, nf
-	}
-
-	for _, subscription := range subscriptions {
+	for _, subscription := range s.namespaced(ctx, resourceId) {
 		if subscription.ID == id {
 			return &subscription, nil
 		}
@@ -80,23 +75,33 @@ func (s *Subscription) FindOne(
 }
 
 // Create a subscription.
-func (s *Subscription) Create(_ context.Context, subscription *flare.Subscription) error {
+func (s *Subscription) Create(ctx context.Context, subscription *flare.Subscription) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	if limit := flare.CreateLimitFromContext(ctx); limit > 0 && s.count(ctx) >= limit {
+		return &errMemory{
+			limitReached: true,
+			message: fmt.Sprintf(
+				"namespace '%s' reached the limit of %d subscriptions",
+				flare.NamespaceFromContext(ctx), limit,
+			),
+		}
+	}
+
 	subscriptions, ok := s.subscriptions[subscription.Resource.ID]
 	if !ok {
 		s.subscriptions[subscription.Resource.ID] = make([]flare.Subscription, 0)
 	}
 
 	for _, subs := range subscriptions {
-		if subs.Endpoint.URL.String() == subscription.Endpoint.URL.String() {
+		if s.sameEndpoint(subs.Endpoint, subscription.Endpoint) {
 			return &errMemory{
 				alreadyExists: true,
 				message: fmt.Sprintf(
-					"already exists a subscription '%s' with the endpoint.URL '%s'",
+					"already exists a subscription '%s' with the endpoint '%s'",
 					subscription.ID,
-					subscription.Endpoint.URL.String(),
+					subscription.Endpoint.Address(),
 				),
 			}
 		}
@@ -107,27 +112,112 @@ func (s *Subscription) Create(_ context.Context, subscription *flare.Subscriptio
 	return nil
 }
 
+// namespaced returns the subscriptions of the resource visible at the namespace of the context.
+func (s *Subscription) namespaced(ctx context.Context, resourceId string) []flare.Subscription {
+	subscriptions := make([]flare.Subscription, 0, len(s.subscriptions[resourceId]))
+	for _, subscription := range s.subscriptions[resourceId] {
+		if flare.NamespaceMatch(ctx, subscription.Namespace) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions
+}
+
+func (s *Subscription) sameEndpoint(a, b flare.SubscriptionEndpoint) bool {
+	return a.TargetKind() == b.TargetKind() && a.Address() == b.Address()
+}
+
+// Update a subscription.
+func (s *Subscription) Update(ctx context.Context, subscription *flare.Subscription) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	index := -1
+	subscriptions := s.subscriptions[subscription.Resource.ID]
+	for i, subs := range subscriptions {
+		if !flare.NamespaceMatch(ctx, subs.Namespace) {
+			continue
+		}
+
+		if subs.ID == subscription.ID {
+			index = i
+			continue
+		}
+
+		if s.sameEndpoint(subs.Endpoint, subscription.Endpoint) {
+			return &errMemory{
+				alreadyExists: true,
+				message: fmt.Sprintf(
+					"already exists a subscription '%s' with the endpoint '%s'",
+					subs.ID,
+					subscription.Endpoint.Address(),
+				),
+			}
+		}
+	}
+
+	if index == -1 {
+		return &errMemory{
+			message: fmt.Sprintf(
+				"subscription '%s' at resource '%s', not found", subscription.ID, subscription.Resource.ID,
+			),
+			notFound: true,
+		}
+	}
+
+	subscription.CreatedAt = subscriptions[index].CreatedAt
+	subscriptions[index] = *subscription
+	return nil
+}
+
+// UpdateSecret set the secret of a subscription.
+func (s *Subscription) UpdateSecret(
+	ctx context.Context, resourceId, id string, secret flare.SubscriptionSecret,
+) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	subscriptions := s.subscriptions[resourceId]
+	for i := range subscriptions {
+		if subscriptions[i].ID == id && flare.NamespaceMatch(ctx, subscriptions[i].Namespace) {
+			subscriptions[i].Secret = secret
+			return nil
+		}
+	}
+
+	return &errMemory{
+		message:  fmt.Sprintf("subscription '%s' at resource '%s', not found", id, resourceId),
+		notFound: true,
+	}
+}
+
 // HasSubscription check if a resource has subscriptions.
 func (s *Subscription) HasSubscription(ctx context.Context, resourceId string) (bool, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	subscriptions, ok := s.subscriptions[resourceId]
-	if !ok {
-		return false, nil
+	return len(s.namespaced(ctx, resourceId)) > 0, nil
+}
+
+// count returns the quantity of subscriptions at the namespace of the context.
+func (s *Subscription) count(ctx context.Context) int {
+	var count int
+	for resourceId := range s.subscriptions {
+		count += len(s.namespaced(ctx, resourceId))
 	}
-	return len(subscriptions) > 0, nil
+	return count
 }
 
 // Delete a given subscription.
-func (s *Subscription) Delete(_ context.Context, resourceId, id string) error {
+func (s *Subscription) Delete(ctx context.Context, resourceId, id string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
 	subscriptions := s.subscriptions[resourceId]
 	for i, subscription := range subscriptions {
-		if subscription.ID == id {
+		if subscription.ID == id && flare.NamespaceMatch(ctx, subscription.Namespace) {
 			s.subscriptions[resourceId] = append(subscriptions[:i], subscriptions[i+1:]...)
+			delete(s.changes, id)
 			return nil
 		}
 	}
@@ -138,38 +228,52 @@ func (s *Subscription) Delete(_ context.Context, resourceId, id string) error {
 	}
 }
 
-// Trigger process the update on a document.
+// Trigger process the update on a document. The changes are resolved with the lock and fn is
+// called after it's released, this way, a slow delivery don't block the repository.
 func (s *Subscription) Trigger(
 	ctx context.Context,
 	kind string,
 	doc *flare.Document,
-	fn func(context.Context, flare.Subscription, string) error,
+	fn func(context.Context, flare.Subscription, string, *flare.Document) error,
 ) error {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	subscriptions, ok := s.subscriptions[doc.Resource.ID]
-	if !ok {
-		subscriptions = make([]flare.Subscription, 0)
+	calls, err := s.triggerCalls(ctx, kind, doc)
+	if err != nil {
+		return err
 	}
 
+	fn = flare.SubscriptionTriggerAction(fn)
 	group, groupCtx := errgroup.WithContext(ctx)
-	for i := range subscriptions {
-		group.Go(s.triggerProcess(groupCtx, subscriptions[i], doc, kind, fn))
+	for i := range calls {
+		call := calls[i]
+		group.Go(func() error {
+			return errors.Wrap(
+				fn(groupCtx, call.subscription, call.action, call.reference),
+				"error during document subscription processing",
+			)
+		})
 	}
 
 	return errors.Wrap(group.Wait(), "error during processing")
 }
 
-func (s *Subscription) triggerProcess(
-	groupCtx context.Context,
-	subs flare.Subscription,
-	doc *flare.Document,
-	kind string,
+// triggerCall is a notification to be sent to a subscription.
+type triggerCall struct {
+	subscription flare.Subscription
+	action       string
+	reference    *flare.Document
+}
+
+// triggerCalls update the last document notified to each subscription and returns the
+// notifications that should be sent.
+func (s *Subscription) triggerCalls(
+	ctx context.Context, kind string, doc *flare.Document,
+) ([]triggerCall, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-	fn func(context.Context, flare.Subscription, string) error,
-) func() error {
-	return func() error {
+	var calls []triggerCall
+	for _, subs := range s.namespaced(ctx, doc.Resource.ID) {
+		subs.Resource = doc.Resource
 		documents, ok := s.changes[subs.ID]
 		if !ok {
 			documents = make(map[string]flare.Document)
@@ -177,41 +281,30 @@ func (s *Subscription) triggerProcess(
 		}
 
 		referenceDocument, ok := documents[doc.Id]
-		if !ok {
-			if kind == flare.SubscriptionTriggerDelete {
-				return nil
-			}
-
+		switch {
+		case !ok && kind == flare.SubscriptionTriggerDelete:
+		case !ok:
 			documents[doc.Id] = *doc
-			return errors.Wrap(
-				fn(groupCtx, subs, flare.SubscriptionTriggerCreate),
-				"error during document subscription processing",
-			)
-		}
-
-		if kind == flare.SubscriptionTriggerDelete {
+			calls = append(calls, triggerCall{subs, flare.SubscriptionTriggerCreate, nil})
+		case kind == flare.SubscriptionTriggerDelete:
 			delete(documents, doc.Id)
-			if err := fn(groupCtx, subs, flare.SubscriptionTriggerDelete); err != nil {
-				return errors.Wrap(err, "error during document subscription processing")
+			calls = append(calls, triggerCall{subs, flare.SubscriptionTriggerDelete, nil})
+		default:
+			newer, err := doc.Newer(&referenceDocument)
+			if err != nil {
+				return nil, errors.Wrap(err, "error during check if document is newer")
+			}
+			if !newer {
+				continue
 			}
-			return nil
-		}
-
-		newer, err := doc.Newer(&referenceDocument)
-		if err != nil {
-			return errors.Wrap(err, "error during check if document is newer")
-		}
-		if !newer {
-			return nil
-		}
 
-		documents[doc.Id] = *doc
-		if err := fn(groupCtx, subs, flare.SubscriptionTriggerUpdate); err != nil {
-			return errors.Wrap(err, "error during document subscription processing")
+			documents[doc.Id] = *doc
+			calls = append(calls, triggerCall{
+				subs, flare.SubscriptionTriggerUpdate, &referenceDocument,
+			})
 		}
-
-		return nil
 	}
+	return calls, nil
 }
 
 // NewSubscription returns a configured subscription repository.
@@ -0,0 +1,70 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/diegobernardes/flare"
+)
+
+func TestSubscriptionFindOne(t *testing.T) {
+	Convey("Given a Subscription with a subscription at the resource 123", t, func() {
+		s := NewSubscription()
+		So(s.Create(context.Background(), &flare.Subscription{
+			ID:       "456",
+			Resource: flare.Resource{ID: "123"},
+			Endpoint: flare.SubscriptionEndpoint{URL: url.URL{Scheme: "http", Host: "app.com"}},
+		}), ShouldBeNil)
+
+		Convey("It should be found at the resource", func() {
+			subscription, err := s.FindOne(context.Background(), "123", "456")
+			So(err, ShouldBeNil)
+			So(subscription.ID, ShouldEqual, "456")
+		})
+
+		Convey("It should not be found through other resource", func() {
+			_, err := s.FindOne(context.Background(), "789", "456")
+			So(err, ShouldNotBeNil)
+
+			errRepo, ok := err.(flare.SubscriptionRepositoryError)
+			So(ok, ShouldBeTrue)
+			So(errRepo.NotFound(), ShouldBeTrue)
+		})
+	})
+}
+
+func TestSubscriptionCreateLimit(t *testing.T) {
+	Convey("Given a Subscription and a context with a create limit", t, func() {
+		s := NewSubscription()
+		ctx := flare.ContextWithCreateLimit(
+			flare.ContextWithNamespace(context.Background(), "team-a"), 2,
+		)
+
+		create := func(ctx context.Context, id, host string) error {
+			return s.Create(ctx, &flare.Subscription{
+				ID:        id,
+				Namespace: flare.NamespaceFromContext(ctx),
+				Resource:  flare.Resource{ID: id},
+				Endpoint:  flare.SubscriptionEndpoint{URL: url.URL{Scheme: "http", Host: host}},
+			})
+		}
+
+		Convey("It should not create above the limit", func() {
+			So(create(ctx, "1", "app1.com"), ShouldBeNil)
+			So(create(ctx, "2", "app2.com"), ShouldBeNil)
+
+			err := create(ctx, "3", "app3.com")
+			So(err, ShouldNotBeNil)
+			So(err.(flare.SubscriptionRepositoryError).LimitReached(), ShouldBeTrue)
+
+			So(create(flare.ContextWithCreateLimit(context.Background(), 2), "4", "app4.com"), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,112 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/diegobernardes/flare"
+)
+
+// SubscriptionLimit implements the data layer for the subscriptions delivery limits. The state is
+// shared by all the workers of the process.
+type SubscriptionLimit struct {
+	mutex  sync.Mutex
+	states map[string]*subscriptionLimitState
+	now    func() time.Time
+}
+
+type subscriptionLimitState struct {
+	leases      map[string]struct{}
+	next        time.Time
+	pausedUntil time.Time
+}
+
+// Acquire reserve a delivery to the subscription.
+func (sl *SubscriptionLimit) Acquire(
+	_ context.Context, subscription flare.Subscription,
+) (string, time.Duration, error) {
+	sl.mutex.Lock()
+	defer sl.mutex.Unlock()
+
+	now := sl.now()
+	state := sl.state(subscription.ID)
+	if wait := state.pausedUntil.Sub(now); wait > 0 {
+		return "", wait, nil
+	}
+
+	limit := subscription.Delivery.Limit
+	if limit.MaxInFlight > 0 && len(state.leases) >= limit.MaxInFlight {
+		return "", 0, nil
+	}
+
+	if interval := limit.Interval(); interval > 0 {
+		if wait := state.next.Sub(now); wait > 0 {
+			return "", wait, nil
+		}
+		state.next = now.Add(interval)
+	}
+
+	lease := uuid.NewV4().String()
+	state.leases[lease] = struct{}{}
+	return lease, 0, nil
+}
+
+// Release free the in-flight delivery.
+func (sl *SubscriptionLimit) Release(
+	_ context.Context, subscription flare.Subscription, lease string,
+) error {
+	sl.mutex.Lock()
+	defer sl.mutex.Unlock()
+
+	state, ok := sl.states[subscription.ID]
+	if !ok {
+		return nil
+	}
+
+	// The state is removed when it has nothing to restrict, this way, the deleted subscriptions
+	// don't leak.
+	delete(state.leases, lease)
+	now := sl.now()
+	if len(state.leases) == 0 && !state.next.After(now) && !state.pausedUntil.After(now) {
+		delete(sl.states, subscription.ID)
+	}
+	return nil
+}
+
+// Pause stop the deliveries to the subscription until the given time.
+func (sl *SubscriptionLimit) Pause(
+	_ context.Context, subscription flare.Subscription, until time.Time,
+) error {
+	sl.mutex.Lock()
+	defer sl.mutex.Unlock()
+
+	state := sl.state(subscription.ID)
+	if until.After(state.pausedUntil) {
+		state.pausedUntil = until
+	}
+	return nil
+}
+
+func (sl *SubscriptionLimit) state(id string) *subscriptionLimitState {
+	state, ok := sl.states[id]
+	if !ok {
+		state = &subscriptionLimitState{leases: make(map[string]struct{})}
+		sl.states[id] = state
+	}
+	return state
+}
+
+// NewSubscriptionLimit returns a configured subscription limit repository.
+func NewSubscriptionLimit() *SubscriptionLimit {
+	return &SubscriptionLimit{
+		states: make(map[string]*subscriptionLimitState),
+		now:    time.Now,
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/diegobernardes/flare"
+)
+
+func TestSubscriptionLimit(t *testing.T) {
+	Convey("Given a SubscriptionLimit", t, func() {
+		now := time.Date(2017, time.November, 10, 23, 0, 0, 0, time.UTC)
+		sl := NewSubscriptionLimit()
+		sl.now = func() time.Time { return now }
+
+		subscription := flare.Subscription{
+			ID: "123",
+			Delivery: flare.SubscriptionDelivery{
+				Limit: flare.SubscriptionDeliveryLimit{RequestsPerSecond: 2, MaxInFlight: 1},
+			},
+		}
+
+		lease, wait, err := sl.Acquire(context.Background(), subscription)
+		So(err, ShouldBeNil)
+		So(lease, ShouldNotBeEmpty)
+		So(wait, ShouldEqual, 0)
+
+		Convey("The max in-flight deliveries should be respected", func() {
+			now = now.Add(time.Second)
+			lease, wait, err := sl.Acquire(context.Background(), subscription)
+			So(err, ShouldBeNil)
+			So(lease, ShouldBeEmpty)
+			So(wait, ShouldEqual, 0)
+		})
+
+		Convey("After the release", func() {
+			So(sl.Release(context.Background(), subscription, lease), ShouldBeNil)
+
+			Convey("The rate should be respected", func() {
+				now = now.Add(100 * time.Millisecond)
+				lease, wait, err := sl.Acquire(context.Background(), subscription)
+				So(err, ShouldBeNil)
+				So(lease, ShouldBeEmpty)
+				So(wait, ShouldEqual, 400*time.Millisecond)
+
+				now = now.Add(wait)
+				lease, _, err = sl.Acquire(context.Background(), subscription)
+				So(err, ShouldBeNil)
+				So(lease, ShouldNotBeEmpty)
+			})
+
+			Convey("The pause should delay the deliveries", func() {
+				err := sl.Pause(context.Background(), subscription, now.Add(5*time.Second))
+				So(err, ShouldBeNil)
+
+				lease, wait, err := sl.Acquire(context.Background(), subscription)
+				So(err, ShouldBeNil)
+				So(lease, ShouldBeEmpty)
+				So(wait, ShouldEqual, 5*time.Second)
+			})
+		})
+
+		Convey("The subscriptions without limits should only respect the pause", func() {
+			unlimited := flare.Subscription{ID: "456"}
+			for i := 0; i < 10; i++ {
+				lease, _, err := sl.Acquire(context.Background(), unlimited)
+				So(err, ShouldBeNil)
+				So(lease, ShouldNotBeEmpty)
+			}
+
+			So(sl.Pause(context.Background(), unlimited, now.Add(time.Second)), ShouldBeNil)
+			lease, wait, err := sl.Acquire(context.Background(), unlimited)
+			So(err, ShouldBeNil)
+			So(lease, ShouldBeEmpty)
+			So(wait, ShouldEqual, time.Second)
+		})
+	})
+}
@@ -0,0 +1,344 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package metrics wraps the repositories to record the latency of each operation.
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+
+	"github.com/diegobernardes/flare"
+	infraMetrics "github.com/diegobernardes/flare/infra/metrics"
+)
+
+type base struct {
+	duration metrics.Histogram
+}
+
+// observe record the time elapsed since start. It's used with defer at the start of the operation.
+func (b base) observe(operation string, start time.Time) {
+	b.duration.With("operation", operation).Observe(time.Since(start).Seconds())
+}
+
+func newBase(registry *infraMetrics.Registry, repository string) base {
+	return base{duration: registry.NewHistogram(
+		"repository_operation_duration_seconds",
+		"Latency of the repository operations.",
+		nil,
+		"repository", "operation",
+	).With("repository", repository)}
+}
+
+// Resource record the metrics of a resource repository.
+type Resource struct {
+	base
+	repository flare.ResourceRepositorier
+}
+
+// FindAll returns a list of resources.
+func (r *Resource) FindAll(
+	ctx context.Context, pagination *flare.Pagination,
+) ([]flare.Resource, *flare.Pagination, error) {
+	defer r.observe("findAll", time.Now())
+	return r.repository.FindAll(ctx, pagination)
+}
+
+// FindOne return the resource that match the id.
+func (r *Resource) FindOne(ctx context.Context, id string) (*flare.Resource, error) {
+	defer r.observe("findOne", time.Now())
+	return r.repository.FindOne(ctx, id)
+}
+
+// FindByURI take a URI and find the resource that match.
+func (r *Resource) FindByURI(ctx context.Context, uri string) (*flare.Resource, error) {
+	defer r.observe("findByURI", time.Now())
+	return r.repository.FindByURI(ctx, uri)
+}
+
+// Create a resource.
+func (r *Resource) Create(ctx context.Context, resource *flare.Resource) error {
+	defer r.observe("create", time.Now())
+	return r.repository.Create(ctx, resource)
+}
+
+// Update a resource.
+func (r *Resource) Update(ctx context.Context, resource *flare.Resource) error {
+	defer r.observe("update", time.Now())
+	return r.repository.Update(ctx, resource)
+}
+
+// Delete a resource.
+func (r *Resource) Delete(ctx context.Context, id string) error {
+	defer r.observe("delete", time.Now())
+	return r.repository.Delete(ctx, id)
+}
+
+// NewResource returns the resource repository with metrics.
+func NewResource(
+	registry *infraMetrics.Registry, repository flare.ResourceRepositorier,
+) *Resource {
+	return &Resource{base: newBase(registry, "resource"), repository: repository}
+}
+
+// Subscription record the metrics of a subscription repository.
+type Subscription struct {
+	base
+	repository flare.SubscriptionRepositorier
+}
+
+// FindAll returns a list of subscriptions.
+func (s *Subscription) FindAll(
+	ctx context.Context, pagination *flare.Pagination, resourceId string,
+) ([]flare.Subscription, *flare.Pagination, error) {
+	defer s.observe("findAll", time.Now())
+	return s.repository.FindAll(ctx, pagination, resourceId)
+}
+
+// FindOne return the subscription that match the id.
+func (s *Subscription) FindOne(
+	ctx context.Context, resourceId, id string,
+) (*flare.Subscription, error) {
+	defer s.observe("findOne", time.Now())
+	return s.repository.FindOne(ctx, resourceId, id)
+}
+
+// Create a subscription.
+func (s *Subscription) Create(ctx context.Context, subscription *flare.Subscription) error {
+	defer s.observe("create", time.Now())
+	return s.repository.Create(ctx, subscription)
+}
+
+// Update a subscription.
+func (s *Subscription) Update(ctx context.Context, subscription *flare.Subscription) error {
+	defer s.observe("update", time.Now())
+	return s.repository.Update(ctx, subscription)
+}
+
+// UpdateSecret change the subscription secret.
+func (s *Subscription) UpdateSecret(
+	ctx context.Context, resourceId, id string, secret flare.SubscriptionSecret,
+) error {
+	defer s.observe("updateSecret", time.Now())
+	return s.repository.UpdateSecret(ctx, resourceId, id, secret)
+}
+
+// Delete a subscription.
+func (s *Subscription) Delete(ctx context.Context, resourceId, id string) error {
+	defer s.observe("delete", time.Now())
+	return s.repository.Delete(ctx, resourceId, id)
+}
+
+// HasSubscription check if a resource has subscriptions.
+func (s *Subscription) HasSubscription(ctx context.Context, resourceId string) (bool, error) {
+	defer s.observe("hasSubscription", time.Now())
+	return s.repository.HasSubscription(ctx, resourceId)
+}
+
+// Trigger process the subscriptions of the document change. The time includes the execution of
+// fn for each subscription.
+func (s *Subscription) Trigger(
+	ctx context.Context,
+	action string,
+	document *flare.Document,
+	fn func(context.Context, flare.Subscription, string, *flare.Document) error,
+) error {
+	defer s.observe("trigger", time.Now())
+	return s.repository.Trigger(ctx, action, document, fn)
+}
+
+// NewSubscription returns the subscription repository with metrics.
+func NewSubscription(
+	registry *infraMetrics.Registry, repository flare.SubscriptionRepositorier,
+) *Subscription {
+	return &Subscription{base: newBase(registry, "subscription"), repository: repository}
+}
+
+// Document record the metrics of a document repository.
+type Document struct {
+	base
+	repository flare.DocumentRepositorier
+}
+
+// FindOne return the latest revision of the document.
+func (d *Document) FindOne(ctx context.Context, id string) (*flare.Document, error) {
+	defer d.observe("findOne", time.Now())
+	return d.repository.FindOne(ctx, id)
+}
+
+// FindOneWithRevision return the document with the given revision.
+func (d *Document) FindOneWithRevision(
+	ctx context.Context, id string, revision interface{},
+) (*flare.Document, error) {
+	defer d.observe("findOneWithRevision", time.Now())
+	return d.repository.FindOneWithRevision(ctx, id, revision)
+}
+
+// Update a document.
+func (d *Document) Update(ctx context.Context, document *flare.Document) error {
+	defer d.observe("update", time.Now())
+	return d.repository.Update(ctx, document)
+}
+
+// Delete a document.
+func (d *Document) Delete(ctx context.Context, id string) error {
+	defer d.observe("delete", time.Now())
+	return d.repository.Delete(ctx, id)
+}
+
+// NewDocument returns the document repository with metrics.
+func NewDocument(
+	registry *infraMetrics.Registry, repository flare.DocumentRepositorier,
+) *Document {
+	return &Document{base: newBase(registry, "document"), repository: repository}
+}
+
+// DeadLetter record the metrics of a dead letter repository.
+type DeadLetter struct {
+	base
+	repository flare.DeadLetterRepositorier
+}
+
+// FindAll returns the dead letters of a subscription.
+func (dl *DeadLetter) FindAll(
+	ctx context.Context, pagination *flare.Pagination, resourceId, subscriptionId string,
+) ([]flare.DeadLetter, *flare.Pagination, error) {
+	defer dl.observe("findAll", time.Now())
+	return dl.repository.FindAll(ctx, pagination, resourceId, subscriptionId)
+}
+
+// FindOne return the dead letter that match the id.
+func (dl *DeadLetter) FindOne(
+	ctx context.Context, resourceId, subscriptionId, id string,
+) (*flare.DeadLetter, error) {
+	defer dl.observe("findOne", time.Now())
+	return dl.repository.FindOne(ctx, resourceId, subscriptionId, id)
+}
+
+// Create a dead letter.
+func (dl *DeadLetter) Create(ctx context.Context, deadLetter *flare.DeadLetter) error {
+	defer dl.observe("create", time.Now())
+	return dl.repository.Create(ctx, deadLetter)
+}
+
+// Delete a dead letter.
+func (dl *DeadLetter) Delete(ctx context.Context, resourceId, subscriptionId, id string) error {
+	defer dl.observe("delete", time.Now())
+	return dl.repository.Delete(ctx, resourceId, subscriptionId, id)
+}
+
+// DeleteAll delete the dead letters of a subscription.
+func (dl *DeadLetter) DeleteAll(ctx context.Context, resourceId, subscriptionId string) error {
+	defer dl.observe("deleteAll", time.Now())
+	return dl.repository.DeleteAll(ctx, resourceId, subscriptionId)
+}
+
+// NewDeadLetter returns the dead letter repository with metrics.
+func NewDeadLetter(
+	registry *infraMetrics.Registry, repository flare.DeadLetterRepositorier,
+) *DeadLetter {
+	return &DeadLetter{base: newBase(registry, "deadLetter"), repository: repository}
+}
+
+// Delivery record the metrics of a delivery repository.
+type Delivery struct {
+	base
+	repository flare.DeliveryRepositorier
+}
+
+// FindAll returns the deliveries of a subscription.
+func (d *Delivery) FindAll(
+	ctx context.Context,
+	pagination *flare.Pagination,
+	resourceId, subscriptionId string,
+	filter *flare.DeliveryFilter,
+) ([]flare.Delivery, *flare.Pagination, error) {
+	defer d.observe("findAll", time.Now())
+	return d.repository.FindAll(ctx, pagination, resourceId, subscriptionId, filter)
+}
+
+// Create a delivery.
+func (d *Delivery) Create(ctx context.Context, delivery *flare.Delivery) error {
+	defer d.observe("create", time.Now())
+	return d.repository.Create(ctx, delivery)
+}
+
+// NewDelivery returns the delivery repository with metrics.
+func NewDelivery(
+	registry *infraMetrics.Registry, repository flare.DeliveryRepositorier,
+) *Delivery {
+	return &Delivery{base: newBase(registry, "delivery"), repository: repository}
+}
+
+// SubscriptionLimit record the metrics of a subscription limit repository.
+type SubscriptionLimit struct {
+	base
+	repository flare.SubscriptionLimitRepositorier
+}
+
+// Acquire reserve a delivery to the subscription.
+func (sl *SubscriptionLimit) Acquire(
+	ctx context.Context, subscription flare.Subscription,
+) (string, time.Duration, error) {
+	defer sl.observe("acquire", time.Now())
+	return sl.repository.Acquire(ctx, subscription)
+}
+
+// Release free the in-flight delivery.
+func (sl *SubscriptionLimit) Release(
+	ctx context.Context, subscription flare.Subscription, lease string,
+) error {
+	defer sl.observe("release", time.Now())
+	return sl.repository.Release(ctx, subscription, lease)
+}
+
+// Pause stop the deliveries to the subscription until the given time.
+func (sl *SubscriptionLimit) Pause(
+	ctx context.Context, subscription flare.Subscription, until time.Time,
+) error {
+	defer sl.observe("pause", time.Now())
+	return sl.repository.Pause(ctx, subscription, until)
+}
+
+// NewSubscriptionLimit returns the subscription limit repository with metrics.
+func NewSubscriptionLimit(
+	registry *infraMetrics.Registry, repository flare.SubscriptionLimitRepositorier,
+) *SubscriptionLimit {
+	return &SubscriptionLimit{base: newBase(registry, "subscriptionLimit"), repository: repository}
+}
+
+// Change record the metrics of a change repository.
+type Change struct {
+	base
+	repository flare.ChangeRepositorier
+}
+
+// FindAll returns the changes of the resource after the sequence.
+func (c *Change) FindAll(
+	ctx context.Context, resourceId string, sequence int64, limit int,
+) ([]flare.Change, error) {
+	defer c.observe("findAll", time.Now())
+	return c.repository.FindAll(ctx, resourceId, sequence, limit)
+}
+
+// FindLatest returns the newest change of a document.
+func (c *Change) FindLatest(
+	ctx context.Context, resourceId, documentId string,
+) (*flare.Change, error) {
+	defer c.observe("findLatest", time.Now())
+	return c.repository.FindLatest(ctx, resourceId, documentId)
+}
+
+// Create a change.
+func (c *Change) Create(ctx context.Context, change *flare.Change, latest int64) error {
+	defer c.observe("create", time.Now())
+	return c.repository.Create(ctx, change, latest)
+}
+
+// NewChange returns the change repository with metrics.
+func NewChange(registry *infraMetrics.Registry, repository flare.ChangeRepositorier) *Change {
+	return &Change{base: newBase(registry, "change"), repository: repository}
+}
@@ -0,0 +1,308 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/diegobernardes/flare"
+)
+
+const (
+	changeLockTimeout = 10 * time.Second
+	changeLockPoll    = 10 * time.Millisecond
+)
+
+type changeEntity struct {
+	ResourceId       string                 `bson:"resourceId"`
+	Sequence         int64                  `bson:"sequence"`
+	DocumentId       string                 `bson:"documentId"`
+	DocumentRevision interface{}            `bson:"documentRevision"`
+	DocumentContent  map[string]interface{} `bson:"documentContent,omitempty"`
+	Action           string                 `bson:"action"`
+	CreatedAt        time.Time              `bson:"createdAt"`
+}
+
+// Change implements the data layer for the change feed. The sequences are generated by a counter
+// per resource and the changes older than the retention are removed by a TTL index.
+type Change struct {
+	client             *Client
+	database           string
+	collection         string
+	collectionSequence string
+	retention          time.Duration
+}
+
+// FindAll returns the changes of the resource after the sequence.
+func (c *Change) FindAll(
+	_ context.Context, resourceId string, sequence int64, limit int,
+) ([]flare.Change, error) {
+	session := c.client.session()
+	session.SetMode(mgo.Monotonic, true)
+	defer session.Close()
+
+	var entities []changeEntity
+	err := session.
+		DB(c.database).
+		C(c.collection).
+		Find(bson.M{"resourceId": resourceId, "sequence": bson.M{"$gt": sequence}}).
+		Sort("sequence").
+		Limit(limit).
+		All(&entities)
+	if err != nil {
+		return nil, errors.Wrap(err, "error during MongoDB access")
+	}
+
+	result := make([]flare.Change, len(entities))
+	for i, entity := range entities {
+		result[i] = *c.unmarshal(&entity)
+	}
+	return result, nil
+}
+
+// FindLatest returns the newest change of a document.
+func (c *Change) FindLatest(
+	_ context.Context, resourceId, documentId string,
+) (*flare.Change, error) {
+	session := c.client.session()
+	session.SetMode(mgo.Monotonic, true)
+	defer session.Close()
+
+	return c.findLatest(session, resourceId, documentId)
+}
+
+func (c *Change) findLatest(
+	session *mgo.Session, resourceId, documentId string,
+) (*flare.Change, error) {
+	var entity changeEntity
+	err := session.
+		DB(c.database).
+		C(c.collection).
+		Find(bson.M{"resourceId": resourceId, "documentId": documentId}).
+		Sort("-sequence").
+		One(&entity)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, &errMemory{
+				message:  fmt.Sprintf("change of document '%s' not found", documentId),
+				notFound: true,
+			}
+		}
+		return nil, errors.Wrap(err, "error during MongoDB access")
+	}
+
+	return c.unmarshal(&entity), nil
+}
+
+// Create a change if the newest change of the document still has the latest sequence. The creates
+// of a resource are serialised by a lock at the sequence counter and the counter is only moved
+// after the insert, this way, a change is never visible before the changes with a lower sequence.
+func (c *Change) Create(ctx context.Context, change *flare.Change, latest int64) error {
+	session := c.client.session()
+	session.SetMode(mgo.Monotonic, true)
+	defer session.Close()
+
+	resourceId := change.Resource.ID
+	lock, sequence, err := c.lock(ctx, session, resourceId)
+	if err != nil {
+		return err
+	}
+
+	sequence, err = c.create(session, change, latest, sequence)
+	if errUnlock := c.unlock(session, resourceId, lock, sequence); errUnlock != nil && err == nil {
+		err = errUnlock
+	}
+	return err
+}
+
+// create insert the change with the sequence after the given one. The returned sequence is the
+// value the counter should have after the create.
+func (c *Change) create(
+	session *mgo.Session, change *flare.Change, latest, sequence int64,
+) (int64, error) {
+	var current int64
+	currentChange, err := c.findLatest(session, change.Resource.ID, change.Document.Id)
+	if err != nil {
+		if errRepo, ok := err.(*errMemory); !ok || !errRepo.NotFound() {
+			return sequence, err
+		}
+	} else {
+		current = currentChange.Sequence
+	}
+
+	if current != latest {
+		return sequence, &errMemory{
+			message:  fmt.Sprintf("change of document '%s' is outdated", change.Document.Id),
+			conflict: true,
+		}
+	}
+
+	change.Sequence = sequence + 1
+	change.CreatedAt = time.Now()
+	collection := session.DB(c.database).C(c.collection)
+	err = collection.Insert(c.marshal(change))
+	if err == nil {
+		return change.Sequence, nil
+	}
+	if !mgo.IsDup(err) {
+		return sequence, errors.Wrap(err, "error during change create")
+	}
+
+	// The counter is behind the changes when a instance died after the insert or lost the lock. The
+	// counter is moved to the newest change and the create should be tried again.
+	var entity changeEntity
+	err = collection.Find(bson.M{"resourceId": change.Resource.ID}).Sort("-sequence").One(&entity)
+	if err != nil {
+		return sequence, errors.Wrap(err, "error during the search of the resource newest change")
+	}
+	return entity.Sequence, &errMemory{
+		message:  fmt.Sprintf("change sequence '%d' already exists", change.Sequence),
+		conflict: true,
+	}
+}
+
+// lock wait until the resource counter is free and returns the lock id and the current sequence.
+// The lock expire, this way, a instance that died don't hold the resource forever.
+func (c *Change) lock(
+	ctx context.Context, session *mgo.Session, resourceId string,
+) (string, int64, error) {
+	collection := session.DB(c.database).C(c.collectionSequence)
+	lock := uuid.NewV4().String()
+
+	for {
+		now := time.Now()
+		var counter struct {
+			Sequence int64 `bson:"sequence"`
+		}
+
+		// When the counter is locked, the upsert try to insert a new counter and fail with a
+		// duplicated key.
+		_, err := collection.
+			Find(bson.M{"_id": resourceId, "lockedUntil": bson.M{"$not": bson.M{"$gt": now}}}).
+			Apply(mgo.Change{
+				Update:    bson.M{"$set": bson.M{"lock": lock, "lockedUntil": now.Add(changeLockTimeout)}},
+				Upsert:    true,
+				ReturnNew: true,
+			}, &counter)
+		if err == nil {
+			return lock, counter.Sequence, nil
+		}
+		if !mgo.IsDup(err) {
+			return "", 0, errors.Wrap(err, "error during change sequence lock")
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", 0, errors.Wrap(ctx.Err(), "error during change sequence lock wait")
+		case <-time.After(changeLockPoll):
+		}
+	}
+}
+
+// unlock free the resource counter and move it to the sequence. If the lock expired, only the
+// sequence is moved.
+func (c *Change) unlock(session *mgo.Session, resourceId, lock string, sequence int64) error {
+	collection := session.DB(c.database).C(c.collectionSequence)
+	err := collection.Update(
+		bson.M{"_id": resourceId, "lock": lock},
+		bson.M{
+			"$max":   bson.M{"sequence": sequence},
+			"$unset": bson.M{"lock": "", "lockedUntil": ""},
+		},
+	)
+	if err == mgo.ErrNotFound {
+		err = collection.UpdateId(resourceId, bson.M{"$max": bson.M{"sequence": sequence}})
+	}
+	return errors.Wrap(err, "error during change sequence unlock")
+}
+
+func (c *Change) ensureIndex() error {
+	session := c.client.session()
+	defer session.Close()
+
+	collection := session.DB(c.database).C(c.collection)
+	indexes := []mgo.Index{
+		{Key: []string{"resourceId", "sequence"}, Unique: true},
+		{Key: []string{"resourceId", "documentId", "-sequence"}},
+	}
+	if c.retention > 0 {
+		indexes = append(indexes, mgo.Index{Key: []string{"createdAt"}, ExpireAfter: c.retention})
+	}
+
+	for _, index := range indexes {
+		if err := collection.EnsureIndex(index); err != nil {
+			return errors.Wrap(err, "error during index creation")
+		}
+	}
+	return nil
+}
+
+func (c *Change) marshal(change *flare.Change) *changeEntity {
+	return &changeEntity{
+		ResourceId:       change.Resource.ID,
+		Sequence:         change.Sequence,
+		DocumentId:       change.Document.Id,
+		DocumentRevision: change.Document.ChangeFieldValue,
+		DocumentContent:  change.Document.Content,
+		Action:           change.Action,
+		CreatedAt:        change.CreatedAt,
+	}
+}
+
+func (c *Change) unmarshal(entity *changeEntity) *flare.Change {
+	resource := flare.Resource{ID: entity.ResourceId}
+
+	return &flare.Change{
+		Sequence: entity.Sequence,
+		Resource: resource,
+		Document: flare.Document{
+			Id:               entity.DocumentId,
+			ChangeFieldValue: entity.DocumentRevision,
+			Content:          entity.DocumentContent,
+			Resource:         resource,
+		},
+		Action:    entity.Action,
+		CreatedAt: entity.CreatedAt,
+	}
+}
+
+// NewChange returns a configured change repository.
+func NewChange(options ...func(*Change)) (*Change, error) {
+	c := &Change{}
+	for _, option := range options {
+		option(c)
+	}
+
+	if c.client == nil {
+		return nil, errors.New("invalid client")
+	}
+	c.collection = "changes"
+	c.collectionSequence = "changeSequences"
+	c.database = c.client.database
+
+	if err := c.ensureIndex(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ChangeClient set the client to access MongoDB.
+func ChangeClient(client *Client) func(*Change) {
+	return func(c *Change) {
+		c.client = client
+	}
+}
+
+// ChangeRetention set the time the changes are kept. The zero value keep the changes forever.
+func ChangeRetention(retention time.Duration) func(*Change) {
+	return func(c *Change) { c.retention = retention }
+}
@@ -5,6 +5,8 @@
 package mongodb
 
 import (
+	"context"
+
 	"github.com/pkg/errors"
 	mgo "gopkg.in/mgo.v2"
 )
@@ -23,6 +25,23 @@ func (c *Client) Stop() {
 	c.sess.Close()
 }
 
+// Ping check the connectivity with MongoDB.
+func (c *Client) Ping(ctx context.Context) error {
+	errs := make(chan error, 1)
+	go func() {
+		session := c.session()
+		defer session.Close()
+		errs <- session.Ping()
+	}()
+
+	select {
+	case err := <-errs:
+		return errors.Wrap(err, "error during MongoDB ping")
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "error during MongoDB ping")
+	}
+}
+
 func (c *Client) session() *mgo.Session { return c.sess.Clone() }
 
 // NewClient returns a configured client to access MongoDB.
@@ -0,0 +1,209 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/diegobernardes/flare"
+)
+
+type deadLetterEntity struct {
+	Id               string      `bson:"id"`
+	SubscriptionId   string      `bson:"subscriptionId"`
+	ResourceId       string      `bson:"resourceId"`
+	DocumentId       string      `bson:"documentId"`
+	DocumentRevision interface{} `bson:"documentRevision"`
+	Action           string      `bson:"action"`
+	Attempts         int         `bson:"attempts"`
+	Error            string      `bson:"error"`
+	CreatedAt        time.Time   `bson:"createdAt"`
+}
+
+// DeadLetter implements the data layer for the dead letters.
+type DeadLetter struct {
+	client     *Client
+	database   string
+	collection string
+}
+
+// FindAll returns a list of dead letters from a subscription.
+func (dl *DeadLetter) FindAll(
+	_ context.Context, pagination *flare.Pagination, resourceId, subscriptionId string,
+) ([]flare.DeadLetter, *flare.Pagination, error) {
+	var (
+		group    errgroup.Group
+		entities []deadLetterEntity
+		total    int
+		query    = bson.M{"resourceId": resourceId, "subscriptionId": subscriptionId}
+	)
+
+	session := dl.client.session()
+	session.SetMode(mgo.Monotonic, true)
+	defer session.Close()
+
+	group.Go(func() error {
+		totalResult, err := session.DB(dl.database).C(dl.collection).Find(query).Count()
+		if err != nil {
+			return err
+		}
+		total = totalResult
+		return nil
+	})
+
+	group.Go(func() error {
+		q := session.
+			DB(dl.database).
+			C(dl.collection).
+			Find(query).
+			Sort("createdAt").
+			Limit(pagination.Limit)
+
+		if pagination.Offset != 0 {
+			q = q.Skip(pagination.Offset)
+		}
+
+		return q.All(&entities)
+	})
+
+	if err := group.Wait(); err != nil {
+		return nil, nil, errors.Wrap(err, "error during MongoDB access")
+	}
+
+	result := make([]flare.DeadLetter, len(entities))
+	for i, entity := range entities {
+		result[i] = *dl.unmarshal(&entity)
+	}
+
+	return result, &flare.Pagination{
+		Limit:  pagination.Limit,
+		Offset: pagination.Offset,
+		Total:  total,
+	}, nil
+}
+
+// FindOne return the dead letter that match the id.
+func (dl *DeadLetter) FindOne(
+	_ context.Context, resourceId, subscriptionId, id string,
+) (*flare.DeadLetter, error) {
+	session := dl.client.session()
+	session.SetMode(mgo.Monotonic, true)
+	defer session.Close()
+
+	entity := &deadLetterEntity{}
+	err := session.DB(dl.database).C(dl.collection).Find(bson.M{
+		"id": id, "resourceId": resourceId, "subscriptionId": subscriptionId,
+	}).One(entity)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, &errMemory{message: fmt.Sprintf("dead letter '%s' not found", id), notFound: true}
+		}
+		return nil, errors.Wrap(err, fmt.Sprintf("error during dead letter '%s' find", id))
+	}
+
+	return dl.unmarshal(entity), nil
+}
+
+// Create a dead letter.
+func (dl *DeadLetter) Create(_ context.Context, deadLetter *flare.DeadLetter) error {
+	session := dl.client.session()
+	session.SetMode(mgo.Monotonic, true)
+	defer session.Close()
+
+	deadLetter.CreatedAt = time.Now()
+	err := session.DB(dl.database).C(dl.collection).Insert(dl.marshal(deadLetter))
+	return errors.Wrap(err, "error during dead letter create")
+}
+
+// Delete a given dead letter.
+func (dl *DeadLetter) Delete(_ context.Context, resourceId, subscriptionId, id string) error {
+	session := dl.client.session()
+	session.SetMode(mgo.Monotonic, true)
+	defer session.Close()
+
+	err := session.DB(dl.database).C(dl.collection).Remove(bson.M{
+		"id": id, "resourceId": resourceId, "subscriptionId": subscriptionId,
+	})
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return &errMemory{message: fmt.Sprintf("dead letter '%s' not found", id), notFound: true}
+		}
+		return errors.Wrap(err, fmt.Sprintf("error during dead letter '%s' delete", id))
+	}
+	return nil
+}
+
+// DeleteAll remove all the dead letters from a subscription.
+func (dl *DeadLetter) DeleteAll(_ context.Context, resourceId, subscriptionId string) error {
+	session := dl.client.session()
+	session.SetMode(mgo.Monotonic, true)
+	defer session.Close()
+
+	_, err := session.DB(dl.database).C(dl.collection).RemoveAll(bson.M{
+		"resourceId": resourceId, "subscriptionId": subscriptionId,
+	})
+	return errors.Wrap(err, "error during dead letters delete")
+}
+
+func (dl *DeadLetter) marshal(deadLetter *flare.DeadLetter) *deadLetterEntity {
+	return &deadLetterEntity{
+		Id:               deadLetter.ID,
+		SubscriptionId:   deadLetter.Subscription.ID,
+		ResourceId:       deadLetter.Subscription.Resource.ID,
+		DocumentId:       deadLetter.Document.Id,
+		DocumentRevision: deadLetter.Document.ChangeFieldValue,
+		Action:           deadLetter.Action,
+		Attempts:         deadLetter.Attempts,
+		Error:            deadLetter.Error,
+		CreatedAt:        deadLetter.CreatedAt,
+	}
+}
+
+func (dl *DeadLetter) unmarshal(entity *deadLetterEntity) *flare.DeadLetter {
+	resource := flare.Resource{ID: entity.ResourceId}
+
+	return &flare.DeadLetter{
+		ID:           entity.Id,
+		Subscription: flare.Subscription{ID: entity.SubscriptionId, Resource: resource},
+		Document: flare.Document{
+			Id:               entity.DocumentId,
+			ChangeFieldValue: entity.DocumentRevision,
+			Resource:         resource,
+		},
+		Action:    entity.Action,
+		Attempts:  entity.Attempts,
+		Error:     entity.Error,
+		CreatedAt: entity.CreatedAt,
+	}
+}
+
+// NewDeadLetter returns a configured dead letter repository.
+func NewDeadLetter(options ...func(*DeadLetter)) (*DeadLetter, error) {
+	dl := &DeadLetter{}
+	for _, option := range options {
+		option(dl)
+	}
+
+	if dl.client == nil {
+		return nil, errors.New("invalid client")
+	}
+	dl.collection = "deadLetters"
+	dl.database = dl.client.database
+	return dl, nil
+}
+
+// DeadLetterClient set the client to access MongoDB.
+func DeadLetterClient(client *Client) func(*DeadLetter) {
+	return func(dl *DeadLetter) {
+		dl.client = client
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/diegobernardes/flare"
+)
+
+type deliveryEntity struct {
+	Id               string        `bson:"id"`
+	SubscriptionId   string        `bson:"subscriptionId"`
+	ResourceId       string        `bson:"resourceId"`
+	DocumentId       string        `bson:"documentId"`
+	DocumentRevision interface{}   `bson:"documentRevision"`
+	Action           string        `bson:"action"`
+	Target           string        `bson:"target"`
+	Attempt          int           `bson:"attempt"`
+	Status           int           `bson:"status"`
+	Latency          time.Duration `bson:"latency"`
+	Error            string        `bson:"error"`
+	ResponseBody     string        `bson:"responseBody"`
+	MessageId        string        `bson:"messageId"`
+	CreatedAt        time.Time     `bson:"createdAt"`
+}
+
+// Delivery implements the data layer for the delivery attempts.
+type Delivery struct {
+	client     *Client
+	database   string
+	collection string
+	retention  time.Duration
+}
+
+// FindAll returns a list of deliveries from a subscription, the newest first.
+func (d *Delivery) FindAll(
+	_ context.Context,
+	pagination *flare.Pagination,
+	resourceId, subscriptionId string,
+	filter *flare.DeliveryFilter,
+) ([]flare.Delivery, *flare.Pagination, error) {
+	var (
+		group    errgroup.Group
+		entities []deliveryEntity
+		total    int
+		query    = d.query(resourceId, subscriptionId, filter)
+	)
+
+	session := d.client.session()
+	session.SetMode(mgo.Monotonic, true)
+	defer session.Close()
+
+	group.Go(func() error {
+		totalResult, err := session.DB(d.database).C(d.collection).Find(query).Count()
+		if err != nil {
+			return err
+		}
+		total = totalResult
+		return nil
+	})
+
+	group.Go(func() error {
+		q := session.
+			DB(d.database).
+			C(d.collection).
+			Find(query).
+			Sort("-createdAt").
+			Limit(pagination.Limit)
+
+		if pagination.Offset != 0 {
+			q = q.Skip(pagination.Offset)
+		}
+
+		return q.All(&entities)
+	})
+
+	if err := group.Wait(); err != nil {
+		return nil, nil, errors.Wrap(err, "error during MongoDB access")
+	}
+
+	result := make([]flare.Delivery, len(entities))
+	for i, entity := range entities {
+		result[i] = *d.unmarshal(&entity)
+	}
+
+	return result, &flare.Pagination{
+		Limit:  pagination.Limit,
+		Offset: pagination.Offset,
+		Total:  total,
+	}, nil
+}
+
+// Create a delivery.
+func (d *Delivery) Create(_ context.Context, delivery *flare.Delivery) error {
+	session := d.client.session()
+	session.SetMode(mgo.Monotonic, true)
+	defer session.Close()
+
+	delivery.CreatedAt = time.Now()
+	err := session.DB(d.database).C(d.collection).Insert(d.marshal(delivery))
+	return errors.Wrap(err, "error during delivery create")
+}
+
+func (d *Delivery) query(resourceId, subscriptionId string, filter *flare.DeliveryFilter) bson.M {
+	query := bson.M{"resourceId": resourceId, "subscriptionId": subscriptionId}
+	if filter == nil {
+		return query
+	}
+
+	if len(filter.Status) > 0 {
+		query["status"] = bson.M{"$in": filter.Status}
+	}
+
+	createdAt := bson.M{}
+	if !filter.From.IsZero() {
+		createdAt["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		createdAt["$lte"] = filter.To
+	}
+	if len(createdAt) > 0 {
+		query["createdAt"] = createdAt
+	}
+
+	return query
+}
+
+func (d *Delivery) marshal(delivery *flare.Delivery) *deliveryEntity {
+	return &deliveryEntity{
+		Id:               delivery.ID,
+		SubscriptionId:   delivery.Subscription.ID,
+		ResourceId:       delivery.Subscription.Resource.ID,
+		DocumentId:       delivery.Document.Id,
+		DocumentRevision: delivery.Document.ChangeFieldValue,
+		Action:           delivery.Action,
+		Target:           delivery.Target,
+		Attempt:          delivery.Attempt,
+		Status:           delivery.Status,
+		Latency:          delivery.Latency,
+		Error:            delivery.Error,
+		ResponseBody:     delivery.ResponseBody,
+		MessageId:        delivery.MessageID,
+		CreatedAt:        delivery.CreatedAt,
+	}
+}
+
+func (d *Delivery) unmarshal(entity *deliveryEntity) *flare.Delivery {
+	resource := flare.Resource{ID: entity.ResourceId}
+
+	return &flare.Delivery{
+		ID:           entity.Id,
+		Subscription: flare.Subscription{ID: entity.SubscriptionId, Resource: resource},
+		Document: flare.Document{
+			Id:               entity.DocumentId,
+			ChangeFieldValue: entity.DocumentRevision,
+			Resource:         resource,
+		},
+		Action:       entity.Action,
+		Target:       entity.Target,
+		Attempt:      entity.Attempt,
+		Status:       entity.Status,
+		Latency:      entity.Latency,
+		Error:        entity.Error,
+		ResponseBody: entity.ResponseBody,
+		MessageID:    entity.MessageId,
+		CreatedAt:    entity.CreatedAt,
+	}
+}
+
+func (d *Delivery) ensureIndex() error {
+	session := d.client.session()
+	defer session.Close()
+
+	collection := session.DB(d.database).C(d.collection)
+	indexes := []mgo.Index{{Key: []string{"resourceId", "subscriptionId", "-createdAt"}}}
+	if d.retention > 0 {
+		indexes = append(indexes, mgo.Index{Key: []string{"createdAt"}, ExpireAfter: d.retention})
+	}
+
+	for _, index := range indexes {
+		if err := collection.EnsureIndex(index); err != nil {
+			return errors.Wrap(err, "error during index creation")
+		}
+	}
+	return nil
+}
+
+// NewDelivery returns a configured delivery repository.
+func NewDelivery(options ...func(*Delivery)) (*Delivery, error) {
+	d := &Delivery{}
+	for _, option := range options {
+		option(d)
+	}
+
+	if d.client == nil {
+		return nil, errors.New("invalid client")
+	}
+	d.collection = "deliveries"
+	d.database = d.client.database
+
+	if err := d.ensureIndex(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DeliveryClient set the client to access MongoDB.
+func DeliveryClient(client *Client) func(*Delivery) {
+	return func(d *Delivery) {
+		d.client = client
+	}
+}
+
+// DeliveryRetention set the time the deliveries are kept. The zero value keep the deliveries
+// forever.
+func DeliveryRetention(retention time.Duration) func(*Delivery) {
+	return func(d *Delivery) { d.retention = retention }
+}
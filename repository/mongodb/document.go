@@ -36,17 +36,21 @@ func (d *Document) FindOneWithRevision(
 }
 
 // Update a given document.
-func (d *Document) Update(_ context.Context, document *flare.Document) error {
+func (d *Document) Update(ctx context.Context, document *flare.Document) error {
 	session := d.client.session()
 	session.SetMode(mgo.Monotonic, true)
 	defer session.Close()
 	document.UpdatedAt = time.Now()
 
-	content := d.marshal(document)
-	_, err := session.DB(d.database).C(d.collection).Upsert(bson.M{
+	content, err := d.marshal(document)
+	if err != nil {
+		return errors.Wrap(err, "error during document marshal")
+	}
+	content["namespace"] = flare.NamespaceFromContext(ctx)
+	_, err = session.DB(d.database).C(d.collection).Upsert(namespaceQuery(ctx, bson.M{
 		"id":       document.Id,
 		"revision": document.ChangeFieldValue,
-	}, content)
+	}), content)
 	if err != nil {
 		return errors.Wrap(err, fmt.Sprintf("error during document '%s' update", document.Id))
 	}
@@ -65,7 +69,7 @@ func (d *Document) findOne(
 	session.SetMode(mgo.Monotonic, true)
 	defer session.Close()
 
-	query := bson.M{"id": id}
+	query := namespaceQuery(ctx, bson.M{"id": id})
 	if revision != nil {
 		query["revision"] = revision
 	}
@@ -75,7 +79,7 @@ func (d *Document) findOne(
 		DB(d.database).
 		C(d.collection).
 		Find(query).
-		Sort("-revision").
+		Sort("-order", "-revision").
 		One(&rawResult)
 	if err != nil {
 		if err == mgo.ErrNotFound {
@@ -91,13 +95,29 @@ func (d *Document) findOne(
 	return result, nil
 }
 
-func (d *Document) marshal(document *flare.Document) map[string]interface{} {
-	return map[string]interface{}{
+func (d *Document) marshal(document *flare.Document) (map[string]interface{}, error) {
+	content := map[string]interface{}{
 		"id":         document.Id,
 		"revision":   document.ChangeFieldValue,
 		"resourceID": document.Resource.ID,
+		"content":    document.Content,
 		"updatedAt":  time.Now(),
 	}
+
+	// The hashes can't be ordered, so, the newest revision is the last persisted. The semver and
+	// composite revisions are strings that don't keep the order, a sort key is used instead. The
+	// other kinds don't set the order and are sorted by the revision.
+	switch document.Resource.Change.Kind {
+	case flare.ResourceChangeHash:
+		content["order"] = document.UpdatedAt
+	case flare.ResourceChangeSemver, flare.ResourceChangeComposite:
+		key, err := document.RevisionSortKey()
+		if err != nil {
+			return nil, errors.Wrap(err, "error during revision sort key generation")
+		}
+		content["order"] = key
+	}
+	return content, nil
 }
 
 func (d *Document) unmarshal(content map[string]interface{}) (*flare.Document, error) {
@@ -121,9 +141,18 @@ func (d *Document) unmarshal(content map[string]interface{}) (*flare.Document, e
 		return nil, errors.New("missing updatedAt")
 	}
 
+	var body map[string]interface{}
+	switch v := content["content"].(type) {
+	case bson.M:
+		body = v
+	case map[string]interface{}:
+		body = v
+	}
+
 	return &flare.Document{
 		Id:               id,
 		ChangeFieldValue: revision,
+		Content:          body,
 		Resource:         flare.Resource{ID: resourceID},
 		UpdatedAt:        updatedAt,
 	}, nil
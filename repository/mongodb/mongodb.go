@@ -4,14 +4,73 @@
 
 package mongodb
 
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/diegobernardes/flare"
+)
+
+// namespaceQuery scope the query to the namespace at the context. The records created before the
+// namespaces don't have the field and belong to the default namespace.
+func namespaceQuery(ctx context.Context, query bson.M) bson.M {
+	switch namespace := flare.NamespaceFromContext(ctx); namespace {
+	case flare.NamespaceAll:
+	case flare.NamespaceDefault:
+		query["namespace"] = bson.M{"$in": []interface{}{namespace, "", nil}}
+	default:
+		query["namespace"] = namespace
+	}
+	return query
+}
+
+// checkCreateLimit verify the create limit at the context after the entity was inserted. MongoDB
+// can't count and insert at once, so, the entity is removed if the namespace is above the limit.
+// The concurrent creates can be all removed, but the namespace never stay above the limit.
+func checkCreateLimit(
+	ctx context.Context, collection *mgo.Collection, query bson.M, kind string,
+) error {
+	limit := flare.CreateLimitFromContext(ctx)
+	if limit == 0 {
+		return nil
+	}
+
+	count, err := collection.Find(namespaceQuery(ctx, bson.M{})).Count()
+	if err != nil {
+		return errors.Wrap(err, "error during create limit count")
+	}
+
+	if count <= limit {
+		return nil
+	}
+
+	if err = collection.Remove(namespaceQuery(ctx, query)); err != nil {
+		return errors.Wrap(err, "error during create limit remove")
+	}
+	return &errMemory{
+		message: fmt.Sprintf(
+			"namespace '%s' reached the limit of %d %s", flare.NamespaceFromContext(ctx), limit, kind,
+		),
+		limitReached: true,
+	}
+}
+
 type errMemory struct {
 	message       string
 	alreadyExists bool
 	pathConflict  bool
 	notFound      bool
+	conflict      bool
+	limitReached  bool
 }
 
 func (e *errMemory) Error() string       { return e.message }
 func (e *errMemory) AlreadyExists() bool { return e.alreadyExists }
 func (e *errMemory) PathConflict() bool  { return e.pathConflict }
 func (e *errMemory) NotFound() bool      { return e.notFound }
+func (e *errMemory) Conflict() bool      { return e.conflict }
+func (e *errMemory) LimitReached() bool  { return e.limitReached }
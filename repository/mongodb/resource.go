@@ -23,16 +23,29 @@ const wildcard = "{*}"
 
 type resourceEntity struct {
 	Id        string               `bson:"id"`
+	Namespace string               `bson:"namespace"`
 	Addresses []string             `bson:"addresses"`
 	Path      string               `bson:"path"`
 	Change    resourceChangeEntity `bson:"change"`
+	Poll      resourcePollEntity   `bson:"poll"`
 	CreatedAt time.Time            `bson:"createdAt"`
 }
 
+type resourcePollEntity struct {
+	Interval  time.Duration     `bson:"interval"`
+	Listing   string            `bson:"listing"`
+	Items     string            `bson:"items"`
+	Wildcards map[string]string `bson:"wildcards"`
+	Documents []string          `bson:"documents"`
+	RateLimit float64           `bson:"rateLimit"`
+}
+
 type resourceChangeEntity struct {
-	Field      string `bson:"field"`
-	Kind       string `bson:"kind"`
-	DateFormat string `bson:"dateFormat"`
+	Field      string   `bson:"field"`
+	Kind       string   `bson:"kind"`
+	DateFormat string   `bson:"dateFormat"`
+	Fields     []string `bson:"fields"`
+	Exclude    []string `bson:"exclude"`
 }
 
 // Resource implements the data layer for the resource service.
@@ -45,7 +58,7 @@ type Resource struct {
 
 // FindAll returns a list of resources.
 func (r *Resource) FindAll(
-	_ context.Context, pagination *flare.Pagination,
+	ctx context.Context, pagination *flare.Pagination,
 ) ([]flare.Resource, *flare.Pagination, error) {
 	var (
 		group     errgroup.Group
@@ -57,8 +70,9 @@ func (r *Resource) FindAll(
 	session.SetMode(mgo.Monotonic, true)
 	defer session.Close()
 
+	query := namespaceQuery(ctx, bson.M{})
 	group.Go(func() error {
-		totalResult, err := session.DB(r.database).C(r.collection).Find(bson.M{}).Count()
+		totalResult, err := session.DB(r.database).C(r.collection).Find(query).Count()
 		if err != nil {
 			return err
 		}
@@ -70,7 +84,7 @@ func (r *Resource) FindAll(
 		q := session.
 			DB(r.database).
 			C(r.collection).
-			Find(bson.M{}).
+			Find(query).
 			Sort("createdAt").
 			Limit(pagination.Limit)
 
@@ -93,13 +107,14 @@ func (r *Resource) FindAll(
 }
 
 // FindOne return the resource that match the id.
-func (r *Resource) FindOne(_ context.Context, id string) (*flare.Resource, error) {
+func (r *Resource) FindOne(ctx context.Context, id string) (*flare.Resource, error) {
 	session := r.client.session()
 	session.SetMode(mgo.Monotonic, true)
 	defer session.Close()
 
+	query := namespaceQuery(ctx, bson.M{"id": id})
 	result := &resourceEntity{}
-	if err := session.DB(r.database).C(r.collection).Find(bson.M{"id": id}).One(result); err != nil {
+	if err := session.DB(r.database).C(r.collection).Find(query).One(result); err != nil {
 		if err == mgo.ErrNotFound {
 			return nil, &errMemory{message: fmt.Sprintf("resource '%s' not found", id), notFound: true}
 		}
@@ -110,15 +125,17 @@ func (r *Resource) FindOne(_ context.Context, id string) (*flare.Resource, error
 }
 
 // FindByURI take a URI and find the resource that match.
-func (r *Resource) FindByURI(_ context.Context, rawAddress string) (*flare.Resource, error) {
+func (r *Resource) FindByURI(ctx context.Context, rawAddress string) (*flare.Resource, error) {
 	address, err := url.Parse(rawAddress)
 	if err != nil {
 		return nil, errors.Wrap(err, fmt.Sprintf("error during url '%s' parse", rawAddress))
 	}
 
 	query, err := r.findResourceByURI(
+		ctx,
 		[]string{fmt.Sprintf("%s://%s", address.Scheme, address.Host)},
 		address.Path,
+		"",
 	)
 	if err != nil {
 		return nil, errors.Wrap(err, "error during resource find")
@@ -145,8 +162,8 @@ func (r *Resource) FindByURI(_ context.Context, rawAddress string) (*flare.Resou
 }
 
 // Create a resource.
-func (r *Resource) Create(_ context.Context, res *flare.Resource) error {
-	_, err := r.findResourceByURI(res.Addresses, res.Path)
+func (r *Resource) Create(ctx context.Context, res *flare.Resource) error {
+	_, err := r.findResourceByURI(ctx, res.Addresses, res.Path, "")
 	if err == nil {
 		return &errMemory{message: "resource already exists", alreadyExists: true}
 	}
@@ -161,20 +178,16 @@ func (r *Resource) Create(_ context.Context, res *flare.Resource) error {
 	}
 
 	res.CreatedAt = time.Now()
-	contentChange := bson.M{
-		"kind":  res.Change.Kind,
-		"field": res.Change.Field,
-	}
-	if res.Change.Kind == flare.ResourceChangeDate {
-		contentChange["dateFormat"] = res.Change.DateFormat
-	}
+	contentChange := r.changeEntity(&res.Change)
 
 	content := bson.M{
 		"id":           res.ID,
+		"namespace":    res.Namespace,
 		"addresses":    res.Addresses,
 		"path":         res.Path,
 		"pathSegments": r.pathSegments(res.Path),
 		"change":       contentChange,
+		"poll":         r.pollEntity(&res.Poll),
 		"createdAt":    res.CreatedAt,
 	}
 
@@ -182,14 +195,72 @@ func (r *Resource) Create(_ context.Context, res *flare.Resource) error {
 	session.SetMode(mgo.Monotonic, true)
 	defer session.Close()
 
-	if err := session.DB(r.database).C(r.collection).Insert(content); err != nil {
-		errors.Wrap(err, "error during resource create")
+	collection := session.DB(r.database).C(r.collection)
+	if err := collection.Insert(content); err != nil {
+		return errors.Wrap(err, "error during resource create")
 	}
 
+	return checkCreateLimit(ctx, collection, bson.M{"id": res.ID}, "resources")
+}
+
+// Update a resource.
+func (r *Resource) Update(ctx context.Context, res *flare.Resource) error {
+	session := r.client.session()
+	session.SetMode(mgo.Monotonic, true)
+	defer session.Close()
+
+	query, err := r.findResourceByURI(ctx, res.Addresses, res.Path, res.ID)
+	if err == nil {
+		conflict := &resourceEntity{}
+		err = session.DB(r.database).C(r.collection).Find(query).One(conflict)
+		if err != nil && err != mgo.ErrNotFound {
+			return errors.Wrap(err, "error during resource search")
+		}
+		if err == nil {
+			return &errMemory{
+				message: fmt.Sprintf(
+					"address+path already associated to another resource '%s'", conflict.Id,
+				),
+				pathConflict: true,
+			}
+		}
+	} else if nErr, ok := err.(flare.ResourceRepositoryError); !ok || !nErr.NotFound() {
+		return err
+	}
+
+	contentChange := r.changeEntity(&res.Change)
+
+	filter := namespaceQuery(ctx, bson.M{"id": res.ID})
+	err = session.DB(r.database).C(r.collection).Update(filter, bson.M{"$set": bson.M{
+		"addresses":    res.Addresses,
+		"path":         res.Path,
+		"pathSegments": r.pathSegments(res.Path),
+		"change":       contentChange,
+		"poll":         r.pollEntity(&res.Poll),
+	}})
+	if err == mgo.ErrNotFound {
+		return &errMemory{message: fmt.Sprintf("resource '%s' not found", res.ID), notFound: true}
+	}
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("error during resource '%s' update", res.ID))
+	}
+
+	result := &resourceEntity{}
+	err = session.DB(r.database).C(r.collection).Find(filter).One(result)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("error during resource '%s' search", res.ID))
+	}
+	res.CreatedAt = result.CreatedAt
+
 	return nil
 }
 
-func (r *Resource) findResourceByURI(addresses []string, path string) (bson.M, error) {
+// findResourceByURI narrow the query one path segment at a time until it match a resource. The
+// resource with the ignoreId is not considered, this way, a update can't match itself and hide a
+// conflict with another resource.
+func (r *Resource) findResourceByURI(
+	ctx context.Context, addresses []string, path, ignoreId string,
+) (bson.M, error) {
 	session := r.client.session()
 	session.SetMode(mgo.Monotonic, true)
 	defer session.Close()
@@ -197,12 +268,7 @@ func (r *Resource) findResourceByURI(addresses []string, path string) (bson.M, e
 	segments := strings.Split(path, "/")
 	segments = segments[1:]
 
-	query := bson.M{"pathSegments": bson.M{"$size": len(segments)}}
-	if len(addresses) > 1 {
-		query["addresses"] = bson.M{"$in": addresses}
-	} else if len(addresses) == 1 {
-		query["addresses"] = addresses[0]
-	}
+	query := r.uriQuery(ctx, addresses, len(segments), ignoreId)
 	count := func() (int, error) { return session.DB(r.database).C(r.collection).Find(query).Count() }
 
 	for i, segment := range segments {
@@ -235,6 +301,22 @@ func (r *Resource) findResourceByURI(addresses []string, path string) (bson.M, e
 	return query, nil
 }
 
+func (r *Resource) uriQuery(
+	ctx context.Context, addresses []string, segments int, ignoreId string,
+) bson.M {
+	query := namespaceQuery(ctx, bson.M{"pathSegments": bson.M{"$size": segments}})
+	if len(addresses) > 1 {
+		query["addresses"] = bson.M{"$in": addresses}
+	} else if len(addresses) == 1 {
+		query["addresses"] = addresses[0]
+	}
+
+	if ignoreId != "" {
+		query["id"] = bson.M{"$ne": ignoreId}
+	}
+	return query
+}
+
 func (r *Resource) pathSegments(path string) []string {
 	segments := strings.Split(path, "/")
 	result := make([]string, len(segments)-1)
@@ -255,12 +337,13 @@ func (r *Resource) pathSegments(path string) []string {
 }
 
 // Delete a given resource.
-func (r *Resource) Delete(_ context.Context, id string) error {
+func (r *Resource) Delete(ctx context.Context, id string) error {
 	session := r.client.session()
 	session.SetMode(mgo.Monotonic, true)
 	defer session.Close()
 
-	if err := session.DB("flare").C("resources").Remove(bson.M{"id": id}); err != nil {
+	query := namespaceQuery(ctx, bson.M{"id": id})
+	if err := session.DB("flare").C("resources").Remove(query); err != nil {
 		if err == mgo.ErrNotFound {
 			return &errMemory{message: fmt.Sprintf("resource '%s' not found", id), notFound: true}
 		}
@@ -273,6 +356,7 @@ func (r *Resource) Delete(_ context.Context, id string) error {
 func (r *Resource) resourceEntityToFlareResource(content *resourceEntity) *flare.Resource {
 	return &flare.Resource{
 		ID:        content.Id,
+		Namespace: content.Namespace,
 		Addresses: content.Addresses,
 		Path:      content.Path,
 		CreatedAt: content.CreatedAt,
@@ -280,10 +364,49 @@ func (r *Resource) resourceEntityToFlareResource(content *resourceEntity) *flare
 			DateFormat: content.Change.DateFormat,
 			Field:      content.Change.Field,
 			Kind:       content.Change.Kind,
+			Fields:     content.Change.Fields,
+			Exclude:    content.Change.Exclude,
+		},
+		Poll: flare.ResourcePoll{
+			Interval:  content.Poll.Interval,
+			Listing:   content.Poll.Listing,
+			Items:     content.Poll.Items,
+			Wildcards: content.Poll.Wildcards,
+			Documents: content.Poll.Documents,
+			RateLimit: content.Poll.RateLimit,
 		},
 	}
 }
 
+func (r *Resource) changeEntity(change *flare.ResourceChange) bson.M {
+	content := bson.M{
+		"kind":  change.Kind,
+		"field": change.Field,
+	}
+
+	switch change.Kind {
+	case flare.ResourceChangeDate:
+		content["dateFormat"] = change.DateFormat
+	case flare.ResourceChangeHash:
+		content["fields"] = change.Fields
+		content["exclude"] = change.Exclude
+	case flare.ResourceChangeComposite:
+		content["fields"] = change.Fields
+	}
+	return content
+}
+
+func (r *Resource) pollEntity(poll *flare.ResourcePoll) *resourcePollEntity {
+	return &resourcePollEntity{
+		Interval:  poll.Interval,
+		Listing:   poll.Listing,
+		Items:     poll.Items,
+		Wildcards: poll.Wildcards,
+		Documents: poll.Documents,
+		RateLimit: poll.RateLimit,
+	}
+}
+
 func (r *Resource) resourceEntitySliceToFlareResourceSlice(
 	entities []resourceEntity,
 ) []flare.Resource {
@@ -0,0 +1,43 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/diegobernardes/flare"
+)
+
+func TestResourceURIQuery(t *testing.T) {
+	Convey("Given a Resource", t, func() {
+		r := &Resource{}
+		namespace := bson.M{"$in": []interface{}{flare.NamespaceDefault, "", nil}}
+
+		Convey("The query should match all the resources at the address", func() {
+			query := r.uriQuery(context.Background(), []string{"http://app.com"}, 2, "")
+			So(query, ShouldResemble, bson.M{
+				"pathSegments": bson.M{"$size": 2},
+				"addresses":    "http://app.com",
+				"namespace":    namespace,
+			})
+		})
+
+		Convey("The query should not match the resource being updated", func() {
+			query := r.uriQuery(
+				context.Background(), []string{"http://app.com", "http://app.io"}, 2, "2",
+			)
+			So(query, ShouldResemble, bson.M{
+				"pathSegments": bson.M{"$size": 2},
+				"addresses":    bson.M{"$in": []string{"http://app.com", "http://app.io"}},
+				"id":           bson.M{"$ne": "2"},
+				"namespace":    namespace,
+			})
+		})
+	})
+}
@@ -28,12 +28,13 @@ type Subscription struct {
 
 // FindAll returns a list of subscriptions.
 func (s *Subscription) FindAll(
-	_ context.Context, pagination *flare.Pagination, id string,
+	ctx context.Context, pagination *flare.Pagination, id string,
 ) ([]flare.Subscription, *flare.Pagination, error) {
 	var (
 		group         errgroup.Group
 		subscriptions []flare.Subscription
 		total         int
+		query         = namespaceQuery(ctx, bson.M{"resource.id": id})
 	)
 
 	group.Go(func() error {
@@ -41,7 +42,7 @@ func (s *Subscription) FindAll(
 		session.SetMode(mgo.Monotonic, true)
 		defer session.Close()
 
-		totalResult, err := session.DB(s.database).C(s.collection).Find(bson.M{"resource.id": id}).Count()
+		totalResult, err := session.DB(s.database).C(s.collection).Find(query).Count()
 		if err != nil {
 			return err
 		}
@@ -57,7 +58,7 @@ func (s *Subscription) FindAll(
 		q := session.
 			DB(s.database).
 			C(s.collection).
-			Find(bson.M{"resource.id": id}).
+			Find(query).
 			Sort("createdAt").
 			Limit(pagination.Limit)
 		if pagination.Offset != 0 {
@@ -80,14 +81,14 @@ func (s *Subscription) FindAll(
 
 // FindOne return the Subscription that match the id.
 func (s *Subscription) FindOne(
-	_ context.Context, resourceId, id string,
+	ctx context.Context, resourceId, id string,
 ) (*flare.Subscription, error) {
 	session := s.client.session()
 	defer session.Close()
 
 	session.SetMode(mgo.Monotonic, true)
 	result := &flare.Subscription{}
-	err := session.DB(s.database).C(s.collection).Find(bson.M{"id": id}).One(result)
+	err := session.DB(s.database).C(s.collection).Find(s.query(ctx, resourceId, id)).One(result)
 	if err == mgo.ErrNotFound {
 		return nil, &errMemory{message: fmt.Sprintf(
 			"subscription '%s' at resource '%s' not found", id, resourceId,
@@ -97,16 +98,15 @@ func (s *Subscription) FindOne(
 }
 
 // Create a subscription.
-func (s *Subscription) Create(_ context.Context, subscription *flare.Subscription) error {
+func (s *Subscription) Create(ctx context.Context, subscription *flare.Subscription) error {
 	session := s.client.session()
 	session.SetMode(mgo.Monotonic, true)
 	defer session.Close()
 
 	resourceEntity := &resourceEntity{}
-	err := session.DB(s.database).C(s.collection).Find(bson.M{
-		"resource.id":  subscription.Resource.ID,
-		"endpoint.url": subscription.Endpoint.URL.String(),
-	}).One(resourceEntity)
+	query := namespaceQuery(ctx, s.endpointQuery(subscription.Endpoint))
+	query["resource.id"] = subscription.Resource.ID
+	err := session.DB(s.database).C(s.collection).Find(query).One(resourceEntity)
 	if err == nil {
 		return fmt.Errorf("already has a subscription '%s' with this endpoint", resourceEntity.Id)
 	}
@@ -115,10 +115,98 @@ func (s *Subscription) Create(_ context.Context, subscription *flare.Subscriptio
 	}
 
 	subscription.CreatedAt = time.Now()
+	collection := session.DB(s.database).C(s.collection)
+	if err = collection.Insert(subscription); err != nil {
+		return errors.Wrap(err, "error during subscription create")
+	}
+
+	return checkCreateLimit(ctx, collection, bson.M{"id": subscription.ID}, "subscriptions")
+}
+
+// endpointQuery match the subscriptions with the same endpoint. The HTTP subscriptions created
+// before the targets don't have the target field, so it's only used by the others targets.
+func (s *Subscription) endpointQuery(endpoint flare.SubscriptionEndpoint) bson.M {
+	switch endpoint.TargetKind() {
+	case flare.SubscriptionTargetHTTP:
+		return bson.M{"endpoint.url": endpoint.URL.String()}
+	case flare.SubscriptionTargetSQS:
+		return bson.M{"endpoint.target": endpoint.Target, "endpoint.queue": endpoint.Queue}
+	case flare.SubscriptionTargetKafka:
+		return bson.M{
+			"endpoint.target": endpoint.Target,
+			"endpoint.url":    endpoint.URL.String(),
+			"endpoint.topic":  endpoint.Topic,
+		}
+	default:
+		return bson.M{"endpoint.target": endpoint.Target, "endpoint.topic": endpoint.Topic}
+	}
+}
+
+// Update a subscription.
+func (s *Subscription) Update(ctx context.Context, subscription *flare.Subscription) error {
+	session := s.client.session()
+	session.SetMode(mgo.Monotonic, true)
+	defer session.Close()
+
+	c := session.DB(s.database).C(s.collection)
+
+	conflict := &flare.Subscription{}
+	query := namespaceQuery(ctx, s.endpointQuery(subscription.Endpoint))
+	query["id"] = bson.M{"$ne": subscription.ID}
+	query["resource.id"] = subscription.Resource.ID
+	err := c.Find(query).One(conflict)
+	if err == nil {
+		return &errMemory{
+			message:       fmt.Sprintf("already has a subscription '%s' with this endpoint", conflict.ID),
+			alreadyExists: true,
+		}
+	}
+	if err != mgo.ErrNotFound {
+		return errors.Wrap(err, "error during subscription search")
+	}
+
+	current := &flare.Subscription{}
+	filter := s.query(ctx, subscription.Resource.ID, subscription.ID)
+	err = c.Find(filter).One(current)
+	if err == mgo.ErrNotFound {
+		return &errMemory{message: fmt.Sprintf(
+			"subscription '%s' at resource '%s' not found", subscription.ID, subscription.Resource.ID,
+		), notFound: true}
+	}
+	if err != nil {
+		return errors.Wrap(err, "error during subscription search")
+	}
+
+	subscription.CreatedAt = current.CreatedAt
 	return errors.Wrap(
-		session.DB(s.database).C(s.collection).Insert(subscription),
-		"error during subscription create",
+		c.Update(filter, subscription),
+		"error during subscription update",
+	)
+}
+
+// query returns the filter of a subscription. The resource is always part of it, this way, a
+// subscription can't be accessed through other resource.
+func (s *Subscription) query(ctx context.Context, resourceId, id string) bson.M {
+	return namespaceQuery(ctx, bson.M{"id": id, "resource.id": resourceId})
+}
+
+// UpdateSecret set the secret of a subscription.
+func (s *Subscription) UpdateSecret(
+	ctx context.Context, resourceId, id string, secret flare.SubscriptionSecret,
+) error {
+	session := s.client.session()
+	defer session.Close()
+
+	err := session.DB(s.database).C(s.collection).Update(
+		s.query(ctx, resourceId, id),
+		bson.M{"$set": bson.M{"secret": secret}},
 	)
+	if err == mgo.ErrNotFound {
+		return &errMemory{message: fmt.Sprintf(
+			"subscription '%s' at resource '%s' not found", id, resourceId,
+		), notFound: true}
+	}
+	return errors.Wrap(err, "error during subscription secret update")
 }
 
 // HasSubscription check if a resource has subscriptions.
@@ -130,7 +218,7 @@ func (s *Subscription) HasSubscription(ctx context.Context, resourceId string) (
 	count, err := session.
 		DB(s.database).
 		C(s.collection).
-		Find(bson.M{"resource.id": resourceId}).
+		Find(namespaceQuery(ctx, bson.M{"resource.id": resourceId})).
 		Count()
 	if err != nil {
 		if err == mgo.ErrNotFound {
@@ -144,14 +232,14 @@ func (s *Subscription) HasSubscription(ctx context.Context, resourceId string) (
 }
 
 // Delete a given subscription.
-func (s *Subscription) Delete(_ context.Context, resourceId, id string) error {
+func (s *Subscription) Delete(ctx context.Context, resourceId, id string) error {
 	session := s.client.session()
 	defer session.Close()
 
 	session.SetMode(mgo.Monotonic, true)
 	c := session.DB(s.database).C(s.collection)
 
-	if err := c.Remove(bson.M{"id": id, "resource.id": resourceId}); err != nil {
+	if err := c.Remove(s.query(ctx, resourceId, id)); err != nil {
 		if err == mgo.ErrNotFound {
 			return &errMemory{message: fmt.Sprintf(
 				"subscription '%s' at resource '%s' not found", id, resourceId,
@@ -166,7 +254,7 @@ func (s *Subscription) Trigger(
 	ctx context.Context,
 	kind string,
 	doc *flare.Document,
-	fn func(context.Context, flare.Subscription, string) error,
+	fn func(context.Context, flare.Subscription, string, *flare.Document) error,
 ) error {
 	session := s.client.session()
 	session.SetMode(mgo.Monotonic, true)
@@ -176,7 +264,7 @@ func (s *Subscription) Trigger(
 	err := session.
 		DB(s.database).
 		C(s.collection).
-		Find(bson.M{"resource.id": doc.Resource.ID}).
+		Find(namespaceQuery(ctx, bson.M{"resource.id": doc.Resource.ID})).
 		All(&subscriptions)
 	if err != nil {
 		return errors.Wrap(err, "error while subscription search")
@@ -188,6 +276,7 @@ func (s *Subscription) Trigger(
 	}
 	doc.Resource = *resource
 
+	fn = flare.SubscriptionTriggerAction(fn)
 	group, groupCtx := errgroup.WithContext(ctx)
 	for i := range subscriptions {
 		subscriptions[i].Resource = *resource
@@ -227,7 +316,7 @@ func (s *Subscription) newEntry(
 	session *mgo.Session,
 	subs flare.Subscription,
 	doc *flare.Document,
-	fn func(context.Context, flare.Subscription, string) error,
+	fn func(context.Context, flare.Subscription, string, *flare.Document) error,
 ) error {
 	if kind == flare.SubscriptionTriggerDelete {
 		return nil
@@ -238,7 +327,7 @@ func (s *Subscription) newEntry(
 		return errors.Wrap(err, "error during document upsert")
 	}
 
-	if err = fn(groupCtx, subs, flare.SubscriptionTriggerCreate); err != nil {
+	if err = fn(groupCtx, subs, flare.SubscriptionTriggerCreate, nil); err != nil {
 		return errors.Wrap(err, "error during document subscription processing")
 	}
 	return nil
@@ -250,9 +339,9 @@ func (s *Subscription) triggerProcessDelete(
 	session *mgo.Session,
 	subs flare.Subscription,
 	doc *flare.Document,
-	fn func(context.Context, flare.Subscription, string) error,
+	fn func(context.Context, flare.Subscription, string, *flare.Document) error,
 ) error {
-	if err := fn(groupCtx, subs, flare.SubscriptionTriggerDelete); err != nil {
+	if err := fn(groupCtx, subs, flare.SubscriptionTriggerDelete, nil); err != nil {
 		return errors.Wrap(err, "error during document subscription processing")
 	}
 
@@ -294,7 +383,7 @@ func (s *Subscription) triggerProcess(
 	subs flare.Subscription,
 	doc *flare.Document,
 	kind string,
-	fn func(context.Context, flare.Subscription, string) error,
+	fn func(context.Context, flare.Subscription, string, *flare.Document) error,
 ) func() error {
 	return func() error {
 		session := s.client.session()
@@ -323,7 +412,7 @@ func (s *Subscription) triggerProcess(
 			return nil
 		}
 
-		if err = fn(groupCtx, subs, flare.SubscriptionTriggerUpdate); err != nil {
+		if err = fn(groupCtx, subs, flare.SubscriptionTriggerUpdate, referenceDocument); err != nil {
 			return errors.Wrap(err, "error during document subscription processing")
 		}
 
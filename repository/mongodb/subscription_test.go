@@ -0,0 +1,36 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/diegobernardes/flare"
+)
+
+func TestSubscriptionQuery(t *testing.T) {
+	Convey("Given a Subscription", t, func() {
+		s := &Subscription{}
+
+		Convey("The query should be restricted to the resource", func() {
+			So(s.query(context.Background(), "123", "456"), ShouldResemble, bson.M{
+				"id":          "456",
+				"resource.id": "123",
+				"namespace":   bson.M{"$in": []interface{}{flare.NamespaceDefault, "", nil}},
+			})
+		})
+
+		Convey("The query should be restricted to the namespace", func() {
+			ctx := flare.ContextWithNamespace(context.Background(), "team-a")
+			So(s.query(ctx, "123", "456"), ShouldResemble, bson.M{
+				"id": "456", "resource.id": "123", "namespace": "team-a",
+			})
+		})
+	})
+}
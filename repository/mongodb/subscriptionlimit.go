@@ -0,0 +1,212 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/diegobernardes/flare"
+)
+
+type subscriptionLimitEntity struct {
+	SubscriptionId string                   `bson:"subscriptionId"`
+	Leases         []subscriptionLimitLease `bson:"leases"`
+	Next           time.Time                `bson:"next"`
+	PausedUntil    time.Time                `bson:"pausedUntil"`
+}
+
+type subscriptionLimitLease struct {
+	Id       string    `bson:"id"`
+	ExpireAt time.Time `bson:"expireAt"`
+}
+
+// SubscriptionLimit implements the data layer for the subscriptions delivery limits. The state is
+// shared by all the Flare instances. The in-flight deliveries are leases that expire, this way, the
+// deliveries of a instance that died don't hold the subscription forever.
+type SubscriptionLimit struct {
+	client       *Client
+	database     string
+	collection   string
+	leaseTimeout time.Duration
+}
+
+// Acquire reserve a delivery to the subscription. The reserve is a conditional update, so the
+// instances can't reserve above the limits at the same time.
+func (sl *SubscriptionLimit) Acquire(
+	_ context.Context, subscription flare.Subscription,
+) (string, time.Duration, error) {
+	session := sl.client.session()
+	defer session.Close()
+	c := session.DB(sl.database).C(sl.collection)
+
+	now := time.Now()
+	limit := subscription.Delivery.Limit
+	if !limit.Enabled() {
+		return sl.acquireUnlimited(c, subscription, now)
+	}
+
+	_, err := c.Upsert(
+		bson.M{"subscriptionId": subscription.ID},
+		bson.M{
+			"$pull":        bson.M{"leases": bson.M{"expireAt": bson.M{"$lte": now}}},
+			"$setOnInsert": bson.M{"next": time.Time{}, "pausedUntil": time.Time{}},
+		},
+	)
+	// Two instances can try to create the state at the same time, the one that lose just continue.
+	if err != nil && !mgo.IsDup(err) {
+		return "", 0, errors.Wrap(err, "error during subscription limit expired leases remove")
+	}
+
+	lease := subscriptionLimitLease{Id: uuid.NewV4().String(), ExpireAt: now.Add(sl.leaseTimeout)}
+	query := bson.M{"subscriptionId": subscription.ID, "pausedUntil": bson.M{"$lte": now}}
+	update := bson.M{"$push": bson.M{"leases": lease}}
+
+	if limit.MaxInFlight > 0 {
+		query[fmt.Sprintf("leases.%d", limit.MaxInFlight-1)] = bson.M{"$exists": false}
+	}
+
+	if interval := limit.Interval(); interval > 0 {
+		query["next"] = bson.M{"$lte": now}
+		update["$set"] = bson.M{"next": now.Add(interval)}
+	}
+
+	err = c.Update(query, update)
+	if err == nil {
+		return lease.Id, 0, nil
+	}
+	if err != mgo.ErrNotFound {
+		return "", 0, errors.Wrap(err, "error during subscription limit acquire")
+	}
+
+	var entity subscriptionLimitEntity
+	if err = c.Find(bson.M{"subscriptionId": subscription.ID}).One(&entity); err != nil {
+		return "", 0, errors.Wrap(err, "error during subscription limit find")
+	}
+
+	wait := entity.PausedUntil.Sub(now)
+	if limit.Interval() > 0 {
+		if next := entity.Next.Sub(now); next > wait {
+			wait = next
+		}
+	}
+
+	if wait < 0 {
+		wait = 0
+	}
+	return "", wait, nil
+}
+
+// acquireUnlimited only check the pause, the deliveries of the subscriptions without limits are
+// not tracked.
+func (sl *SubscriptionLimit) acquireUnlimited(
+	c *mgo.Collection, subscription flare.Subscription, now time.Time,
+) (string, time.Duration, error) {
+	var entity subscriptionLimitEntity
+	err := c.Find(bson.M{"subscriptionId": subscription.ID}).One(&entity)
+	if err != nil && err != mgo.ErrNotFound {
+		return "", 0, errors.Wrap(err, "error during subscription limit find")
+	}
+
+	if wait := entity.PausedUntil.Sub(now); wait > 0 {
+		return "", wait, nil
+	}
+	return uuid.NewV4().String(), 0, nil
+}
+
+// Release free the in-flight delivery.
+func (sl *SubscriptionLimit) Release(
+	_ context.Context, subscription flare.Subscription, lease string,
+) error {
+	if !subscription.Delivery.Limit.Enabled() {
+		return nil
+	}
+
+	session := sl.client.session()
+	defer session.Close()
+
+	err := session.DB(sl.database).C(sl.collection).Update(
+		bson.M{"subscriptionId": subscription.ID},
+		bson.M{"$pull": bson.M{"leases": bson.M{"id": lease}}},
+	)
+	if err != nil && err != mgo.ErrNotFound {
+		return errors.Wrap(err, "error during subscription limit release")
+	}
+	return nil
+}
+
+// Pause stop the deliveries to the subscription until the given time.
+func (sl *SubscriptionLimit) Pause(
+	ctx context.Context, subscription flare.Subscription, until time.Time,
+) error {
+	session := sl.client.session()
+	defer session.Close()
+
+	_, err := session.DB(sl.database).C(sl.collection).Upsert(
+		bson.M{"subscriptionId": subscription.ID},
+		bson.M{
+			"$max":         bson.M{"pausedUntil": until},
+			"$setOnInsert": bson.M{"next": time.Time{}},
+		},
+	)
+	if err != nil && mgo.IsDup(err) {
+		return sl.Pause(ctx, subscription, until)
+	}
+	return errors.Wrap(err, "error during subscription pause")
+}
+
+func (sl *SubscriptionLimit) ensureIndex() error {
+	session := sl.client.session()
+	defer session.Close()
+
+	err := session.DB(sl.database).C(sl.collection).EnsureIndex(
+		mgo.Index{Key: []string{"subscriptionId"}, Unique: true},
+	)
+	return errors.Wrap(err, "error during index creation")
+}
+
+// NewSubscriptionLimit returns a configured subscription limit repository.
+func NewSubscriptionLimit(options ...func(*SubscriptionLimit)) (*SubscriptionLimit, error) {
+	sl := &SubscriptionLimit{leaseTimeout: time.Minute}
+	for _, option := range options {
+		option(sl)
+	}
+
+	if sl.client == nil {
+		return nil, errors.New("invalid client")
+	}
+
+	if sl.leaseTimeout <= 0 {
+		return nil, errors.New("invalid lease timeout")
+	}
+	sl.collection = "subscriptionLimits"
+	sl.database = sl.client.database
+
+	if err := sl.ensureIndex(); err != nil {
+		return nil, err
+	}
+	return sl, nil
+}
+
+// SubscriptionLimitClient set the client to access MongoDB.
+func SubscriptionLimitClient(client *Client) func(*SubscriptionLimit) {
+	return func(sl *SubscriptionLimit) {
+		sl.client = client
+	}
+}
+
+// SubscriptionLimitLeaseTimeout set the time a in-flight delivery is held when it's not released,
+// it should be bigger than the delivery timeout. Default value: 1 minute.
+func SubscriptionLimitLeaseTimeout(timeout time.Duration) func(*SubscriptionLimit) {
+	return func(sl *SubscriptionLimit) {
+		sl.leaseTimeout = timeout
+	}
+}
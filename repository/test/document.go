@@ -45,7 +45,17 @@ func (d *Document) FindOne(ctx context.Context, id string) (*flare.Document, err
 func (d *Document) FindOneWithRevision(
 	ctx context.Context, id string, revision interface{},
 ) (*flare.Document, error) {
-	return d.base.FindOneWithRevision(ctx, id, revision)
+	if d.findOneErr != nil {
+		return nil, d.findOneErr
+	} else if d.err != nil {
+		return nil, d.err
+	}
+	document, err := d.base.FindOneWithRevision(ctx, id, revision)
+	if err != nil {
+		return document, err
+	}
+	document.UpdatedAt = d.date
+	return document, err
 }
 
 // Update mock flare.DocumentRepositorier.Update.
@@ -82,6 +82,17 @@ func (r *Resource) Create(ctx context.Context, resource *flare.Resource) error {
 	return err
 }
 
+// Update mock flare.ResourceRepositorier.Update.
+func (r *Resource) Update(ctx context.Context, resource *flare.Resource) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	err := r.base.Update(ctx, resource)
+	resource.CreatedAt = r.date
+	return err
+}
+
 // Delete mock flare.ResourceRepositorier.Delete.
 func (r *Resource) Delete(ctx context.Context, id string) error {
 	if r.err != nil {
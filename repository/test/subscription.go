@@ -81,6 +81,29 @@ func (r *Subscription) Create(ctx context.Context, subcr *flare.Subscription) er
 	return nil
 }
 
+// Update mock flare.SubscriptionRepositorier.Update.
+func (r *Subscription) Update(ctx context.Context, subcr *flare.Subscription) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	if err := r.base.Update(ctx, subcr); err != nil {
+		return err
+	}
+	subcr.CreatedAt = r.date
+	return nil
+}
+
+// UpdateSecret mock flare.SubscriptionRepositorier.UpdateSecret.
+func (r *Subscription) UpdateSecret(
+	ctx context.Context, resourceId, id string, secret flare.SubscriptionSecret,
+) error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.base.UpdateSecret(ctx, resourceId, id, secret)
+}
+
 // Delete mock flare.SubscriptionRepositorier.Delete.
 func (r *Subscription) Delete(ctx context.Context, resourceId, id string) error {
 	if r.err != nil {
@@ -104,7 +127,7 @@ func (r *Subscription) Trigger(
 	ctx context.Context,
 	action string,
 	document *flare.Document,
-	fn func(context.Context, flare.Subscription, string) error,
+	fn func(context.Context, flare.Subscription, string, *flare.Document) error,
 ) error {
 	if r.triggerErr != nil {
 		return r.triggerErr
@@ -6,6 +6,9 @@ package flare
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -18,9 +21,11 @@ import (
 // Resource is the base component from Flare. It is holds the data to detect the document change.
 type Resource struct {
 	ID        string
+	Namespace string
 	Addresses []string
 	Path      string
 	Change    ResourceChange
+	Poll      ResourcePoll
 	CreatedAt time.Time
 }
 
@@ -52,12 +57,37 @@ func (r *Resource) WildcardReplace(
 	}, nil
 }
 
+// Wildcards extract the value of each path wildcard from the document path. The keys are the
+// wildcard names without the braces.
+func (r *Resource) Wildcards(documentPath string) (map[string]string, error) {
+	endpoint, err := url.Parse(documentPath)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("error during url parse of '%s'", documentPath))
+	}
+	wildcards := strings.Split(r.Path, "/")
+	documentWildcards := strings.Split(endpoint.Path, "/")
+
+	result := make(map[string]string)
+	for i, wildcard := range wildcards {
+		if i >= len(documentWildcards) || len(wildcard) < 2 {
+			continue
+		}
+
+		if wildcard[0] == '{' && wildcard[len(wildcard)-1] == '}' {
+			result[wildcard[1:len(wildcard)-1]] = documentWildcards[i]
+		}
+	}
+	return result, nil
+}
+
 func (r *Resource) genRevision(revision interface{}) string {
 	switch v := revision.(type) {
 	case time.Time:
 		return v.Format(time.RFC3339)
 	case int:
 		return strconv.FormatInt((int64)(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
 	case string:
 		return v
 	}
@@ -66,22 +96,48 @@ func (r *Resource) genRevision(revision interface{}) string {
 
 // The types of value Flare support to detect document change.
 const (
-	ResourceChangeInteger = "integer"
-	ResourceChangeString  = "string"
-	ResourceChangeDate    = "date"
+	ResourceChangeInteger   = "integer"
+	ResourceChangeString    = "string"
+	ResourceChangeDate      = "date"
+	ResourceChangeHash      = "hash"
+	ResourceChangeNumeric   = "numeric"
+	ResourceChangeSemver    = "semver"
+	ResourceChangeComposite = "composite"
 )
 
-// ResourceChange holds the information to detect document change.
+// ResourceChange holds the information to detect document change. The Field is the location of
+// the revision at the document. It can be a key, a dotted path like "meta.updatedAt" or a RFC 6901
+// JSON pointer like "/_links/self/version". Numeric segments are used as index at the arrays.
+//
+// The numeric kind is a int64 revision sent as a number or as a string, the numbers beyond 2^53
+// should be sent as strings to not lose precision. The semver kind is compared by the semantic
+// version precedence, so "1.10.0" is newer than "1.9.0".
+//
+// The composite kind is used by the revisions split in many integer fields, like (epoch, counter).
+// The Fields are compared in order and the revision is kept as the values joined by ":".
+//
+// The hash kind is used by the documents without a revision field. The revision is the hash of
+// the document, restricted to the Fields or without the Exclude fields, and any difference at the
+// hash is a update.
 type ResourceChange struct {
 	Field      string
 	Kind       string
 	DateFormat string
+	Fields     []string
+	Exclude    []string
 }
 
 // Valid indicates if the current resourceChange is valid.
 func (rc *ResourceChange) Valid() error {
-	if rc.Field == "" {
-		return errors.New("blank field")
+	switch rc.Kind {
+	case ResourceChangeHash:
+		return rc.validHash()
+	case ResourceChangeComposite:
+		return rc.validComposite()
+	}
+
+	if err := rc.ValidField(); err != nil {
+		return err
 	}
 
 	if rc.Kind == "" {
@@ -91,15 +147,303 @@ func (rc *ResourceChange) Valid() error {
 	if rc.Kind == ResourceChangeDate && rc.DateFormat == "" {
 		return errors.New("blank dateFormat")
 	}
+
+	if len(rc.Fields) > 0 || len(rc.Exclude) > 0 {
+		return errors.New("fields and exclude are only used with the hash and composite kinds")
+	}
+	return nil
+}
+
+func (rc *ResourceChange) validHash() error {
+	if rc.Field != "" {
+		return errors.New("field is not used with the hash kind")
+	}
+
+	if len(rc.Fields) > 0 && len(rc.Exclude) > 0 {
+		return errors.New("fields and exclude can't be used together")
+	}
+
+	for _, field := range append(append([]string{}, rc.Fields...), rc.Exclude...) {
+		if field == "" {
+			return errors.New("blank field at the hash fields")
+		}
+
+		if _, err := parseFieldPath(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rc *ResourceChange) validComposite() error {
+	if rc.Field != "" {
+		return errors.New("field is not used with the composite kind")
+	}
+
+	if len(rc.Exclude) > 0 {
+		return errors.New("exclude is not used with the composite kind")
+	}
+
+	if len(rc.Fields) < 2 {
+		return errors.New("the composite kind should have at least two fields")
+	}
+
+	for _, field := range rc.Fields {
+		if field == "" {
+			return errors.New("blank field at the composite fields")
+		}
+
+		if _, err := parseFieldPath(field); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// ValidField indicates if the field is a valid key, dotted path or JSON pointer.
+func (rc *ResourceChange) ValidField() error {
+	if rc.Field == "" {
+		return errors.New("blank field")
+	}
+
+	_, err := parseFieldPath(rc.Field)
+	return err
+}
+
+// Value returns the revision from the document content. On the hash kind, the revision is the
+// hash of the content and, on the composite kind, the list of the fields values.
+func (rc *ResourceChange) Value(content map[string]interface{}) (interface{}, bool) {
+	switch rc.Kind {
+	case ResourceChangeHash:
+		value, err := rc.hash(content)
+		if err != nil {
+			return nil, false
+		}
+		return value, true
+	case ResourceChangeComposite:
+		values := make([]interface{}, len(rc.Fields))
+		for i, field := range rc.Fields {
+			path, err := parseFieldPath(field)
+			if err != nil {
+				return nil, false
+			}
+
+			var ok bool
+			if values[i], ok = lookupFieldPath(content, path); !ok {
+				return nil, false
+			}
+		}
+		return values, true
+	}
+
+	path, err := parseFieldPath(rc.Field)
+	if err != nil {
+		return nil, false
+	}
+	return lookupFieldPath(content, path)
+}
+
+// Equal indicates if both changes are the same.
+func (rc *ResourceChange) Equal(other *ResourceChange) bool {
+	equalSlice := func(a, b []string) bool {
+		if len(a) != len(b) {
+			return false
+		}
+
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	return rc.Field == other.Field && rc.Kind == other.Kind && rc.DateFormat == other.DateFormat &&
+		equalSlice(rc.Fields, other.Fields) && equalSlice(rc.Exclude, other.Exclude)
+}
+
+// hash generate a SHA-256 of the content encoded as JSON. The JSON encoding sort the object keys,
+// so, the same content always have the same hash.
+func (rc *ResourceChange) hash(content map[string]interface{}) (string, error) {
+	var value interface{} = content
+	switch {
+	case len(rc.Fields) > 0:
+		subset := make(map[string]interface{}, len(rc.Fields))
+		for _, field := range rc.Fields {
+			path, err := parseFieldPath(field)
+			if err != nil {
+				return "", err
+			}
+
+			if fieldValue, ok := lookupFieldPath(content, path); ok {
+				subset[field] = fieldValue
+			}
+		}
+		value = subset
+	case len(rc.Exclude) > 0:
+		for _, field := range rc.Exclude {
+			path, err := parseFieldPath(field)
+			if err != nil {
+				return "", err
+			}
+			value = removeFieldPath(value, path)
+		}
+	}
+
+	body, err := json.Marshal(hashNumbers(value))
+	if err != nil {
+		return "", errors.Wrap(err, "error during content marshal")
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// hashNumbers returns a copy of the value with the json.Number converted to int64 or float64. The
+// json.Number is marshaled as received, this way, 1.0 and 1 have the same hash, like when the
+// content is decoded as float64.
+func hashNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if result, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return result
+		}
+		if result, err := v.Float64(); err == nil {
+			return result
+		}
+		return v
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = hashNumbers(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = hashNumbers(item)
+		}
+		return result
+	}
+	return value
+}
+
+func parseFieldPath(field string) ([]string, error) {
+	if !strings.HasPrefix(field, "/") {
+		path := strings.Split(field, ".")
+		for _, segment := range path {
+			if segment == "" {
+				return nil, fmt.Errorf("field '%s' has a blank segment", field)
+			}
+		}
+		return path, nil
+	}
+
+	path := strings.Split(field[1:], "/")
+	for i, segment := range path {
+		for j := 0; j < len(segment); j++ {
+			if segment[j] != '~' {
+				continue
+			}
+
+			if j+1 == len(segment) || (segment[j+1] != '0' && segment[j+1] != '1') {
+				return nil, fmt.Errorf("field '%s' has a invalid escape sequence", field)
+			}
+			j++
+		}
+		path[i] = strings.Replace(strings.Replace(segment, "~1", "/", -1), "~0", "~", -1)
+	}
+	return path, nil
+}
+
+func lookupFieldPath(value interface{}, path []string) (interface{}, bool) {
+	for _, segment := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if value, ok = v[segment]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, false
+			}
+			value = v[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// removeFieldPath returns the value without the field at the path. The objects and arrays at the
+// path are copied, so, the original value is not changed. The array elements can't be removed.
+func removeFieldPath(value interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		child, ok := v[path[0]]
+		if !ok {
+			return value
+		}
+
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = item
+		}
+
+		if len(path) == 1 {
+			delete(result, path[0])
+		} else {
+			result[path[0]] = removeFieldPath(child, path[1:])
+		}
+		return result
+	case []interface{}:
+		index, err := strconv.Atoi(path[0])
+		if err != nil || index < 0 || index >= len(v) || len(path) == 1 {
+			return value
+		}
+
+		result := make([]interface{}, len(v))
+		copy(result, v)
+		result[index] = removeFieldPath(v[index], path[1:])
+		return result
+	}
+	return value
+}
+
+// ResourcePoll holds the information to fetch the documents from the resource, for the APIs that
+// can't send the changes to Flare. The documents come from the Listing, a path at the first address
+// that returns a list of documents, or from the Documents, a list of document URLs.
+//
+// At the listing, the Items is the path to the list at the response, empty when the response is
+// the list, and the Wildcards map the path wildcards to the item fields used to build the
+// document id. By default, the field has the same name of the wildcard.
+type ResourcePoll struct {
+	Interval  time.Duration
+	Listing   string
+	Items     string
+	Wildcards map[string]string
+	Documents []string
+	RateLimit float64
+}
+
+// Enabled indicates if the resource should be polled.
+func (rp *ResourcePoll) Enabled() bool { return rp.Interval > 0 }
+
 // ResourceRepositorier is used to interact with Resource repository.
 type ResourceRepositorier interface {
 	FindAll(context.Context, *Pagination) ([]Resource, *Pagination, error)
 	FindOne(context.Context, string) (*Resource, error)
 	FindByURI(context.Context, string) (*Resource, error)
+	// Create a resource. When the context has a create limit, the create fails with LimitReached if
+	// the namespace already has the limit of resources.
 	Create(context.Context, *Resource) error
+	Update(context.Context, *Resource) error
 	Delete(context.Context, string) error
 }
 
@@ -108,4 +452,5 @@ type ResourceRepositoryError interface {
 	AlreadyExists() bool
 	PathConflict() bool
 	NotFound() bool
+	LimitReached() bool
 }
@@ -34,40 +34,224 @@ func (p *pagination) MarshalJSON() ([]byte, error) {
 type resource flare.Resource
 
 func (r *resource) MarshalJSON() ([]byte, error) {
-	change := map[string]string{
-		"kind":  r.Change.Kind,
-		"field": r.Change.Field,
+	change := map[string]interface{}{"kind": r.Change.Kind}
+	if r.Change.Field != "" {
+		change["field"] = r.Change.Field
 	}
 
 	if r.Change.DateFormat != "" {
 		change["dateFormat"] = r.Change.DateFormat
 	}
 
+	if len(r.Change.Fields) > 0 {
+		change["fields"] = r.Change.Fields
+	}
+
+	if len(r.Change.Exclude) > 0 {
+		change["exclude"] = r.Change.Exclude
+	}
+
+	var poll *resourceCreatePoll
+	if r.Poll.Enabled() {
+		poll = transformResourcePoll(&r.Poll)
+	}
+
 	return json.Marshal(&struct {
-		Id        string            `json:"id"`
-		Addresses []string          `json:"addresses"`
-		Path      string            `json:"path"`
-		Change    map[string]string `json:"change"`
-		CreatedAt string            `json:"createdAt"`
+		Id        string                 `json:"id"`
+		Namespace string                 `json:"namespace,omitempty"`
+		Addresses []string               `json:"addresses"`
+		Path      string                 `json:"path"`
+		Change    map[string]interface{} `json:"change"`
+		Poll      *resourceCreatePoll    `json:"poll,omitempty"`
+		CreatedAt string                 `json:"createdAt"`
 	}{
 		Id:        r.ID,
+		Namespace: r.Namespace,
 		Addresses: r.Addresses,
 		Path:      r.Path,
 		Change:    change,
+		Poll:      poll,
 		CreatedAt: r.CreatedAt.Format(time.RFC3339),
 	})
 }
 
 type resourceCreateChange struct {
-	Kind       string `json:"kind"`
-	Field      string `json:"field"`
-	DateFormat string `json:"dateFormat"`
+	Kind       string   `json:"kind"`
+	Field      string   `json:"field"`
+	DateFormat string   `json:"dateFormat"`
+	Fields     []string `json:"fields"`
+	Exclude    []string `json:"exclude"`
+}
+
+func (c *resourceCreateChange) valid() error {
+	change := c.toFlareResourceChange()
+	if c.Kind == flare.ResourceChangeHash || c.Kind == flare.ResourceChangeComposite {
+		return errors.Wrap(change.Valid(), "invalid change")
+	}
+
+	if c.Field == "" {
+		return errors.New("missing change")
+	}
+
+	if err := change.ValidField(); err != nil {
+		return errors.Wrap(err, "invalid change.field")
+	}
+
+	switch c.Kind {
+	case flare.ResourceChangeInteger, flare.ResourceChangeString, flare.ResourceChangeNumeric,
+		flare.ResourceChangeSemver:
+	case flare.ResourceChangeDate:
+		if c.DateFormat == "" {
+			return errors.New("missing change.dateFormat")
+		}
+	default:
+		return errors.New("invalid change.kind")
+	}
+
+	if len(c.Fields) > 0 || len(c.Exclude) > 0 {
+		return errors.New(
+			"change.fields and change.exclude are only used with the hash and composite kinds",
+		)
+	}
+	return nil
+}
+
+func (c *resourceCreateChange) toFlareResourceChange() flare.ResourceChange {
+	return flare.ResourceChange{
+		Kind:       c.Kind,
+		Field:      c.Field,
+		DateFormat: c.DateFormat,
+		Fields:     c.Fields,
+		Exclude:    c.Exclude,
+	}
+}
+
+type resourceCreatePoll struct {
+	Interval  string            `json:"interval"`
+	Listing   string            `json:"listing,omitempty"`
+	Items     string            `json:"items,omitempty"`
+	Wildcards map[string]string `json:"wildcards,omitempty"`
+	Documents []string          `json:"documents,omitempty"`
+	RateLimit float64           `json:"rateLimit,omitempty"`
+}
+
+// Min interval between the resource polls.
+const resourcePollMinInterval = time.Second
+
+func (p *resourceCreatePoll) valid(r *resourceCreate) error {
+	interval, err := time.ParseDuration(p.Interval)
+	if err != nil {
+		return errors.Wrap(err, "invalid poll.interval")
+	}
+
+	if interval < resourcePollMinInterval {
+		return fmt.Errorf("poll.interval should be at least %s", resourcePollMinInterval)
+	}
+
+	if p.RateLimit < 0 {
+		return errors.New("invalid poll.rateLimit")
+	}
+
+	switch {
+	case p.Listing != "" && len(p.Documents) > 0:
+		return errors.New("poll.listing and poll.documents can't be used together")
+	case p.Listing != "":
+		return p.validListing(r)
+	case len(p.Documents) > 0:
+		return p.validDocuments(r)
+	default:
+		return errors.New("missing poll.listing or poll.documents")
+	}
+}
+
+func (p *resourceCreatePoll) validListing(r *resourceCreate) error {
+	listing, err := url.Parse(p.Listing)
+	if err != nil {
+		return errors.Wrap(err, "invalid poll.listing")
+	}
+
+	if listing.IsAbs() || !strings.HasPrefix(listing.Path, "/") {
+		return fmt.Errorf("poll.listing '%s' should be a path", p.Listing)
+	}
+
+	wildcards := make(map[string]struct{})
+	for _, value := range strings.Split(r.Path, "/") {
+		if len(value) > 1 && value[0] == '{' && value[len(value)-1] == '}' {
+			wildcards[strings.TrimSpace(value[1:len(value)-1])] = struct{}{}
+		}
+	}
+
+	for wildcard, field := range p.Wildcards {
+		if _, ok := wildcards[wildcard]; !ok {
+			return fmt.Errorf("poll.wildcards has a unknown wildcard '%s'", wildcard)
+		}
+
+		if field == "" {
+			return fmt.Errorf("poll.wildcards has a blank field at wildcard '%s'", wildcard)
+		}
+	}
+	return nil
+}
+
+func (p *resourceCreatePoll) validDocuments(r *resourceCreate) error {
+	if len(p.Wildcards) > 0 || p.Items != "" {
+		return errors.New("poll.wildcards and poll.items are only used with poll.listing")
+	}
+
+	for _, document := range p.Documents {
+		var found bool
+		for _, address := range r.Addresses {
+			if strings.HasPrefix(document, address+"/") {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("poll.documents '%s' don't belong to the resource addresses", document)
+		}
+	}
+	return nil
+}
+
+func (p *resourceCreatePoll) toFlareResourcePoll() flare.ResourcePoll {
+	// The interval is already validated.
+	interval, _ := time.ParseDuration(p.Interval)
+
+	return flare.ResourcePoll{
+		Interval:  interval,
+		Listing:   p.Listing,
+		Items:     p.Items,
+		Wildcards: p.Wildcards,
+		Documents: p.Documents,
+		RateLimit: p.RateLimit,
+	}
+}
+
+func transformResourcePoll(p *flare.ResourcePoll) *resourceCreatePoll {
+	documents := make([]string, len(p.Documents))
+	copy(documents, p.Documents)
+
+	wildcards := make(map[string]string, len(p.Wildcards))
+	for key, value := range p.Wildcards {
+		wildcards[key] = value
+	}
+
+	return &resourceCreatePoll{
+		Interval:  p.Interval.String(),
+		Listing:   p.Listing,
+		Items:     p.Items,
+		Wildcards: wildcards,
+		Documents: documents,
+		RateLimit: p.RateLimit,
+	}
 }
 
 type resourceCreate struct {
 	Path      string               `json:"path"`
 	Addresses []string             `json:"addresses"`
 	Change    resourceCreateChange `json:"change"`
+	Poll      *resourceCreatePoll  `json:"poll"`
 }
 
 func (r *resourceCreate) valid() error {
@@ -79,18 +263,14 @@ func (r *resourceCreate) valid() error {
 		return err
 	}
 
-	if r.Change.Field == "" {
-		return errors.New("missing change")
+	if err := r.Change.valid(); err != nil {
+		return err
 	}
 
-	switch r.Change.Kind {
-	case flare.ResourceChangeInteger, flare.ResourceChangeString:
-	case flare.ResourceChangeDate:
-		if r.Change.DateFormat == "" {
-			return errors.New("missing change.dateFormat")
+	if r.Poll != nil {
+		if err := r.Poll.valid(r); err != nil {
+			return err
 		}
-	default:
-		return errors.New("invalid change.kind")
 	}
 
 	return nil
@@ -187,17 +367,41 @@ func (r *resourceCreate) validAddresses() error {
 	return nil
 }
 
-func (r *resourceCreate) toFlareResource() *flare.Resource {
-	return &flare.Resource{
-		ID:        uuid.NewV4().String(),
-		Addresses: r.Addresses,
+// transformResourceCreate is used to build the base content on PATCH requests.
+func transformResourceCreate(r *flare.Resource) *resourceCreate {
+	addresses := make([]string, len(r.Addresses))
+	copy(addresses, r.Addresses)
+
+	result := &resourceCreate{
 		Path:      r.Path,
-		Change: flare.ResourceChange{
+		Addresses: addresses,
+		Change: resourceCreateChange{
 			Kind:       r.Change.Kind,
 			Field:      r.Change.Field,
 			DateFormat: r.Change.DateFormat,
+			Fields:     append([]string(nil), r.Change.Fields...),
+			Exclude:    append([]string(nil), r.Change.Exclude...),
 		},
 	}
+
+	if r.Poll.Enabled() {
+		result.Poll = transformResourcePoll(&r.Poll)
+	}
+	return result
+}
+
+func (r *resourceCreate) toFlareResource() *flare.Resource {
+	result := &flare.Resource{
+		ID:        uuid.NewV4().String(),
+		Addresses: r.Addresses,
+		Path:      r.Path,
+		Change:    r.Change.toFlareResourceChange(),
+	}
+
+	if r.Poll != nil {
+		result.Poll = r.Poll.toFlareResourcePoll()
+	}
+	return result
 }
 
 func transformResources(r []flare.Resource) []resource {
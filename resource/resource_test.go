@@ -246,6 +246,63 @@ func TestResourceCreateValid(t *testing.T) {
 				Path:      "/users/{*}",
 				Change:    resourceCreateChange{Field: "incrCounter", Kind: flare.ResourceChangeInteger},
 			},
+			{
+				Addresses: []string{"http://app.com"},
+				Path:      "/users/{*}",
+				Change:    resourceCreateChange{Field: "meta.revision", Kind: flare.ResourceChangeInteger},
+			},
+			{
+				Addresses: []string{"http://app.com"},
+				Path:      "/users/{*}",
+				Change: resourceCreateChange{
+					Field: "/_links/self/version", Kind: flare.ResourceChangeString,
+				},
+			},
+			{
+				Addresses: []string{"http://app.com"},
+				Path:      "/users/{*}",
+				Change: resourceCreateChange{
+					Kind: flare.ResourceChangeHash, Exclude: []string{"meta.fetchedAt"},
+				},
+			},
+			{
+				Addresses: []string{"http://app.com"},
+				Path:      "/users/{*}",
+				Change:    resourceCreateChange{Kind: flare.ResourceChangeSemver, Field: "version"},
+			},
+			{
+				Addresses: []string{"http://app.com"},
+				Path:      "/users/{*}",
+				Change:    resourceCreateChange{Kind: flare.ResourceChangeNumeric, Field: "sequence"},
+			},
+			{
+				Addresses: []string{"http://app.com"},
+				Path:      "/users/{*}",
+				Change: resourceCreateChange{
+					Kind: flare.ResourceChangeComposite, Fields: []string{"epoch", "counter"},
+				},
+			},
+			{
+				Addresses: []string{"http://app.com"},
+				Path:      "/users/{id}",
+				Change:    resourceCreateChange{Field: "revision", Kind: flare.ResourceChangeInteger},
+				Poll: &resourceCreatePoll{
+					Interval:  "1m",
+					Listing:   "/users?sort=updatedAt",
+					Items:     "data",
+					Wildcards: map[string]string{"id": "userId"},
+					RateLimit: 10,
+				},
+			},
+			{
+				Addresses: []string{"http://app.com"},
+				Path:      "/users/{*}",
+				Change:    resourceCreateChange{Field: "revision", Kind: flare.ResourceChangeInteger},
+				Poll: &resourceCreatePoll{
+					Interval:  "30s",
+					Documents: []string{"http://app.com/users/1", "http://app.com/users/2"},
+				},
+			},
 		}
 
 		Convey("The validation should not return a error", func() {
@@ -281,6 +338,54 @@ func TestResourceCreateValid(t *testing.T) {
 			},
 		}
 
+		changes := []resourceCreateChange{
+			{Kind: flare.ResourceChangeHash, Field: "revision"},
+			{Kind: flare.ResourceChangeHash, Fields: []string{"name"}, Exclude: []string{"meta"}},
+			{Kind: flare.ResourceChangeInteger, Field: "revision", Exclude: []string{"meta"}},
+			{Kind: flare.ResourceChangeSemver},
+			{Kind: flare.ResourceChangeComposite, Fields: []string{"epoch"}},
+			{Kind: flare.ResourceChangeComposite, Field: "epoch", Fields: []string{"epoch", "counter"}},
+			{Kind: flare.ResourceChangeNumeric, Field: "sequence", Fields: []string{"epoch", "counter"}},
+		}
+		for _, change := range changes {
+			tests = append(tests, resourceCreate{
+				Addresses: []string{"http://app.com"},
+				Path:      "/users/{*}",
+				Change:    change,
+			})
+		}
+
+		for _, field := range []string{"meta..revision", ".revision", "/meta/revision~"} {
+			tests = append(tests, resourceCreate{
+				Addresses: []string{"http://app.com"},
+				Path:      "/users/{*}",
+				Change:    resourceCreateChange{Field: field, Kind: flare.ResourceChangeInteger},
+			})
+		}
+
+		polls := []resourceCreatePoll{
+			{},
+			{Interval: "invalid", Listing: "/users"},
+			{Interval: "100ms", Listing: "/users"},
+			{Interval: "1m", Listing: "/users", RateLimit: -1},
+			{Interval: "1m"},
+			{Interval: "1m", Listing: "/users", Documents: []string{"http://app.com/users/1"}},
+			{Interval: "1m", Listing: "http://app.com/users"},
+			{Interval: "1m", Listing: "users"},
+			{Interval: "1m", Listing: "/users", Wildcards: map[string]string{"userId": "id"}},
+			{Interval: "1m", Listing: "/users", Wildcards: map[string]string{"id": ""}},
+			{Interval: "1m", Documents: []string{"http://other.com/users/1"}},
+			{Interval: "1m", Documents: []string{"http://app.com/users/1"}, Items: "data"},
+		}
+		for i := range polls {
+			tests = append(tests, resourceCreate{
+				Addresses: []string{"http://app.com"},
+				Path:      "/users/{id}",
+				Change:    resourceCreateChange{Field: "revision", Kind: flare.ResourceChangeInteger},
+				Poll:      &polls[i],
+			})
+		}
+
 		Convey("The validation should return a error", func() {
 			for _, tt := range tests {
 				result := tt.valid()
@@ -5,6 +5,7 @@
 package resource
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 
@@ -18,9 +19,15 @@ import (
 type Service struct {
 	repository      flare.ResourceRepositorier
 	getResourceID   func(*http.Request) string
-	getResourceURI  func(string) string
+	getResourceURI  func(namespace, id string) string
 	parsePagination func(r *http.Request) (*flare.Pagination, error)
 	writer          *infraHTTP.Writer
+	quota           quota
+}
+
+// quota returns the limits of a namespace.
+type quota interface {
+	Get(namespace string) flare.NamespaceQuota
 }
 
 // HandleIndex receive the request to list the resources.
@@ -64,7 +71,8 @@ func (s *Service) HandleShow(w http.ResponseWriter, r *http.Request) {
 	s.writer.Response(w, transformResource(re), http.StatusOK, nil)
 }
 
-// HandleCreate receive the request to create a resource.
+// HandleCreate receive the request to create a resource. The resource belongs to the namespace of
+// the request.
 func (s *Service) HandleCreate(w http.ResponseWriter, r *http.Request) {
 	var (
 		d       = json.NewDecoder(r.Body)
@@ -82,12 +90,18 @@ func (s *Service) HandleCreate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	result := content.toFlareResource()
-	if err := s.repository.Create(r.Context(), result); err != nil {
+	result.Namespace = flare.NamespaceFromContext(r.Context())
+	if err := s.repository.Create(s.quotaContext(r, result.Namespace), result); err != nil {
 		status := http.StatusInternalServerError
 		if errRepo, ok := err.(flare.ResourceRepositoryError); ok {
 			if errRepo.PathConflict() || errRepo.AlreadyExists() {
 				status = http.StatusConflict
 			}
+
+			if errRepo.LimitReached() {
+				s.writer.Error(w, "quota exceeded", err, http.StatusForbidden)
+				return
+			}
 		}
 
 		s.writer.Error(w, "error during resource create", err, status)
@@ -95,10 +109,70 @@ func (s *Service) HandleCreate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	header := make(http.Header)
-	header.Set("Location", s.getResourceURI(result.ID))
+	header.Set("Location", s.getResourceURI(result.Namespace, result.ID))
 	s.writer.Response(w, &response{Resource: transformResource(result)}, http.StatusCreated, header)
 }
 
+// HandleUpdate receive the request to update a resource. A PUT replace the whole resource and a
+// PATCH only the fields present at the body. The change can't be updated because the documents
+// already processed depend on it.
+func (s *Service) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	current, err := s.repository.FindOne(r.Context(), s.getResourceID(r))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errRepo, ok := err.(flare.ResourceRepositoryError); ok && errRepo.NotFound() {
+			status = http.StatusNotFound
+		}
+
+		s.writer.Error(w, "error during resource search", err, status)
+		return
+	}
+
+	var (
+		d       = json.NewDecoder(r.Body)
+		content = &resourceCreate{}
+	)
+	if r.Method == http.MethodPatch {
+		content = transformResourceCreate(current)
+	}
+
+	if err = d.Decode(content); err != nil {
+		s.writer.Error(w, "error during body parse", err, http.StatusBadRequest)
+		return
+	}
+
+	if err = content.valid(); err != nil {
+		s.writer.Error(w, "invalid body content", err, http.StatusBadRequest)
+		return
+	}
+
+	result := content.toFlareResource()
+	if !result.Change.Equal(&current.Change) {
+		s.writer.Error(
+			w, "invalid body content", errors.New("change can't be updated"), http.StatusBadRequest,
+		)
+		return
+	}
+	result.ID = current.ID
+	result.Namespace = current.Namespace
+
+	if err := s.repository.Update(r.Context(), result); err != nil {
+		status := http.StatusInternalServerError
+		if errRepo, ok := err.(flare.ResourceRepositoryError); ok {
+			if errRepo.PathConflict() || errRepo.AlreadyExists() {
+				status = http.StatusConflict
+			} else if errRepo.NotFound() {
+				status = http.StatusNotFound
+			}
+		}
+
+		s.writer.Error(w, "error during resource update", err, status)
+		return
+	}
+
+	s.writer.Response(w, &response{Resource: transformResource(result)}, http.StatusOK, nil)
+}
+
 // HandleDelete receive the request to delete a resource.
 func (s *Service) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	if err := s.repository.Delete(r.Context(), s.getResourceID(r)); err != nil {
@@ -114,6 +188,14 @@ func (s *Service) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	s.writer.Response(w, nil, http.StatusNoContent, nil)
 }
 
+// quotaContext returns the context with the namespace resources limit to the create.
+func (s *Service) quotaContext(r *http.Request, namespace string) context.Context {
+	if s.quota == nil {
+		return r.Context()
+	}
+	return flare.ContextWithCreateLimit(r.Context(), s.quota.Get(namespace).Resources)
+}
+
 // NewService initialize the service to handle HTTP requests.
 func NewService(options ...func(*Service)) (*Service, error) {
 	service := &Service{}
@@ -170,6 +252,11 @@ func ServiceGetResourceID(fn func(*http.Request) string) func(*Service) {
 }
 
 // ServiceGetResourceURI set the function used to generate the URI for a resource.
-func ServiceGetResourceURI(fn func(string) string) func(*Service) {
+func ServiceGetResourceURI(fn func(namespace, id string) string) func(*Service) {
 	return func(s *Service) { s.getResourceURI = fn }
 }
+
+// ServiceQuota set the quotas of the namespaces. It's optional.
+func ServiceQuota(q quota) func(*Service) {
+	return func(s *Service) { s.quota = q }
+}
@@ -33,7 +33,7 @@ func TestNewService(t *testing.T) {
 			{
 				ServiceRepository(memory.NewResource()),
 				ServiceGetResourceID(func(*http.Request) string { return "" }),
-				ServiceGetResourceURI(func(string) string { return "" }),
+				ServiceGetResourceURI(func(string, string) string { return "" }),
 				ServiceParsePagination(infraHTTP.ParsePagination(0)),
 				ServiceWriter(writer),
 			},
@@ -60,12 +60,12 @@ func TestNewService(t *testing.T) {
 			{
 				ServiceRepository(memory.NewResource()),
 				ServiceGetResourceID(func(*http.Request) string { return "" }),
-				ServiceGetResourceURI(func(string) string { return "" }),
+				ServiceGetResourceURI(func(string, string) string { return "" }),
 			},
 			{
 				ServiceRepository(memory.NewResource()),
 				ServiceGetResourceID(func(*http.Request) string { return "" }),
-				ServiceGetResourceURI(func(string) string { return "" }),
+				ServiceGetResourceURI(func(string, string) string { return "" }),
 				ServiceParsePagination(infraHTTP.ParsePagination(0)),
 			},
 		}
@@ -168,7 +168,7 @@ func TestServiceHandleIndex(t *testing.T) {
 					ServiceGetResourceID(func(r *http.Request) string {
 						return strings.Replace(r.URL.String(), "http://resources/", "", -1)
 					}),
-					ServiceGetResourceURI(func(string) string { return "" }),
+					ServiceGetResourceURI(func(string, string) string { return "" }),
 					ServiceParsePagination(infraHTTP.ParsePagination(30)),
 					ServiceWriter(writer),
 				)
@@ -234,7 +234,7 @@ func TestServiceHandleShow(t *testing.T) {
 					ServiceGetResourceID(func(r *http.Request) string {
 						return strings.Replace(r.URL.String(), "http://resources/", "", -1)
 					}),
-					ServiceGetResourceURI(func(string) string { return "" }),
+					ServiceGetResourceURI(func(string, string) string { return "" }),
 					ServiceParsePagination(infraHTTP.ParsePagination(30)),
 					ServiceWriter(writer),
 				)
@@ -300,7 +300,7 @@ func TestServiceHandleDelete(t *testing.T) {
 					ServiceGetResourceID(func(r *http.Request) string {
 						return strings.Replace(r.URL.String(), "http://resources/", "", -1)
 					}),
-					ServiceGetResourceURI(func(string) string { return "" }),
+					ServiceGetResourceURI(func(string, string) string { return "" }),
 					ServiceParsePagination(infraHTTP.ParsePagination(30)),
 					ServiceWriter(writer),
 				)
@@ -399,7 +399,7 @@ func TestServiceHandleCreate(t *testing.T) {
 					ServiceGetResourceID(func(r *http.Request) string {
 						return strings.Replace(r.URL.String(), "http://resources/", "", -1)
 					}),
-					ServiceGetResourceURI(func(id string) string {
+					ServiceGetResourceURI(func(_, id string) string {
 						return "http://resources/" + id
 					}),
 					ServiceParsePagination(infraHTTP.ParsePagination(30)),
@@ -414,4 +414,119 @@ func TestServiceHandleCreate(t *testing.T) {
 			})
 		}
 	})
+
+	Convey("Given a Service with a namespace that reached the resources quota", t, func() {
+		writer, err := infraHTTP.NewWriter(log.NewNopLogger())
+		So(err, ShouldBeNil)
+
+		service, err := NewService(
+			ServiceRepository(repositoryTest.NewResource(
+				repositoryTest.ResourceLoadSliceByteResource(infraTest.Load("resource.input.3.json")),
+			)),
+			ServiceGetResourceID(func(r *http.Request) string { return "" }),
+			ServiceGetResourceURI(func(string, string) string { return "" }),
+			ServiceParsePagination(infraHTTP.ParsePagination(30)),
+			ServiceQuota(quotaMock{Resources: 1}),
+			ServiceWriter(writer),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("The request should be forbidden", func() {
+			w := httptest.NewRecorder()
+			service.HandleCreate(w, httptest.NewRequest(
+				http.MethodPost,
+				"http://resources",
+				bytes.NewBuffer(infraTest.Load("serviceHandleCreate.input.1.json")),
+			))
+			So(w.Code, ShouldEqual, http.StatusForbidden)
+		})
+	})
+}
+
+type quotaMock flare.NamespaceQuota
+
+func (qm quotaMock) Get(string) flare.NamespaceQuota { return flare.NamespaceQuota(qm) }
+
+func TestServiceHandleUpdate(t *testing.T) {
+	Convey("Given a list of requests", t, func() {
+		tests := []struct {
+			title      string
+			req        *http.Request
+			status     int
+			header     http.Header
+			body       []byte
+			repository flare.ResourceRepositorier
+		}{
+			{
+				"The response should be a resource not found",
+				httptest.NewRequest(http.MethodPut, "http://resources/123", bytes.NewBufferString("{}")),
+				http.StatusNotFound,
+				http.Header{"Content-Type": []string{"application/json"}},
+				infraTest.Load("serviceHandleUpdate.notFound.json"),
+				repositoryTest.NewResource(),
+			},
+			{
+				"The request should have a invalid resource",
+				httptest.NewRequest(http.MethodPut, "http://resources/123", bytes.NewBufferString("{}")),
+				http.StatusBadRequest,
+				http.Header{"Content-Type": []string{"application/json"}},
+				infraTest.Load("serviceHandleUpdate.invalid.1.json"),
+				repositoryTest.NewResource(
+					repositoryTest.ResourceLoadSliceByteResource(infraTest.Load("resource.input.3.json")),
+				),
+			},
+			{
+				"The request should not update the change",
+				httptest.NewRequest(
+					http.MethodPatch,
+					"http://resources/123",
+					bytes.NewBufferString(`{"change":{"field":"revision","kind":"integer"}}`),
+				),
+				http.StatusBadRequest,
+				http.Header{"Content-Type": []string{"application/json"}},
+				infraTest.Load("serviceHandleUpdate.invalid.2.json"),
+				repositoryTest.NewResource(
+					repositoryTest.ResourceLoadSliceByteResource(infraTest.Load("resource.input.3.json")),
+				),
+			},
+			{
+				"The response should be a updated resource",
+				httptest.NewRequest(
+					http.MethodPatch,
+					"http://resources/123",
+					bytes.NewBufferString(`{"addresses":["http://app1.com","http://app2.com"]}`),
+				),
+				http.StatusOK,
+				http.Header{"Content-Type": []string{"application/json"}},
+				infraTest.Load("serviceHandleUpdate.valid.json"),
+				repositoryTest.NewResource(
+					repositoryTest.ResourceDate(time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)),
+					repositoryTest.ResourceLoadSliceByteResource(infraTest.Load("resource.input.3.json")),
+				),
+			},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				writer, err := infraHTTP.NewWriter(log.NewNopLogger())
+				So(err, ShouldBeNil)
+
+				service, err := NewService(
+					ServiceRepository(tt.repository),
+					ServiceGetResourceID(func(r *http.Request) string {
+						return strings.Replace(r.URL.String(), "http://resources/", "", -1)
+					}),
+					ServiceGetResourceURI(func(string, string) string { return "" }),
+					ServiceParsePagination(infraHTTP.ParsePagination(30)),
+					ServiceWriter(writer),
+				)
+				if err != nil {
+					t.Error(errors.Wrap(err, "error during service initialization"))
+					t.FailNow()
+				}
+
+				test.Runner(tt.status, tt.header, service.HandleUpdate, tt.req, tt.body)
+			})
+		}
+	})
 }
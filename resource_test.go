@@ -5,6 +5,7 @@
 package flare
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -16,6 +17,12 @@ func TestResourceChangeValid(t *testing.T) {
 		tests := []ResourceChange{
 			{Field: "updatedAt", Kind: ResourceChangeDate, DateFormat: "2006-01-02"},
 			{Field: "revision", Kind: ResourceChangeInteger},
+			{Field: "meta.revision", Kind: ResourceChangeInteger},
+			{Field: "/_links/self/version", Kind: ResourceChangeString},
+			{Field: "/a~1b/c~0d", Kind: ResourceChangeString},
+			{Kind: ResourceChangeHash},
+			{Kind: ResourceChangeHash, Fields: []string{"name", "/address/city"}},
+			{Kind: ResourceChangeHash, Exclude: []string{"meta.fetchedAt"}},
 		}
 
 		Convey("The validation should not return a error", func() {
@@ -42,6 +49,30 @@ func TestResourceChangeValid(t *testing.T) {
 				"Should be missing the format",
 				ResourceChange{Field: "updatedAt", Kind: ResourceChangeDate},
 			},
+			{
+				"Should have a blank segment at the field",
+				ResourceChange{Field: "meta..revision", Kind: ResourceChangeInteger},
+			},
+			{
+				"Should not have the field on hash",
+				ResourceChange{Field: "revision", Kind: ResourceChangeHash},
+			},
+			{
+				"Should not have fields and exclude together",
+				ResourceChange{Kind: ResourceChangeHash, Fields: []string{"a"}, Exclude: []string{"b"}},
+			},
+			{
+				"Should have a invalid hash field",
+				ResourceChange{Kind: ResourceChangeHash, Exclude: []string{"meta..fetchedAt"}},
+			},
+			{
+				"Should only have the hash fields on hash",
+				ResourceChange{Field: "revision", Kind: ResourceChangeInteger, Fields: []string{"a"}},
+			},
+			{
+				"Should have a invalid escape at the field",
+				ResourceChange{Field: "/meta/revision~2", Kind: ResourceChangeInteger},
+			},
 		}
 
 		for _, tt := range tests {
@@ -52,6 +83,100 @@ func TestResourceChangeValid(t *testing.T) {
 	})
 }
 
+func TestResourceChangeValue(t *testing.T) {
+	Convey("Given a document content", t, func() {
+		content := map[string]interface{}{
+			"revision": 1,
+			"meta":     map[string]interface{}{"updatedAt": "2017-01-01"},
+			"_links": map[string]interface{}{
+				"self": map[string]interface{}{"version": "v2"},
+			},
+			"a/b":      map[string]interface{}{"c~d": 3},
+			"versions": []interface{}{4, 5},
+		}
+
+		Convey("It should return the value of the valid fields", func() {
+			tests := []struct {
+				field string
+				value interface{}
+			}{
+				{"revision", 1},
+				{"meta.updatedAt", "2017-01-01"},
+				{"/_links/self/version", "v2"},
+				{"/a~1b/c~0d", 3},
+				{"versions.1", 5},
+				{"/versions/0", 4},
+			}
+
+			for _, tt := range tests {
+				rc := ResourceChange{Field: tt.field}
+				value, ok := rc.Value(content)
+				So(ok, ShouldBeTrue)
+				So(value, ShouldEqual, tt.value)
+			}
+		})
+
+		Convey("It should not find the missing fields", func() {
+			fields := []string{
+				"updatedAt", "meta.revision", "revision.value", "/meta/revision", "versions.2", "/versions/-1",
+				"meta..updatedAt", "/a~2b",
+			}
+
+			for _, field := range fields {
+				rc := ResourceChange{Field: field}
+				_, ok := rc.Value(content)
+				So(ok, ShouldBeFalse)
+			}
+		})
+	})
+}
+
+func TestResourceChangeValueHash(t *testing.T) {
+	Convey("Given a document content", t, func() {
+		content := map[string]interface{}{
+			"name": "Diego",
+			"meta": map[string]interface{}{"fetchedAt": "2017-01-01", "source": "api"},
+		}
+
+		hash := func(rc ResourceChange, content map[string]interface{}) interface{} {
+			value, ok := rc.Value(content)
+			So(ok, ShouldBeTrue)
+			So(value, ShouldHaveLength, 64)
+			return value
+		}
+
+		Convey("The hash should only change with the hashed fields", func() {
+			changed := map[string]interface{}{
+				"meta": map[string]interface{}{"source": "api", "fetchedAt": "2017-01-02"},
+				"name": "Diego",
+			}
+
+			rc := ResourceChange{Kind: ResourceChangeHash}
+			So(hash(rc, content), ShouldNotEqual, hash(rc, changed))
+
+			rc = ResourceChange{Kind: ResourceChangeHash, Exclude: []string{"meta.fetchedAt"}}
+			So(hash(rc, content), ShouldEqual, hash(rc, changed))
+			So(content["meta"], ShouldContainKey, "fetchedAt")
+
+			rc = ResourceChange{Kind: ResourceChangeHash, Fields: []string{"name", "/meta/source"}}
+			So(hash(rc, content), ShouldEqual, hash(rc, changed))
+
+			changed["name"] = "Bernardes"
+			So(hash(rc, content), ShouldNotEqual, hash(rc, changed))
+		})
+
+		Convey("The hash should be the same with the numbers decoded as json.Number", func() {
+			rc := ResourceChange{Kind: ResourceChangeHash}
+			numbers := []interface{}{json.Number("1.0"), json.Number("1e2")}
+			So(
+				hash(rc, map[string]interface{}{"values": []interface{}{float64(1), float64(100)}}),
+				ShouldEqual,
+				hash(rc, map[string]interface{}{"values": numbers}),
+			)
+		})
+	})
+}
+
 func TestResourceWildcardReplace(t *testing.T) {
 	Convey("Given a list of valid wildcards to be replaced", t, func() {
 		tests := []struct {
@@ -137,3 +262,40 @@ func TestResourceWildcardReplace(t *testing.T) {
 		})
 	})
 }
+
+func TestResourceWildcards(t *testing.T) {
+	Convey("Given a list of documents", t, func() {
+		tests := []struct {
+			title    string
+			resource Resource
+			id       string
+			expected map[string]string
+		}{
+			{
+				"It should extract the wildcards",
+				Resource{Path: "/users/{user}/orders/{id}"},
+				"http://app.com/users/1/orders/2",
+				map[string]string{"user": "1", "id": "2"},
+			},
+			{
+				"It should ignore the missing wildcards",
+				Resource{Path: "/users/{user}/orders/{id}"},
+				"http://app.com/users/1",
+				map[string]string{"user": "1"},
+			},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				wildcards, err := tt.resource.Wildcards(tt.id)
+				So(err, ShouldBeNil)
+				So(wildcards, ShouldResemble, tt.expected)
+			})
+		}
+
+		Convey("It should not extract from a invalid document id", func() {
+			_, err := (&Resource{}).Wildcards("%zzzzz")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
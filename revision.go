@@ -0,0 +1,242 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flare
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Max integer that can be represented without loss by a float64, the type of the numbers decoded
+// from JSON.
+const revisionMaxSafeFloat = 1 << 53
+
+// Separator between the values of the composite revisions.
+const revisionCompositeSeparator = ":"
+
+// revisionInt64 convert the revision to int64. The numbers beyond the float64 precision should be
+// sent as strings.
+func revisionInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > revisionMaxSafeFloat {
+			return 0, fmt.Errorf("invalid revision '%v', expected a integer", v)
+		}
+		return int64(v), nil
+	case json.Number:
+		if result, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return result, nil
+		}
+
+		// Numbers like 1e3 or 10.0 are accepted the same way as when decoded as float64.
+		result, err := v.Float64()
+		if err != nil {
+			return 0, errors.Wrapf(err, "error during parse '%s' to int64", v)
+		}
+		return revisionInt64(result)
+	case string:
+		result, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "error during parse '%s' to int64", v)
+		}
+		return result, nil
+	}
+	return 0, fmt.Errorf("invalid revision type '%T'", value)
+}
+
+// revisionComposite parse a composite revision, from the canonical string or from the list of
+// values extracted from the document.
+func revisionComposite(value interface{}) ([]int64, error) {
+	var values []interface{}
+	switch v := value.(type) {
+	case string:
+		for _, segment := range strings.Split(v, revisionCompositeSeparator) {
+			values = append(values, segment)
+		}
+	case []interface{}:
+		values = v
+	default:
+		return nil, fmt.Errorf("invalid revision type '%T'", value)
+	}
+
+	if len(values) < 2 {
+		return nil, fmt.Errorf("invalid composite revision '%v', expected at least two values", value)
+	}
+
+	result := make([]int64, len(values))
+	for i, value := range values {
+		var err error
+		if result[i], err = revisionInt64(value); err != nil {
+			return nil, errors.Wrapf(err, "invalid composite revision value at position %d", i)
+		}
+	}
+	return result, nil
+}
+
+func formatRevisionComposite(values []int64) string {
+	segments := make([]string, len(values))
+	for i, value := range values {
+		segments[i] = strconv.FormatInt(value, 10)
+	}
+	return strings.Join(segments, revisionCompositeSeparator)
+}
+
+// revisionCompositeSortKey format the values with a fixed width and the sign bit flipped, this
+// way, the negative values come first.
+func revisionCompositeSortKey(values []int64) string {
+	segments := make([]string, len(values))
+	for i, value := range values {
+		segments[i] = fmt.Sprintf("%020d", uint64(value)^(1<<63))
+	}
+	return strings.Join(segments, revisionCompositeSeparator)
+}
+
+// compareRevisionComposite returns -1, 0 or 1 comparing the values in order.
+func compareRevisionComposite(a, b []int64) (int, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("composite revisions with different sizes, %d and %d", len(a), len(b))
+	}
+
+	for i := range a {
+		switch {
+		case a[i] > b[i]:
+			return 1, nil
+		case a[i] < b[i]:
+			return -1, nil
+		}
+	}
+	return 0, nil
+}
+
+// semver represents a semantic version, https://semver.org. The build metadata is not used at the
+// comparison, so, it's discarded.
+type semver struct {
+	major, minor, patch uint64
+	prerelease          []string
+}
+
+func parseSemver(value string) (*semver, error) {
+	content := strings.TrimPrefix(value, "v")
+	if i := strings.Index(content, "+"); i >= 0 {
+		content = content[:i]
+	}
+
+	var result semver
+	if i := strings.Index(content, "-"); i >= 0 {
+		result.prerelease = strings.Split(content[i+1:], ".")
+		content = content[:i]
+
+		for _, identifier := range result.prerelease {
+			if identifier == "" {
+				return nil, fmt.Errorf("invalid semver '%s', blank pre-release identifier", value)
+			}
+		}
+	}
+
+	segments := strings.Split(content, ".")
+	if len(segments) != 3 {
+		return nil, fmt.Errorf("invalid semver '%s', expected major.minor.patch", value)
+	}
+
+	versions := []*uint64{&result.major, &result.minor, &result.patch}
+	for i, segment := range segments {
+		version, err := strconv.ParseUint(segment, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid semver '%s'", value)
+		}
+		*versions[i] = version
+	}
+	return &result, nil
+}
+
+// compare returns -1, 0 or 1 following the semver precedence.
+func (s *semver) compare(other *semver) int {
+	for _, pair := range [][2]uint64{
+		{s.major, other.major}, {s.minor, other.minor}, {s.patch, other.patch},
+	} {
+		switch {
+		case pair[0] > pair[1]:
+			return 1
+		case pair[0] < pair[1]:
+			return -1
+		}
+	}
+
+	// A version without pre-release has a higher precedence.
+	switch {
+	case len(s.prerelease) == 0 && len(other.prerelease) == 0:
+		return 0
+	case len(s.prerelease) == 0:
+		return 1
+	case len(other.prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(s.prerelease) && i < len(other.prerelease); i++ {
+		if result := compareSemverIdentifier(s.prerelease[i], other.prerelease[i]); result != 0 {
+			return result
+		}
+	}
+
+	switch {
+	case len(s.prerelease) > len(other.prerelease):
+		return 1
+	case len(s.prerelease) < len(other.prerelease):
+		return -1
+	}
+	return 0
+}
+
+// sortKey returns a string with the same precedence of the version when compared byte by byte. The
+// numbers have a fixed width, the version without pre-release ends with a byte greater than the
+// pre-release separator and each identifier ends with a byte lower than any identifier byte.
+func (s *semver) sortKey() string {
+	key := fmt.Sprintf("%020d.%020d.%020d", s.major, s.minor, s.patch)
+	if len(s.prerelease) == 0 {
+		return key + "~"
+	}
+
+	key += "-"
+	for _, identifier := range s.prerelease {
+		if number, err := strconv.ParseUint(identifier, 10, 64); err == nil {
+			key += fmt.Sprintf("0%020d ", number)
+		} else {
+			key += "1" + identifier + " "
+		}
+	}
+	return key
+}
+
+// compareSemverIdentifier compare the pre-release identifiers. The numeric identifiers are compared
+// numerically and have lower precedence than the alphanumeric ones.
+func compareSemverIdentifier(a, b string) int {
+	aNumber, aErr := strconv.ParseUint(a, 10, 64)
+	bNumber, bErr := strconv.ParseUint(b, 10, 64)
+
+	switch {
+	case aErr == nil && bErr == nil:
+		switch {
+		case aNumber > bNumber:
+			return 1
+		case aNumber < bNumber:
+			return -1
+		}
+		return 0
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
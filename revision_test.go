@@ -0,0 +1,217 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flare
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func revisionDocument(kind string, revision interface{}) Document {
+	return Document{ChangeFieldValue: revision, Resource: Resource{Change: ResourceChange{Kind: kind}}}
+}
+
+func TestDocumentNewerRevisionKinds(t *testing.T) {
+	Convey("Given a list of documents", t, func() {
+		tests := []struct {
+			kind      string
+			revision  interface{}
+			reference interface{}
+			newer     bool
+		}{
+			{ResourceChangeNumeric, int64(10), int64(9), true},
+			{ResourceChangeNumeric, int64(9), int64(10), false},
+			{ResourceChangeNumeric, int64(9223372036854775807), int64(9223372036854775806), true},
+			{ResourceChangeSemver, "1.10.0", "1.9.0", true},
+			{ResourceChangeSemver, "v2.0.0", "1.99.99", true},
+			{ResourceChangeSemver, "1.0.0", "1.0.0-rc.1", true},
+			{ResourceChangeSemver, "1.0.0-rc.10", "1.0.0-rc.9", true},
+			{ResourceChangeSemver, "1.0.0-beta", "1.0.0-alpha.1", true},
+			{ResourceChangeSemver, "1.0.0-alpha.1", "1.0.0-alpha", true},
+			{ResourceChangeSemver, "1.0.0-alpha", "1.0.0-1", true},
+			{ResourceChangeSemver, "1.0.0+build.2", "1.0.0+build.1", false},
+			{ResourceChangeSemver, "1.9.0", "1.10.0", false},
+			{ResourceChangeComposite, "2:1", "1:10", true},
+			{ResourceChangeComposite, "1:10", "1:9", true},
+			{ResourceChangeComposite, "1:9", "1:10", false},
+			{ResourceChangeComposite, "1:9", "1:9", false},
+		}
+
+		Convey("The output should be valid", func() {
+			for _, tt := range tests {
+				target := revisionDocument(tt.kind, tt.revision)
+				reference := revisionDocument(tt.kind, tt.reference)
+				newer, err := target.Newer(&reference)
+				So(err, ShouldBeNil)
+				So(newer, ShouldEqual, tt.newer)
+			}
+		})
+	})
+
+	Convey("Given a list of invalid documents", t, func() {
+		tests := []struct {
+			kind      string
+			revision  interface{}
+			reference interface{}
+		}{
+			{ResourceChangeNumeric, "a", int64(1)},
+			{ResourceChangeSemver, "1.0", "1.0.0"},
+			{ResourceChangeSemver, "1.0.0-", "1.0.0"},
+			{ResourceChangeSemver, 1, "1.0.0"},
+			{ResourceChangeComposite, "1:1", "1:1:1"},
+			{ResourceChangeComposite, "1", "1:1"},
+		}
+
+		Convey("The output should be a error", func() {
+			for _, tt := range tests {
+				target := revisionDocument(tt.kind, tt.revision)
+				reference := revisionDocument(tt.kind, tt.reference)
+				_, err := target.Newer(&reference)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestDocumentTransformRevisionKinds(t *testing.T) {
+	Convey("Given a list of documents", t, func() {
+		tests := []struct {
+			document Document
+			revision interface{}
+		}{
+			{revisionDocument(ResourceChangeNumeric, "9223372036854775807"), int64(9223372036854775807)},
+			{revisionDocument(ResourceChangeNumeric, json.Number("10")), int64(10)},
+			{
+				revisionDocument(ResourceChangeNumeric, json.Number("9007199254740993")),
+				int64(9007199254740993),
+			},
+			{revisionDocument(ResourceChangeNumeric, json.Number("1e3")), int64(1000)},
+			{revisionDocument(ResourceChangeNumeric, float64(10)), int64(10)},
+			{revisionDocument(ResourceChangeNumeric, 10), int64(10)},
+			{revisionDocument(ResourceChangeSemver, "1.2.3-rc.1+build"), "1.2.3-rc.1+build"},
+			{revisionDocument(ResourceChangeComposite, []interface{}{float64(3), "42"}), "3:42"},
+			{revisionDocument(ResourceChangeComposite, "3:42"), "3:42"},
+			{revisionDocument(ResourceChangeInteger, json.Number("10")), 10},
+			{revisionDocument(ResourceChangeInteger, json.Number("10.0")), 10},
+		}
+
+		Convey("The output should be valid", func() {
+			for _, tt := range tests {
+				So(tt.document.TransformRevision(), ShouldBeNil)
+				So(tt.document.ChangeFieldValue, ShouldEqual, tt.revision)
+				So(tt.document.validChangeFieldValue(), ShouldBeNil)
+			}
+		})
+	})
+
+	Convey("Given a list of invalid documents", t, func() {
+		tests := []Document{
+			revisionDocument(ResourceChangeNumeric, "10.5"),
+			revisionDocument(ResourceChangeNumeric, float64(1<<60)),
+			revisionDocument(ResourceChangeInteger, float64(1<<60)),
+			revisionDocument(ResourceChangeNumeric, 1.5),
+			revisionDocument(ResourceChangeNumeric, json.Number("1.5")),
+			revisionDocument(ResourceChangeSemver, "1.2"),
+			revisionDocument(ResourceChangeSemver, "1.2.x"),
+			revisionDocument(ResourceChangeComposite, []interface{}{float64(3)}),
+			revisionDocument(ResourceChangeComposite, []interface{}{float64(3), "a"}),
+			revisionDocument(ResourceChangeComposite, "3"),
+		}
+
+		Convey("The output should be a error", func() {
+			for _, tt := range tests {
+				So(tt.TransformRevision(), ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestResourceChangeValueComposite(t *testing.T) {
+	Convey("Given a composite ResourceChange", t, func() {
+		rc := ResourceChange{Kind: ResourceChangeComposite, Fields: []string{"epoch", "meta.counter"}}
+		So(rc.Valid(), ShouldBeNil)
+
+		Convey("It should return the values of the fields", func() {
+			value, ok := rc.Value(map[string]interface{}{
+				"epoch": float64(3), "meta": map[string]interface{}{"counter": float64(42)},
+			})
+			So(ok, ShouldBeTrue)
+			So(value, ShouldResemble, []interface{}{float64(3), float64(42)})
+
+			_, ok = rc.Value(map[string]interface{}{"epoch": float64(3)})
+			So(ok, ShouldBeFalse)
+		})
+	})
+
+	Convey("Given a list of invalid composite ResourceChange", t, func() {
+		tests := []ResourceChange{
+			{Kind: ResourceChangeComposite},
+			{Kind: ResourceChangeComposite, Fields: []string{"epoch"}},
+			{Kind: ResourceChangeComposite, Field: "revision", Fields: []string{"epoch", "counter"}},
+			{Kind: ResourceChangeComposite, Fields: []string{"epoch", ""}},
+			{Kind: ResourceChangeComposite, Fields: []string{"a", "b"}, Exclude: []string{"c"}},
+		}
+
+		Convey("The validation should return a error", func() {
+			for _, tt := range tests {
+				So(tt.Valid(), ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestDocumentRevisionSortKey(t *testing.T) {
+	Convey("Given a list of revisions in ascending order", t, func() {
+		tests := []struct {
+			kind      string
+			revisions []interface{}
+		}{
+			{
+				ResourceChangeSemver,
+				[]interface{}{
+					"1.0.0-1", "1.0.0-2", "1.0.0-10", "1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta",
+					"1.0.0-alphab", "1.0.0-beta", "1.0.0-rc.9", "1.0.0-rc.10", "1.0.0", "1.9.0", "1.10.0",
+					"v2.0.0",
+				},
+			},
+			{
+				ResourceChangeComposite,
+				[]interface{}{"-10:5", "-1:9", "0:0", "1:9", "1:10", "2:1", []interface{}{float64(10), "0"}},
+			},
+		}
+
+		Convey("The keys should keep the order", func() {
+			for _, tt := range tests {
+				var previous string
+				for i, revision := range tt.revisions {
+					document := revisionDocument(tt.kind, revision)
+					key, err := document.RevisionSortKey()
+					So(err, ShouldBeNil)
+					if i > 0 {
+						So(key, ShouldBeGreaterThan, previous)
+					}
+					previous = key
+				}
+			}
+		})
+	})
+
+	Convey("Given a list of invalid documents", t, func() {
+		tests := []Document{
+			revisionDocument(ResourceChangeSemver, "1.0"),
+			revisionDocument(ResourceChangeComposite, "1"),
+			revisionDocument(ResourceChangeInteger, 1),
+		}
+
+		Convey("The output should be a error", func() {
+			for _, tt := range tests {
+				_, err := tt.RevisionSortKey()
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
@@ -0,0 +1,88 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flare
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi"
+
+	"github.com/diegobernardes/flare"
+	infraMiddleware "github.com/diegobernardes/flare/infra/http/middleware"
+)
+
+// authTargetResource resolve the target of the requests by the resource id at the URL param. The
+// hosts are the ones from the resource addresses and the namespace is the one from the request.
+func authTargetResource(
+	repository flare.ResourceRepositorier, param string,
+) infraMiddleware.AuthTargetFunc {
+	return func(r *http.Request) (*infraMiddleware.AuthTarget, error) {
+		target := &infraMiddleware.AuthTarget{Namespace: flare.NamespaceFromContext(r.Context())}
+		id := chi.URLParam(r, param)
+		if id == "" {
+			return target, nil
+		}
+
+		target.ResourceID = id
+		resource, err := repository.FindOne(r.Context(), id)
+		if err != nil {
+			if errRepo, ok := err.(flare.ResourceRepositoryError); ok && errRepo.NotFound() {
+				return target, nil
+			}
+			return nil, err
+		}
+
+		for _, address := range resource.Addresses {
+			if endpoint, err := url.Parse(address); err == nil {
+				target.Hosts = append(target.Hosts, endpoint.Hostname())
+			}
+		}
+		return target, nil
+	}
+}
+
+// authTargetDocument resolve the target of a document by the host of the document id and the
+// resource it belongs.
+func authTargetDocument(
+	ctx context.Context, repository flare.ResourceRepositorier, id string,
+) (*infraMiddleware.AuthTarget, error) {
+	rawURI := id
+	if !strings.HasPrefix(rawURI, "http") {
+		rawURI = "//" + rawURI
+	}
+
+	target := &infraMiddleware.AuthTarget{Namespace: flare.NamespaceFromContext(ctx)}
+	if endpoint, err := url.Parse(rawURI); err == nil && endpoint.Hostname() != "" {
+		target.Hosts = []string{endpoint.Hostname()}
+	}
+
+	resource, err := repository.FindByURI(ctx, id)
+	if err != nil {
+		if errRepo, ok := err.(flare.ResourceRepositoryError); ok && errRepo.NotFound() {
+			return target, nil
+		}
+		return nil, err
+	}
+	target.ResourceID = resource.ID
+	return target, nil
+}
+
+// authorizeDocument check if the principal at the context can change the document.
+func authorizeDocument(
+	repository flare.ResourceRepositorier,
+) func(ctx context.Context, id string) (bool, error) {
+	return func(ctx context.Context, id string) (bool, error) {
+		return infraMiddleware.Authorize(
+			ctx,
+			infraMiddleware.ScopeDocuments,
+			func() (*infraMiddleware.AuthTarget, error) {
+				return authTargetDocument(ctx, repository, id)
+			},
+		)
+	}
+}
@@ -7,6 +7,9 @@ package flare
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
 	"time"
 
 	"github.com/pkg/errors"
@@ -14,22 +17,30 @@ import (
 
 	"github.com/diegobernardes/flare"
 	"github.com/diegobernardes/flare/aws"
+	infraMiddleware "github.com/diegobernardes/flare/infra/http/middleware"
 	"github.com/diegobernardes/flare/infra/task"
+	"github.com/diegobernardes/flare/infra/trace"
+	"github.com/diegobernardes/flare/namespace"
+	queueMemory "github.com/diegobernardes/flare/queue/memory"
 	"github.com/diegobernardes/flare/repository/memory"
 	"github.com/diegobernardes/flare/repository/mongodb"
+	"github.com/diegobernardes/flare/target/kafka"
+	targetMemory "github.com/diegobernardes/flare/target/memory"
 )
 
 const (
 	engineMemory  = "memory"
 	engineMongoDB = "mongodb"
+	engineSQS     = "sqs"
 )
 
 type config struct {
 	content string
 	viper   *viper.Viper
 
-	subscription *mongodb.Subscription
-	resource     *mongodb.Resource
+	subscription  *mongodb.Subscription
+	resource      *mongodb.Resource
+	mongodbClient *mongodb.Client
 }
 
 func (c *config) getString(key string) string { return c.viper.GetString(key) }
@@ -99,29 +110,150 @@ func (c *config) resourceRepository() (flare.ResourceRepositorier, error) {
 	}
 }
 
+func (c *config) deadLetterRepository() (flare.DeadLetterRepositorier, error) {
+	engine := c.getString("repository.engine")
+	switch engine {
+	case engineMongoDB:
+		client, err := c.mongodb()
+		if err != nil {
+			return nil, err
+		}
+
+		repository, err := mongodb.NewDeadLetter(mongodb.DeadLetterClient(client))
+		if err != nil {
+			return nil, err
+		}
+		return repository, nil
+	case engineMemory:
+		return memory.NewDeadLetter(), nil
+	default:
+		return nil, fmt.Errorf("invalid repository.engine '%s'", engine)
+	}
+}
+
+func (c *config) deliveryRepository() (flare.DeliveryRepositorier, error) {
+	engine := c.getString("repository.engine")
+	switch engine {
+	case engineMongoDB:
+		client, err := c.mongodb()
+		if err != nil {
+			return nil, err
+		}
+
+		retention, err := c.deliveryRetention()
+		if err != nil {
+			return nil, errors.Wrap(err, "error during config subscription.delivery-retention parse")
+		}
+
+		repository, err := mongodb.NewDelivery(
+			mongodb.DeliveryClient(client), mongodb.DeliveryRetention(retention),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return repository, nil
+	case engineMemory:
+		return memory.NewDelivery(), nil
+	default:
+		return nil, fmt.Errorf("invalid repository.engine '%s'", engine)
+	}
+}
+
+// subscriptionLimitRepository returns the state of the subscriptions delivery limits. At MongoDB,
+// the limits are shared by all the Flare instances.
+func (c *config) subscriptionLimitRepository() (flare.SubscriptionLimitRepositorier, error) {
+	engine := c.getString("repository.engine")
+	switch engine {
+	case engineMongoDB:
+		client, err := c.mongodb()
+		if err != nil {
+			return nil, err
+		}
+
+		repository, err := mongodb.NewSubscriptionLimit(mongodb.SubscriptionLimitClient(client))
+		if err != nil {
+			return nil, err
+		}
+		return repository, nil
+	case engineMemory:
+		return memory.NewSubscriptionLimit(), nil
+	default:
+		return nil, fmt.Errorf("invalid repository.engine '%s'", engine)
+	}
+}
+
+func (c *config) changeRepository() (flare.ChangeRepositorier, error) {
+	retention, err := c.changeRetention()
+	if err != nil {
+		return nil, errors.Wrap(err, "error during config document.change-retention parse")
+	}
+
+	engine := c.getString("repository.engine")
+	switch engine {
+	case engineMongoDB:
+		client, err := c.mongodb()
+		if err != nil {
+			return nil, err
+		}
+
+		repository, err := mongodb.NewChange(
+			mongodb.ChangeClient(client), mongodb.ChangeRetention(retention),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return repository, nil
+	case engineMemory:
+		return memory.NewChange(memory.ChangeRetention(retention)), nil
+	default:
+		return nil, fmt.Errorf("invalid repository.engine '%s'", engine)
+	}
+}
+
+// mongodb returns the client shared by the repositories and the health check.
 func (c *config) mongodb() (*mongodb.Client, error) {
+	if c.mongodbClient != nil {
+		return c.mongodbClient, nil
+	}
+
 	client, err := mongodb.NewClient(
 		mongodb.ClientAddrs(c.getStringSlice("repository.addrs")),
 		mongodb.ClientDatabase(c.getString("repository.database")),
 		mongodb.ClientUsername(c.getString("repository.username")),
 		mongodb.ClientPassword(c.getString("repository.password")),
 	)
-	return client, errors.Wrap(err, "error during MongoDB connection")
+	if err != nil {
+		return nil, errors.Wrap(err, "error during MongoDB connection")
+	}
+	c.mongodbClient = client
+	return client, nil
 }
 
 func (c *config) queue(name string) (task.Pusher, task.Puller, error) {
 	engine := c.getString("task.engine")
-	if engine != "sqs" {
+	switch engine {
+	case engineSQS:
+		return c.queueSQS(name)
+	case engineMemory:
+		return c.queueMemory()
+	default:
 		return nil, nil, fmt.Errorf("invalid task.engine '%s'", engine)
 	}
+}
 
+func (c *config) awsSession() (*aws.Session, error) {
 	session, err := aws.NewSession(
 		aws.SessionKey(c.getString("aws.key")),
 		aws.SessionSecret(c.getString("aws.secret")),
 		aws.SessionRegion(c.getString("aws.region")),
 	)
+	return session, errors.Wrap(err, "error during AWS session initialization")
+}
+
+func (c *config) queueSQS(name string) (task.Pusher, task.Puller, error) {
+	session, err := c.awsSession()
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "error during AWS session initialization")
+		return nil, nil, err
 	}
 
 	sqs, err := aws.NewSQS(
@@ -135,6 +267,66 @@ func (c *config) queue(name string) (task.Pusher, task.Puller, error) {
 	return sqs, sqs, nil
 }
 
+func (c *config) queueMemory() (task.Pusher, task.Puller, error) {
+	var visibilityTimeout time.Duration
+	if value := c.getString("task.memory-visibility-timeout"); value != "" {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error during config task.memory-visibility-timeout parse")
+		}
+		visibilityTimeout = duration
+	}
+
+	queue, err := queueMemory.NewQueue(
+		queueMemory.QueueBufferSize(c.getInt("task.memory-buffer-size")),
+		queueMemory.QueueVisibilityTimeout(visibilityTimeout),
+	)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error during memory queue initialization")
+	}
+
+	return queue, queue, nil
+}
+
+// subscriptionTargets returns the targets enabled besides the HTTP, that is always available.
+func (c *config) subscriptionTargets() (map[string]flare.SubscriptionTarget, error) {
+	targets := make(map[string]flare.SubscriptionTarget)
+	for _, kind := range c.getStringSlice("subscription.targets") {
+		var (
+			target flare.SubscriptionTarget
+			err    error
+		)
+
+		switch kind {
+		case flare.SubscriptionTargetSQS, flare.SubscriptionTargetSNS:
+			target, err = c.subscriptionTargetAWS(kind)
+		case flare.SubscriptionTargetKafka:
+			target, err = kafka.NewProxy(kafka.ProxyHTTPClient(http.DefaultClient))
+		case flare.SubscriptionTargetMemory:
+			target, err = targetMemory.NewSink()
+		default:
+			return nil, fmt.Errorf("invalid subscription.targets '%s'", kind)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("error during %s target initialization", kind))
+		}
+		targets[kind] = target
+	}
+	return targets, nil
+}
+
+func (c *config) subscriptionTargetAWS(kind string) (flare.SubscriptionTarget, error) {
+	session, err := c.awsSession()
+	if err != nil {
+		return nil, err
+	}
+
+	if kind == flare.SubscriptionTargetSQS {
+		return aws.NewSQSTarget(aws.SQSTargetSession(session))
+	}
+	return aws.NewSNSTarget(aws.SNSTargetSession(session))
+}
+
 func (c *config) httpDefaultLimit() int {
 	value := c.getInt("http.default-limit")
 	if value == 0 {
@@ -143,6 +335,201 @@ func (c *config) httpDefaultLimit() int {
 	return value
 }
 
+func (c *config) documentMaxSize() int64 { return c.viper.GetInt64("document.max-size") }
+
+func (c *config) documentMaxBatchBodySize() int64 {
+	return c.viper.GetInt64("document.max-batch-body-size")
+}
+
+func (c *config) documentMaxBatchSize() int {
+	value := c.getInt("document.max-batch-size")
+	if value == 0 {
+		return 1000
+	}
+	return value
+}
+
+func (c *config) changeRetention() (time.Duration, error) {
+	s := c.getString("document.change-retention")
+	if s == "" {
+		s = "24h"
+	}
+	return time.ParseDuration(s)
+}
+
+func (c *config) deliveryRetention() (time.Duration, error) {
+	s := c.getString("subscription.delivery-retention")
+	if s == "" {
+		s = "168h"
+	}
+	return time.ParseDuration(s)
+}
+
+func (c *config) streamBufferSize() int {
+	value := c.getInt("subscription.stream-buffer-size")
+	if value == 0 {
+		return 1000
+	}
+	return value
+}
+
+func (c *config) pollerEnabled() bool { return c.viper.GetBool("poller.enabled") }
+
+func (c *config) pollerTimeout() (time.Duration, error) {
+	s := c.getString("poller.timeout")
+	if s == "" {
+		s = "10s"
+	}
+	return time.ParseDuration(s)
+}
+
+func (c *config) metricsEnabled() bool { return c.viper.GetBool("metrics.enabled") }
+
+func (c *config) metricsAddr() string { return c.getString("metrics.addr") }
+
+// adminAddr is the address of the health endpoints, when empty, they are served at the main port.
+func (c *config) adminAddr() string { return c.getString("admin.addr") }
+
+func (c *config) healthTimeout() (time.Duration, error) {
+	s := c.getString("admin.timeout")
+	if s == "" {
+		s = "5s"
+	}
+	return time.ParseDuration(s)
+}
+
+func (c *config) authEnabled() bool { return c.viper.GetBool("auth.enabled") }
+
+// authAuthenticators returns the authenticators configured by the auth.keys and the auth.jwks.
+func (c *config) authAuthenticators() ([]infraMiddleware.Authenticator, error) {
+	var authenticators []infraMiddleware.Authenticator
+
+	var keys []struct {
+		Subject string
+		Key     string
+		Scopes  []string
+	}
+	if err := c.viper.UnmarshalKey("auth.keys", &keys); err != nil {
+		return nil, errors.Wrap(err, "error during auth.keys parse")
+	}
+
+	if len(keys) > 0 {
+		options := make([]func(*infraMiddleware.APIKey), 0, len(keys))
+		for _, key := range keys {
+			grants, err := infraMiddleware.ParseGrants(key.Scopes)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error during auth.keys scopes parse of '%s'", key.Subject)
+			}
+
+			principal := &infraMiddleware.Principal{Subject: key.Subject, Grants: grants}
+			options = append(options, infraMiddleware.APIKeyPrincipal(key.Key, principal))
+		}
+
+		apiKey, err := infraMiddleware.NewAPIKey(options...)
+		if err != nil {
+			return nil, errors.Wrap(err, "error during api key authenticator initialization")
+		}
+		authenticators = append(authenticators, apiKey)
+	}
+
+	if path := c.getString("auth.jwks"); path != "" {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error during read of the JWKS file '%s'", path)
+		}
+
+		jwks, err := infraMiddleware.ParseJWKS(content)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error during parse of the JWKS file '%s'", path)
+		}
+
+		jwt, err := infraMiddleware.NewJWT(
+			infraMiddleware.JWTKeys(jwks),
+			infraMiddleware.JWTIssuer(c.getString("auth.issuer")),
+			infraMiddleware.JWTAudience(c.getString("auth.audience")),
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "error during jwt authenticator initialization")
+		}
+		authenticators = append(authenticators, jwt)
+	}
+
+	if len(authenticators) == 0 {
+		return nil, errors.New("auth.enabled without auth.keys or auth.jwks")
+	}
+	return authenticators, nil
+}
+
+// namespaceQuota returns the quotas of the namespaces. The namespace section is the default of all
+// the namespaces and the namespace.quotas override it by namespace.
+func (c *config) namespaceQuota() (*namespace.Quota, error) {
+	type quota struct {
+		Resources      int
+		Subscriptions  int
+		IngestionRate  float64 `mapstructure:"ingestion-rate"`
+		IngestionBurst int     `mapstructure:"ingestion-burst"`
+	}
+
+	var base quota
+	if err := c.viper.UnmarshalKey("namespace", &base); err != nil {
+		return nil, errors.Wrap(err, "error during namespace parse")
+	}
+
+	var overrides map[string]quota
+	if err := c.viper.UnmarshalKey("namespace.quotas", &overrides); err != nil {
+		return nil, errors.Wrap(err, "error during namespace.quotas parse")
+	}
+
+	options := []func(*namespace.Quota){namespace.QuotaDefault(flare.NamespaceQuota(base))}
+	for name, override := range overrides {
+		options = append(options, namespace.QuotaNamespace(name, flare.NamespaceQuota(override)))
+	}
+
+	q, err := namespace.NewQuota(options...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error during namespace.Quota initialization")
+	}
+	return q, nil
+}
+
+// traceExporter returns the exporter of the spans, nil when the trace.exporter is not set.
+func (c *config) traceExporter() (trace.Exporter, error) {
+	serviceName := c.getString("trace.service-name")
+
+	exporter := c.getString("trace.exporter")
+	switch exporter {
+	case "":
+		return nil, nil
+	case "otlp":
+		endpoint := c.getString("trace.otlp-endpoint")
+		if endpoint == "" {
+			endpoint = "http://localhost:4318/v1/traces"
+		}
+
+		return trace.NewOTLPExporter(
+			trace.OTLPExporterEndpoint(endpoint),
+			trace.OTLPExporterServiceName(serviceName),
+			trace.OTLPExporterHTTPClient(&http.Client{Timeout: 10 * time.Second}),
+		)
+	case "file":
+		path := c.getString("trace.file")
+		if path == "" {
+			path = "flare-traces.json"
+		}
+
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error during open of the trace file '%s'", path)
+		}
+
+		return trace.NewFileExporter(
+			trace.FileExporterWriter(file), trace.FileExporterServiceName(serviceName),
+		)
+	default:
+		return nil, fmt.Errorf("invalid trace.exporter '%s'", exporter)
+	}
+}
+
 func (c *config) serverMiddlewareTimeout() (time.Duration, error) {
 	s := c.getString("http.timeout")
 	if s == "" {
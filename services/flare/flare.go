@@ -5,7 +5,9 @@
 package flare
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"time"
@@ -18,8 +20,14 @@ import (
 
 	"github.com/diegobernardes/flare"
 	"github.com/diegobernardes/flare/document"
+	"github.com/diegobernardes/flare/health"
 	infraHTTP "github.com/diegobernardes/flare/infra/http"
+	infraMiddleware "github.com/diegobernardes/flare/infra/http/middleware"
+	infraMetrics "github.com/diegobernardes/flare/infra/metrics"
 	"github.com/diegobernardes/flare/infra/task"
+	"github.com/diegobernardes/flare/infra/trace"
+	"github.com/diegobernardes/flare/namespace"
+	repositoryMetrics "github.com/diegobernardes/flare/repository/metrics"
 	"github.com/diegobernardes/flare/resource"
 	"github.com/diegobernardes/flare/subscription"
 )
@@ -37,6 +45,21 @@ type Client struct {
 	rawConfig string
 	config    *config
 	logger    log.Logger
+	poller    *document.Poller
+	tracer    *trace.Tracer
+	auth      *infraMiddleware.Auth
+	quota     *namespace.Quota
+	metrics   struct {
+		registry *infraMetrics.Registry
+	}
+	admin struct {
+		routers map[string]chi.Router
+		servers []*http.Server
+	}
+	worker struct {
+		document     *task.Worker
+		subscription *task.Worker
+	}
 }
 
 // Start is used to start the service.
@@ -52,6 +75,22 @@ func (c *Client) Start() error {
 	}
 	level.Info(c.logger).Log("message", "starting Flare")
 
+	if c.config.metricsEnabled() {
+		c.metrics.registry = infraMetrics.NewRegistry()
+	}
+
+	if err = c.initTracer(); err != nil {
+		return errors.Wrap(err, "error during tracer initialization")
+	}
+
+	if err = c.initAuth(); err != nil {
+		return errors.Wrap(err, "error during auth initialization")
+	}
+
+	if c.quota, err = c.config.namespaceQuota(); err != nil {
+		return err
+	}
+
 	documentRepository, err := c.config.documentRepository()
 	if err != nil {
 		return err
@@ -62,6 +101,37 @@ func (c *Client) Start() error {
 		return err
 	}
 
+	deadLetterRepository, err := c.config.deadLetterRepository()
+	if err != nil {
+		return err
+	}
+
+	deliveryRepository, err := c.config.deliveryRepository()
+	if err != nil {
+		return err
+	}
+
+	changeRepository, err := c.config.changeRepository()
+	if err != nil {
+		return err
+	}
+
+	subscriptionLimitRepository, err := c.config.subscriptionLimitRepository()
+	if err != nil {
+		return err
+	}
+
+	if registry := c.metrics.registry; registry != nil {
+		documentRepository = repositoryMetrics.NewDocument(registry, documentRepository)
+		subscriptionRepository = repositoryMetrics.NewSubscription(registry, subscriptionRepository)
+		deadLetterRepository = repositoryMetrics.NewDeadLetter(registry, deadLetterRepository)
+		deliveryRepository = repositoryMetrics.NewDelivery(registry, deliveryRepository)
+		changeRepository = repositoryMetrics.NewChange(registry, changeRepository)
+		subscriptionLimitRepository = repositoryMetrics.NewSubscriptionLimit(
+			registry, subscriptionLimitRepository,
+		)
+	}
+
 	resourceService, resourceRepository, err := c.initResourceService(subscriptionRepository)
 	if err != nil {
 		level.Debug(c.logger).Log(
@@ -70,10 +140,20 @@ func (c *Client) Start() error {
 		return err
 	}
 
-	documentService, err := c.initDocumentService(
+	stream, err := subscription.NewStream(subscription.StreamSize(c.config.streamBufferSize()))
+	if err != nil {
+		return errors.Wrap(err, "error during stream initialization")
+	}
+
+	documentService, trigger, err := c.initDocumentService(
+		stream,
+		changeRepository,
 		documentRepository,
 		resourceRepository,
 		subscriptionRepository,
+		deadLetterRepository,
+		deliveryRepository,
+		subscriptionLimitRepository,
 	)
 	if err != nil {
 		return errors.Wrap(err, "error during document service initialization")
@@ -84,27 +164,90 @@ func (c *Client) Start() error {
 		return errors.Wrap(err, "error during subscription service initialization")
 	}
 
-	return c.initServer(resourceService, subscriptionService, documentService)
+	deadLetterService, err := c.initDeadLetterService(
+		subscriptionRepository, deadLetterRepository, trigger,
+	)
+	if err != nil {
+		return errors.Wrap(err, "error during dead letter service initialization")
+	}
+
+	deliveryService, err := c.initDeliveryService(subscriptionRepository, deliveryRepository)
+	if err != nil {
+		return errors.Wrap(err, "error during delivery service initialization")
+	}
+
+	streamService, err := c.initStreamService(resourceRepository, stream)
+	if err != nil {
+		return errors.Wrap(err, "error during stream service initialization")
+	}
+
+	changeService, err := c.initChangeService(resourceRepository, changeRepository)
+	if err != nil {
+		return errors.Wrap(err, "error during change service initialization")
+	}
+
+	healthService, err := c.initHealthService()
+	if err != nil {
+		return errors.Wrap(err, "error during health service initialization")
+	}
+
+	err = c.initServer(
+		resourceRepository,
+		resourceService,
+		subscriptionService,
+		deadLetterService,
+		deliveryService,
+		streamService,
+		changeService,
+		documentService,
+		healthService,
+	)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(c.initAdminServers(healthService), "error during admin server initialization")
 }
 
 func (c *Client) initServer(
+	resourceRepository flare.ResourceRepositorier,
 	resourceService *resource.Service,
 	subscriptionService *subscription.Service,
+	deadLetterService *subscription.DeadLetterService,
+	deliveryService *subscription.DeliveryService,
+	streamService *subscription.StreamService,
+	changeService *document.ChangeService,
 	documentService *document.Service,
+	healthService *health.Service,
 ) error {
 	duration, err := c.config.serverMiddlewareTimeout()
 	if err != nil {
 		return errors.Wrap(err, "error during config http.timeout parse")
 	}
 
-	srv, err := newServer(
+	options := []func(*server){
 		serverAddr(c.config.getString("http.addr")),
 		serverHandlerResource(resourceService),
 		serverHandlerSubscription(subscriptionService),
+		serverHandlerDeadLetter(deadLetterService),
+		serverHandlerDelivery(deliveryService),
+		serverHandlerStream(streamService),
+		serverHandlerChange(changeService),
 		serverHandlerDocument(documentService),
 		serverLogger(c.logger),
 		serverMiddlewareTimeout(duration),
-	)
+		serverMetrics(c.metrics.registry, c.metrics.registry != nil && c.config.metricsAddr() == ""),
+		serverTracer(c.tracer),
+	}
+	if c.config.adminAddr() == "" {
+		options = append(options, serverHandlerHealth(healthService))
+	}
+
+	if c.auth != nil {
+		options = append(options, serverAuth(c.auth, resourceRepository))
+	}
+
+	srv, err := newServer(options...)
 	if err != nil {
 		return errors.Wrap(err, "error during server initialization")
 	}
@@ -115,11 +258,176 @@ func (c *Client) initServer(
 	return nil
 }
 
+// initAdminServers start the servers that expose the metrics at metrics.addr and the health
+// endpoints at admin.addr. When both have the same address, they share the server. Without the
+// address, the endpoints are exposed by the main server.
+func (c *Client) initAdminServers(healthService *health.Service) error {
+	if addr := c.config.metricsAddr(); c.metrics.registry != nil && addr != "" {
+		c.adminRouter(addr).Get("/metrics", c.metrics.registry.ServeHTTP)
+	}
+
+	if addr := c.config.adminAddr(); addr != "" {
+		routerHealth(c.adminRouter(addr), healthService)
+	}
+
+	for addr, router := range c.admin.routers {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return errors.Wrapf(err, "error during listen at '%s'", addr)
+		}
+
+		server := &http.Server{Handler: router}
+		c.admin.servers = append(c.admin.servers, server)
+
+		go func() {
+			if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+				level.Error(c.logger).Log(
+					"error", err.Error(), "message", "error during admin server serve",
+				)
+			}
+		}()
+	}
+	return nil
+}
+
+func (c *Client) adminRouter(addr string) chi.Router {
+	if c.admin.routers == nil {
+		c.admin.routers = make(map[string]chi.Router)
+	}
+
+	router, ok := c.admin.routers[addr]
+	if !ok {
+		router = chi.NewRouter()
+		c.admin.routers[addr] = router
+	}
+	return router
+}
+
+// initHealthService register the checks of the dependencies: the MongoDB connectivity, the queues
+// and the workers.
+func (c *Client) initHealthService() (*health.Service, error) {
+	writer, err := infraHTTP.NewWriter(c.logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "error during writer initialization")
+	}
+
+	timeout, err := c.config.healthTimeout()
+	if err != nil {
+		return nil, errors.Wrap(err, "error during config admin.timeout parse")
+	}
+
+	options := []func(*health.Service){
+		health.ServiceWriter(writer),
+		health.ServiceTimeout(timeout),
+		health.ServiceChecker("queue.document", health.CheckerFunc(c.worker.document.Ping)),
+		health.ServiceChecker("queue.subscription", health.CheckerFunc(c.worker.subscription.Ping)),
+		health.ServiceChecker("worker.document", c.worker.document),
+		health.ServiceChecker("worker.subscription", c.worker.subscription),
+	}
+
+	if c.config.getString("repository.engine") == engineMongoDB {
+		client, err := c.config.mongodb()
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, health.ServiceChecker("mongodb", health.CheckerFunc(client.Ping)))
+	}
+
+	healthService, err := health.NewService(options...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error during health.Service initialization")
+	}
+	return healthService, nil
+}
+
 // Stop is used to graceful stop the service.
 func (c *Client) Stop() error {
 	if err := c.server.stop(); err != nil {
 		return errors.Wrap(err, "error during server stop")
 	}
+
+	for _, server := range c.admin.servers {
+		if err := server.Shutdown(context.Background()); err != nil {
+			return errors.Wrap(err, "error during admin server stop")
+		}
+	}
+
+	// The poller push messages to the document worker, so it should be stopped before the workers.
+	if c.poller != nil {
+		c.poller.Stop()
+	}
+
+	// The document worker push messages to the subscription worker, so it should be stopped first.
+	workers := []struct {
+		name   string
+		worker *task.Worker
+	}{
+		{"document", c.worker.document},
+		{"subscription", c.worker.subscription},
+	}
+
+	for _, w := range workers {
+		ctx, ctxCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := w.worker.Stop(ctx)
+		ctxCancel()
+		if err != nil {
+			return errors.Wrapf(err, "error during %s worker stop", w.name)
+		}
+	}
+
+	// The tracer is the last one, this way, the spans of the pending tasks are exported.
+	if c.tracer != nil {
+		c.tracer.Stop()
+	}
+	return nil
+}
+
+// initAuth initialize the authentication when auth.enabled is set. Without it, the API is open.
+func (c *Client) initAuth() error {
+	if !c.config.authEnabled() {
+		return nil
+	}
+
+	authenticators, err := c.config.authAuthenticators()
+	if err != nil {
+		return err
+	}
+
+	writer, err := infraHTTP.NewWriter(c.logger)
+	if err != nil {
+		return errors.Wrap(err, "error during writer initialization")
+	}
+
+	options := []func(*infraMiddleware.Auth){infraMiddleware.AuthWriter(writer)}
+	for _, authenticator := range authenticators {
+		options = append(options, infraMiddleware.AuthAuthenticator(authenticator))
+	}
+
+	auth, err := infraMiddleware.NewAuth(options...)
+	if err != nil {
+		return errors.Wrap(err, "error during middleware.Auth initialization")
+	}
+	c.auth = auth
+	return nil
+}
+
+// initTracer start the tracer when a trace.exporter is configured. Without it, the trace context
+// is propagated but the spans are not exported.
+func (c *Client) initTracer() error {
+	exporter, err := c.config.traceExporter()
+	if err != nil {
+		return errors.Wrap(err, "error during trace exporter initialization")
+	}
+	if exporter == nil {
+		return nil
+	}
+
+	tracer, err := trace.NewTracer(trace.TracerExporter(exporter), trace.TracerLogger(c.logger))
+	if err != nil {
+		return errors.Wrap(err, "error during trace.Tracer initialization")
+	}
+	tracer.Start()
+	c.tracer = tracer
 	return nil
 }
 
@@ -201,6 +509,10 @@ func (c *Client) initResourceService(
 		return nil, nil, errors.Wrap(err, "error during resource repository initialization")
 	}
 
+	if c.metrics.registry != nil {
+		repository = repositoryMetrics.NewResource(c.metrics.registry, repository)
+	}
+
 	writer, err := infraHTTP.NewWriter(c.logger)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "error during http.Writer initialization")
@@ -208,12 +520,13 @@ func (c *Client) initResourceService(
 
 	resourceService, err := resource.NewService(
 		resource.ServiceGetResourceID(func(r *http.Request) string { return chi.URLParam(r, "id") }),
-		resource.ServiceGetResourceURI(func(id string) string {
-			return fmt.Sprintf("/resources/%s", id)
+		resource.ServiceGetResourceURI(func(namespace, id string) string {
+			return fmt.Sprintf("%s/resources/%s", namespacePath(namespace), id)
 		}),
 		resource.ServiceParsePagination(infraHTTP.ParsePagination(c.config.httpDefaultLimit())),
 		resource.ServiceWriter(writer),
 		resource.ServiceRepository(repository),
+		resource.ServiceQuota(c.quota),
 	)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "error during resource.Service initialization")
@@ -242,11 +555,14 @@ func (c *Client) initSubscriptionService(
 		subscription.ServiceGetSubscriptionID(func(r *http.Request) string {
 			return chi.URLParam(r, "id")
 		}),
-		subscription.ServiceGetSubscriptionURI(func(resourceId, id string) string {
-			return fmt.Sprintf("/resources/%s/subscriptions/%s", resourceId, id)
+		subscription.ServiceGetSubscriptionURI(func(namespace, resourceId, id string) string {
+			return fmt.Sprintf(
+				"%s/resources/%s/subscriptions/%s", namespacePath(namespace), resourceId, id,
+			)
 		}),
 		subscription.ServiceResourceRepository(resourceRepository),
 		subscription.ServiceSubscriptionRepository(subscriptionRepository),
+		subscription.ServiceQuota(c.quota),
 	)
 	if err != nil {
 		return nil, errors.Wrap(err, "error during subscription.Service initialization")
@@ -255,19 +571,140 @@ func (c *Client) initSubscriptionService(
 	return subscriptionService, nil
 }
 
+func (c *Client) initDeadLetterService(
+	subscriptionRepository flare.SubscriptionRepositorier,
+	deadLetterRepository flare.DeadLetterRepositorier,
+	trigger *subscription.Trigger,
+) (*subscription.DeadLetterService, error) {
+	writer, err := infraHTTP.NewWriter(c.logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "error during http.Writer initialization")
+	}
+
+	deadLetterService, err := subscription.NewDeadLetterService(
+		subscription.DeadLetterServiceParsePagination(
+			infraHTTP.ParsePagination(c.config.httpDefaultLimit()),
+		),
+		subscription.DeadLetterServiceWriter(writer),
+		subscription.DeadLetterServiceGetResourceID(func(r *http.Request) string {
+			return chi.URLParam(r, "resourceId")
+		}),
+		subscription.DeadLetterServiceGetSubscriptionID(func(r *http.Request) string {
+			return chi.URLParam(r, "id")
+		}),
+		subscription.DeadLetterServiceGetDeadLetterID(func(r *http.Request) string {
+			return chi.URLParam(r, "deadLetterId")
+		}),
+		subscription.DeadLetterServiceSubscriptionRepository(subscriptionRepository),
+		subscription.DeadLetterServiceDeadLetterRepository(deadLetterRepository),
+		subscription.DeadLetterServiceReplayer(trigger),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "error during subscription.DeadLetterService initialization")
+	}
+
+	return deadLetterService, nil
+}
+
+func (c *Client) initDeliveryService(
+	subscriptionRepository flare.SubscriptionRepositorier,
+	deliveryRepository flare.DeliveryRepositorier,
+) (*subscription.DeliveryService, error) {
+	writer, err := infraHTTP.NewWriter(c.logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "error during http.Writer initialization")
+	}
+
+	deliveryService, err := subscription.NewDeliveryService(
+		subscription.DeliveryServiceParsePagination(
+			infraHTTP.ParsePagination(c.config.httpDefaultLimit()),
+		),
+		subscription.DeliveryServiceWriter(writer),
+		subscription.DeliveryServiceGetResourceID(func(r *http.Request) string {
+			return chi.URLParam(r, "resourceId")
+		}),
+		subscription.DeliveryServiceGetSubscriptionID(func(r *http.Request) string {
+			return chi.URLParam(r, "id")
+		}),
+		subscription.DeliveryServiceSubscriptionRepository(subscriptionRepository),
+		subscription.DeliveryServiceDeliveryRepository(deliveryRepository),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "error during subscription.DeliveryService initialization")
+	}
+
+	return deliveryService, nil
+}
+
+func (c *Client) initStreamService(
+	resourceRepository flare.ResourceRepositorier,
+	stream *subscription.Stream,
+) (*subscription.StreamService, error) {
+	writer, err := infraHTTP.NewWriter(c.logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "error during writer initialization")
+	}
+
+	streamService, err := subscription.NewStreamService(
+		subscription.StreamServiceStream(stream),
+		subscription.StreamServiceWriter(writer),
+		subscription.StreamServiceGetResourceID(func(r *http.Request) string {
+			return chi.URLParam(r, "resourceId")
+		}),
+		subscription.StreamServiceResourceRepository(resourceRepository),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "error during subscription.StreamService initialization")
+	}
+
+	return streamService, nil
+}
+
+func (c *Client) initChangeService(
+	resourceRepository flare.ResourceRepositorier,
+	changeRepository flare.ChangeRepositorier,
+) (*document.ChangeService, error) {
+	writer, err := infraHTTP.NewWriter(c.logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "error during writer initialization")
+	}
+
+	changeService, err := document.NewChangeService(
+		document.ChangeServiceParsePagination(
+			infraHTTP.ParsePagination(c.config.httpDefaultLimit()),
+		),
+		document.ChangeServiceWriter(writer),
+		document.ChangeServiceGetResourceID(func(r *http.Request) string {
+			return chi.URLParam(r, "resourceId")
+		}),
+		document.ChangeServiceResourceRepository(resourceRepository),
+		document.ChangeServiceChangeRepository(changeRepository),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "error during document.ChangeService initialization")
+	}
+
+	return changeService, nil
+}
+
 func (c *Client) initDocumentService(
+	stream *subscription.Stream,
+	cr flare.ChangeRepositorier,
 	dr flare.DocumentRepositorier,
 	rr flare.ResourceRepositorier,
 	sr flare.SubscriptionRepositorier,
-) (*document.Service, error) {
+	dlr flare.DeadLetterRepositorier,
+	der flare.DeliveryRepositorier,
+	slr flare.SubscriptionLimitRepositorier,
+) (*document.Service, *subscription.Trigger, error) {
 	documentPusher, documentPuller, err := c.config.queue("document")
 	if err != nil {
-		return nil, errors.Wrap(err, "error during queue initialization")
+		return nil, nil, errors.Wrap(err, "error during queue initialization")
 	}
 
 	subscriptionPusher, subscriptionPuller, err := c.config.queue("subscription")
 	if err != nil {
-		return nil, errors.Wrap(err, "error during queue initialization")
+		return nil, nil, errors.Wrap(err, "error during queue initialization")
 	}
 
 	trigger := &subscription.Trigger{}
@@ -279,21 +716,43 @@ func (c *Client) initDocumentService(
 		task.WorkerTimeoutProcess(30*time.Second),
 		task.WorkerTimeoutPush(30*time.Second),
 		task.WorkerLogger(c.logger),
+		task.WorkerMetrics("subscription", c.metrics.registry),
 	)
 	if err != nil {
-		return nil, errors.Wrap(err, "error during worker initialization")
+		return nil, nil, errors.Wrap(err, "error during worker initialization")
 	}
 
-	err = trigger.Init(
+	targets, err := c.config.subscriptionTargets()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error during subscription targets initialization")
+	}
+
+	triggerOptions := []func(*subscription.Trigger){
 		subscription.TriggerRepository(sr),
+		subscription.TriggerResourceRepository(rr),
+		subscription.TriggerDeadLetterRepository(dlr),
+		subscription.TriggerDeliveryRepository(der),
+		subscription.TriggerLimitRepository(slr),
+		subscription.TriggerLogger(c.logger),
 		subscription.TriggerHTTPClient(http.DefaultClient),
 		subscription.TriggerDocumentRepository(dr),
 		subscription.TriggerPusher(triggerWorker),
-	)
+		subscription.TriggerStream(stream),
+		subscription.TriggerTracer(c.tracer),
+	}
+	for kind, target := range targets {
+		triggerOptions = append(triggerOptions, subscription.TriggerTarget(kind, target))
+	}
+	if c.metrics.registry != nil {
+		triggerOptions = append(triggerOptions, subscription.TriggerMetrics(c.metrics.registry))
+	}
+
+	err = trigger.Init(triggerOptions...)
 	if err != nil {
-		return nil, errors.Wrap(err, "error during subscription.Trigger initialization")
+		return nil, nil, errors.Wrap(err, "error during subscription.Trigger initialization")
 	}
 	triggerWorker.Start()
+	c.worker.subscription = triggerWorker
 
 	documentWorker := &document.Worker{}
 	jobWorker, err := task.NewWorker(
@@ -304,9 +763,10 @@ func (c *Client) initDocumentService(
 		task.WorkerTimeoutProcess(30*time.Second),
 		task.WorkerTimeoutPush(30*time.Second),
 		task.WorkerLogger(c.logger),
+		task.WorkerMetrics("document", c.metrics.registry),
 	)
 	if err != nil {
-		return nil, errors.Wrap(err, "error during worker initialization")
+		return nil, nil, errors.Wrap(err, "error during worker initialization")
 	}
 
 	err = documentWorker.Init(
@@ -314,30 +774,72 @@ func (c *Client) initDocumentService(
 		document.WorkerResourceRepository(rr),
 		document.WorkerSubscriptionRepository(sr),
 		document.WorkerSubscriptionTrigger(trigger),
+		document.WorkerListener(stream),
+		document.WorkerChangeRepository(cr),
 		document.WorkerPusher(jobWorker),
+		document.WorkerTracer(c.tracer),
 	)
 	if err != nil {
-		return nil, errors.Wrap(err, "error during worker initialization")
+		return nil, nil, errors.Wrap(err, "error during worker initialization")
 	}
 	jobWorker.Start()
+	c.worker.document = jobWorker
+
+	if err = c.initPoller(rr, documentWorker); err != nil {
+		return nil, nil, errors.Wrap(err, "error during poller initialization")
+	}
 
 	writer, err := infraHTTP.NewWriter(c.logger)
 	if err != nil {
-		return nil, errors.Wrap(err, "error during writer initialization")
+		return nil, nil, errors.Wrap(err, "error during writer initialization")
 	}
 
-	documentService, err := document.NewService(
+	documentOptions := []func(*document.Service){
 		document.ServiceDocumentRepository(dr),
 		document.ServiceResourceRepository(rr),
 		document.ServiceGetDocumentId(func(r *http.Request) string { return chi.URLParam(r, "*") }),
 		document.ServicePusher(documentWorker),
+		document.ServiceMaxSize(c.config.documentMaxSize()),
+		document.ServiceMaxBatchSize(c.config.documentMaxBatchSize()),
+		document.ServiceMaxBatchBodySize(c.config.documentMaxBatchBodySize()),
 		document.ServiceWriter(writer),
-	)
+		document.ServiceLimiter(c.quota),
+	}
+	if c.auth != nil {
+		documentOptions = append(documentOptions, document.ServiceAuthorizer(authorizeDocument(rr)))
+	}
+
+	documentService, err := document.NewService(documentOptions...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error during document.Service initialization")
+	}
+
+	return documentService, trigger, nil
+}
+
+func (c *Client) initPoller(rr flare.ResourceRepositorier, documentWorker *document.Worker) error {
+	if !c.config.pollerEnabled() {
+		return nil
+	}
+
+	timeout, err := c.config.pollerTimeout()
 	if err != nil {
-		return nil, errors.Wrap(err, "error during document.Service initialization")
+		return errors.Wrap(err, "error during config poller.timeout parse")
 	}
 
-	return documentService, nil
+	poller, err := document.NewPoller(
+		document.PollerResourceRepository(rr),
+		document.PollerPusher(documentWorker),
+		document.PollerHTTPClient(&http.Client{Timeout: timeout}),
+		document.PollerLogger(c.logger),
+		document.PollerMaxSize(c.config.documentMaxSize()),
+	)
+	if err != nil {
+		return errors.Wrap(err, "error during document.Poller initialization")
+	}
+	poller.Start()
+	c.poller = poller
+	return nil
 }
 
 func (c *Client) loggerColor(keyvals ...interface{}) term.FgBgColor {
@@ -17,9 +17,13 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/pkg/errors"
 
+	"github.com/diegobernardes/flare"
 	"github.com/diegobernardes/flare/document"
+	"github.com/diegobernardes/flare/health"
 	infraHTTP "github.com/diegobernardes/flare/infra/http"
 	infraMiddleware "github.com/diegobernardes/flare/infra/http/middleware"
+	infraMetrics "github.com/diegobernardes/flare/infra/metrics"
+	"github.com/diegobernardes/flare/infra/trace"
 	"github.com/diegobernardes/flare/resource"
 	"github.com/diegobernardes/flare/subscription"
 )
@@ -30,11 +34,25 @@ type server struct {
 	handler    struct {
 		resource     *resource.Service
 		subscription *subscription.Service
+		deadLetter   *subscription.DeadLetterService
+		delivery     *subscription.DeliveryService
+		stream       *subscription.StreamService
+		change       *document.ChangeService
 		document     *document.Service
+		health       *health.Service
 	}
 	middleware struct {
 		timeout time.Duration
 	}
+	metrics struct {
+		registry *infraMetrics.Registry
+		route    bool
+	}
+	auth struct {
+		middleware         *infraMiddleware.Auth
+		resourceRepository flare.ResourceRepositorier
+	}
+	tracer        *trace.Tracer
 	logger        log.Logger
 	writeResponse func(http.ResponseWriter, interface{}, int, http.Header)
 }
@@ -103,13 +121,98 @@ func (s *server) router() (http.Handler, error) {
 		}, http.StatusNotFound, nil)
 	})
 
-	r.Route("/resources", s.routerResource)
-	r.Route("/resources/{resourceId}/subscriptions", s.routerSubscription)
-	r.Route("/documents", s.routerDocument)
+	if s.metrics.route {
+		r.Get("/metrics", s.metrics.registry.ServeHTTP)
+	}
+
+	if s.handler.health != nil {
+		routerHealth(r, s.handler.health)
+	}
+
+	r.Group(func(r chi.Router) {
+		if s.auth.middleware != nil {
+			r.Use(s.auth.middleware.Handler)
+		}
+
+		// The routes without the namespace prefix are from the default namespace.
+		s.routerAPI(r)
+		r.Route("/namespaces/{namespace}", func(r chi.Router) {
+			r.Use(s.namespace)
+			s.routerAPI(r)
+		})
+	})
 
 	return r, nil
 }
 
+func (s *server) routerAPI(r chi.Router) {
+	// The streams are kept open, so they can't have the timeout and the compression.
+	stream := r.With(s.authorize(infraMiddleware.ScopeDocuments, s.authTarget("resourceId")))
+	stream.Get("/resources/{resourceId}/stream", s.handler.stream.HandleEvents)
+	stream.Get("/resources/{resourceId}/stream/websocket", s.handler.stream.HandleWebSocket)
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.DefaultCompress)
+		r.Use(middleware.Timeout(s.middleware.timeout))
+
+		r.Route("/resources", s.routerResource)
+		r.Route("/resources/{resourceId}/subscriptions", s.routerSubscription)
+		r.With(
+			s.authorize(infraMiddleware.ScopeDocuments, s.authTarget("resourceId")),
+		).Get("/resources/{resourceId}/changes", s.handler.change.HandleIndex)
+
+		// Each entry of the batch is authorized by the document service.
+		r.With(
+			s.authorize(infraMiddleware.ScopeDocuments, nil),
+		).Post("/documents:batch", s.handler.document.HandleBatch)
+		r.Route("/documents", s.routerDocument)
+	})
+}
+
+// namespace scope the requests to the namespace at the URL.
+func (s *server) namespace(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		namespace := chi.URLParam(r, "namespace")
+		if err := flare.ValidNamespace(namespace); err != nil {
+			s.writeResponse(w, map[string]interface{}{
+				"error": map[string]interface{}{
+					"status": http.StatusBadRequest,
+					"title":  "invalid namespace",
+					"detail": err.Error(),
+				},
+			}, http.StatusBadRequest, nil)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(flare.ContextWithNamespace(r.Context(), namespace)))
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// namespacePath returns the prefix of the namespace routes. The default namespace don't have it.
+func namespacePath(namespace string) string {
+	if namespace == flare.NamespaceDefault {
+		return ""
+	}
+	return "/namespaces/" + namespace
+}
+
+// authorize returns a middleware to check the scope of the requests, when the authentication is
+// disabled, the requests are not checked.
+func (s *server) authorize(
+	scope string, target infraMiddleware.AuthTargetFunc,
+) func(http.Handler) http.Handler {
+	if s.auth.middleware == nil {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return s.auth.middleware.Require(scope, target)
+}
+
+func (s *server) authTarget(param string) infraMiddleware.AuthTargetFunc {
+	return authTargetResource(s.auth.resourceRepository, param)
+}
+
 func (s *server) initMiddleware(r chi.Router) error {
 	logger := infraMiddleware.NewLog(s.logger)
 	writer, err := infraHTTP.NewWriter(s.logger)
@@ -123,31 +226,68 @@ func (s *server) initMiddleware(r chi.Router) error {
 	}
 
 	r.Use(recoverMiddleware.Handler)
-	r.Use(middleware.DefaultCompress)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.RequestID)
 	r.Use(middleware.StripSlashes)
-	r.Use(middleware.Timeout(s.middleware.timeout))
+
+	tracer := infraMiddleware.NewTrace(s.tracer)
+	r.Use(tracer.Handler)
+
+	if s.metrics.registry != nil {
+		metrics := infraMiddleware.NewMetrics(s.metrics.registry)
+		r.Use(metrics.Handler)
+	}
 	r.Use(logger.Handler)
 
 	return nil
 }
 
+// routerHealth register the health endpoints, it's shared by the main and the admin servers.
+func routerHealth(r chi.Router, handler *health.Service) {
+	r.Get("/healthz", handler.HandleLive)
+	r.Get("/readyz", handler.HandleReady)
+	r.Get("/status", handler.HandleStatus)
+}
+
 func (s *server) routerResource(r chi.Router) {
+	r = r.With(s.authorize(infraMiddleware.ScopeResources, s.authTarget("id")))
 	r.Get("/", s.handler.resource.HandleIndex)
 	r.Post("/", s.handler.resource.HandleCreate)
 	r.Get("/{id}", s.handler.resource.HandleShow)
+	r.Put("/{id}", s.handler.resource.HandleUpdate)
+	r.Patch("/{id}", s.handler.resource.HandleUpdate)
 	r.Delete("/{id}", s.handler.resource.HandleDelete)
 }
 
 func (s *server) routerSubscription(r chi.Router) {
+	r = r.With(s.authorize(infraMiddleware.ScopeSubscriptions, s.authTarget("resourceId")))
 	r.Get("/", s.handler.subscription.HandleIndex)
 	r.Post("/", s.handler.subscription.HandleCreate)
 	r.Get("/{id}", s.handler.subscription.HandleShow)
+	r.Put("/{id}", s.handler.subscription.HandleUpdate)
+	r.Patch("/{id}", s.handler.subscription.HandleUpdate)
 	r.Delete("/{id}", s.handler.subscription.HandleDelete)
+	r.Put("/{id}/secret", s.handler.subscription.HandleSecret)
+	r.Route("/{id}/dead-letters", s.routerDeadLetter)
+	r.Get("/{id}/deliveries", s.handler.delivery.HandleIndex)
+}
+
+func (s *server) routerDeadLetter(r chi.Router) {
+	r.Get("/", s.handler.deadLetter.HandleIndex)
+	r.Delete("/", s.handler.deadLetter.HandlePurge)
+	r.Get("/{deadLetterId}", s.handler.deadLetter.HandleShow)
+	r.Delete("/{deadLetterId}", s.handler.deadLetter.HandleDelete)
+	r.Post("/{deadLetterId}/replay", s.handler.deadLetter.HandleReplay)
 }
 
 func (s *server) routerDocument(r chi.Router) {
+	r = r.With(s.authorize(
+		infraMiddleware.ScopeDocuments,
+		func(req *http.Request) (*infraMiddleware.AuthTarget, error) {
+			id := chi.URLParam(req, "*")
+			return authTargetDocument(req.Context(), s.auth.resourceRepository, id)
+		},
+	))
 	r.Get("/*", s.handler.document.HandleShow)
 	r.Put("/*", s.handler.document.HandleUpdate)
 	r.Delete("/*", s.handler.document.HandleDelete)
@@ -172,6 +312,22 @@ func newServer(options ...func(*server)) (*server, error) {
 		return nil, errors.New("missing handler.subscription")
 	}
 
+	if s.handler.deadLetter == nil {
+		return nil, errors.New("missing handler.deadLetter")
+	}
+
+	if s.handler.delivery == nil {
+		return nil, errors.New("missing handler.delivery")
+	}
+
+	if s.handler.stream == nil {
+		return nil, errors.New("missing handler.stream")
+	}
+
+	if s.handler.change == nil {
+		return nil, errors.New("missing handler.change")
+	}
+
 	if s.handler.document == nil {
 		return nil, errors.New("missing handler.document")
 	}
@@ -180,6 +336,10 @@ func newServer(options ...func(*server)) (*server, error) {
 		return nil, errors.New("missing logger")
 	}
 
+	if s.auth.middleware != nil && s.auth.resourceRepository == nil {
+		return nil, errors.New("missing auth.resourceRepository")
+	}
+
 	s.writeResponse = infraHTTP.WriteResponse(s.logger)
 	return s, nil
 }
@@ -196,10 +356,32 @@ func serverHandlerSubscription(handler *subscription.Service) func(*server) {
 	return func(s *server) { s.handler.subscription = handler }
 }
 
+func serverHandlerDeadLetter(handler *subscription.DeadLetterService) func(*server) {
+	return func(s *server) { s.handler.deadLetter = handler }
+}
+
+func serverHandlerDelivery(handler *subscription.DeliveryService) func(*server) {
+	return func(s *server) { s.handler.delivery = handler }
+}
+
+func serverHandlerStream(handler *subscription.StreamService) func(*server) {
+	return func(s *server) { s.handler.stream = handler }
+}
+
+func serverHandlerChange(handler *document.ChangeService) func(*server) {
+	return func(s *server) { s.handler.change = handler }
+}
+
 func serverHandlerDocument(handler *document.Service) func(*server) {
 	return func(s *server) { s.handler.document = handler }
 }
 
+// serverHandlerHealth set the health endpoints at the main server, it's optional because the
+// endpoints can be served at the admin port.
+func serverHandlerHealth(handler *health.Service) func(*server) {
+	return func(s *server) { s.handler.health = handler }
+}
+
 func serverLogger(logger log.Logger) func(*server) {
 	return func(s *server) { s.logger = logger }
 }
@@ -207,3 +389,25 @@ func serverLogger(logger log.Logger) func(*server) {
 func serverMiddlewareTimeout(duration time.Duration) func(*server) {
 	return func(s *server) { s.middleware.timeout = duration }
 }
+
+// serverMetrics set the registry to record the requests. When route is set, the metrics are exposed
+// at /metrics.
+func serverMetrics(registry *infraMetrics.Registry, route bool) func(*server) {
+	return func(s *server) {
+		s.metrics.registry = registry
+		s.metrics.route = route
+	}
+}
+
+// serverAuth set the middleware to authenticate and authorize the requests. The repository is used
+// to check the grants restricted by resource or host.
+func serverAuth(auth *infraMiddleware.Auth, repository flare.ResourceRepositorier) func(*server) {
+	return func(s *server) {
+		s.auth.middleware = auth
+		s.auth.resourceRepository = repository
+	}
+}
+
+func serverTracer(tracer *trace.Tracer) func(*server) {
+	return func(s *server) { s.tracer = tracer }
+}
@@ -6,33 +6,232 @@ package flare
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/rand"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 )
 
 // Subscription is used to notify the clients about changes on documents.
 type Subscription struct {
 	ID        string
+	Namespace string
 	Endpoint  SubscriptionEndpoint
 	Delivery  SubscriptionDelivery
 	Resource  Resource
 	Data      map[string]interface{}
+	Filter    string
+	Actions   []string
+	Template  SubscriptionTemplate
+	Secret    SubscriptionSecret
 	CreatedAt time.Time
 }
 
-// SubscriptionEndpoint has the address information to notify the clients.
+// HasAction indicates if the subscription should be notified about the action. When the actions
+// are not set, all of them are notified.
+func (s *Subscription) HasAction(action string) bool {
+	if len(s.Actions) == 0 {
+		return true
+	}
+
+	for _, value := range s.Actions {
+		if value == action {
+			return true
+		}
+	}
+	return false
+}
+
+// SubscriptionTriggerAction wraps the fn of SubscriptionRepositorier.Trigger to skip the
+// subscriptions that don't want the action. The repositories still update the reference revisions,
+// this way, the later comparisons are correct.
+func SubscriptionTriggerAction(
+	fn func(context.Context, Subscription, string, *Document) error,
+) func(context.Context, Subscription, string, *Document) error {
+	return func(
+		ctx context.Context, subscription Subscription, action string, reference *Document,
+	) error {
+		if !subscription.HasAction(action) {
+			return nil
+		}
+		return fn(ctx, subscription, action, reference)
+	}
+}
+
+// SubscriptionTemplate is used to build the notification. When the Body is set, it replace the
+// default notification content, and the Headers values are rendered and sent with the request.
+type SubscriptionTemplate struct {
+	Body    string
+	Headers map[string]string
+}
+
+// Enabled indicates if the notification has a template.
+func (st *SubscriptionTemplate) Enabled() bool { return st.Body != "" || len(st.Headers) > 0 }
+
+// SubscriptionSecret is used to sign the notifications. During a rotation, the previous value is
+// still used to sign the notifications until it expires.
+type SubscriptionSecret struct {
+	Value            string
+	PreviousValue    string
+	PreviousExpireAt time.Time
+}
+
+// Enabled indicates if the notifications should be signed.
+func (ss *SubscriptionSecret) Enabled() bool { return ss.Value != "" }
+
+// Rotate replace the secret value and keep the current one valid for the overlap duration.
+func (ss *SubscriptionSecret) Rotate(value string, overlap time.Duration) {
+	if ss.Value != "" && overlap > 0 {
+		ss.PreviousValue = ss.Value
+		ss.PreviousExpireAt = time.Now().Add(overlap)
+	} else {
+		ss.PreviousValue = ""
+		ss.PreviousExpireAt = time.Time{}
+	}
+	ss.Value = value
+}
+
+// Sign generate a HMAC-SHA256 signature, encoded in hex, from the timestamp and the content with
+// each valid secret.
+func (ss *SubscriptionSecret) Sign(timestamp time.Time, content []byte) []string {
+	secrets := []string{ss.Value}
+	if ss.PreviousValue != "" && timestamp.Before(ss.PreviousExpireAt) {
+		secrets = append(secrets, ss.PreviousValue)
+	}
+
+	signatures := make([]string, len(secrets))
+	for i, secret := range secrets {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+		mac.Write([]byte("."))
+		mac.Write(content)
+		signatures[i] = hex.EncodeToString(mac.Sum(nil))
+	}
+	return signatures
+}
+
+// SubscriptionEndpoint has the address information to notify the clients. The Target is the kind
+// of endpoint, by default the notification is a HTTP request to the URL. The SQS target use the
+// Queue URL, the SNS target the Topic ARN, the Kafka target the URL of a Kafka REST Proxy and the
+// Topic, and the memory target the Topic as the sink name.
 type SubscriptionEndpoint struct {
+	Target  string
 	URL     url.URL
 	Method  string
 	Headers http.Header
+	Queue   string
+	Topic   string
+}
+
+// All kinds of targets a subscription can be delivered to.
+const (
+	SubscriptionTargetHTTP   = "http"
+	SubscriptionTargetSQS    = "sqs"
+	SubscriptionTargetSNS    = "sns"
+	SubscriptionTargetKafka  = "kafka"
+	SubscriptionTargetMemory = "memory"
+)
+
+// TargetKind return the kind of the target, the HTTP is the default.
+func (se *SubscriptionEndpoint) TargetKind() string {
+	if se.Target == "" {
+		return SubscriptionTargetHTTP
+	}
+	return se.Target
+}
+
+// Address return the destination of the notifications at the target.
+func (se *SubscriptionEndpoint) Address() string {
+	switch se.TargetKind() {
+	case SubscriptionTargetSQS:
+		return se.Queue
+	case SubscriptionTargetSNS, SubscriptionTargetMemory:
+		return se.Topic
+	case SubscriptionTargetKafka:
+		return se.URL.String() + "/topics/" + se.Topic
+	default:
+		return se.URL.String()
+	}
+}
+
+// SubscriptionTarget deliver the notifications to a kind of endpoint. The delivery should be
+// filled with the result of the attempt, like the status or the message id, and a error is
+// returned if the notification was not delivered.
+type SubscriptionTarget interface {
+	Deliver(
+		ctx context.Context,
+		subscription Subscription,
+		content []byte,
+		header http.Header,
+		delivery *Delivery,
+	) error
 }
 
 // SubscriptionDelivery is used to control whenever the notification can be considered successful
-// or not.
+// or not. When IncludeDocument is set, the notification has the document body and when Diff is
+// set, the update notifications have a JSON Patch with the changes from the previous revision.
 type SubscriptionDelivery struct {
-	Success []int
-	Discard []int
+	Success         []int
+	Discard         []int
+	Retry           SubscriptionDeliveryRetry
+	Limit           SubscriptionDeliveryLimit
+	IncludeDocument bool
+	Diff            bool
+}
+
+// SubscriptionDeliveryLimit control how hard the subscriber is hit. RequestsPerSecond is the max
+// rate of the deliveries and MaxInFlight the max deliveries being sent at the same time. The zero
+// values are unlimited.
+type SubscriptionDeliveryLimit struct {
+	RequestsPerSecond float64
+	MaxInFlight       int
+}
+
+// Enabled indicates if the deliveries are limited.
+func (sdl *SubscriptionDeliveryLimit) Enabled() bool {
+	return sdl.RequestsPerSecond > 0 || sdl.MaxInFlight > 0
+}
+
+// Interval returns the min time between the deliveries, zero if the rate is unlimited.
+func (sdl *SubscriptionDeliveryLimit) Interval() time.Duration {
+	if sdl.RequestsPerSecond <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / sdl.RequestsPerSecond)
+}
+
+// SubscriptionDeliveryRetry control how the failed deliveries are retried. The zero value disable
+// the retry and the error is returned to the queue.
+type SubscriptionDeliveryRetry struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Jitter         float64
+}
+
+// Enabled indicates if the retry policy is configured.
+func (sdr *SubscriptionDeliveryRetry) Enabled() bool { return sdr.MaxAttempts > 0 }
+
+// Backoff returns how long to wait before the next attempt. The backoff grows exponentially from
+// the InitialBackoff up to MaxBackoff and a random part, controlled by Jitter, is removed from it.
+func (sdr *SubscriptionDeliveryRetry) Backoff(attempt int) time.Duration {
+	backoff := sdr.InitialBackoff
+	for i := 1; i < attempt && backoff < sdr.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > sdr.MaxBackoff {
+		backoff = sdr.MaxBackoff
+	}
+
+	if sdr.Jitter > 0 {
+		backoff -= time.Duration(rand.Float64() * sdr.Jitter * float64(backoff))
+	}
+	return backoff
 }
 
 // All kinds of actions a subscription trigger supports.
@@ -46,17 +245,43 @@ const (
 type SubscriptionRepositorier interface {
 	FindAll(context.Context, *Pagination, string) ([]Subscription, *Pagination, error)
 	FindOne(ctx context.Context, resourceId, id string) (*Subscription, error)
+	// Create a subscription. When the context has a create limit, the create fails with
+	// LimitReached if the namespace already has the limit of subscriptions.
 	Create(context.Context, *Subscription) error
+	Update(context.Context, *Subscription) error
+	UpdateSecret(ctx context.Context, resourceId, id string, secret SubscriptionSecret) error
 	Delete(ctx context.Context, resourceId, id string) error
 	HasSubscription(ctx context.Context, resourceId string) (bool, error)
+
+	// Trigger call fn for each subscription that should be notified about the document change. On
+	// updates, the reference is the last document notified to the subscription.
 	Trigger(
 		ctx context.Context,
 		action string,
 		document *Document,
-		fn func(context.Context, Subscription, string) error,
+		fn func(
+			ctx context.Context, subscription Subscription, action string, reference *Document,
+		) error,
 	) error
 }
 
+// SubscriptionLimitRepositorier keep the state of the subscriptions delivery limits. The state is
+// shared by all the workers and, at the persistent repositories, by all the Flare instances.
+type SubscriptionLimitRepositorier interface {
+	// Acquire reserve a delivery to the subscription. When the limits don't allow the delivery, the
+	// lease is empty and the wait is the time until the next delivery is allowed, zero if unknown,
+	// like when the max in-flight deliveries is reached.
+	Acquire(
+		ctx context.Context, subscription Subscription,
+	) (lease string, wait time.Duration, err error)
+
+	// Release free the in-flight delivery reserved by Acquire.
+	Release(ctx context.Context, subscription Subscription, lease string) error
+
+	// Pause stop the deliveries to the subscription until the given time.
+	Pause(ctx context.Context, subscription Subscription, until time.Time) error
+}
+
 // SubscriptionTrigger is used to trigger the change on Documents.
 type SubscriptionTrigger interface {
 	Update(ctx context.Context, document *Document) error
@@ -67,4 +292,5 @@ type SubscriptionTrigger interface {
 type SubscriptionRepositoryError interface {
 	NotFound() bool
 	AlreadyExists() bool
+	LimitReached() bool
 }
@@ -0,0 +1,309 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subscription
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/diegobernardes/flare"
+	infraHTTP "github.com/diegobernardes/flare/infra/http"
+)
+
+type replayer interface {
+	Replay(context.Context, *flare.DeadLetter) error
+}
+
+type deadLetter flare.DeadLetter
+
+func (dl *deadLetter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Id       string `json:"id"`
+		Action   string `json:"action"`
+		Attempts int    `json:"attempts"`
+		Error    string `json:"error"`
+		Document struct {
+			Id               string      `json:"id"`
+			ChangeFieldValue interface{} `json:"changeFieldValue"`
+		} `json:"document"`
+		CreatedAt string `json:"createdAt"`
+	}{
+		Id:       dl.ID,
+		Action:   dl.Action,
+		Attempts: dl.Attempts,
+		Error:    dl.Error,
+		Document: struct {
+			Id               string      `json:"id"`
+			ChangeFieldValue interface{} `json:"changeFieldValue"`
+		}{
+			Id:               dl.Document.Id,
+			ChangeFieldValue: dl.Document.ChangeFieldValue,
+		},
+		CreatedAt: dl.CreatedAt.Format(time.RFC3339),
+	})
+}
+
+type deadLetterResponse struct {
+	Pagination  *pagination
+	DeadLetters []deadLetter
+	DeadLetter  *deadLetter
+}
+
+func (r *deadLetterResponse) MarshalJSON() ([]byte, error) {
+	var result interface{}
+
+	if r.DeadLetter != nil {
+		result = r.DeadLetter
+	} else {
+		result = map[string]interface{}{"pagination": r.Pagination, "deadLetters": r.DeadLetters}
+	}
+
+	return json.Marshal(result)
+}
+
+func transformDeadLetter(dl *flare.DeadLetter) *deadLetter { return (*deadLetter)(dl) }
+
+func transformDeadLetters(dl []flare.DeadLetter) []deadLetter {
+	result := make([]deadLetter, len(dl))
+	for i := 0; i < len(dl); i++ {
+		result[i] = (deadLetter)(dl[i])
+	}
+	return result
+}
+
+// DeadLetterService implements the HTTP handler to manage the subscription dead letters.
+type DeadLetterService struct {
+	subscriptionRepository flare.SubscriptionRepositorier
+	deadLetterRepository   flare.DeadLetterRepositorier
+	replayer               replayer
+	getResourceID          func(*http.Request) string
+	getSubscriptionID      func(*http.Request) string
+	getDeadLetterID        func(*http.Request) string
+	writer                 *infraHTTP.Writer
+	parsePagination        func(r *http.Request) (*flare.Pagination, error)
+}
+
+// HandleIndex receive the request to list the dead letters of a subscription.
+func (s *DeadLetterService) HandleIndex(w http.ResponseWriter, r *http.Request) {
+	pag, err := s.parsePagination(r)
+	if err != nil {
+		s.writer.Error(w, "error during pagination parse", err, http.StatusBadRequest)
+		return
+	}
+
+	if err = pag.Valid(); err != nil {
+		s.writer.Error(w, "invalid pagination", err, http.StatusBadRequest)
+		return
+	}
+
+	resourceID, subscriptionID := s.getResourceID(r), s.getSubscriptionID(r)
+	if !s.findSubscription(w, r, resourceID, subscriptionID) {
+		return
+	}
+
+	dls, dlsPag, err := s.deadLetterRepository.FindAll(r.Context(), pag, resourceID, subscriptionID)
+	if err != nil {
+		s.writer.Error(w, "error during dead letters search", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.writer.Response(w, &deadLetterResponse{
+		DeadLetters: transformDeadLetters(dls),
+		Pagination:  transformPagination(dlsPag),
+	}, http.StatusOK, nil)
+}
+
+// HandleShow receive the request to show a dead letter.
+func (s *DeadLetterService) HandleShow(w http.ResponseWriter, r *http.Request) {
+	dl, ok := s.findDeadLetter(w, r)
+	if !ok {
+		return
+	}
+
+	s.writer.Response(w, &deadLetterResponse{DeadLetter: transformDeadLetter(dl)}, http.StatusOK, nil)
+}
+
+// HandleReplay receive the request to schedule a new delivery of a dead letter.
+func (s *DeadLetterService) HandleReplay(w http.ResponseWriter, r *http.Request) {
+	dl, ok := s.findDeadLetter(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.replayer.Replay(r.Context(), dl); err != nil {
+		s.writer.Error(w, "error during dead letter replay", err, http.StatusInternalServerError)
+		return
+	}
+
+	err := s.deadLetterRepository.Delete(
+		r.Context(), dl.Subscription.Resource.ID, dl.Subscription.ID, dl.ID,
+	)
+	if err != nil {
+		s.writer.Error(w, "error during dead letter delete", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.writer.Response(w, nil, http.StatusAccepted, nil)
+}
+
+// HandleDelete receive the request to delete a dead letter.
+func (s *DeadLetterService) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	err := s.deadLetterRepository.Delete(
+		r.Context(), s.getResourceID(r), s.getSubscriptionID(r), s.getDeadLetterID(r),
+	)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errRepo, ok := err.(flare.DeadLetterRepositoryError); ok && errRepo.NotFound() {
+			status = http.StatusNotFound
+		}
+
+		s.writer.Error(w, "error during dead letter delete", err, status)
+		return
+	}
+
+	s.writer.Response(w, nil, http.StatusNoContent, nil)
+}
+
+// HandlePurge receive the request to delete all the dead letters of a subscription.
+func (s *DeadLetterService) HandlePurge(w http.ResponseWriter, r *http.Request) {
+	resourceID, subscriptionID := s.getResourceID(r), s.getSubscriptionID(r)
+	if !s.findSubscription(w, r, resourceID, subscriptionID) {
+		return
+	}
+
+	if err := s.deadLetterRepository.DeleteAll(r.Context(), resourceID, subscriptionID); err != nil {
+		s.writer.Error(w, "error during dead letters delete", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.writer.Response(w, nil, http.StatusNoContent, nil)
+}
+
+func (s *DeadLetterService) findSubscription(
+	w http.ResponseWriter, r *http.Request, resourceID, subscriptionID string,
+) bool {
+	_, err := s.subscriptionRepository.FindOne(r.Context(), resourceID, subscriptionID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errRepo, ok := err.(flare.SubscriptionRepositoryError); ok && errRepo.NotFound() {
+			status = http.StatusNotFound
+		}
+
+		s.writer.Error(w, "error during subscription search", err, status)
+		return false
+	}
+	return true
+}
+
+func (s *DeadLetterService) findDeadLetter(
+	w http.ResponseWriter, r *http.Request,
+) (*flare.DeadLetter, bool) {
+	dl, err := s.deadLetterRepository.FindOne(
+		r.Context(), s.getResourceID(r), s.getSubscriptionID(r), s.getDeadLetterID(r),
+	)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errRepo, ok := err.(flare.DeadLetterRepositoryError); ok && errRepo.NotFound() {
+			status = http.StatusNotFound
+		}
+
+		s.writer.Error(w, "error during dead letter search", err, status)
+		return nil, false
+	}
+	return dl, true
+}
+
+// NewDeadLetterService initialize the service to handle HTTP Requests.
+func NewDeadLetterService(options ...func(*DeadLetterService)) (*DeadLetterService, error) {
+	service := &DeadLetterService{}
+
+	for _, option := range options {
+		option(service)
+	}
+
+	if service.subscriptionRepository == nil {
+		return nil, errors.New("subscriptionRepository not found")
+	}
+
+	if service.deadLetterRepository == nil {
+		return nil, errors.New("deadLetterRepository not found")
+	}
+
+	if service.replayer == nil {
+		return nil, errors.New("replayer not found")
+	}
+
+	if service.getResourceID == nil {
+		return nil, errors.New("getResourceID not found")
+	}
+
+	if service.getSubscriptionID == nil {
+		return nil, errors.New("getSubscriptionID not found")
+	}
+
+	if service.getDeadLetterID == nil {
+		return nil, errors.New("getDeadLetterID not found")
+	}
+
+	if service.parsePagination == nil {
+		return nil, errors.New("parsePagination not found")
+	}
+
+	if service.writer == nil {
+		return nil, errors.New("writer not found")
+	}
+
+	return service, nil
+}
+
+// DeadLetterServiceSubscriptionRepository set the repository to access the subscriptions.
+func DeadLetterServiceSubscriptionRepository(
+	repo flare.SubscriptionRepositorier,
+) func(*DeadLetterService) {
+	return func(s *DeadLetterService) { s.subscriptionRepository = repo }
+}
+
+// DeadLetterServiceDeadLetterRepository set the repository to access the dead letters.
+func DeadLetterServiceDeadLetterRepository(
+	repo flare.DeadLetterRepositorier,
+) func(*DeadLetterService) {
+	return func(s *DeadLetterService) { s.deadLetterRepository = repo }
+}
+
+// DeadLetterServiceReplayer set the replayer used to schedule a new delivery of a dead letter.
+func DeadLetterServiceReplayer(r replayer) func(*DeadLetterService) {
+	return func(s *DeadLetterService) { s.replayer = r }
+}
+
+// DeadLetterServiceGetResourceID set the function to fetch the resourceId from the URL.
+func DeadLetterServiceGetResourceID(fn func(*http.Request) string) func(*DeadLetterService) {
+	return func(s *DeadLetterService) { s.getResourceID = fn }
+}
+
+// DeadLetterServiceGetSubscriptionID set the function to fetch the subscriptionId from the URL.
+func DeadLetterServiceGetSubscriptionID(fn func(*http.Request) string) func(*DeadLetterService) {
+	return func(s *DeadLetterService) { s.getSubscriptionID = fn }
+}
+
+// DeadLetterServiceGetDeadLetterID set the function to fetch the deadLetterId from the URL.
+func DeadLetterServiceGetDeadLetterID(fn func(*http.Request) string) func(*DeadLetterService) {
+	return func(s *DeadLetterService) { s.getDeadLetterID = fn }
+}
+
+// DeadLetterServiceParsePagination set the function used to parse the pagination.
+func DeadLetterServiceParsePagination(
+	fn func(r *http.Request) (*flare.Pagination, error),
+) func(*DeadLetterService) {
+	return func(s *DeadLetterService) { s.parsePagination = fn }
+}
+
+// DeadLetterServiceWriter set the function that return the content to client.
+func DeadLetterServiceWriter(writer *infraHTTP.Writer) func(*DeadLetterService) {
+	return func(s *DeadLetterService) { s.writer = writer }
+}
@@ -0,0 +1,246 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subscription
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/diegobernardes/flare"
+	infraHTTP "github.com/diegobernardes/flare/infra/http"
+)
+
+type delivery flare.Delivery
+
+func (d *delivery) MarshalJSON() ([]byte, error) {
+	type document struct {
+		Id               string      `json:"id"`
+		ChangeFieldValue interface{} `json:"changeFieldValue"`
+	}
+
+	return json.Marshal(&struct {
+		Id           string   `json:"id"`
+		Action       string   `json:"action"`
+		Target       string   `json:"target,omitempty"`
+		Attempt      int      `json:"attempt"`
+		Status       int      `json:"status,omitempty"`
+		Latency      string   `json:"latency"`
+		Error        string   `json:"error,omitempty"`
+		ResponseBody string   `json:"responseBody,omitempty"`
+		MessageID    string   `json:"messageId,omitempty"`
+		Document     document `json:"document"`
+		CreatedAt    string   `json:"createdAt"`
+	}{
+		Id:           d.ID,
+		Action:       d.Action,
+		Target:       d.Target,
+		Attempt:      d.Attempt,
+		Status:       d.Status,
+		Latency:      d.Latency.String(),
+		Error:        d.Error,
+		ResponseBody: d.ResponseBody,
+		MessageID:    d.MessageID,
+		Document:     document{Id: d.Document.Id, ChangeFieldValue: d.Document.ChangeFieldValue},
+		CreatedAt:    d.CreatedAt.Format(time.RFC3339),
+	})
+}
+
+type deliveryResponse struct {
+	Pagination *pagination
+	Deliveries []delivery
+}
+
+func (r *deliveryResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"pagination": r.Pagination,
+		"deliveries": r.Deliveries,
+	})
+}
+
+func transformDeliveries(d []flare.Delivery) []delivery {
+	result := make([]delivery, len(d))
+	for i := 0; i < len(d); i++ {
+		result[i] = (delivery)(d[i])
+	}
+	return result
+}
+
+// DeliveryService implements the HTTP handler to show the delivery attempts of a subscription.
+type DeliveryService struct {
+	subscriptionRepository flare.SubscriptionRepositorier
+	deliveryRepository     flare.DeliveryRepositorier
+	getResourceID          func(*http.Request) string
+	getSubscriptionID      func(*http.Request) string
+	writer                 *infraHTTP.Writer
+	parsePagination        func(r *http.Request) (*flare.Pagination, error)
+}
+
+// HandleIndex receive the request to list the deliveries of a subscription.
+func (s *DeliveryService) HandleIndex(w http.ResponseWriter, r *http.Request) {
+	pag, err := s.parsePagination(r)
+	if err != nil {
+		s.writer.Error(w, "error during pagination parse", err, http.StatusBadRequest)
+		return
+	}
+
+	if err = pag.Valid(); err != nil {
+		s.writer.Error(w, "invalid pagination", err, http.StatusBadRequest)
+		return
+	}
+
+	filter, err := s.parseFilter(r)
+	if err != nil {
+		s.writer.Error(w, "invalid filter", err, http.StatusBadRequest)
+		return
+	}
+
+	resourceID, subscriptionID := s.getResourceID(r), s.getSubscriptionID(r)
+	_, err = s.subscriptionRepository.FindOne(r.Context(), resourceID, subscriptionID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errRepo, ok := err.(flare.SubscriptionRepositoryError); ok && errRepo.NotFound() {
+			status = http.StatusNotFound
+		}
+
+		s.writer.Error(w, "error during subscription search", err, status)
+		return
+	}
+
+	deliveries, deliveriesPag, err := s.deliveryRepository.FindAll(
+		r.Context(), pag, resourceID, subscriptionID, filter,
+	)
+	if err != nil {
+		s.writer.Error(w, "error during deliveries search", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.writer.Response(w, &deliveryResponse{
+		Deliveries: transformDeliveries(deliveries),
+		Pagination: transformPagination(deliveriesPag),
+	}, http.StatusOK, nil)
+}
+
+func (s *DeliveryService) parseFilter(r *http.Request) (*flare.DeliveryFilter, error) {
+	var (
+		filter flare.DeliveryFilter
+		query  = r.URL.Query()
+	)
+
+	for _, rawStatus := range query["status"] {
+		status, err := strconv.Atoi(rawStatus)
+		if err != nil {
+			return nil, errors.Wrapf(
+				err, "error during parameter 'status' parse with value '%s'", rawStatus,
+			)
+		}
+		filter.Status = append(filter.Status, status)
+	}
+
+	parseTime := func(key string) (time.Time, error) {
+		rawValue := query.Get(key)
+		if rawValue == "" {
+			return time.Time{}, nil
+		}
+
+		value, err := time.Parse(time.RFC3339, rawValue)
+		if err != nil {
+			return time.Time{}, errors.Wrapf(
+				err, "error during parameter '%s' parse with value '%s'", key, rawValue,
+			)
+		}
+		return value, nil
+	}
+
+	var err error
+	if filter.From, err = parseTime("from"); err != nil {
+		return nil, err
+	}
+
+	if filter.To, err = parseTime("to"); err != nil {
+		return nil, err
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return nil, fmt.Errorf(
+			"parameter 'to' '%s' is before 'from' '%s'", query.Get("to"), query.Get("from"),
+		)
+	}
+
+	return &filter, nil
+}
+
+// NewDeliveryService initialize the service to handle HTTP Requests.
+func NewDeliveryService(options ...func(*DeliveryService)) (*DeliveryService, error) {
+	service := &DeliveryService{}
+
+	for _, option := range options {
+		option(service)
+	}
+
+	if service.subscriptionRepository == nil {
+		return nil, errors.New("subscriptionRepository not found")
+	}
+
+	if service.deliveryRepository == nil {
+		return nil, errors.New("deliveryRepository not found")
+	}
+
+	if service.getResourceID == nil {
+		return nil, errors.New("getResourceID not found")
+	}
+
+	if service.getSubscriptionID == nil {
+		return nil, errors.New("getSubscriptionID not found")
+	}
+
+	if service.parsePagination == nil {
+		return nil, errors.New("parsePagination not found")
+	}
+
+	if service.writer == nil {
+		return nil, errors.New("writer not found")
+	}
+
+	return service, nil
+}
+
+// DeliveryServiceSubscriptionRepository set the repository to access the subscriptions.
+func DeliveryServiceSubscriptionRepository(
+	repo flare.SubscriptionRepositorier,
+) func(*DeliveryService) {
+	return func(s *DeliveryService) { s.subscriptionRepository = repo }
+}
+
+// DeliveryServiceDeliveryRepository set the repository to access the deliveries.
+func DeliveryServiceDeliveryRepository(repo flare.DeliveryRepositorier) func(*DeliveryService) {
+	return func(s *DeliveryService) { s.deliveryRepository = repo }
+}
+
+// DeliveryServiceGetResourceID set the function to fetch the resourceId from the URL.
+func DeliveryServiceGetResourceID(fn func(*http.Request) string) func(*DeliveryService) {
+	return func(s *DeliveryService) { s.getResourceID = fn }
+}
+
+// DeliveryServiceGetSubscriptionID set the function to fetch the subscriptionId from the URL.
+func DeliveryServiceGetSubscriptionID(fn func(*http.Request) string) func(*DeliveryService) {
+	return func(s *DeliveryService) { s.getSubscriptionID = fn }
+}
+
+// DeliveryServiceParsePagination set the function used to parse the pagination.
+func DeliveryServiceParsePagination(
+	fn func(r *http.Request) (*flare.Pagination, error),
+) func(*DeliveryService) {
+	return func(s *DeliveryService) { s.parsePagination = fn }
+}
+
+// DeliveryServiceWriter set the function that return the content to client.
+func DeliveryServiceWriter(writer *infraHTTP.Writer) func(*DeliveryService) {
+	return func(s *DeliveryService) { s.writer = writer }
+}
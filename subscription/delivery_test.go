@@ -0,0 +1,68 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subscription
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/diegobernardes/flare"
+)
+
+func TestDeliveryServiceParseFilter(t *testing.T) {
+	Convey("Given a list of valid requests", t, func() {
+		tests := []struct {
+			uri    string
+			filter *flare.DeliveryFilter
+		}{
+			{
+				"http://resources/123/subscriptions/456/deliveries",
+				&flare.DeliveryFilter{},
+			},
+			{
+				"http://resources/123/subscriptions/456/deliveries?status=500&status=502",
+				&flare.DeliveryFilter{Status: []int{500, 502}},
+			},
+			{
+				"http://resources/123/subscriptions/456/deliveries?from=2017-11-10T00:00:00Z" +
+					"&to=2017-11-11T00:00:00Z",
+				&flare.DeliveryFilter{
+					From: time.Date(2017, time.November, 10, 0, 0, 0, 0, time.UTC),
+					To:   time.Date(2017, time.November, 11, 0, 0, 0, 0, time.UTC),
+				},
+			},
+		}
+
+		Convey("The output should be valid", func() {
+			var s DeliveryService
+			for _, tt := range tests {
+				filter, err := s.parseFilter(httptest.NewRequest("GET", tt.uri, nil))
+				So(err, ShouldBeNil)
+				So(filter, ShouldResemble, tt.filter)
+			}
+		})
+	})
+
+	Convey("Given a list of invalid requests", t, func() {
+		tests := []string{
+			"http://resources/123/subscriptions/456/deliveries?status=sample",
+			"http://resources/123/subscriptions/456/deliveries?from=sample",
+			"http://resources/123/subscriptions/456/deliveries?to=sample",
+			"http://resources/123/subscriptions/456/deliveries?from=2017-11-11T00:00:00Z" +
+				"&to=2017-11-10T00:00:00Z",
+		}
+
+		Convey("The output should be invalid", func() {
+			var s DeliveryService
+			for _, tt := range tests {
+				_, err := s.parseFilter(httptest.NewRequest("GET", tt, nil))
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
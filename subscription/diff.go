@@ -0,0 +1,103 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subscription
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// The JSON Patch, RFC 6902, operations generated by the diff.
+const (
+	patchOperationAdd     = "add"
+	patchOperationRemove  = "remove"
+	patchOperationReplace = "replace"
+)
+
+type patchOperation struct {
+	Op    string
+	Path  string
+	Value interface{}
+}
+
+func (p patchOperation) MarshalJSON() ([]byte, error) {
+	content := map[string]interface{}{"op": p.Op, "path": p.Path}
+	if p.Op != patchOperationRemove {
+		content["value"] = p.Value
+	}
+	return json.Marshal(content)
+}
+
+var patchPathEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// diff generate the JSON Patch to transform the document from into to. The objects are compared
+// field by field and any other value, including arrays, is replaced as a whole.
+func diff(from, to map[string]interface{}) []patchOperation {
+	return diffObject("", from, to, make([]patchOperation, 0))
+}
+
+func diffObject(
+	path string, from, to map[string]interface{}, result []patchOperation,
+) []patchOperation {
+	keys := make([]string, 0, len(from)+len(to))
+	for key := range from {
+		keys = append(keys, key)
+	}
+	for key := range to {
+		if _, ok := from[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		keyPath := path + "/" + patchPathEscaper.Replace(key)
+		fromValue, fromOK := from[key]
+		toValue, toOK := to[key]
+
+		switch {
+		case !toOK:
+			result = append(result, patchOperation{Op: patchOperationRemove, Path: keyPath})
+		case !fromOK:
+			result = append(result, patchOperation{Op: patchOperationAdd, Path: keyPath, Value: toValue})
+		default:
+			result = diffValue(keyPath, fromValue, toValue, result)
+		}
+	}
+	return result
+}
+
+func diffValue(path string, from, to interface{}, result []patchOperation) []patchOperation {
+	fromObject, fromOK := diffToObject(from)
+	toObject, toOK := diffToObject(to)
+	if fromOK && toOK {
+		return diffObject(path, fromObject, toObject, result)
+	}
+
+	if reflect.DeepEqual(from, to) {
+		return result
+	}
+	return append(result, patchOperation{Op: patchOperationReplace, Path: path, Value: to})
+}
+
+// diffToObject is used because the repositories can return objects with different map types.
+func diffToObject(value interface{}) (map[string]interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+
+	result := make(map[string]interface{}, rv.Len())
+	for _, key := range rv.MapKeys() {
+		result[key.String()] = rv.MapIndex(key).Interface()
+	}
+	return result, true
+}
@@ -0,0 +1,74 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subscription
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDiff(t *testing.T) {
+	Convey("Given a list of documents", t, func() {
+		tests := []struct {
+			title  string
+			from   string
+			to     string
+			output string
+		}{
+			{
+				"It should not have changes",
+				`{"name":"Diego","tags":["a","b"]}`,
+				`{"name":"Diego","tags":["a","b"]}`,
+				`[]`,
+			},
+			{
+				"It should have a added, removed and changed field",
+				`{"name":"Diego","age":27}`,
+				`{"name":"Bernardes","city":"Rio"}`,
+				`[
+					{"op":"remove","path":"/age"},
+					{"op":"add","path":"/city","value":"Rio"},
+					{"op":"replace","path":"/name","value":"Bernardes"}
+				]`,
+			},
+			{
+				"It should compare the nested objects and replace the arrays",
+				`{"address":{"city":"Rio","zip":null},"tags":["a"]}`,
+				`{"address":{"city":"Rio","zip":0},"tags":["a","b"]}`,
+				`[
+					{"op":"replace","path":"/address/zip","value":0},
+					{"op":"replace","path":"/tags","value":["a","b"]}
+				]`,
+			},
+			{
+				"It should escape the path",
+				`{"a/b":1,"c~d":1}`,
+				`{"a/b":2}`,
+				`[
+					{"op":"replace","path":"/a~1b","value":2},
+					{"op":"remove","path":"/c~0d"}
+				]`,
+			},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				from, to := make(map[string]interface{}), make(map[string]interface{})
+				So(json.Unmarshal([]byte(tt.from), &from), ShouldBeNil)
+				So(json.Unmarshal([]byte(tt.to), &to), ShouldBeNil)
+
+				content, err := json.Marshal(diff(from, to))
+				So(err, ShouldBeNil)
+
+				var result, expected []interface{}
+				So(json.Unmarshal(content, &result), ShouldBeNil)
+				So(json.Unmarshal([]byte(tt.output), &expected), ShouldBeNil)
+				So(result, ShouldResemble, expected)
+			})
+		}
+	})
+}
@@ -0,0 +1,514 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subscription
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// contentFilter is a compiled subscription filter. The filters are expressions evaluated against
+// the document body, for example:
+//
+//	status == "active" && country in ["BR", "US"]
+//	exists($.address.city) || !(age < 18)
+//
+// The fields are referenced by a path with the keys separated by dots, optionally prefixed by the
+// JSONPath root "$.". The operators are ==, !=, <, <=, >, >=, in, exists, &&, || and !. The values
+// can be strings, numbers, booleans, null and lists of them.
+type contentFilter struct {
+	expression string
+	root       filterNode
+}
+
+// match indicates if the document body match the filter.
+func (f *contentFilter) match(content map[string]interface{}) bool {
+	return f.root.eval(content)
+}
+
+type filterNode interface {
+	eval(map[string]interface{}) bool
+}
+
+type filterAnd struct{ left, right filterNode }
+
+func (n *filterAnd) eval(c map[string]interface{}) bool { return n.left.eval(c) && n.right.eval(c) }
+
+type filterOr struct{ left, right filterNode }
+
+func (n *filterOr) eval(c map[string]interface{}) bool { return n.left.eval(c) || n.right.eval(c) }
+
+type filterNot struct{ node filterNode }
+
+func (n *filterNot) eval(c map[string]interface{}) bool { return !n.node.eval(c) }
+
+type filterExists struct{ path []string }
+
+func (n *filterExists) eval(c map[string]interface{}) bool {
+	_, ok := filterLookup(c, n.path)
+	return ok
+}
+
+type filterCompare struct {
+	path     []string
+	operator string
+	value    interface{}
+}
+
+func (n *filterCompare) eval(c map[string]interface{}) bool {
+	value, ok := filterLookup(c, n.path)
+	if !ok {
+		return n.operator == "!="
+	}
+
+	switch n.operator {
+	case "==":
+		return filterEqual(value, n.value)
+	case "!=":
+		return !filterEqual(value, n.value)
+	case "in":
+		for _, option := range n.value.([]interface{}) {
+			if filterEqual(value, option) {
+				return true
+			}
+		}
+		return false
+	}
+
+	result, ok := filterCompareValues(value, n.value)
+	if !ok {
+		return false
+	}
+
+	switch n.operator {
+	case "<":
+		return result < 0
+	case "<=":
+		return result <= 0
+	case ">":
+		return result > 0
+	default:
+		return result >= 0
+	}
+}
+
+func filterLookup(content map[string]interface{}, path []string) (interface{}, bool) {
+	var current interface{} = content
+	for _, key := range path {
+		object, ok := diffToObject(current)
+		if !ok {
+			return nil, false
+		}
+
+		current, ok = object[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func filterEqual(a, b interface{}) bool {
+	if result, ok := filterCompareValues(a, b); ok {
+		return result == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// filterCompareValues compare numbers and strings. The second return indicates if the values are
+// comparable.
+func filterCompareValues(a, b interface{}) (int, bool) {
+	aNumber, aOK := filterNumber(a)
+	bNumber, bOK := filterNumber(b)
+	if aOK && bOK {
+		switch {
+		case aNumber < bNumber:
+			return -1, true
+		case aNumber > bNumber:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	aString, aOK := a.(string)
+	bString, bOK := b.(string)
+	if aOK && bOK {
+		return strings.Compare(aString, bString), true
+	}
+	return 0, false
+}
+
+func filterNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// compileFilter parse the expression into a contentFilter.
+func compileFilter(expression string) (*contentFilter, error) {
+	tokens, err := filterTokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected '%s' at position %d", p.peek().value, p.peek().position)
+	}
+	return &contentFilter{expression: expression, root: root}, nil
+}
+
+const (
+	filterTokenPath = iota
+	filterTokenString
+	filterTokenNumber
+	filterTokenSymbol
+)
+
+// The symbols are ordered to match the longest first.
+var filterSymbols = []string{
+	"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ",",
+}
+
+type filterToken struct {
+	kind     int
+	value    string
+	position int
+}
+
+func filterTokenize(expression string) ([]filterToken, error) {
+	var (
+		tokens []filterToken
+		runes  = []rune(expression)
+	)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			value, end, err := filterTokenizeString(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, filterToken{kind: filterTokenString, value: value, position: i})
+			i = end
+		case r == '-' || unicode.IsDigit(r):
+			end := i + 1
+			for end < len(runes) && filterNumberRune(runes[end]) {
+				end++
+			}
+			tokens = append(tokens, filterToken{
+				kind: filterTokenNumber, value: string(runes[i:end]), position: i,
+			})
+			i = end
+		case r == '$' || r == '_' || unicode.IsLetter(r):
+			end := i + 1
+			for end < len(runes) && filterPathRune(runes[end]) {
+				end++
+			}
+			tokens = append(tokens, filterToken{
+				kind: filterTokenPath, value: string(runes[i:end]), position: i,
+			})
+			i = end
+		default:
+			symbol := ""
+			for _, candidate := range filterSymbols {
+				if strings.HasPrefix(string(runes[i:]), candidate) {
+					symbol = candidate
+					break
+				}
+			}
+			if symbol == "" {
+				return nil, fmt.Errorf("unexpected character '%c' at position %d", r, i)
+			}
+			tokens = append(tokens, filterToken{kind: filterTokenSymbol, value: symbol, position: i})
+			i += len(symbol)
+		}
+	}
+
+	return tokens, nil
+}
+
+func filterTokenizeString(runes []rune, start int) (string, int, error) {
+	for end := start + 1; end < len(runes); end++ {
+		if runes[end] == '\\' {
+			end++
+			continue
+		}
+
+		if runes[end] == '"' {
+			value, err := strconv.Unquote(string(runes[start : end+1]))
+			if err != nil {
+				return "", 0, errors.Wrapf(err, "invalid string at position %d", start)
+			}
+			return value, end + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string at position %d", start)
+}
+
+func filterNumberRune(r rune) bool {
+	return r == '.' || r == 'e' || r == 'E' || unicode.IsDigit(r)
+}
+
+func filterPathRune(r rune) bool {
+	return r == '_' || r == '.' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+type filterParser struct {
+	tokens   []filterToken
+	position int
+}
+
+func (p *filterParser) done() bool { return p.position >= len(p.tokens) }
+
+func (p *filterParser) peek() filterToken {
+	if p.done() {
+		return filterToken{position: -1}
+	}
+	return p.tokens[p.position]
+}
+
+func (p *filterParser) next() (filterToken, error) {
+	if p.done() {
+		return filterToken{}, errors.New("unexpected end of filter")
+	}
+	token := p.tokens[p.position]
+	p.position++
+	return token, nil
+}
+
+func (p *filterParser) acceptSymbol(symbol string) bool {
+	token := p.peek()
+	if token.kind == filterTokenSymbol && token.value == symbol && !p.done() {
+		p.position++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) expectSymbol(symbol string) error {
+	if p.acceptSymbol(symbol) {
+		return nil
+	}
+
+	if p.done() {
+		return fmt.Errorf("expected '%s' at the end of filter", symbol)
+	}
+	token := p.peek()
+	return fmt.Errorf("expected '%s' at position %d, got '%s'", symbol, token.position, token.value)
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptSymbol("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &filterOr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptSymbol("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &filterAnd{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.acceptSymbol("!") {
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &filterNot{node: node}, nil
+	}
+
+	if p.acceptSymbol("(") {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+
+	return p.parseCondition()
+}
+
+func (p *filterParser) parseCondition() (filterNode, error) {
+	token, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if token.kind != filterTokenPath {
+		return nil, fmt.Errorf("expected a field at position %d, got '%s'", token.position, token.value)
+	}
+
+	if token.value == "exists" && p.acceptSymbol("(") {
+		pathToken, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if pathToken.kind != filterTokenPath {
+			return nil, fmt.Errorf("expected a field at position %d", pathToken.position)
+		}
+
+		path, err := filterPath(pathToken)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		return &filterExists{path: path}, nil
+	}
+
+	path, err := filterPath(token)
+	if err != nil {
+		return nil, err
+	}
+
+	operator, err := p.next()
+	if err != nil {
+		return nil, fmt.Errorf("missing operator after '%s'", token.value)
+	}
+
+	switch {
+	case operator.kind == filterTokenPath && operator.value == "in":
+		value, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return &filterCompare{path: path, operator: "in", value: value}, nil
+	case operator.kind == filterTokenSymbol:
+		switch operator.value {
+		case "==", "!=", "<", "<=", ">", ">=":
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			return &filterCompare{path: path, operator: operator.value, value: value}, nil
+		}
+	}
+
+	return nil, fmt.Errorf(
+		"invalid operator '%s' at position %d", operator.value, operator.position,
+	)
+}
+
+func (p *filterParser) parseList() ([]interface{}, error) {
+	if err := p.expectSymbol("["); err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, 0)
+	if p.acceptSymbol("]") {
+		return values, nil
+	}
+
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		if p.acceptSymbol("]") {
+			return values, nil
+		}
+		if err = p.expectSymbol(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *filterParser) parseValue() (interface{}, error) {
+	token, err := p.next()
+	if err != nil {
+		return nil, errors.New("missing value at the end of filter")
+	}
+
+	switch token.kind {
+	case filterTokenString:
+		return token.value, nil
+	case filterTokenNumber:
+		value, err := strconv.ParseFloat(token.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s' at position %d", token.value, token.position)
+		}
+		return value, nil
+	case filterTokenPath:
+		switch token.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid value '%s' at position %d", token.value, token.position)
+}
+
+func filterPath(token filterToken) ([]string, error) {
+	value := token.value
+	if strings.HasPrefix(value, "$") {
+		if !strings.HasPrefix(value, "$.") {
+			return nil, fmt.Errorf("invalid field '%s' at position %d", value, token.position)
+		}
+		value = value[2:]
+	}
+
+	path := strings.Split(value, ".")
+	for _, key := range path {
+		if key == "" {
+			return nil, fmt.Errorf("invalid field '%s' at position %d", token.value, token.position)
+		}
+	}
+	return path, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subscription
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCompileFilter(t *testing.T) {
+	Convey("Given a list of invalid filters", t, func() {
+		tests := []struct {
+			title      string
+			expression string
+		}{
+			{"Should be empty", ""},
+			{"Should be missing the value", `status ==`},
+			{"Should have a invalid operator", `status = "active"`},
+			{"Should have a unterminated string", `status == "active`},
+			{"Should have a unbalanced parenthesis", `(status == "active"`},
+			{"Should have a invalid list", `country in "BR"`},
+			{"Should have a invalid value", `status == active`},
+			{"Should have a invalid path", `$address == "Rio"`},
+			{"Should have a trailing token", `status == "active" "BR"`},
+			{"Should have a missing condition", `status == "active" &&`},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				_, err := compileFilter(tt.expression)
+				So(err, ShouldNotBeNil)
+			})
+		}
+	})
+}
+
+func TestContentFilterMatch(t *testing.T) {
+	Convey("Given a document", t, func() {
+		content := make(map[string]interface{})
+		err := json.Unmarshal([]byte(`{
+			"status": "active",
+			"country": "BR",
+			"age": 27,
+			"deleted": false,
+			"manager": null,
+			"address": {"city": "Rio", "zip": "22000"}
+		}`), &content)
+		So(err, ShouldBeNil)
+
+		tests := []struct {
+			expression string
+			match      bool
+		}{
+			{`status == "active"`, true},
+			{`status != "active"`, false},
+			{`status == "active" && country in ["BR", "US"]`, true},
+			{`status == "active" && country in ["US"]`, false},
+			{`country in ["US"] || age >= 18`, true},
+			{`age > 27`, false},
+			{`age <= 27.0`, true},
+			{`age < 30 && !(deleted == true)`, true},
+			{`manager == null`, true},
+			{`address.city == "Rio"`, true},
+			{`$.address.zip >= "20000"`, true},
+			{`exists($.address.city)`, true},
+			{`exists(address.street)`, false},
+			{`missing == "value"`, false},
+			{`missing != "value"`, true},
+			{`age == "27"`, false},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.expression, func() {
+				filter, err := compileFilter(tt.expression)
+				So(err, ShouldBeNil)
+				So(filter.match(content), ShouldEqual, tt.match)
+			})
+		}
+	})
+}
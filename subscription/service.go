@@ -5,8 +5,10 @@
 package subscription
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"net/http"
 
 	"github.com/diegobernardes/flare"
@@ -19,9 +21,15 @@ type Service struct {
 	subscriptionRepository flare.SubscriptionRepositorier
 	getResourceID          func(*http.Request) string
 	getSubscriptionID      func(*http.Request) string
-	getSubscriptionURI     func(string, string) string
+	getSubscriptionURI     func(namespace, resourceId, id string) string
 	writer                 *infraHTTP.Writer
 	parsePagination        func(r *http.Request) (*flare.Pagination, error)
+	quota                  quota
+}
+
+// quota returns the limits of a namespace.
+type quota interface {
+	Get(namespace string) flare.NamespaceQuota
 }
 
 // HandleIndex receive the request to list the subscriptions.
@@ -80,7 +88,8 @@ func (s *Service) HandleShow(w http.ResponseWriter, r *http.Request) {
 	s.writer.Response(w, transformSubscription(subs), http.StatusOK, nil)
 }
 
-// HandleCreate receive the request to create a subscription.
+// HandleCreate receive the request to create a subscription. The subscription belongs to the
+// namespace of the resource.
 func (s *Service) HandleCreate(w http.ResponseWriter, r *http.Request) {
 	var (
 		d       = json.NewDecoder(r.Body)
@@ -114,11 +123,20 @@ func (s *Service) HandleCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	result.Resource.ID = resource.ID
+	result.Namespace = flare.NamespaceFromContext(r.Context())
 
-	if err := s.subscriptionRepository.Create(r.Context(), result); err != nil {
+	err = s.subscriptionRepository.Create(s.quotaContext(r, result.Namespace), result)
+	if err != nil {
 		status := http.StatusInternalServerError
-		if errRepo, ok := err.(flare.SubscriptionRepositoryError); ok && errRepo.AlreadyExists() {
-			status = http.StatusConflict
+		if errRepo, ok := err.(flare.SubscriptionRepositoryError); ok {
+			if errRepo.AlreadyExists() {
+				status = http.StatusConflict
+			}
+
+			if errRepo.LimitReached() {
+				s.writer.Error(w, "quota exceeded", err, http.StatusForbidden)
+				return
+			}
 		}
 
 		s.writer.Error(w, "error during subscription create", err, status)
@@ -126,11 +144,133 @@ func (s *Service) HandleCreate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	header := make(http.Header)
-	header.Set("Location", s.getSubscriptionURI(result.Resource.ID, result.ID))
+	header.Set("Location", s.getSubscriptionURI(result.Namespace, result.Resource.ID, result.ID))
 	resp := &response{Subscription: transformSubscription(result)}
 	s.writer.Response(w, resp, http.StatusCreated, header)
 }
 
+// HandleUpdate receive the request to update a subscription. A PUT replace the whole subscription
+// and a PATCH only the fields present at the body, the maps present at the body are replaced as
+// a whole. The secret is kept when not present at the body.
+func (s *Service) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	current, err := s.subscriptionRepository.FindOne(
+		r.Context(), s.getResourceID(r), s.getSubscriptionID(r),
+	)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errRepo, ok := err.(flare.SubscriptionRepositoryError); ok && errRepo.NotFound() {
+			status = http.StatusNotFound
+		}
+
+		s.writer.Error(w, "error during subscription search", err, status)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.writer.Error(w, "error during body read", err, http.StatusBadRequest)
+		return
+	}
+
+	content := &subscriptionCreate{}
+	if r.Method == http.MethodPatch {
+		content = transformSubscriptionCreate(current)
+		if err = content.resetPatchMaps(body); err != nil {
+			s.writer.Error(w, "error during body parse", err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err = json.Unmarshal(body, content); err != nil {
+		s.writer.Error(w, "error during body parse", err, http.StatusBadRequest)
+		return
+	}
+
+	if err = content.valid(); err != nil {
+		s.writer.Error(w, "invalid body content", err, http.StatusBadRequest)
+		return
+	}
+
+	result, err := content.toFlareSubscription()
+	if err != nil {
+		s.writer.Error(w, "invalid subscription", err, http.StatusBadRequest)
+		return
+	}
+	result.ID = current.ID
+	result.Namespace = current.Namespace
+	result.Resource.ID = s.getResourceID(r)
+	result.Secret = current.Secret
+	if content.Secret != "" {
+		result.Secret.Rotate(content.Secret, 0)
+	}
+
+	if err := s.subscriptionRepository.Update(r.Context(), result); err != nil {
+		status := http.StatusInternalServerError
+		if errRepo, ok := err.(flare.SubscriptionRepositoryError); ok {
+			if errRepo.AlreadyExists() {
+				status = http.StatusConflict
+			} else if errRepo.NotFound() {
+				status = http.StatusNotFound
+			}
+		}
+
+		s.writer.Error(w, "error during subscription update", err, status)
+		return
+	}
+
+	s.writer.Response(w, &response{Subscription: transformSubscription(result)}, http.StatusOK, nil)
+}
+
+// HandleSecret receive the request to rotate the secret used to sign the notifications. The
+// previous secret stays valid during the overlap window.
+func (s *Service) HandleSecret(w http.ResponseWriter, r *http.Request) {
+	var (
+		d       = json.NewDecoder(r.Body)
+		content = &subscriptionSecretRotate{}
+	)
+
+	if err := d.Decode(content); err != nil {
+		s.writer.Error(w, "error during body parse", err, http.StatusBadRequest)
+		return
+	}
+
+	if err := content.valid(); err != nil {
+		s.writer.Error(w, "invalid body content", err, http.StatusBadRequest)
+		return
+	}
+
+	subs, err := s.subscriptionRepository.FindOne(
+		r.Context(), s.getResourceID(r), s.getSubscriptionID(r),
+	)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errRepo, ok := err.(flare.SubscriptionRepositoryError); ok && errRepo.NotFound() {
+			status = http.StatusNotFound
+		}
+
+		s.writer.Error(w, "error during subscription search", err, status)
+		return
+	}
+
+	overlap, _ := content.overlap()
+	subs.Secret.Rotate(content.Secret, overlap)
+
+	err = s.subscriptionRepository.UpdateSecret(
+		r.Context(), s.getResourceID(r), subs.ID, subs.Secret,
+	)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errRepo, ok := err.(flare.SubscriptionRepositoryError); ok && errRepo.NotFound() {
+			status = http.StatusNotFound
+		}
+
+		s.writer.Error(w, "error during subscription secret update", err, status)
+		return
+	}
+
+	s.writer.Response(w, transformSubscription(subs), http.StatusOK, nil)
+}
+
 // HandleDelete receive the request to delete a subscription.
 func (s *Service) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	err := s.subscriptionRepository.Delete(r.Context(), s.getResourceID(r), s.getSubscriptionID(r))
@@ -147,6 +287,14 @@ func (s *Service) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	s.writer.Response(w, nil, http.StatusNoContent, nil)
 }
 
+// quotaContext returns the context with the namespace subscriptions limit to the create.
+func (s *Service) quotaContext(r *http.Request, namespace string) context.Context {
+	if s.quota == nil {
+		return r.Context()
+	}
+	return flare.ContextWithCreateLimit(r.Context(), s.quota.Get(namespace).Subscriptions)
+}
+
 // NewService initialize the service to handle HTTP Requests.
 func NewService(options ...func(*Service)) (*Service, error) {
 	service := &Service{}
@@ -207,10 +355,15 @@ func ServiceGetSubscriptionID(fn func(*http.Request) string) func(*Service) {
 }
 
 // ServiceGetSubscriptionURI set the function to generate the URI or a given subscription.
-func ServiceGetSubscriptionURI(fn func(string, string) string) func(*Service) {
+func ServiceGetSubscriptionURI(fn func(namespace, resourceId, id string) string) func(*Service) {
 	return func(s *Service) { s.getSubscriptionURI = fn }
 }
 
+// ServiceQuota set the quotas of the namespaces. It's optional.
+func ServiceQuota(q quota) func(*Service) {
+	return func(s *Service) { s.quota = q }
+}
+
 // ServiceParsePagination set the function used to parse the pagination.
 func ServiceParsePagination(fn func(r *http.Request) (*flare.Pagination, error)) func(*Service) {
 	return func(s *Service) {
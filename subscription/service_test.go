@@ -35,7 +35,7 @@ func TestNewService(t *testing.T) {
 				ServiceResourceRepository(memory.NewResource()),
 				ServiceGetResourceID(func(*http.Request) string { return "" }),
 				ServiceGetSubscriptionID(func(*http.Request) string { return "" }),
-				ServiceGetSubscriptionURI(func(string, string) string { return "" }),
+				ServiceGetSubscriptionURI(func(string, string, string) string { return "" }),
 				ServiceParsePagination(infraHTTP.ParsePagination(30)),
 				ServiceWriter(writer),
 			},
@@ -75,14 +75,14 @@ func TestNewService(t *testing.T) {
 				ServiceResourceRepository(memory.NewResource()),
 				ServiceGetResourceID(func(*http.Request) string { return "" }),
 				ServiceGetSubscriptionID(func(*http.Request) string { return "" }),
-				ServiceGetSubscriptionURI(func(string, string) string { return "" }),
+				ServiceGetSubscriptionURI(func(string, string, string) string { return "" }),
 			},
 			{
 				ServiceSubscriptionRepository(memory.NewSubscription()),
 				ServiceResourceRepository(memory.NewResource()),
 				ServiceGetResourceID(func(*http.Request) string { return "" }),
 				ServiceGetSubscriptionID(func(*http.Request) string { return "" }),
-				ServiceGetSubscriptionURI(func(string, string) string { return "" }),
+				ServiceGetSubscriptionURI(func(string, string, string) string { return "" }),
 				ServiceParsePagination(infraHTTP.ParsePagination(30)),
 			},
 		}
@@ -218,7 +218,7 @@ func TestServiceHandleIndex(t *testing.T) {
 					ServiceResourceRepository(tt.resourceRepository),
 					ServiceGetResourceID(func(r *http.Request) string { return "123" }),
 					ServiceGetSubscriptionID(func(r *http.Request) string { return "" }),
-					ServiceGetSubscriptionURI(func(reId, subId string) string { return "" }),
+					ServiceGetSubscriptionURI(func(_, reId, subId string) string { return "" }),
 					ServiceParsePagination(infraHTTP.ParsePagination(30)),
 					ServiceWriter(writer),
 				)
@@ -275,7 +275,7 @@ func TestServiceHandleShow(t *testing.T) {
 					ServiceResourceRepository(tt.resourceRepository),
 					ServiceGetResourceID(func(r *http.Request) string { return "123" }),
 					ServiceGetSubscriptionID(func(r *http.Request) string { return "456" }),
-					ServiceGetSubscriptionURI(func(reId, subId string) string {
+					ServiceGetSubscriptionURI(func(_, reId, subId string) string {
 						return fmt.Sprintf("http://resources/%s/subscriptions/%s", reId, subId)
 					}),
 					ServiceParsePagination(infraHTTP.ParsePagination(30)),
@@ -334,7 +334,7 @@ func TestServiceHandleDelete(t *testing.T) {
 					ServiceResourceRepository(tt.resourceRepository),
 					ServiceGetResourceID(func(r *http.Request) string { return "123" }),
 					ServiceGetSubscriptionID(func(r *http.Request) string { return "456" }),
-					ServiceGetSubscriptionURI(func(reId, subId string) string { return "" }),
+					ServiceGetSubscriptionURI(func(_, reId, subId string) string { return "" }),
 					ServiceParsePagination(infraHTTP.ParsePagination(30)),
 					ServiceWriter(writer),
 				)
@@ -459,7 +459,7 @@ func TestServiceHandleCreate(t *testing.T) {
 					ServiceResourceRepository(tt.resourceRepository),
 					ServiceGetResourceID(func(r *http.Request) string { return "123" }),
 					ServiceGetSubscriptionID(func(r *http.Request) string { return "456" }),
-					ServiceGetSubscriptionURI(func(reId, subId string) string {
+					ServiceGetSubscriptionURI(func(_, reId, subId string) string {
 						return fmt.Sprintf("http://resources/%s/subscriptions/%s", reId, subId)
 					}),
 					ServiceParsePagination(infraHTTP.ParsePagination(30)),
@@ -470,4 +470,134 @@ func TestServiceHandleCreate(t *testing.T) {
 			})
 		}
 	})
+
+	Convey("Given a Service with a namespace that reached the subscriptions quota", t, func() {
+		writer, err := infraHTTP.NewWriter(log.NewNopLogger())
+		So(err, ShouldBeNil)
+
+		service, err := NewService(
+			ServiceSubscriptionRepository(test.NewSubscription(
+				test.SubscriptionLoadSliceByteSubscription(
+					infraTest.Load("serviceHandleIndex.inputSubscription.json"),
+				),
+			)),
+			ServiceResourceRepository(test.NewResource(
+				test.ResourceLoadSliceByteResource(infraTest.Load("serviceHandleCreate.resourceInput.json")),
+			)),
+			ServiceGetResourceID(func(r *http.Request) string { return "123" }),
+			ServiceGetSubscriptionID(func(r *http.Request) string { return "456" }),
+			ServiceGetSubscriptionURI(func(string, string, string) string { return "" }),
+			ServiceParsePagination(infraHTTP.ParsePagination(30)),
+			ServiceQuota(quotaMock{Subscriptions: 1}),
+			ServiceWriter(writer),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("The request should be forbidden", func() {
+			w := httptest.NewRecorder()
+			service.HandleCreate(w, httptest.NewRequest(
+				http.MethodPost,
+				"http://resources/123/subscriptions",
+				bytes.NewBuffer(infraTest.Load("serviceHandleCreate.input.json")),
+			))
+			So(w.Code, ShouldEqual, http.StatusForbidden)
+		})
+	})
+}
+
+type quotaMock flare.NamespaceQuota
+
+func (qm quotaMock) Get(string) flare.NamespaceQuota { return flare.NamespaceQuota(qm) }
+
+func TestServiceHandleUpdate(t *testing.T) {
+	Convey("Given a list of requests", t, func() {
+		tests := []struct {
+			title                  string
+			req                    *http.Request
+			status                 int
+			header                 http.Header
+			body                   []byte
+			subscriptionRepository flare.SubscriptionRepositorier
+		}{
+			{
+				"The response should be a subscription not found",
+				httptest.NewRequest(
+					http.MethodPut, "http://resources/123/subscriptions/456", bytes.NewBufferString("{}"),
+				),
+				http.StatusNotFound,
+				http.Header{"Content-Type": []string{"application/json"}},
+				infraTest.Load("serviceHandleShow.notFound.json"),
+				test.NewSubscription(),
+			},
+			{
+				"The request should have a invalid subscription",
+				httptest.NewRequest(
+					http.MethodPut, "http://resources/123/subscriptions/456", bytes.NewBufferString("{}"),
+				),
+				http.StatusBadRequest,
+				http.Header{"Content-Type": []string{"application/json"}},
+				infraTest.Load("serviceHandleUpdate.invalid.json"),
+				test.NewSubscription(
+					test.SubscriptionLoadSliceByteSubscription(
+						infraTest.Load("serviceHandleShow.valid.input.json"),
+					),
+				),
+			},
+			{
+				"The response should be a updated subscription",
+				httptest.NewRequest(
+					http.MethodPatch,
+					"http://resources/123/subscriptions/456",
+					bytes.NewBufferString(`{"endpoint":{"url":"http://app3.io/update"}}`),
+				),
+				http.StatusOK,
+				http.Header{"Content-Type": []string{"application/json"}},
+				infraTest.Load("serviceHandleUpdate.valid.json"),
+				test.NewSubscription(
+					test.SubscriptionDate(time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)),
+					test.SubscriptionLoadSliceByteSubscription(
+						infraTest.Load("serviceHandleShow.valid.input.json"),
+					),
+				),
+			},
+			{
+				"The response should be a subscription with the headers replaced",
+				httptest.NewRequest(
+					http.MethodPatch,
+					"http://resources/123/subscriptions/456",
+					bytes.NewBufferString(`{"endpoint":{"headers":{"X-Token":["123"]}}}`),
+				),
+				http.StatusOK,
+				http.Header{"Content-Type": []string{"application/json"}},
+				infraTest.Load("serviceHandleUpdate.replaceHeaders.json"),
+				test.NewSubscription(
+					test.SubscriptionDate(time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)),
+					test.SubscriptionLoadSliceByteSubscription(
+						infraTest.Load("serviceHandleShow.valid.input.json"),
+					),
+				),
+			},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				writer, err := infraHTTP.NewWriter(log.NewNopLogger())
+				So(err, ShouldBeNil)
+
+				service, err := NewService(
+					ServiceSubscriptionRepository(tt.subscriptionRepository),
+					ServiceResourceRepository(test.NewResource()),
+					ServiceGetResourceID(func(r *http.Request) string { return "123" }),
+					ServiceGetSubscriptionID(func(r *http.Request) string { return "456" }),
+					ServiceGetSubscriptionURI(func(_, reId, subId string) string {
+						return fmt.Sprintf("http://resources/%s/subscriptions/%s", reId, subId)
+					}),
+					ServiceParsePagination(infraHTTP.ParsePagination(30)),
+					ServiceWriter(writer),
+				)
+				So(err, ShouldBeNil)
+				httpTest.Runner(tt.status, tt.header, service.HandleUpdate, tt.req, tt.body)
+			})
+		}
+	})
 }
@@ -0,0 +1,481 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subscription
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/websocket"
+
+	"github.com/diegobernardes/flare"
+	infraHTTP "github.com/diegobernardes/flare/infra/http"
+)
+
+const (
+	// Quantity of events a listener can have waiting to be sent. When it's full, the listener is
+	// dropped and the client should reconnect with the last event id received.
+	streamListenerBuffer = 64
+
+	// Interval between the comments sent to keep the connections alive.
+	streamKeepAlive = 15 * time.Second
+
+	// Time a resource is still tracked after the last listener left, this way, the clients that
+	// reconnect don't lose the events in between.
+	streamIdleTimeout = time.Minute
+
+	// Quantity of document revisions kept per resource to detect the actions. When it's full, the
+	// least recently changed document is discarded and its next change is sent as a create.
+	streamRevisions = 10000
+)
+
+// Stream fan-out the document changes to the streaming clients. Like the subscriptions, the changes
+// are tracked per resource to detect the action and discard the older revisions. Only the resources
+// with listeners are tracked and the newest events of each one are kept at a buffer, this way, the
+// clients can reconnect without gaps.
+//
+// The stream is per process, it only receive the changes processed by the process itself. When
+// the tasks are shared by many instances, like with SQS, a client miss the changes processed by the
+// other instances and should use the change feed instead.
+//
+// The event ids have the format "<epoch>-<sequence>", the epoch change every time the process
+// start, so the clients from a previous process receive all the events at the buffer.
+type Stream struct {
+	mutex     sync.Mutex
+	epoch     string
+	size      int
+	resources map[string]*streamResource
+	now       func() time.Time
+}
+
+type streamResource struct {
+	sequence       int64
+	events         []streamEvent
+	revisions      map[string]*list.Element
+	revisionsOrder *list.List
+	listeners      map[*streamListener]struct{}
+	idleSince      time.Time
+}
+
+type streamEvent struct {
+	ID      string
+	Action  string
+	Content []byte
+}
+
+type streamListener struct {
+	events chan streamEvent
+}
+
+// Listening indicates if the resource has clients waiting for changes. The resources that just
+// lost the listeners are still listening until the idle timeout.
+func (s *Stream) Listening(resourceID string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	resource, ok := s.resources[resourceID]
+	return ok && !s.idle(resource)
+}
+
+// publish send the document change to the listeners. The content is generated with the action
+// detected from the last revision seen of the document.
+func (s *Stream) publish(
+	document *flare.Document,
+	kind string,
+	build func(document, reference *flare.Document, kind string) ([]byte, error),
+) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	resource, ok := s.resources[document.Resource.ID]
+	if !ok {
+		return nil
+	}
+	if s.idle(resource) {
+		delete(s.resources, document.Resource.ID)
+		return nil
+	}
+
+	reference, ok := resource.revision(document.Id)
+	switch {
+	case kind == flare.SubscriptionTriggerDelete:
+		if !ok {
+			return nil
+		}
+		resource.deleteRevision(document.Id)
+	case !ok:
+		kind = flare.SubscriptionTriggerCreate
+	default:
+		newer, err := document.Newer(&reference)
+		if err != nil {
+			return errors.Wrap(err, "error during check if document is newer")
+		}
+		if !newer {
+			return nil
+		}
+	}
+
+	var referencePtr *flare.Document
+	if ok && kind == flare.SubscriptionTriggerUpdate {
+		referencePtr = &reference
+	}
+
+	content, err := build(document, referencePtr, kind)
+	if err != nil {
+		return errors.Wrap(err, "error during event content build")
+	}
+
+	if kind != flare.SubscriptionTriggerDelete {
+		// Only the revision is needed to detect the next changes.
+		revision := *document
+		revision.Content = nil
+		resource.setRevision(revision)
+	}
+
+	resource.sequence++
+	event := streamEvent{
+		ID:      fmt.Sprintf("%s-%d", s.epoch, resource.sequence),
+		Action:  kind,
+		Content: content,
+	}
+
+	resource.events = append(resource.events, event)
+	if len(resource.events) > s.size {
+		resource.events = resource.events[len(resource.events)-s.size:]
+	}
+
+	for listener := range resource.listeners {
+		select {
+		case listener.events <- event:
+		default:
+			delete(resource.listeners, listener)
+			close(listener.events)
+			if len(resource.listeners) == 0 {
+				resource.idleSince = s.now()
+			}
+		}
+	}
+	return nil
+}
+
+// subscribe register a listener at the resource and return the events after the last event id.
+// Without the last event id, only the new events are sent.
+func (s *Stream) subscribe(resourceID, lastEventID string) (*streamListener, []streamEvent) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for id, resource := range s.resources {
+		if s.idle(resource) {
+			delete(s.resources, id)
+		}
+	}
+
+	resource := s.resource(resourceID)
+	listener := &streamListener{events: make(chan streamEvent, streamListenerBuffer)}
+	resource.listeners[listener] = struct{}{}
+	resource.idleSince = time.Time{}
+
+	if lastEventID == "" || len(resource.events) == 0 {
+		return listener, nil
+	}
+
+	// If the event is from another process or is not at the buffer anymore, all the buffer is sent.
+	sequence, ok := s.sequence(lastEventID)
+	first := resource.sequence - int64(len(resource.events)) + 1
+	if !ok || sequence < first-1 || sequence > resource.sequence {
+		return listener, append([]streamEvent{}, resource.events...)
+	}
+	return listener, append([]streamEvent{}, resource.events[sequence-first+1:]...)
+}
+
+func (s *Stream) unsubscribe(resourceID string, listener *streamListener) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	resource, ok := s.resources[resourceID]
+	if !ok {
+		return
+	}
+
+	if _, ok := resource.listeners[listener]; ok {
+		delete(resource.listeners, listener)
+		close(listener.events)
+	}
+	if len(resource.listeners) == 0 {
+		resource.idleSince = s.now()
+	}
+}
+
+// idle indicates if the resource is without listeners for more then the idle timeout.
+func (s *Stream) idle(resource *streamResource) bool {
+	return len(resource.listeners) == 0 && s.now().Sub(resource.idleSince) > streamIdleTimeout
+}
+
+func (s *Stream) sequence(id string) (int64, bool) {
+	fragments := strings.Split(id, "-")
+	if len(fragments) != 2 || fragments[0] != s.epoch {
+		return 0, false
+	}
+
+	sequence, err := strconv.ParseInt(fragments[1], 10, 64)
+	return sequence, err == nil
+}
+
+func (s *Stream) resource(id string) *streamResource {
+	resource, ok := s.resources[id]
+	if !ok {
+		resource = &streamResource{
+			revisions:      make(map[string]*list.Element),
+			revisionsOrder: list.New(),
+			listeners:      make(map[*streamListener]struct{}),
+		}
+		s.resources[id] = resource
+	}
+	return resource
+}
+
+func (r *streamResource) revision(id string) (flare.Document, bool) {
+	element, ok := r.revisions[id]
+	if !ok {
+		return flare.Document{}, false
+	}
+	r.revisionsOrder.MoveToFront(element)
+	return element.Value.(flare.Document), true
+}
+
+func (r *streamResource) setRevision(document flare.Document) {
+	if element, ok := r.revisions[document.Id]; ok {
+		element.Value = document
+		r.revisionsOrder.MoveToFront(element)
+		return
+	}
+
+	r.revisions[document.Id] = r.revisionsOrder.PushFront(document)
+	if r.revisionsOrder.Len() > streamRevisions {
+		r.deleteRevision(r.revisionsOrder.Back().Value.(flare.Document).Id)
+	}
+}
+
+func (r *streamResource) deleteRevision(id string) {
+	if element, ok := r.revisions[id]; ok {
+		r.revisionsOrder.Remove(element)
+		delete(r.revisions, id)
+	}
+}
+
+// NewStream returns a configured stream.
+func NewStream(options ...func(*Stream)) (*Stream, error) {
+	s := &Stream{
+		epoch:     strconv.FormatInt(time.Now().UnixNano(), 10),
+		resources: make(map[string]*streamResource),
+		now:       time.Now,
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	if s.size == 0 {
+		s.size = 1000
+	} else if s.size < 0 {
+		return nil, errors.New("invalid size")
+	}
+
+	return s, nil
+}
+
+// StreamSize set the quantity of events kept per resource.
+func StreamSize(size int) func(*Stream) {
+	return func(s *Stream) { s.size = size }
+}
+
+// StreamService implements the HTTP handlers to stream the document changes of a resource with
+// Server-Sent Events or WebSocket.
+type StreamService struct {
+	stream             *Stream
+	resourceRepository flare.ResourceRepositorier
+	getResourceID      func(*http.Request) string
+	writer             *infraHTTP.Writer
+}
+
+// HandleEvents stream the changes with Server-Sent Events. The last event id is read from the
+// Last-Event-ID header or, at the first connection, from the lastEventId parameter.
+func (s *StreamService) HandleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.writer.Error(
+			w, "streaming not supported", errors.New("response can't be flushed"),
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	resourceID, ok := s.findResource(w, r)
+	if !ok {
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+
+	listener, backlog := s.stream.subscribe(resourceID, lastEventID)
+	defer s.stream.unsubscribe(resourceID, listener)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range backlog {
+		fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Action, event.Content)
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event, ok := <-listener.events:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Action, event.Content)
+		}
+		flusher.Flush()
+	}
+}
+
+// HandleWebSocket stream the changes with WebSocket. Each event is sent as a text message with
+// the id, the action and the content. The last event id is read from the lastEventId parameter.
+func (s *StreamService) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	resourceID, ok := s.findResource(w, r)
+	if !ok {
+		return
+	}
+
+	// The server is used instead of the websocket.Handler to accept clients without the origin.
+	server := websocket.Server{Handler: func(conn *websocket.Conn) {
+		listener, backlog := s.stream.subscribe(resourceID, r.URL.Query().Get("lastEventId"))
+		defer s.stream.unsubscribe(resourceID, listener)
+
+		// The client messages are discarded, the read is used to detect the connection close.
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			var msg string
+			for {
+				if err := websocket.Message.Receive(conn, &msg); err != nil {
+					return
+				}
+			}
+		}()
+
+		send := func(event streamEvent) error {
+			return websocket.JSON.Send(conn, map[string]interface{}{
+				"id":     event.ID,
+				"action": event.Action,
+				"data":   json.RawMessage(event.Content),
+			})
+		}
+
+		for _, event := range backlog {
+			if err := send(event); err != nil {
+				return
+			}
+		}
+
+		for {
+			select {
+			case <-closed:
+				return
+			case <-r.Context().Done():
+				return
+			case event, ok := <-listener.events:
+				if !ok {
+					return
+				}
+				if err := send(event); err != nil {
+					return
+				}
+			}
+		}
+	}}
+	server.ServeHTTP(w, r)
+}
+
+func (s *StreamService) findResource(w http.ResponseWriter, r *http.Request) (string, bool) {
+	resourceID := s.getResourceID(r)
+	if _, err := s.resourceRepository.FindOne(r.Context(), resourceID); err != nil {
+		status := http.StatusInternalServerError
+		if errRepo, ok := err.(flare.ResourceRepositoryError); ok && errRepo.NotFound() {
+			status = http.StatusNotFound
+		}
+
+		s.writer.Error(w, "error during resource search", err, status)
+		return "", false
+	}
+	return resourceID, true
+}
+
+// NewStreamService returns a configured stream service.
+func NewStreamService(options ...func(*StreamService)) (*StreamService, error) {
+	service := &StreamService{}
+
+	for _, option := range options {
+		option(service)
+	}
+
+	if service.stream == nil {
+		return nil, errors.New("stream not found")
+	}
+
+	if service.resourceRepository == nil {
+		return nil, errors.New("resourceRepository not found")
+	}
+
+	if service.getResourceID == nil {
+		return nil, errors.New("getResourceID not found")
+	}
+
+	if service.writer == nil {
+		return nil, errors.New("writer not found")
+	}
+
+	return service, nil
+}
+
+// StreamServiceStream set the stream used to receive the document changes.
+func StreamServiceStream(stream *Stream) func(*StreamService) {
+	return func(s *StreamService) { s.stream = stream }
+}
+
+// StreamServiceResourceRepository set the repository to access the resources.
+func StreamServiceResourceRepository(repo flare.ResourceRepositorier) func(*StreamService) {
+	return func(s *StreamService) { s.resourceRepository = repo }
+}
+
+// StreamServiceGetResourceID set the function to fetch the resourceId from the URL.
+func StreamServiceGetResourceID(fn func(*http.Request) string) func(*StreamService) {
+	return func(s *StreamService) { s.getResourceID = fn }
+}
+
+// StreamServiceWriter set the function that return the content to client.
+func StreamServiceWriter(writer *infraHTTP.Writer) func(*StreamService) {
+	return func(s *StreamService) { s.writer = writer }
+}
@@ -0,0 +1,202 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subscription
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/diegobernardes/flare"
+	infraHTTP "github.com/diegobernardes/flare/infra/http"
+	"github.com/diegobernardes/flare/repository/memory"
+)
+
+func TestStreamPublish(t *testing.T) {
+	Convey("Given a Stream", t, func() {
+		stream, err := NewStream(StreamSize(3))
+		So(err, ShouldBeNil)
+
+		resource := flare.Resource{
+			ID:     "123",
+			Change: flare.ResourceChange{Field: "revision", Kind: flare.ResourceChangeInteger},
+		}
+
+		publish := func(revision interface{}, kind string) {
+			document := &flare.Document{
+				Id:               "http://app.com/users/1",
+				ChangeFieldValue: revision,
+				Resource:         resource,
+			}
+			So(document.TransformRevision(), ShouldBeNil)
+
+			err := stream.publish(document, kind, func(_, _ *flare.Document, kind string) ([]byte, error) {
+				return []byte(kind), nil
+			})
+			So(err, ShouldBeNil)
+		}
+
+		Convey("It should detect the actions and discard the older revisions", func() {
+			So(stream.Listening(resource.ID), ShouldBeFalse)
+			listener, backlog := stream.subscribe(resource.ID, "")
+			So(backlog, ShouldBeEmpty)
+			So(stream.Listening(resource.ID), ShouldBeTrue)
+
+			publish(float64(1), flare.SubscriptionTriggerUpdate)
+			publish(float64(2), flare.SubscriptionTriggerUpdate)
+			publish(float64(1), flare.SubscriptionTriggerUpdate)
+			publish(float64(2), flare.SubscriptionTriggerDelete)
+			publish(float64(2), flare.SubscriptionTriggerDelete)
+
+			So(listener.events, ShouldHaveLength, 3)
+			So((<-listener.events).Action, ShouldEqual, flare.SubscriptionTriggerCreate)
+			So((<-listener.events).Action, ShouldEqual, flare.SubscriptionTriggerUpdate)
+			So((<-listener.events).Action, ShouldEqual, flare.SubscriptionTriggerDelete)
+
+			stream.unsubscribe(resource.ID, listener)
+			So(stream.Listening(resource.ID), ShouldBeTrue)
+
+			now := time.Now()
+			stream.now = func() time.Time { return now.Add(streamIdleTimeout + time.Second) }
+			So(stream.Listening(resource.ID), ShouldBeFalse)
+			publish(float64(3), flare.SubscriptionTriggerUpdate)
+			So(stream.resources, ShouldBeEmpty)
+		})
+
+		Convey("It should not track the resources without listeners", func() {
+			publish(float64(1), flare.SubscriptionTriggerUpdate)
+			So(stream.resources, ShouldBeEmpty)
+		})
+
+		Convey("It should discard the least recently changed revisions", func() {
+			stream.subscribe(resource.ID, "")
+			for i := 0; i <= streamRevisions; i++ {
+				So(stream.publish(
+					&flare.Document{
+						Id:               fmt.Sprintf("http://app.com/users/%d", i),
+						ChangeFieldValue: 1,
+						Resource:         resource,
+					},
+					flare.SubscriptionTriggerUpdate,
+					func(_, _ *flare.Document, kind string) ([]byte, error) { return nil, nil },
+				), ShouldBeNil)
+			}
+
+			revisions := stream.resources[resource.ID].revisions
+			So(revisions, ShouldHaveLength, streamRevisions)
+			So(revisions, ShouldNotContainKey, "http://app.com/users/0")
+			So(revisions, ShouldContainKey, fmt.Sprintf("http://app.com/users/%d", streamRevisions))
+		})
+
+		Convey("It should send the events after the last event id", func() {
+			stream.subscribe(resource.ID, "")
+			for i := 1; i <= 4; i++ {
+				publish(float64(i), flare.SubscriptionTriggerUpdate)
+			}
+
+			_, backlog := stream.subscribe(resource.ID, stream.epoch+"-3")
+			So(backlog, ShouldHaveLength, 1)
+			So(backlog[0].ID, ShouldEqual, stream.epoch+"-4")
+
+			_, backlog = stream.subscribe(resource.ID, stream.epoch+"-4")
+			So(backlog, ShouldBeEmpty)
+
+			Convey("It should send all the buffer when the last event id is not found", func() {
+				for _, id := range []string{stream.epoch + "-1", "1-2", "invalid"} {
+					_, backlog = stream.subscribe(resource.ID, id)
+					So(backlog, ShouldHaveLength, 3)
+					So(backlog[0].ID, ShouldEqual, stream.epoch+"-2")
+				}
+			})
+		})
+	})
+
+	Convey("Given a invalid size", t, func() {
+		Convey("It should return a error", func() {
+			_, err := NewStream(StreamSize(-1))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestStreamServiceHandleEvents(t *testing.T) {
+	Convey("Given a StreamService", t, func() {
+		resourceRepository := memory.NewResource()
+		So(resourceRepository.Create(context.Background(), &flare.Resource{
+			ID:        "123",
+			Addresses: []string{"http://app.com"},
+			Path:      "/users/{id}",
+			Change:    flare.ResourceChange{Field: "revision", Kind: flare.ResourceChangeInteger},
+		}), ShouldBeNil)
+
+		stream, err := NewStream()
+		So(err, ShouldBeNil)
+
+		writer, err := infraHTTP.NewWriter(log.NewNopLogger())
+		So(err, ShouldBeNil)
+
+		service, err := NewStreamService(
+			StreamServiceStream(stream),
+			StreamServiceResourceRepository(resourceRepository),
+			StreamServiceGetResourceID(func(r *http.Request) string {
+				return strings.Split(r.URL.Path, "/")[2]
+			}),
+			StreamServiceWriter(writer),
+		)
+		So(err, ShouldBeNil)
+
+		server := httptest.NewServer(http.HandlerFunc(service.HandleEvents))
+		defer server.Close()
+
+		Convey("It should return not found for a unknown resource", func() {
+			resp, err := http.Get(server.URL + "/resources/456/stream")
+			So(err, ShouldBeNil)
+			defer resp.Body.Close()
+			So(resp.StatusCode, ShouldEqual, http.StatusNotFound)
+		})
+
+		Convey("It should stream the document changes", func() {
+			resp, err := http.Get(server.URL + "/resources/123/stream")
+			So(err, ShouldBeNil)
+			defer resp.Body.Close()
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(resp.Header.Get("Content-Type"), ShouldEqual, "text/event-stream")
+			So(stream.Listening("123"), ShouldBeTrue)
+
+			resource, err := resourceRepository.FindOne(context.Background(), "123")
+			So(err, ShouldBeNil)
+
+			document := &flare.Document{
+				Id:               "http://app.com/users/1",
+				ChangeFieldValue: float64(1),
+				Resource:         *resource,
+			}
+			So(document.TransformRevision(), ShouldBeNil)
+			So(stream.publish(
+				document,
+				flare.SubscriptionTriggerUpdate,
+				func(_, _ *flare.Document, _ string) ([]byte, error) { return []byte(`{"id":1}`), nil },
+			), ShouldBeNil)
+
+			reader := bufio.NewReader(resp.Body)
+			lines := make([]string, 3)
+			for i := range lines {
+				lines[i], err = reader.ReadString('\n')
+				So(err, ShouldBeNil)
+			}
+			So(lines, ShouldResemble, []string{
+				"id: " + stream.epoch + "-1\n", "event: create\n", "data: {\"id\":1}\n",
+			})
+		})
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
@@ -53,30 +54,91 @@ func (r *response) MarshalJSON() ([]byte, error) {
 type subscription flare.Subscription
 
 func (s *subscription) MarshalJSON() ([]byte, error) {
-	endpoint := map[string]interface{}{
-		"url":    s.Endpoint.URL.String(),
-		"method": s.Endpoint.Method,
+	endpoint := make(map[string]interface{})
+	if s.Endpoint.Target != "" {
+		endpoint["target"] = s.Endpoint.Target
+	}
+
+	switch s.Endpoint.TargetKind() {
+	case flare.SubscriptionTargetHTTP:
+		endpoint["url"] = s.Endpoint.URL.String()
+		endpoint["method"] = s.Endpoint.Method
+	case flare.SubscriptionTargetSQS:
+		endpoint["queue"] = s.Endpoint.Queue
+	case flare.SubscriptionTargetKafka:
+		endpoint["url"] = s.Endpoint.URL.String()
+		endpoint["topic"] = s.Endpoint.Topic
+	default:
+		endpoint["topic"] = s.Endpoint.Topic
 	}
 
 	if len(s.Endpoint.Headers) > 0 {
 		endpoint["headers"] = s.Endpoint.Headers
 	}
 
-	delivery := map[string][]int{
+	delivery := map[string]interface{}{
 		"success": s.Delivery.Success,
 		"discard": s.Delivery.Discard,
 	}
 
+	if s.Delivery.IncludeDocument {
+		delivery["includeDocument"] = true
+	}
+
+	if s.Delivery.Diff {
+		delivery["diff"] = true
+	}
+
+	if s.Delivery.Retry.Enabled() {
+		delivery["retry"] = map[string]interface{}{
+			"maxAttempts":    s.Delivery.Retry.MaxAttempts,
+			"initialBackoff": s.Delivery.Retry.InitialBackoff.String(),
+			"maxBackoff":     s.Delivery.Retry.MaxBackoff.String(),
+			"jitter":         s.Delivery.Retry.Jitter,
+		}
+	}
+
+	if s.Delivery.Limit.Enabled() {
+		delivery["limit"] = &subscriptionCreateLimit{
+			RequestsPerSecond: s.Delivery.Limit.RequestsPerSecond,
+			MaxInFlight:       s.Delivery.Limit.MaxInFlight,
+		}
+	}
+
+	// The secret is never returned, only if the notifications are signed.
+	var signature map[string]interface{}
+	if s.Secret.Enabled() {
+		signature = map[string]interface{}{"enabled": true}
+		if s.Secret.PreviousExpireAt.After(time.Now()) {
+			signature["previousSecretExpireAt"] = s.Secret.PreviousExpireAt.Format(time.RFC3339)
+		}
+	}
+
+	var tmpl *subscriptionCreateTemplate
+	if s.Template.Enabled() {
+		tmpl = &subscriptionCreateTemplate{Body: s.Template.Body, Headers: s.Template.Headers}
+	}
+
 	return json.Marshal(&struct {
-		Id        string                 `json:"id"`
-		Endpoint  map[string]interface{} `json:"endpoint"`
-		Delivery  map[string][]int       `json:"delivery"`
-		CreatedAt string                 `json:"createdAt"`
-		Data      map[string]interface{} `json:"data,omitempty"`
+		Id        string                      `json:"id"`
+		Namespace string                      `json:"namespace,omitempty"`
+		Endpoint  map[string]interface{}      `json:"endpoint"`
+		Delivery  map[string]interface{}      `json:"delivery"`
+		Signature map[string]interface{}      `json:"signature,omitempty"`
+		Filter    string                      `json:"filter,omitempty"`
+		Actions   []string                    `json:"actions,omitempty"`
+		Template  *subscriptionCreateTemplate `json:"template,omitempty"`
+		CreatedAt string                      `json:"createdAt"`
+		Data      map[string]interface{}      `json:"data,omitempty"`
 	}{
 		Id:        s.ID,
+		Namespace: s.Namespace,
 		Endpoint:  endpoint,
 		Delivery:  delivery,
+		Signature: signature,
+		Filter:    s.Filter,
+		Actions:   s.Actions,
+		Template:  tmpl,
 		CreatedAt: s.CreatedAt.Format(time.RFC3339),
 		Data:      s.Data,
 	})
@@ -96,18 +158,255 @@ func transformSubscriptions(s []flare.Subscription) []subscription {
 
 type subscriptionCreate struct {
 	Endpoint struct {
+		Target  string      `json:"target"`
 		URL     string      `json:"url"`
 		Method  string      `json:"method"`
 		Headers http.Header `json:"headers"`
+		Queue   string      `json:"queue"`
+		Topic   string      `json:"topic"`
 	} `json:"endpoint"`
 	Delivery struct {
-		Success []int `json:"success"`
-		Discard []int `json:"discard"`
+		Success         []int                    `json:"success"`
+		Discard         []int                    `json:"discard"`
+		Retry           *subscriptionCreateRetry `json:"retry"`
+		Limit           *subscriptionCreateLimit `json:"limit"`
+		IncludeDocument bool                     `json:"includeDocument"`
+		Diff            bool                     `json:"diff"`
 	} `json:"delivery"`
-	Data map[string]interface{} `json:"data"`
+	Filter   string                      `json:"filter"`
+	Actions  []string                    `json:"actions"`
+	Template *subscriptionCreateTemplate `json:"template"`
+	Secret   string                      `json:"secret"`
+	Data     map[string]interface{}      `json:"data"`
+}
+
+type subscriptionCreateTemplate struct {
+	Body    string            `json:"body,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+func (s *subscriptionCreateTemplate) valid() error {
+	tmpl, err := compileTemplate(s.toFlareSubscriptionTemplate())
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(tmpl.dryRun(), "error during dry run")
+}
+
+func (s *subscriptionCreateTemplate) toFlareSubscriptionTemplate() flare.SubscriptionTemplate {
+	return flare.SubscriptionTemplate{Body: s.Body, Headers: s.Headers}
+}
+
+// Min length of the secret used to sign the notifications.
+const subscriptionSecretMinLength = 16
+
+func validSecret(secret string) error {
+	if len(secret) < subscriptionSecretMinLength {
+		return fmt.Errorf("secret should have at least %d characters", subscriptionSecretMinLength)
+	}
+	return nil
+}
+
+type subscriptionSecretRotate struct {
+	Secret  string `json:"secret"`
+	Overlap string `json:"overlap"`
+}
+
+func (s *subscriptionSecretRotate) valid() error {
+	if err := validSecret(s.Secret); err != nil {
+		return err
+	}
+
+	if _, err := s.overlap(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *subscriptionSecretRotate) overlap() (time.Duration, error) {
+	if s.Overlap == "" {
+		return 0, nil
+	}
+
+	overlap, err := time.ParseDuration(s.Overlap)
+	if err != nil {
+		return 0, errors.Wrap(err, "error during overlap parse")
+	}
+
+	if overlap < 0 {
+		return 0, fmt.Errorf("invalid overlap '%s'", s.Overlap)
+	}
+	return overlap, nil
+}
+
+type subscriptionCreateRetry struct {
+	MaxAttempts    int     `json:"maxAttempts"`
+	InitialBackoff string  `json:"initialBackoff"`
+	MaxBackoff     string  `json:"maxBackoff"`
+	Jitter         float64 `json:"jitter"`
+}
+
+func (s *subscriptionCreateRetry) valid() error {
+	if s.MaxAttempts < 1 {
+		return fmt.Errorf("invalid delivery.retry.maxAttempts '%d'", s.MaxAttempts)
+	}
+
+	initialBackoff, maxBackoff, err := s.backoff()
+	if err != nil {
+		return err
+	}
+
+	if initialBackoff <= 0 {
+		return fmt.Errorf("invalid delivery.retry.initialBackoff '%s'", s.InitialBackoff)
+	}
+
+	if maxBackoff < initialBackoff {
+		return errors.New("delivery.retry.maxBackoff should be bigger then initialBackoff")
+	}
+
+	if s.Jitter < 0 || s.Jitter > 1 {
+		return fmt.Errorf("invalid delivery.retry.jitter '%v', should be between 0 and 1", s.Jitter)
+	}
+
+	return nil
+}
+
+func (s *subscriptionCreateRetry) backoff() (time.Duration, time.Duration, error) {
+	initialBackoff, err := time.ParseDuration(s.InitialBackoff)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "error during delivery.retry.initialBackoff parse")
+	}
+
+	maxBackoff, err := time.ParseDuration(s.MaxBackoff)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "error during delivery.retry.maxBackoff parse")
+	}
+
+	return initialBackoff, maxBackoff, nil
+}
+
+func (s *subscriptionCreateRetry) toFlareSubscriptionDeliveryRetry() flare.SubscriptionDeliveryRetry {
+	initialBackoff, maxBackoff, _ := s.backoff()
+	return flare.SubscriptionDeliveryRetry{
+		MaxAttempts:    s.MaxAttempts,
+		InitialBackoff: initialBackoff,
+		MaxBackoff:     maxBackoff,
+		Jitter:         s.Jitter,
+	}
+}
+
+type subscriptionCreateLimit struct {
+	RequestsPerSecond float64 `json:"requestsPerSecond,omitempty"`
+	MaxInFlight       int     `json:"maxInFlight,omitempty"`
+}
+
+func (s *subscriptionCreateLimit) valid() error {
+	if s.RequestsPerSecond < 0 {
+		return fmt.Errorf("invalid delivery.limit.requestsPerSecond '%v'", s.RequestsPerSecond)
+	}
+
+	if s.MaxInFlight < 0 {
+		return fmt.Errorf("invalid delivery.limit.maxInFlight '%d'", s.MaxInFlight)
+	}
+
+	return nil
+}
+
+func (s *subscriptionCreateLimit) toFlareSubscriptionDeliveryLimit() flare.SubscriptionDeliveryLimit {
+	return flare.SubscriptionDeliveryLimit{
+		RequestsPerSecond: s.RequestsPerSecond,
+		MaxInFlight:       s.MaxInFlight,
+	}
 }
 
 func (s *subscriptionCreate) valid() error {
+	if err := s.validEndpoint(); err != nil {
+		return err
+	}
+
+	if s.Delivery.Retry != nil {
+		if err := s.Delivery.Retry.valid(); err != nil {
+			return err
+		}
+	}
+
+	if s.Delivery.Limit != nil {
+		if err := s.Delivery.Limit.valid(); err != nil {
+			return err
+		}
+	}
+
+	if s.Filter != "" {
+		if _, err := compileFilter(s.Filter); err != nil {
+			return errors.Wrap(err, "invalid filter")
+		}
+	}
+
+	if err := s.validActions(); err != nil {
+		return err
+	}
+
+	if s.Template != nil {
+		if err := s.Template.valid(); err != nil {
+			return errors.Wrap(err, "invalid template")
+		}
+	}
+
+	if s.Secret != "" {
+		if err := validSecret(s.Secret); err != nil {
+			return err
+		}
+	}
+
+	if err := s.validData(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validEndpoint check the endpoint with the rules of each target. Only the HTTP target use the
+// delivery success and discard status.
+func (s *subscriptionCreate) validEndpoint() error {
+	switch s.Endpoint.Target {
+	case "", flare.SubscriptionTargetHTTP:
+		return s.validEndpointHTTP()
+	case flare.SubscriptionTargetSQS:
+		if s.Endpoint.Queue == "" {
+			return errors.New("missing endpoint.queue")
+		}
+
+		if queue, err := url.Parse(s.Endpoint.Queue); err != nil || queue.Host == "" {
+			return fmt.Errorf("invalid endpoint.queue '%s', should be the queue URL", s.Endpoint.Queue)
+		}
+	case flare.SubscriptionTargetSNS:
+		if !strings.HasPrefix(s.Endpoint.Topic, "arn:") {
+			return fmt.Errorf("invalid endpoint.topic '%s', should be the topic ARN", s.Endpoint.Topic)
+		}
+	case flare.SubscriptionTargetKafka:
+		if s.Endpoint.URL == "" {
+			return errors.New("missing endpoint.url")
+		}
+
+		if !subscriptionKafkaTopic.MatchString(s.Endpoint.Topic) {
+			return fmt.Errorf("invalid endpoint.topic '%s'", s.Endpoint.Topic)
+		}
+	case flare.SubscriptionTargetMemory:
+		if s.Endpoint.Topic == "" {
+			return errors.New("missing endpoint.topic")
+		}
+	default:
+		return fmt.Errorf("invalid endpoint.target '%s'", s.Endpoint.Target)
+	}
+
+	return nil
+}
+
+// The valid Kafka topic names.
+var subscriptionKafkaTopic = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,249}$`)
+
+func (s *subscriptionCreate) validEndpointHTTP() error {
 	if s.Endpoint.URL == "" {
 		return errors.New("missing endpoint.URL")
 	}
@@ -127,8 +426,23 @@ func (s *subscriptionCreate) valid() error {
 		return errors.New("missing delivery.Discard")
 	}
 
-	if err := s.validData(); err != nil {
-		return err
+	return nil
+}
+
+func (s *subscriptionCreate) validActions() error {
+	actions := make(map[string]bool, len(s.Actions))
+	for _, action := range s.Actions {
+		switch action {
+		case flare.SubscriptionTriggerCreate, flare.SubscriptionTriggerUpdate,
+			flare.SubscriptionTriggerDelete:
+		default:
+			return fmt.Errorf("invalid action '%s'", action)
+		}
+
+		if actions[action] {
+			return fmt.Errorf("duplicated action '%s'", action)
+		}
+		actions[action] = true
 	}
 
 	return nil
@@ -154,23 +468,124 @@ func (s *subscriptionCreate) validData() error {
 	return nil
 }
 
+// transformSubscriptionCreate is used to build the base content on PATCH requests. The secret is
+// not loaded because it's only replaced when present at the body.
+// resetPatchMaps remove the maps present at the patch body. The decode merge the maps instead of
+// replacing them, so, without this, a patch could never remove a key from the headers or the data.
+func (s *subscriptionCreate) resetPatchMaps(body []byte) error {
+	var content struct {
+		Endpoint map[string]json.RawMessage `json:"endpoint"`
+		Template map[string]json.RawMessage `json:"template"`
+		Data     json.RawMessage            `json:"data"`
+	}
+	if err := json.Unmarshal(body, &content); err != nil {
+		return err
+	}
+
+	if _, ok := content.Endpoint["headers"]; ok {
+		s.Endpoint.Headers = nil
+	}
+	if _, ok := content.Template["headers"]; ok && s.Template != nil {
+		s.Template.Headers = nil
+	}
+	if content.Data != nil {
+		s.Data = nil
+	}
+	return nil
+}
+
+func transformSubscriptionCreate(s *flare.Subscription) *subscriptionCreate {
+	content := &subscriptionCreate{Data: make(map[string]interface{})}
+	content.Endpoint.Target = s.Endpoint.Target
+	content.Endpoint.URL = s.Endpoint.URL.String()
+	content.Endpoint.Method = s.Endpoint.Method
+	content.Endpoint.Queue = s.Endpoint.Queue
+	content.Endpoint.Topic = s.Endpoint.Topic
+	content.Endpoint.Headers = make(http.Header)
+	for key, values := range s.Endpoint.Headers {
+		content.Endpoint.Headers[key] = append([]string{}, values...)
+	}
+	content.Delivery.Success = append([]int{}, s.Delivery.Success...)
+	content.Delivery.Discard = append([]int{}, s.Delivery.Discard...)
+	content.Delivery.IncludeDocument = s.Delivery.IncludeDocument
+	content.Delivery.Diff = s.Delivery.Diff
+	content.Filter = s.Filter
+	content.Actions = append([]string{}, s.Actions...)
+	if s.Template.Enabled() {
+		content.Template = &subscriptionCreateTemplate{
+			Body:    s.Template.Body,
+			Headers: make(map[string]string, len(s.Template.Headers)),
+		}
+		for key, value := range s.Template.Headers {
+			content.Template.Headers[key] = value
+		}
+	}
+	for key, value := range s.Data {
+		content.Data[key] = value
+	}
+
+	if s.Delivery.Retry.Enabled() {
+		content.Delivery.Retry = &subscriptionCreateRetry{
+			MaxAttempts:    s.Delivery.Retry.MaxAttempts,
+			InitialBackoff: s.Delivery.Retry.InitialBackoff.String(),
+			MaxBackoff:     s.Delivery.Retry.MaxBackoff.String(),
+			Jitter:         s.Delivery.Retry.Jitter,
+		}
+	}
+
+	if s.Delivery.Limit.Enabled() {
+		content.Delivery.Limit = &subscriptionCreateLimit{
+			RequestsPerSecond: s.Delivery.Limit.RequestsPerSecond,
+			MaxInFlight:       s.Delivery.Limit.MaxInFlight,
+		}
+	}
+
+	return content
+}
+
 func (s *subscriptionCreate) toFlareSubscription() (*flare.Subscription, error) {
 	path, err := url.Parse(s.Endpoint.URL)
 	if err != nil {
 		return nil, errors.Wrap(err, fmt.Sprintf("error during parse '%s' to url.URL", s.Endpoint.URL))
 	}
 
+	var retry flare.SubscriptionDeliveryRetry
+	if s.Delivery.Retry != nil {
+		retry = s.Delivery.Retry.toFlareSubscriptionDeliveryRetry()
+	}
+
+	var limit flare.SubscriptionDeliveryLimit
+	if s.Delivery.Limit != nil {
+		limit = s.Delivery.Limit.toFlareSubscriptionDeliveryLimit()
+	}
+
+	var tmpl flare.SubscriptionTemplate
+	if s.Template != nil {
+		tmpl = s.Template.toFlareSubscriptionTemplate()
+	}
+
 	return &flare.Subscription{
 		ID: uuid.NewV4().String(),
 		Endpoint: flare.SubscriptionEndpoint{
+			Target:  s.Endpoint.Target,
 			URL:     *path,
 			Method:  s.Endpoint.Method,
 			Headers: s.Endpoint.Headers,
+			Queue:   s.Endpoint.Queue,
+			Topic:   s.Endpoint.Topic,
 		},
 		Delivery: flare.SubscriptionDelivery{
-			Discard: s.Delivery.Discard,
-			Success: s.Delivery.Success,
+			Discard:         s.Delivery.Discard,
+			Success:         s.Delivery.Success,
+			Retry:           retry,
+			Limit:           limit,
+			IncludeDocument: s.Delivery.IncludeDocument,
+			Diff:            s.Delivery.Diff,
 		},
-		Data: s.Data,
+		Filter:   s.Filter,
+		Actions:  s.Actions,
+		Template: tmpl,
+		Secret:   flare.SubscriptionSecret{Value: s.Secret},
+		Data:     s.Data,
 	}, nil
 }
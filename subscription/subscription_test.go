@@ -101,6 +101,24 @@ func TestResponseMarshalJSON(t *testing.T) {
 				},
 				infraTest.Load("responseMarshalJSON.valid.2.json"),
 			},
+			{
+				response{
+					Subscription: &subscription{
+						ID: "123",
+						Endpoint: flare.SubscriptionEndpoint{
+							URL:    url.URL{Scheme: "http", Host: "app.io", Path: "/update"},
+							Method: http.MethodPost,
+						},
+						Delivery: flare.SubscriptionDelivery{
+							Success: []int{200},
+							Discard: []int{500},
+						},
+						Secret:    flare.SubscriptionSecret{Value: "a7f3c1e09b5d4f2a"},
+						CreatedAt: time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC),
+					},
+				},
+				infraTest.Load("responseMarshalJSON.valid.3.json"),
+			},
 		}
 
 		Convey("The output should be valid", func() {
@@ -125,6 +143,11 @@ func TestSubscriptionCreateValid(t *testing.T) {
 	Convey("Given a list of valid subscriptionCreate", t, func() {
 		tests := [][]byte{
 			infraTest.Load("subscriptionCreateValid.valid.json"),
+			infraTest.Load("subscriptionCreateValid.valid.retry.json"),
+			infraTest.Load("subscriptionCreateValid.valid.limit.json"),
+			infraTest.Load("subscriptionCreateValid.valid.actions.json"),
+			infraTest.Load("subscriptionCreateValid.valid.template.json"),
+			infraTest.Load("subscriptionCreateValid.valid.target.json"),
 		}
 
 		Convey("The output should be valid", func() {
@@ -160,6 +183,54 @@ func TestSubscriptionCreateValid(t *testing.T) {
 				"Should be missing delivery discard",
 				infraTest.Load("subscriptionCreateValid.invalid.4.json"),
 			},
+			{
+				"Should have a invalid delivery retry maxAttempts",
+				infraTest.Load("subscriptionCreateValid.invalid.5.json"),
+			},
+			{
+				"Should have a delivery retry maxBackoff smaller then initialBackoff",
+				infraTest.Load("subscriptionCreateValid.invalid.6.json"),
+			},
+			{
+				"Should have a invalid delivery retry jitter",
+				infraTest.Load("subscriptionCreateValid.invalid.7.json"),
+			},
+			{
+				"Should have a secret too short",
+				infraTest.Load("subscriptionCreateValid.invalid.8.json"),
+			},
+			{
+				"Should have a invalid filter",
+				infraTest.Load("subscriptionCreateValid.invalid.9.json"),
+			},
+			{
+				"Should have a invalid action",
+				infraTest.Load("subscriptionCreateValid.invalid.10.json"),
+			},
+			{
+				"Should have a duplicated action",
+				infraTest.Load("subscriptionCreateValid.invalid.11.json"),
+			},
+			{
+				"Should have a invalid template",
+				infraTest.Load("subscriptionCreateValid.invalid.12.json"),
+			},
+			{
+				"Should have a template that fail on the dry run",
+				infraTest.Load("subscriptionCreateValid.invalid.13.json"),
+			},
+			{
+				"Should have a invalid SNS topic",
+				infraTest.Load("subscriptionCreateValid.invalid.14.json"),
+			},
+			{
+				"Should have a invalid target",
+				infraTest.Load("subscriptionCreateValid.invalid.15.json"),
+			},
+			{
+				"Should have a invalid delivery limit maxInFlight",
+				infraTest.Load("subscriptionCreateValid.invalid.16.json"),
+			},
 		}
 
 		for _, tt := range tests {
@@ -0,0 +1,98 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subscription
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/diegobernardes/flare"
+)
+
+// Max size of the response body stored at the delivery log.
+const targetHTTPMaxResponseBody = 1024
+
+// targetHTTP is the default target, it deliver the notification with a HTTP request. The
+// notification is successful if the response status is one of the delivery success or discard.
+type targetHTTP struct {
+	client *http.Client
+}
+
+func (th *targetHTTP) Deliver(
+	ctx context.Context,
+	sub flare.Subscription,
+	content []byte,
+	header http.Header,
+	delivery *flare.Delivery,
+) error {
+	req, err := http.NewRequest(
+		sub.Endpoint.Method, sub.Endpoint.URL.String(), bytes.NewBuffer(content),
+	)
+	if err != nil {
+		return errors.Wrap(err, "error during http request create")
+	}
+	req = req.WithContext(ctx)
+	req.Header = header
+
+	resp, err := th.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error during http request")
+	}
+	defer resp.Body.Close()
+
+	delivery.Status = resp.StatusCode
+	if resp.StatusCode == http.StatusTooManyRequests {
+		delivery.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, targetHTTPMaxResponseBody))
+	if err == nil {
+		delivery.ResponseBody = string(body)
+	}
+
+	for _, status := range sub.Delivery.Success {
+		if status == resp.StatusCode {
+			return nil
+		}
+	}
+
+	for _, status := range sub.Delivery.Discard {
+		if status == resp.StatusCode {
+			return nil
+		}
+	}
+
+	return errors.Errorf(
+		"success and discard status don't match with the response value '%d'", resp.StatusCode,
+	)
+}
+
+// parseRetryAfter returns the wait at the Retry-After header, in seconds or as a HTTP date. The
+// invalid and the past values are zero.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil || !date.After(now) {
+		return 0
+	}
+	return date.Sub(now)
+}
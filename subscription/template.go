@@ -0,0 +1,156 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subscription
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/diegobernardes/flare"
+)
+
+// templateData is the content available to the notification templates, like
+// {{.Document.Content.name}} or {{json .Diff}}.
+type templateData struct {
+	Action    string
+	Document  templateDocument
+	Resource  templateResource
+	Wildcards map[string]string
+	Data      map[string]interface{}
+	Diff      []patchOperation
+	Timestamp time.Time
+}
+
+type templateDocument struct {
+	ID        string
+	Revision  interface{}
+	Content   map[string]interface{}
+	UpdatedAt time.Time
+}
+
+type templateResource struct {
+	ID        string
+	Path      string
+	Addresses []string
+}
+
+var templateFuncs = template.FuncMap{
+	"json": templateJSON,
+	"date": func(layout string, value time.Time) string { return value.Format(layout) },
+}
+
+// templateJSON is used to output any value, like strings, objects and arrays, as valid JSON.
+func templateJSON(value interface{}) (string, error) {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// notificationTemplate has the compiled body and headers of a subscription template.
+type notificationTemplate struct {
+	body    *template.Template
+	headers map[string]*template.Template
+}
+
+// render execute the templates. The body is nil if the template don't have one.
+func (nt *notificationTemplate) render(data *templateData) ([]byte, http.Header, error) {
+	var body []byte
+	if nt.body != nil {
+		buf := bytes.NewBuffer(nil)
+		if err := nt.body.Execute(buf, data); err != nil {
+			return nil, nil, errors.Wrap(err, "error during body render")
+		}
+		body = buf.Bytes()
+	}
+
+	header := make(http.Header, len(nt.headers))
+	for key, tmpl := range nt.headers {
+		buf := bytes.NewBuffer(nil)
+		if err := tmpl.Execute(buf, data); err != nil {
+			return nil, nil, errors.Wrap(err, fmt.Sprintf("error during header '%s' render", key))
+		}
+
+		value := buf.String()
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, nil, fmt.Errorf("invalid header '%s' value '%s'", key, value)
+		}
+		header.Set(key, value)
+	}
+
+	return body, header, nil
+}
+
+// dryRun render the template with a sample notification to detect errors on execution.
+func (nt *notificationTemplate) dryRun() error {
+	now := time.Now()
+	_, _, err := nt.render(&templateData{
+		Action: flare.SubscriptionTriggerUpdate,
+		Document: templateDocument{
+			ID:        "http://app.com/users/1",
+			Revision:  1,
+			Content:   make(map[string]interface{}),
+			UpdatedAt: now,
+		},
+		Resource: templateResource{
+			ID:        "1",
+			Path:      "/users/{id}",
+			Addresses: []string{"http://app.com"},
+		},
+		Wildcards: map[string]string{"id": "1"},
+		Data:      make(map[string]interface{}),
+		Diff:      make([]patchOperation, 0),
+		Timestamp: now,
+	})
+	return err
+}
+
+// templateKey identify the template by the body and headers. The headers are encoded in order, this
+// way, the same template always has the same key.
+func templateKey(st flare.SubscriptionTemplate) (string, error) {
+	key, err := json.Marshal(st)
+	if err != nil {
+		return "", errors.Wrap(err, "error during template key generation")
+	}
+	return string(key), nil
+}
+
+func compileTemplate(st flare.SubscriptionTemplate) (*notificationTemplate, error) {
+	result := &notificationTemplate{headers: make(map[string]*template.Template, len(st.Headers))}
+	if st.Body != "" {
+		body, err := template.New("body").Funcs(templateFuncs).Parse(st.Body)
+		if err != nil {
+			return nil, errors.Wrap(err, "error during body parse")
+		}
+		result.body = body
+	}
+
+	for key, value := range st.Headers {
+		if key == "" || strings.ContainsAny(key, " :\t\r\n") {
+			return nil, fmt.Errorf("invalid header '%s'", key)
+		}
+
+		switch http.CanonicalHeaderKey(key) {
+		case triggerHeaderDelivery, triggerHeaderSignature:
+			return nil, fmt.Errorf("header '%s' is reserved", key)
+		}
+
+		tmpl, err := template.New(key).Funcs(templateFuncs).Parse(value)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("error during header '%s' parse", key))
+		}
+		result.headers[key] = tmpl
+	}
+
+	return result, nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subscription
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/diegobernardes/flare"
+)
+
+func TestCompileTemplate(t *testing.T) {
+	Convey("Given a list of invalid templates", t, func() {
+		tests := []struct {
+			title    string
+			template flare.SubscriptionTemplate
+		}{
+			{
+				"Should have a invalid body",
+				flare.SubscriptionTemplate{Body: `{"id": {{.Document.ID}`},
+			},
+			{
+				"Should have a unknown function",
+				flare.SubscriptionTemplate{Body: `{"id": {{xml .Document.ID}}}`},
+			},
+			{
+				"Should have a invalid header name",
+				flare.SubscriptionTemplate{Headers: map[string]string{"X Event": "{{.Action}}"}},
+			},
+			{
+				"Should have a reserved header",
+				flare.SubscriptionTemplate{Headers: map[string]string{"x-flare-signature": "value"}},
+			},
+			{
+				"Should have a invalid header value",
+				flare.SubscriptionTemplate{Headers: map[string]string{"X-Event": "{{.Action"}},
+			},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				_, err := compileTemplate(tt.template)
+				So(err, ShouldNotBeNil)
+			})
+		}
+	})
+
+	Convey("Given a template with a invalid field", t, func() {
+		tmpl, err := compileTemplate(flare.SubscriptionTemplate{Body: `{{.Document.Name}}`})
+		So(err, ShouldBeNil)
+
+		Convey("The dry run should fail", func() {
+			So(tmpl.dryRun(), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestNotificationTemplateRender(t *testing.T) {
+	Convey("Given a template", t, func() {
+		tmpl, err := compileTemplate(flare.SubscriptionTemplate{
+			Body: `{
+				"text": {{json (printf "User %s %sd" .Wildcards.id .Action)}},
+				"user": {"name": {{json .Document.Content.name}}, "tags": {{json .Document.Content.tags}}},
+				{{if eq .Action "delete"}}"deleted": true,{{end}}
+				"at": {{json (date "2006-01-02" .Timestamp)}}
+			}`,
+			Headers: map[string]string{"X-Event": "user.{{.Action}}"},
+		})
+		So(err, ShouldBeNil)
+		So(tmpl.dryRun(), ShouldBeNil)
+
+		data := &templateData{
+			Action: flare.SubscriptionTriggerDelete,
+			Document: templateDocument{
+				ID:      "http://app.com/users/1",
+				Content: map[string]interface{}{"name": "Diego", "tags": []interface{}{"a", "b"}},
+			},
+			Wildcards: map[string]string{"id": "1"},
+			Timestamp: time.Date(2017, time.November, 17, 0, 0, 0, 0, time.UTC),
+		}
+
+		Convey("It should render the body and headers", func() {
+			body, header, err := tmpl.render(data)
+			So(err, ShouldBeNil)
+			So(header.Get("X-Event"), ShouldEqual, "user.delete")
+
+			content := make(map[string]interface{})
+			So(json.Unmarshal(body, &content), ShouldBeNil)
+			So(content, ShouldResemble, map[string]interface{}{
+				"text": "User 1 deleted",
+				"user": map[string]interface{}{
+					"name": "Diego",
+					"tags": []interface{}{"a", "b"},
+				},
+				"deleted": true,
+				"at":      "2017-11-17",
+			})
+		})
+
+		Convey("It should not render a header with a line break", func() {
+			tmpl, err := compileTemplate(flare.SubscriptionTemplate{
+				Headers: map[string]string{"X-Name": "{{.Document.Content.name}}"},
+			})
+			So(err, ShouldBeNil)
+
+			data.Document.Content["name"] = "Diego\r\nX-Injected: true"
+			_, _, err = tmpl.render(data)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -8,24 +8,108 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
 	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
 
 	"github.com/diegobernardes/flare"
+	infraMetrics "github.com/diegobernardes/flare/infra/metrics"
 	"github.com/diegobernardes/flare/infra/task"
+	"github.com/diegobernardes/flare/infra/trace"
 )
 
 // Trigger is used to process the signals on documents change.
 type Trigger struct {
 	document   flare.DocumentRepositorier
 	repository flare.SubscriptionRepositorier
+	resource   flare.ResourceRepositorier
+	deadLetter flare.DeadLetterRepositorier
+	delivery   flare.DeliveryRepositorier
+	limit      flare.SubscriptionLimitRepositorier
 	httpClient *http.Client
 	pusher     task.Pusher
+	logger     log.Logger
+	filters    sync.Map
+	templates  sync.Map
+	targets    map[string]flare.SubscriptionTarget
+	stream     *Stream
+	tracer     *trace.Tracer
+	metrics    struct {
+		deliveries metrics.Counter
+		latency    metrics.Histogram
+	}
+}
+
+const (
+	// Headers sent with the notifications. The signature header has the format
+	// "t=<unix timestamp>,v1=<signature>" and during a secret rotation it has one v1 entry per
+	// valid secret.
+	triggerHeaderDelivery  = "X-Flare-Delivery"
+	triggerHeaderSignature = "X-Flare-Signature"
+)
+
+// The delivery limits. The waits up to triggerLimitMaxWait are done at the worker and the longer
+// ones schedule the delivery to later. When the subscriber answer 429 without the Retry-After, the
+// subscription is paused by triggerLimitPause, and never more than triggerLimitMaxPause.
+const (
+	triggerLimitPoll     = 50 * time.Millisecond
+	triggerLimitMaxWait  = time.Second
+	triggerLimitPause    = time.Second
+	triggerLimitMaxPause = 15 * time.Minute
+)
+
+// The results of a delivery attempt used at the metrics.
+const (
+	triggerDeliverySuccess = "success"
+	triggerDeliveryDiscard = "discard"
+	triggerDeliveryFailure = "failure"
+)
+
+// triggerMessage is the content of the messages processed by the Trigger. When the subscriptionID
+// is present, the message is a delivery to a single subscription, usually a retry. The reference
+// is the revision of the document previously notified to the subscription. The trace is the
+// context of the span that sent the message.
+type triggerMessage struct {
+	document       *flare.Document
+	action         string
+	subscriptionID string
+	attempt        int
+	notBefore      time.Time
+	reference      interface{}
+	trace          trace.SpanContext
 }
 
-func (t *Trigger) marshal(document *flare.Document, action string) ([]byte, error) {
+func (t *Trigger) marshal(
+	ctx context.Context, document *flare.Document, action string,
+) ([]byte, error) {
+	return t.marshalContent(t.baseContent(ctx, document, action))
+}
+
+func (t *Trigger) marshalDelivery(ctx context.Context, msg *triggerMessage) ([]byte, error) {
+	rawContent := t.baseContent(ctx, msg.document, msg.action)
+	rawContent["subscriptionID"] = msg.subscriptionID
+	rawContent["attempt"] = msg.attempt
+	if !msg.notBefore.IsZero() {
+		rawContent["notBefore"] = msg.notBefore.Format(time.RFC3339Nano)
+	}
+	if msg.reference != nil {
+		rawContent["referenceChangeFieldValue"] = msg.reference
+	}
+	return t.marshalContent(rawContent)
+}
+
+func (t *Trigger) baseContent(
+	ctx context.Context, document *flare.Document, action string,
+) map[string]interface{} {
 	rawContent := map[string]interface{}{
 		"action":                   action,
 		"documentID":               document.Id,
@@ -35,10 +119,18 @@ func (t *Trigger) marshal(document *flare.Document, action string) ([]byte, erro
 		"documentChangeFieldValue": document.ChangeFieldValue,
 	}
 
+	if namespace := flare.NamespaceFromContext(ctx); namespace != flare.NamespaceDefault {
+		rawContent["namespace"] = namespace
+	}
+
 	if document.Resource.Change.Kind == flare.ResourceChangeDate {
 		rawContent["changeDateFormat"] = document.Resource.Change.DateFormat
 	}
+	trace.InjectMap(ctx, rawContent)
+	return rawContent
+}
 
+func (t *Trigger) marshalContent(rawContent map[string]interface{}) ([]byte, error) {
 	content, err := json.Marshal(rawContent)
 	if err != nil {
 		return nil, errors.Wrap(err, "error during message marshal")
@@ -46,7 +138,7 @@ func (t *Trigger) marshal(document *flare.Document, action string) ([]byte, erro
 	return content, nil
 }
 
-func (t *Trigger) unmarshal(rawContent []byte) (*flare.Document, string, error) {
+func (t *Trigger) unmarshal(rawContent []byte) (*triggerMessage, error) {
 	type content struct {
 		Action           string      `json:"action"`
 		DocumentID       string      `json:"documentID"`
@@ -55,15 +147,26 @@ func (t *Trigger) unmarshal(rawContent []byte) (*flare.Document, string, error)
 		ChangeKindFormat string      `json:"changeDateFormat"`
 		UpdateAt         time.Time   `json:"updatedAt"`
 		Revision         interface{} `json:"documentChangeFieldValue"`
+		SubscriptionID   string      `json:"subscriptionID"`
+		Attempt          int         `json:"attempt"`
+		NotBefore        time.Time   `json:"notBefore"`
+		Reference        interface{} `json:"referenceChangeFieldValue"`
+		Traceparent      string      `json:"traceparent"`
+		Tracestate       string      `json:"tracestate"`
+		Namespace        string      `json:"namespace"`
 	}
 
+	// The numbers are kept as json.Number to not lose the precision of the numeric revisions.
 	var value content
-	if err := json.Unmarshal(rawContent, &value); err != nil {
-		panic(err)
+	decoder := json.NewDecoder(bytes.NewReader(rawContent))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, errors.Wrap(err, "error during message unmarshal")
 	}
 
 	resource := flare.Resource{
-		ID: value.ResourceID,
+		ID:        value.ResourceID,
+		Namespace: value.Namespace,
 		Change: flare.ResourceChange{
 			Kind:       value.ChangeKind,
 			DateFormat: value.ChangeKindFormat,
@@ -77,15 +180,35 @@ func (t *Trigger) unmarshal(rawContent []byte) (*flare.Document, string, error)
 		Resource:         resource,
 	}
 	if err := document.TransformRevision(); err != nil {
-		return nil, "", errors.Wrap(err, "error during revison transformation")
+		return nil, errors.Wrap(err, "error during revison transformation")
+	}
+
+	var reference interface{}
+	if value.Reference != nil {
+		referenceDocument := &flare.Document{ChangeFieldValue: value.Reference, Resource: resource}
+		if err := referenceDocument.TransformRevision(); err != nil {
+			return nil, errors.Wrap(err, "error during reference revison transformation")
+		}
+		reference = referenceDocument.ChangeFieldValue
 	}
 
-	return document, value.Action, nil
+	// The messages without a valid trace context are processed at a new trace.
+	sc, _ := trace.Parse(value.Traceparent, value.Tracestate)
+
+	return &triggerMessage{
+		document:       document,
+		action:         value.Action,
+		subscriptionID: value.SubscriptionID,
+		attempt:        value.Attempt,
+		notBefore:      value.NotBefore,
+		reference:      reference,
+		trace:          sc,
+	}, nil
 }
 
 // Update the document change signal.
 func (t *Trigger) Update(ctx context.Context, document *flare.Document) error {
-	content, err := t.marshal(document, flare.SubscriptionTriggerUpdate)
+	content, err := t.marshal(ctx, document, flare.SubscriptionTriggerUpdate)
 	if err != nil {
 		return errors.Wrap(err, "error during trigger")
 	}
@@ -98,7 +221,7 @@ func (t *Trigger) Update(ctx context.Context, document *flare.Document) error {
 
 // Delete the document change signal.
 func (t *Trigger) Delete(ctx context.Context, document *flare.Document) error {
-	content, err := t.marshal(document, flare.SubscriptionTriggerDelete)
+	content, err := t.marshal(ctx, document, flare.SubscriptionTriggerDelete)
 	if err != nil {
 		return errors.Wrap(err, "error during trigger")
 	}
@@ -109,98 +232,590 @@ func (t *Trigger) Delete(ctx context.Context, document *flare.Document) error {
 	return nil
 }
 
-// Process is used to consume the tasks.
-func (t *Trigger) Process(ctx context.Context, rawContent []byte) error {
-	rawDocument, action, err := t.unmarshal(rawContent)
+// Replay schedule a new delivery of a dead letter.
+func (t *Trigger) Replay(ctx context.Context, deadLetter *flare.DeadLetter) error {
+	resource, err := t.resource.FindOne(ctx, deadLetter.Subscription.Resource.ID)
+	if err != nil {
+		return errors.Wrap(err, "error during resource find")
+	}
+
+	document := deadLetter.Document
+	document.Resource = *resource
+
+	content, err := t.marshalDelivery(ctx, &triggerMessage{
+		document:       &document,
+		action:         deadLetter.Action,
+		subscriptionID: deadLetter.Subscription.ID,
+		attempt:        1,
+	})
+	if err != nil {
+		return errors.Wrap(err, "error during trigger")
+	}
+
+	if err = t.pusher.Push(ctx, content); err != nil {
+		return errors.Wrap(err, "error during message delivery")
+	}
+	return nil
+}
+
+// Process is used to consume the tasks. The span continues the trace received with the message
+// and the repositories are scoped to the namespace of the message.
+func (t *Trigger) Process(ctx context.Context, rawContent []byte) (err error) {
+	msg, err := t.unmarshal(rawContent)
 	if err != nil {
 		return errors.Wrap(err, "could not unmarshal the message")
 	}
 
-	document, err := t.document.FindOneWithRevision(ctx, rawDocument.Id, rawDocument.ChangeFieldValue)
+	ctx = flare.ContextWithNamespace(ctx, msg.document.Resource.Namespace)
+	if msg.trace.Valid() {
+		ctx = trace.ContextWithRemote(ctx, msg.trace)
+	}
+	ctx, span := t.tracer.StartSpan(ctx, "subscription "+msg.action, trace.SpanKindConsumer)
+	span.SetAttribute("document.id", msg.document.Id)
+	span.SetAttribute("resource.id", msg.document.Resource.ID)
+	if msg.subscriptionID != "" {
+		span.SetAttribute("subscription.id", msg.subscriptionID)
+	}
+	defer func() {
+		span.SetError(err)
+		span.Finish()
+	}()
+
+	if msg.subscriptionID != "" {
+		return errors.Wrap(t.processDelivery(ctx, msg), "error during delivery process")
+	}
+
+	document, err := t.document.FindOneWithRevision(
+		ctx, msg.document.Id, msg.document.ChangeFieldValue,
+	)
 	if err != nil {
 		return errors.Wrap(err, "error during document find")
 	}
 
-	if err = t.repository.Trigger(ctx, action, document, t.exec(document)); err != nil {
+	if err = t.repository.Trigger(ctx, msg.action, document, t.exec(document)); err != nil {
 		return errors.Wrap(err, "error during message process")
 	}
+
+	if t.stream != nil {
+		if err = t.stream.publish(document, msg.action, t.streamContent); err != nil {
+			return errors.Wrap(err, "error during stream publish")
+		}
+	}
 	return nil
 }
 
+// streamContent generate the stream events with the same content of the notifications, always
+// with the document and the diff.
+func (t *Trigger) streamContent(document, reference *flare.Document, kind string) ([]byte, error) {
+	content, _, err := t.buildContent(document, reference, flare.Subscription{
+		Resource: document.Resource,
+		Delivery: flare.SubscriptionDelivery{IncludeDocument: true, Diff: true},
+	}, kind)
+	return content, err
+}
+
+func (t *Trigger) processDelivery(ctx context.Context, msg *triggerMessage) error {
+	if delay := msg.notBefore.Sub(time.Now()); delay > 0 {
+		return t.schedule(ctx, msg, delay)
+	}
+
+	resourceID := msg.document.Resource.ID
+	subscription, err := t.repository.FindOne(ctx, resourceID, msg.subscriptionID)
+	if err != nil {
+		if errRepo, ok := err.(flare.SubscriptionRepositoryError); ok && errRepo.NotFound() {
+			return nil
+		}
+		return errors.Wrap(err, "error during subscription find")
+	}
+
+	resource, err := t.resource.FindOne(ctx, resourceID)
+	if err != nil {
+		return errors.Wrap(err, "error during resource find")
+	}
+	subscription.Resource = *resource
+
+	document, err := t.document.FindOneWithRevision(
+		ctx, msg.document.Id, msg.document.ChangeFieldValue,
+	)
+	if err != nil {
+		return errors.Wrap(err, "error during document find")
+	}
+	document.Resource = *resource
+
+	var reference *flare.Document
+	if msg.reference != nil {
+		reference = &flare.Document{Id: document.Id, ChangeFieldValue: msg.reference}
+	}
+	reference, err = t.loadReference(ctx, *subscription, reference)
+	if err != nil {
+		return errors.Wrap(err, "error during reference document find")
+	}
+
+	return t.deliver(ctx, document, reference, *subscription, msg.action, msg.attempt)
+}
+
 func (t *Trigger) exec(
 	document *flare.Document,
-) func(context.Context, flare.Subscription, string) error {
-	return func(ctx context.Context, sub flare.Subscription, kind string) error {
-		content, err := t.buildContent(document, sub, kind)
+) func(context.Context, flare.Subscription, string, *flare.Document) error {
+	return func(
+		ctx context.Context, sub flare.Subscription, kind string, reference *flare.Document,
+	) error {
+		match, err := t.match(sub, document)
 		if err != nil {
-			return errors.Wrap(err, "error during content build")
+			return errors.Wrap(err, "error during subscription filter")
+		}
+		if !match {
+			return nil
 		}
 
-		buf := bytes.NewBuffer(content)
-		req, err := http.NewRequest(sub.Endpoint.Method, sub.Endpoint.URL.String(), buf)
+		reference, err = t.loadReference(ctx, sub, reference)
 		if err != nil {
-			return errors.Wrap(err, "error during http request create")
+			return errors.Wrap(err, "error during reference document find")
+		}
+		return t.deliver(ctx, document, reference, sub, kind, 1)
+	}
+}
+
+// match indicates if the document match the subscription filter. The compiled filters are cached
+// by expression.
+func (t *Trigger) match(sub flare.Subscription, document *flare.Document) (bool, error) {
+	if sub.Filter == "" {
+		return true, nil
+	}
+
+	if value, ok := t.filters.Load(sub.Filter); ok {
+		return value.(*contentFilter).match(document.Content), nil
+	}
+
+	filter, err := compileFilter(sub.Filter)
+	if err != nil {
+		return false, errors.Wrap(err, fmt.Sprintf("error during filter '%s' compile", sub.Filter))
+	}
+	t.filters.Store(sub.Filter, filter)
+	return filter.match(document.Content), nil
+}
+
+// template returns the compiled subscription template. The compiled templates are cached by
+// content.
+func (t *Trigger) template(st flare.SubscriptionTemplate) (*notificationTemplate, error) {
+	key, err := templateKey(st)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, ok := t.templates.Load(key); ok {
+		return value.(*notificationTemplate), nil
+	}
+
+	tmpl, err := compileTemplate(st)
+	if err != nil {
+		return nil, errors.Wrap(err, "error during template compile")
+	}
+	t.templates.Store(key, tmpl)
+	return tmpl, nil
+}
+
+// loadReference fetch the content of the reference document when the subscription need it to
+// generate the diff. Some repositories only keep the revision of the reference document.
+func (t *Trigger) loadReference(
+	ctx context.Context, sub flare.Subscription, reference *flare.Document,
+) (*flare.Document, error) {
+	if !sub.Delivery.Diff || reference == nil || reference.Content != nil {
+		return reference, nil
+	}
+
+	document, err := t.document.FindOneWithRevision(ctx, reference.Id, reference.ChangeFieldValue)
+	if err != nil {
+		if errRepo, ok := err.(flare.DocumentRepositoryError); ok && errRepo.NotFound() {
+			return reference, nil
 		}
-		req = req.WithContext(ctx)
+		return nil, err
+	}
+	return document, nil
+}
 
-		for key, values := range sub.Endpoint.Headers {
-			for _, value := range values {
-				req.Header.Add(key, value)
-			}
+// deliver send the notification and, if the subscription has a retry policy, handle the failures
+// by scheduling a new attempt or moving the delivery to the dead letters. When the subscription
+// limits don't allow the delivery, it's scheduled to later without consuming the attempt.
+func (t *Trigger) deliver(
+	ctx context.Context,
+	document, reference *flare.Document,
+	sub flare.Subscription,
+	kind string,
+	attempt int,
+) error {
+	lease, wait, err := t.acquire(ctx, sub)
+	if err != nil {
+		return errors.Wrap(err, "error during delivery limit acquire")
+	}
+
+	if lease == "" {
+		level.Debug(t.logger).Log(
+			"subscription", sub.ID,
+			"wait", wait.String(),
+			"message", "delivery limited, scheduled to later",
+		)
+		msg := t.deliveryMessage(document, reference, sub, kind, attempt, wait)
+		return errors.Wrap(t.schedule(ctx, msg, wait), "error during delivery limit schedule")
+	}
+
+	err = t.send(ctx, document, reference, sub, kind, attempt)
+	if errRelease := t.limit.Release(ctx, sub, lease); errRelease != nil {
+		level.Error(t.logger).Log(
+			"error", errRelease.Error(),
+			"subscription", sub.ID,
+			"message", "error during delivery limit release",
+		)
+	}
+
+	if err == nil || !sub.Delivery.Retry.Enabled() {
+		return err
+	}
+
+	if attempt >= sub.Delivery.Retry.MaxAttempts {
+		deadLetter := &flare.DeadLetter{
+			ID:           uuid.NewV4().String(),
+			Subscription: sub,
+			Document:     *document,
+			Action:       kind,
+			Attempts:     attempt,
+			Error:        err.Error(),
 		}
-		req.Header.Add("Content-Type", "application/json")
 
-		resp, err := t.httpClient.Do(req)
-		if err != nil {
-			return errors.Wrap(err, "error during http request")
+		if errDL := t.deadLetter.Create(ctx, deadLetter); errDL != nil {
+			return errors.Wrap(errDL, "error during dead letter create")
 		}
+		return nil
+	}
 
-		for _, status := range sub.Delivery.Success {
-			if status == resp.StatusCode {
-				return nil
-			}
+	delay := sub.Delivery.Retry.Backoff(attempt)
+	msg := t.deliveryMessage(document, reference, sub, kind, attempt+1, delay)
+	return errors.Wrap(t.schedule(ctx, msg, delay), "error during delivery retry schedule")
+}
+
+func (t *Trigger) deliveryMessage(
+	document, reference *flare.Document,
+	sub flare.Subscription,
+	kind string,
+	attempt int,
+	delay time.Duration,
+) *triggerMessage {
+	msg := &triggerMessage{
+		document:       document,
+		action:         kind,
+		subscriptionID: sub.ID,
+		attempt:        attempt,
+		notBefore:      time.Now().Add(delay),
+	}
+	if reference != nil {
+		msg.reference = reference.ChangeFieldValue
+	}
+	return msg
+}
+
+// acquire reserve a delivery at the subscription limits. The short waits are done here, when the
+// wait is longer, the lease is empty and the wait is returned.
+func (t *Trigger) acquire(
+	ctx context.Context, sub flare.Subscription,
+) (string, time.Duration, error) {
+	var waited time.Duration
+	for {
+		lease, wait, err := t.limit.Acquire(ctx, sub)
+		if err != nil || lease != "" {
+			return lease, 0, err
+		}
+
+		if wait <= 0 {
+			wait = triggerLimitPoll
+		}
+
+		if waited+wait > triggerLimitMaxWait {
+			return "", wait, nil
 		}
+		waited += wait
 
+		select {
+		case <-ctx.Done():
+			return "", 0, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// pause stop the deliveries to the subscription by the time asked by the subscriber.
+func (t *Trigger) pause(ctx context.Context, sub flare.Subscription, wait time.Duration) {
+	if wait <= 0 {
+		wait = triggerLimitPause
+	}
+
+	if wait > triggerLimitMaxPause {
+		wait = triggerLimitMaxPause
+	}
+
+	if err := t.limit.Pause(ctx, sub, time.Now().Add(wait)); err != nil {
+		level.Error(t.logger).Log(
+			"error", err.Error(),
+			"subscription", sub.ID,
+			"message", "error during subscription pause",
+		)
+	}
+}
+
+func (t *Trigger) schedule(ctx context.Context, msg *triggerMessage, delay time.Duration) error {
+	pusher, ok := t.pusher.(task.DelayPusher)
+	if !ok {
+		return errors.New("pusher does not support delayed tasks")
+	}
+
+	content, err := t.marshalDelivery(ctx, msg)
+	if err != nil {
+		return errors.Wrap(err, "error during trigger")
+	}
+
+	if err = pusher.PushDelay(ctx, content, delay); err != nil {
+		return errors.Wrap(err, "error during message delivery")
+	}
+	return nil
+}
+
+func (t *Trigger) send(
+	ctx context.Context,
+	document, reference *flare.Document,
+	sub flare.Subscription,
+	kind string,
+	attempt int,
+) error {
+	delivery := &flare.Delivery{
+		ID:           uuid.NewV4().String(),
+		Subscription: sub,
+		Document:     *document,
+		Action:       kind,
+		Attempt:      attempt,
+	}
+
+	ctx, span := t.tracer.StartSpan(
+		ctx, "delivery "+sub.Endpoint.TargetKind(), trace.SpanKindClient,
+	)
+	span.SetAttribute("subscription.id", sub.ID)
+	span.SetAttribute("delivery.id", delivery.ID)
+	span.SetAttribute("delivery.attempt", strconv.Itoa(attempt))
+	defer span.Finish()
+
+	err := t.request(ctx, document, reference, sub, kind, delivery)
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	if delivery.Status == http.StatusTooManyRequests {
+		t.pause(ctx, sub, delivery.RetryAfter)
+	}
+	span.SetError(err)
+	if delivery.Status != 0 {
+		span.SetAttribute("http.status_code", strconv.Itoa(delivery.Status))
+	}
+
+	labels := []string{
+		"resource", sub.Resource.ID,
+		"subscription", sub.ID,
+		"target", delivery.Target,
+	}
+	result := t.deliveryResult(sub, delivery, err)
+	t.metrics.deliveries.With(append(labels, "result", result)...).Add(1)
+	t.metrics.latency.With(labels...).Observe(delivery.Latency.Seconds())
+
+	if errDelivery := t.delivery.Create(ctx, delivery); errDelivery != nil {
+		level.Error(t.logger).Log(
+			"error", errDelivery.Error(),
+			"subscription", sub.ID,
+			"message", "error during delivery log",
+		)
+	}
+
+	return err
+}
+
+// deliveryResult classify the delivery attempt. The discard is a HTTP response with one of the
+// subscription discard status.
+func (t *Trigger) deliveryResult(
+	sub flare.Subscription, delivery *flare.Delivery, err error,
+) string {
+	if err != nil {
+		return triggerDeliveryFailure
+	}
+
+	if delivery.Target == flare.SubscriptionTargetHTTP {
 		for _, status := range sub.Delivery.Discard {
-			if status == resp.StatusCode {
-				return nil
+			if status == delivery.Status {
+				return triggerDeliveryDiscard
 			}
 		}
+	}
+	return triggerDeliverySuccess
+}
 
-		return errors.Errorf(
-			"success and discard status don't match with the response value '%d'", resp.StatusCode,
-		)
+func (t *Trigger) request(
+	ctx context.Context,
+	document, reference *flare.Document,
+	sub flare.Subscription,
+	kind string,
+	delivery *flare.Delivery,
+) error {
+	delivery.Target = sub.Endpoint.TargetKind()
+	target, ok := t.targets[delivery.Target]
+	if !ok {
+		return fmt.Errorf("target '%s' not configured", delivery.Target)
+	}
+
+	content, templateHeader, err := t.buildContent(document, reference, sub, kind)
+	if err != nil {
+		return errors.Wrap(err, "error during content build")
+	}
+
+	header := make(http.Header)
+	for key, values := range sub.Endpoint.Headers {
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
+	header.Add("Content-Type", "application/json")
+	for key, values := range templateHeader {
+		header[key] = values
 	}
+	trace.Inject(ctx, header)
+	header.Set(triggerHeaderDelivery, delivery.ID)
+	if sub.Secret.Enabled() {
+		header.Set(triggerHeaderSignature, t.signature(sub.Secret, time.Now(), content))
+	}
+
+	start := time.Now()
+	err = target.Deliver(ctx, sub, content, header, delivery)
+	delivery.Latency = time.Since(start)
+	return errors.Wrap(err, fmt.Sprintf("error during delivery to the %s target", delivery.Target))
+}
+
+func (t *Trigger) signature(
+	secret flare.SubscriptionSecret, timestamp time.Time, content []byte,
+) string {
+	value := "t=" + strconv.FormatInt(timestamp.Unix(), 10)
+	for _, signature := range secret.Sign(timestamp, content) {
+		value += ",v1=" + signature
+	}
+	return value
 }
 
+// buildContent generate the notification body and the headers from the subscription template.
 func (t *Trigger) buildContent(
-	document *flare.Document, sub flare.Subscription, kind string,
-) ([]byte, error) {
+	document, reference *flare.Document, sub flare.Subscription, kind string,
+) ([]byte, http.Header, error) {
+	data, err := t.buildData(document, sub)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var patch []patchOperation
+	if sub.Delivery.Diff && kind == flare.SubscriptionTriggerUpdate && reference != nil {
+		if document.Content != nil && reference.Content != nil {
+			patch = diff(reference.Content, document.Content)
+		}
+	}
+
+	var header http.Header
+	if sub.Template.Enabled() {
+		var content []byte
+		content, header, err = t.buildTemplate(document, sub, kind, data, patch)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error during template render")
+		}
+
+		if content != nil {
+			return content, header, nil
+		}
+	}
+
 	rawContent := map[string]interface{}{
 		"id":        document.Id,
 		"action":    kind,
 		"updatedAt": document.UpdatedAt.String(),
 	}
-	if sub.Data != nil {
-		replacer, err := sub.Resource.WildcardReplace(document.Id, document.ChangeFieldValue)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to extract the wildcards from document id")
-		}
+	if data != nil {
+		rawContent["data"] = data
+	}
 
-		for key, rawValue := range sub.Data {
-			if value, ok := rawValue.(string); ok {
-				sub.Data[key] = replacer(value)
-			}
-		}
+	if sub.Delivery.IncludeDocument && document.Content != nil {
+		rawContent["document"] = document.Content
+	}
 
-		rawContent["data"] = sub.Data
+	if patch != nil {
+		rawContent["diff"] = patch
 	}
 
 	content, err := json.Marshal(rawContent)
 	if err != nil {
-		return nil, errors.Wrap(err, "error during response generate")
+		return nil, nil, errors.Wrap(err, "error during response generate")
 	}
-	return content, nil
+	return content, header, nil
+}
+
+// buildData replace the wildcards at the subscription data.
+func (t *Trigger) buildData(
+	document *flare.Document, sub flare.Subscription,
+) (map[string]interface{}, error) {
+	if sub.Data == nil {
+		return nil, nil
+	}
+
+	replacer, err := sub.Resource.WildcardReplace(document.Id, document.ChangeFieldValue)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to extract the wildcards from document id")
+	}
+
+	// The subscription data is shared between the deliveries, so a copy is used.
+	data := make(map[string]interface{}, len(sub.Data))
+	for key, rawValue := range sub.Data {
+		if value, ok := rawValue.(string); ok {
+			data[key] = replacer(value)
+		} else {
+			data[key] = rawValue
+		}
+	}
+	return data, nil
+}
+
+func (t *Trigger) buildTemplate(
+	document *flare.Document,
+	sub flare.Subscription,
+	kind string,
+	data map[string]interface{},
+	patch []patchOperation,
+) ([]byte, http.Header, error) {
+	tmpl, err := t.template(sub.Template)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wildcards, err := sub.Resource.Wildcards(document.Id)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to extract the wildcards from document id")
+	}
+
+	return tmpl.render(&templateData{
+		Action: kind,
+		Document: templateDocument{
+			ID:        document.Id,
+			Revision:  document.ChangeFieldValue,
+			Content:   document.Content,
+			UpdatedAt: document.UpdatedAt,
+		},
+		Resource: templateResource{
+			ID:        sub.Resource.ID,
+			Path:      sub.Resource.Path,
+			Addresses: sub.Resource.Addresses,
+		},
+		Wildcards: wildcards,
+		Data:      data,
+		Diff:      patch,
+		Timestamp: time.Now(),
+	})
 }
 
 // Init initialize the Trigger.
@@ -221,10 +836,42 @@ func (t *Trigger) Init(options ...func(*Trigger)) error {
 		return errors.New("repository not found")
 	}
 
+	if t.resource == nil {
+		return errors.New("resource repository not found")
+	}
+
+	if t.deadLetter == nil {
+		return errors.New("dead letter repository not found")
+	}
+
+	if t.delivery == nil {
+		return errors.New("delivery repository not found")
+	}
+
+	if t.limit == nil {
+		return errors.New("limit repository not found")
+	}
+
+	if t.logger == nil {
+		return errors.New("logger not found")
+	}
+
 	if t.httpClient == nil {
 		return errors.New("httpClient not found")
 	}
 
+	if t.metrics.deliveries == nil {
+		t.metrics.deliveries = discard.NewCounter()
+		t.metrics.latency = discard.NewHistogram()
+	}
+
+	if t.targets == nil {
+		t.targets = make(map[string]flare.SubscriptionTarget)
+	}
+	if _, ok := t.targets[flare.SubscriptionTargetHTTP]; !ok {
+		t.targets[flare.SubscriptionTargetHTTP] = &targetHTTP{client: t.httpClient}
+	}
+
 	return nil
 }
 
@@ -255,3 +902,81 @@ func TriggerDocumentRepository(repo flare.DocumentRepositorier) func(*Trigger) {
 		t.document = repo
 	}
 }
+
+// TriggerResourceRepository set the resource repository.
+func TriggerResourceRepository(repo flare.ResourceRepositorier) func(*Trigger) {
+	return func(t *Trigger) {
+		t.resource = repo
+	}
+}
+
+// TriggerDeadLetterRepository set the repository to store the deliveries that exhausted the retries.
+func TriggerDeadLetterRepository(repo flare.DeadLetterRepositorier) func(*Trigger) {
+	return func(t *Trigger) {
+		t.deadLetter = repo
+	}
+}
+
+// TriggerDeliveryRepository set the repository to log the delivery attempts.
+func TriggerDeliveryRepository(repo flare.DeliveryRepositorier) func(*Trigger) {
+	return func(t *Trigger) {
+		t.delivery = repo
+	}
+}
+
+// TriggerLimitRepository set the repository that keep the state of the subscriptions limits.
+func TriggerLimitRepository(repo flare.SubscriptionLimitRepositorier) func(*Trigger) {
+	return func(t *Trigger) {
+		t.limit = repo
+	}
+}
+
+// TriggerTarget set a target to deliver the subscriptions of a given kind. The HTTP target is
+// configured by default with the HTTP client.
+func TriggerTarget(kind string, target flare.SubscriptionTarget) func(*Trigger) {
+	return func(t *Trigger) {
+		if t.targets == nil {
+			t.targets = make(map[string]flare.SubscriptionTarget)
+		}
+		t.targets[kind] = target
+	}
+}
+
+// TriggerStream set the stream that receive the document changes.
+func TriggerStream(stream *Stream) func(*Trigger) {
+	return func(t *Trigger) {
+		t.stream = stream
+	}
+}
+
+// TriggerMetrics set the registry used to record the deliveries and its latency per subscription.
+func TriggerMetrics(registry *infraMetrics.Registry) func(*Trigger) {
+	return func(t *Trigger) {
+		t.metrics.deliveries = registry.NewCounter(
+			"subscription_deliveries_total",
+			"Quantity of delivery attempts by result.",
+			"resource", "subscription", "target", "result",
+		)
+		t.metrics.latency = registry.NewHistogram(
+			"subscription_delivery_duration_seconds",
+			"Latency of the delivery attempts.",
+			nil,
+			"resource", "subscription", "target",
+		)
+	}
+}
+
+// TriggerTracer set the tracer of the deliveries. It's optional, without it the trace context is
+// propagated to the notifications but the spans are not exported.
+func TriggerTracer(tracer *trace.Tracer) func(*Trigger) {
+	return func(t *Trigger) {
+		t.tracer = tracer
+	}
+}
+
+// TriggerLogger set the logger on Trigger.
+func TriggerLogger(logger log.Logger) func(*Trigger) {
+	return func(t *Trigger) {
+		t.logger = log.With(logger, "package", "subscription")
+	}
+}
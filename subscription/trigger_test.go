@@ -0,0 +1,364 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subscription
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/diegobernardes/flare"
+	"github.com/diegobernardes/flare/infra/trace"
+	queueMemory "github.com/diegobernardes/flare/queue/memory"
+	"github.com/diegobernardes/flare/repository/memory"
+	targetMemory "github.com/diegobernardes/flare/target/memory"
+)
+
+func TestTriggerProcess(t *testing.T) {
+	Convey("Given a Trigger with the memory repositories", t, func() {
+		notifications := make(chan map[string]interface{}, 10)
+		status := http.StatusOK
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "30")
+				w.WriteHeader(status)
+				return
+			}
+
+			content := make(map[string]interface{})
+			if err := json.NewDecoder(r.Body).Decode(&content); err == nil {
+				notifications <- content
+			}
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+
+		resource := &flare.Resource{
+			ID:        "123",
+			Addresses: []string{"http://app.com"},
+			Path:      "/users/{id}",
+			Change:    flare.ResourceChange{Field: "revision", Kind: flare.ResourceChangeInteger},
+		}
+		resourceRepository := memory.NewResource()
+		So(resourceRepository.Create(context.Background(), resource), ShouldBeNil)
+
+		endpoint, err := url.Parse(server.URL)
+		So(err, ShouldBeNil)
+
+		subscriptionRepository := memory.NewSubscription()
+		So(subscriptionRepository.Create(context.Background(), &flare.Subscription{
+			ID:       "456",
+			Resource: flare.Resource{ID: resource.ID},
+			Endpoint: flare.SubscriptionEndpoint{URL: *endpoint, Method: http.MethodPost},
+			Delivery: flare.SubscriptionDelivery{
+				Success: []int{200}, Discard: []int{500}, Diff: true,
+			},
+			Data: map[string]interface{}{"user": "{id}"},
+		}), ShouldBeNil)
+
+		queue, err := queueMemory.NewQueue()
+		So(err, ShouldBeNil)
+
+		sink, err := targetMemory.NewSink()
+		So(err, ShouldBeNil)
+
+		deliveryRepository := memory.NewDelivery()
+		documentRepository := memory.NewDocument()
+		limitRepository := memory.NewSubscriptionLimit()
+		trigger := &Trigger{}
+		err = trigger.Init(
+			TriggerRepository(subscriptionRepository),
+			TriggerResourceRepository(resourceRepository),
+			TriggerDocumentRepository(documentRepository),
+			TriggerDeadLetterRepository(memory.NewDeadLetter()),
+			TriggerDeliveryRepository(deliveryRepository),
+			TriggerLimitRepository(limitRepository),
+			TriggerLogger(log.NewNopLogger()),
+			TriggerHTTPClient(http.DefaultClient),
+			TriggerPusher(queue),
+			TriggerTarget(flare.SubscriptionTargetMemory, sink),
+		)
+		So(err, ShouldBeNil)
+
+		update := func(revision interface{}) {
+			document := &flare.Document{
+				Id:               "http://app.com/users/1",
+				ChangeFieldValue: revision,
+				Content:          map[string]interface{}{"revision": revision},
+				Resource:         *resource,
+			}
+			So(document.TransformRevision(), ShouldBeNil)
+			So(documentRepository.Update(context.Background(), document), ShouldBeNil)
+			So(trigger.Update(context.Background(), document), ShouldBeNil)
+			So(queue.Pull(context.Background(), trigger.Process), ShouldBeNil)
+		}
+
+		Convey("It should notify the document create and update", func() {
+			update(float64(1))
+			notification := <-notifications
+			So(notification["action"], ShouldEqual, flare.SubscriptionTriggerCreate)
+			So(notification["id"], ShouldEqual, "http://app.com/users/1")
+			So(notification["data"], ShouldResemble, map[string]interface{}{"user": "1"})
+
+			update("2")
+			notification = <-notifications
+			So(notification["action"], ShouldEqual, flare.SubscriptionTriggerUpdate)
+			So(notification["diff"], ShouldResemble, []interface{}{
+				map[string]interface{}{"op": "replace", "path": "/revision", "value": "2"},
+			})
+
+			Convey("It should not notify a older revision", func() {
+				update(1)
+				So(notifications, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When the subscription only wants the delete action", func() {
+			subscription, err := subscriptionRepository.FindOne(context.Background(), resource.ID, "456")
+			So(err, ShouldBeNil)
+			subscription.Actions = []string{flare.SubscriptionTriggerDelete}
+			So(subscriptionRepository.Update(context.Background(), subscription), ShouldBeNil)
+
+			Convey("It should notify only the document delete", func() {
+				update(float64(1))
+				update(float64(2))
+				So(notifications, ShouldBeEmpty)
+
+				document := &flare.Document{
+					Id: "http://app.com/users/1", ChangeFieldValue: 2, Resource: *resource,
+				}
+				So(trigger.Delete(context.Background(), document), ShouldBeNil)
+				So(queue.Pull(context.Background(), trigger.Process), ShouldBeNil)
+				notification := <-notifications
+				So(notification["action"], ShouldEqual, flare.SubscriptionTriggerDelete)
+			})
+		})
+
+		Convey("When the subscription has a rate limit", func() {
+			subscription, err := subscriptionRepository.FindOne(context.Background(), resource.ID, "456")
+			So(err, ShouldBeNil)
+			subscription.Delivery.Limit = flare.SubscriptionDeliveryLimit{RequestsPerSecond: 0.1}
+			So(subscriptionRepository.Update(context.Background(), subscription), ShouldBeNil)
+
+			Convey("The deliveries above the rate should be scheduled to later", func() {
+				update(float64(1))
+				So(<-notifications, ShouldNotBeNil)
+
+				update(float64(2))
+				So(notifications, ShouldBeEmpty)
+
+				deliveries, _, err := deliveryRepository.FindAll(
+					context.Background(), &flare.Pagination{Limit: 10}, resource.ID, "456", nil,
+				)
+				So(err, ShouldBeNil)
+				So(deliveries, ShouldHaveLength, 1)
+			})
+		})
+
+		Convey("When the subscriber answer 429", func() {
+			status = http.StatusTooManyRequests
+			document := &flare.Document{
+				Id:               "http://app.com/users/1",
+				ChangeFieldValue: 1,
+				Content:          map[string]interface{}{"revision": 1},
+				Resource:         *resource,
+			}
+			So(documentRepository.Update(context.Background(), document), ShouldBeNil)
+			So(trigger.Update(context.Background(), document), ShouldBeNil)
+			So(queue.Pull(context.Background(), trigger.Process), ShouldNotBeNil)
+
+			Convey("The subscription should be paused by the Retry-After", func() {
+				subscription, err := subscriptionRepository.FindOne(
+					context.Background(), resource.ID, "456",
+				)
+				So(err, ShouldBeNil)
+
+				lease, wait, err := limitRepository.Acquire(context.Background(), *subscription)
+				So(err, ShouldBeNil)
+				So(lease, ShouldBeEmpty)
+				So(wait, ShouldBeBetweenOrEqual, 29*time.Second, 30*time.Second)
+			})
+		})
+
+		Convey("When the subscription is delivered to the memory target", func() {
+			subscription, err := subscriptionRepository.FindOne(context.Background(), resource.ID, "456")
+			So(err, ShouldBeNil)
+			subscription.Endpoint = flare.SubscriptionEndpoint{
+				Target: flare.SubscriptionTargetMemory, Topic: "users",
+			}
+			So(subscriptionRepository.Update(context.Background(), subscription), ShouldBeNil)
+
+			Convey("It should notify the sink and log the delivery", func() {
+				update(float64(1))
+				So(notifications, ShouldBeEmpty)
+
+				messages := sink.Messages("users")
+				So(messages, ShouldHaveLength, 1)
+				So(messages[0].Header.Get(triggerHeaderDelivery), ShouldNotBeEmpty)
+
+				content := make(map[string]interface{})
+				So(json.Unmarshal(messages[0].Content, &content), ShouldBeNil)
+				So(content["action"], ShouldEqual, flare.SubscriptionTriggerCreate)
+
+				deliveries, _, err := deliveryRepository.FindAll(
+					context.Background(), &flare.Pagination{Limit: 10}, resource.ID, "456", nil,
+				)
+				So(err, ShouldBeNil)
+				So(deliveries, ShouldHaveLength, 1)
+				So(deliveries[0].Target, ShouldEqual, flare.SubscriptionTargetMemory)
+				So(deliveries[0].MessageID, ShouldEqual, messages[0].ID)
+				So(deliveries[0].Error, ShouldBeEmpty)
+			})
+
+			Convey("It should continue the trace of the document change", func() {
+				parent, err := trace.Parse(
+					"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "vendor=value",
+				)
+				So(err, ShouldBeNil)
+
+				document := &flare.Document{
+					Id:               "http://app.com/users/1",
+					ChangeFieldValue: 1,
+					Content:          map[string]interface{}{"revision": 1},
+					Resource:         *resource,
+				}
+				So(documentRepository.Update(context.Background(), document), ShouldBeNil)
+
+				ctx := trace.ContextWithRemote(context.Background(), parent)
+				So(trigger.Update(ctx, document), ShouldBeNil)
+				So(queue.Pull(context.Background(), trigger.Process), ShouldBeNil)
+
+				messages := sink.Messages("users")
+				So(messages, ShouldHaveLength, 1)
+
+				sc, err := trace.Parse(
+					messages[0].Header.Get(trace.HeaderTraceparent),
+					messages[0].Header.Get(trace.HeaderTracestate),
+				)
+				So(err, ShouldBeNil)
+				So(sc.TraceID, ShouldEqual, parent.TraceID)
+				So(sc.SpanID, ShouldNotEqual, parent.SpanID)
+				So(sc.State, ShouldEqual, "vendor=value")
+			})
+		})
+	})
+}
+
+func TestTriggerBuildContent(t *testing.T) {
+	Convey("Given a document with content", t, func() {
+		document := &flare.Document{
+			Id:               "http://app.com/users/1",
+			ChangeFieldValue: 1,
+			Content:          map[string]interface{}{"name": "Diego", "revision": float64(1)},
+		}
+
+		tests := []struct {
+			title           string
+			includeDocument bool
+			hasDocument     bool
+		}{
+			{"The notification should not have the document", false, false},
+			{"The notification should have the document", true, true},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				sub := flare.Subscription{
+					Delivery: flare.SubscriptionDelivery{IncludeDocument: tt.includeDocument},
+				}
+
+				content, _, err := (&Trigger{}).buildContent(
+					document, nil, sub, flare.SubscriptionTriggerUpdate,
+				)
+				So(err, ShouldBeNil)
+
+				result := make(map[string]interface{})
+				So(json.Unmarshal(content, &result), ShouldBeNil)
+
+				_, ok := result["document"]
+				So(ok, ShouldEqual, tt.hasDocument)
+				if tt.hasDocument {
+					So(result["document"], ShouldResemble, document.Content)
+				}
+			})
+		}
+	})
+
+	Convey("Given a subscription with a template", t, func() {
+		document := &flare.Document{
+			Id:               "http://app.com/users/1",
+			ChangeFieldValue: 1,
+			Content:          map[string]interface{}{"name": "Diego"},
+		}
+
+		sub := flare.Subscription{
+			Resource: flare.Resource{Path: "/users/{id}"},
+			Template: flare.SubscriptionTemplate{
+				Body:    `{"user": {{json .Wildcards.id}}, "name": {{json .Document.Content.name}}}`,
+				Headers: map[string]string{"X-Event": "user.{{.Action}}"},
+			},
+		}
+
+		Convey("The notification should be rendered from the template", func() {
+			content, header, err := (&Trigger{}).buildContent(
+				document, nil, sub, flare.SubscriptionTriggerCreate,
+			)
+			So(err, ShouldBeNil)
+			So(string(content), ShouldEqual, `{"user": "1", "name": "Diego"}`)
+			So(header.Get("X-Event"), ShouldEqual, "user.create")
+		})
+
+		Convey("The compiled template should be reused", func() {
+			trigger := &Trigger{}
+			for i := 0; i < 2; i++ {
+				_, _, err := trigger.buildContent(document, nil, sub, flare.SubscriptionTriggerCreate)
+				So(err, ShouldBeNil)
+			}
+
+			var templates []interface{}
+			trigger.templates.Range(func(_, value interface{}) bool {
+				templates = append(templates, value)
+				return true
+			})
+			So(templates, ShouldHaveLength, 1)
+
+			tmpl, err := trigger.template(sub.Template)
+			So(err, ShouldBeNil)
+			So(tmpl, ShouldEqual, templates[0])
+		})
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	Convey("Given a list of Retry-After values", t, func() {
+		now := time.Date(2017, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+		tests := []struct {
+			title    string
+			value    string
+			expected time.Duration
+		}{
+			{"Empty", "", 0},
+			{"Seconds", "120", 2 * time.Minute},
+			{"Negative seconds", "-1", 0},
+			{"Date", "Fri, 10 Nov 2017 23:00:30 GMT", 30 * time.Second},
+			{"Past date", "Fri, 10 Nov 2017 22:00:00 GMT", 0},
+			{"Invalid", "soon", 0},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				So(parseRetryAfter(tt.value, now), ShouldEqual, tt.expected)
+			})
+		}
+	})
+}
@@ -0,0 +1,181 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flare
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSubscriptionDeliveryRetryBackoff(t *testing.T) {
+	Convey("Given a SubscriptionDeliveryRetry without jitter", t, func() {
+		retry := SubscriptionDeliveryRetry{
+			MaxAttempts:    10,
+			InitialBackoff: time.Second,
+			MaxBackoff:     10 * time.Second,
+		}
+
+		tests := []struct {
+			attempt int
+			backoff time.Duration
+		}{
+			{1, time.Second},
+			{2, 2 * time.Second},
+			{3, 4 * time.Second},
+			{4, 8 * time.Second},
+			{5, 10 * time.Second},
+			{9, 10 * time.Second},
+		}
+
+		Convey("The backoff should grow exponentially up to the MaxBackoff", func() {
+			for _, tt := range tests {
+				So(retry.Backoff(tt.attempt), ShouldEqual, tt.backoff)
+			}
+		})
+	})
+
+	Convey("Given a SubscriptionDeliveryRetry with jitter", t, func() {
+		retry := SubscriptionDeliveryRetry{
+			MaxAttempts:    10,
+			InitialBackoff: time.Second,
+			MaxBackoff:     10 * time.Second,
+			Jitter:         0.5,
+		}
+
+		Convey("The backoff should be inside the jitter range", func() {
+			for i := 0; i < 100; i++ {
+				backoff := retry.Backoff(3)
+				So(backoff, ShouldBeLessThanOrEqualTo, 4*time.Second)
+				So(backoff, ShouldBeGreaterThanOrEqualTo, 2*time.Second)
+			}
+		})
+	})
+
+	Convey("Given a list of SubscriptionDeliveryRetry", t, func() {
+		Convey("It should be enabled only when MaxAttempts is set", func() {
+			So((&SubscriptionDeliveryRetry{}).Enabled(), ShouldBeFalse)
+			So((&SubscriptionDeliveryRetry{MaxAttempts: 1}).Enabled(), ShouldBeTrue)
+		})
+	})
+}
+
+func TestSubscriptionSecretSign(t *testing.T) {
+	Convey("Given a SubscriptionSecret", t, func() {
+		timestamp := time.Unix(1500000000, 0)
+		secret := SubscriptionSecret{Value: "secret"}
+
+		Convey("It should generate a HMAC-SHA256 signature from the timestamp and content", func() {
+			signatures := secret.Sign(timestamp, []byte("content"))
+			So(signatures, ShouldResemble, []string{
+				"70cbf5802292b3317307b2e3ef5705b58c3673e3d53215815181fddb5dd2f407",
+			})
+		})
+
+		Convey("When the secret is rotated with a overlap", func() {
+			secret.Rotate("new-secret", time.Hour)
+
+			Convey("It should sign with both secrets", func() {
+				signatures := secret.Sign(time.Now(), []byte("content"))
+				So(signatures, ShouldHaveLength, 2)
+				So(secret.PreviousValue, ShouldEqual, "secret")
+			})
+
+			Convey("It should sign only with the new secret after the overlap", func() {
+				signatures := secret.Sign(time.Now().Add(2*time.Hour), []byte("content"))
+				So(signatures, ShouldHaveLength, 1)
+			})
+		})
+
+		Convey("When the secret is rotated without a overlap", func() {
+			secret.Rotate("new-secret", 0)
+
+			Convey("It should sign only with the new secret", func() {
+				So(secret.Sign(time.Now(), []byte("content")), ShouldHaveLength, 1)
+				So(secret.PreviousValue, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestSubscriptionHasAction(t *testing.T) {
+	Convey("Given a list of subscriptions", t, func() {
+		tests := []struct {
+			title   string
+			actions []string
+			action  string
+			expect  bool
+		}{
+			{"All the actions should be notified by default", nil, SubscriptionTriggerDelete, true},
+			{
+				"The action should be notified",
+				[]string{SubscriptionTriggerCreate, SubscriptionTriggerDelete},
+				SubscriptionTriggerDelete,
+				true,
+			},
+			{
+				"The action should not be notified",
+				[]string{SubscriptionTriggerDelete},
+				SubscriptionTriggerUpdate,
+				false,
+			},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				subscription := Subscription{Actions: tt.actions}
+				So(subscription.HasAction(tt.action), ShouldEqual, tt.expect)
+			})
+		}
+	})
+}
+
+func TestSubscriptionTriggerAction(t *testing.T) {
+	Convey("Given a wrapped trigger function", t, func() {
+		var called bool
+		fn := SubscriptionTriggerAction(
+			func(context.Context, Subscription, string, *Document) error {
+				called = true
+				return nil
+			},
+		)
+		subscription := Subscription{Actions: []string{SubscriptionTriggerDelete}}
+
+		Convey("It should call the function if the subscription wants the action", func() {
+			So(fn(context.Background(), subscription, SubscriptionTriggerDelete, nil), ShouldBeNil)
+			So(called, ShouldBeTrue)
+		})
+
+		Convey("It should skip the function if the subscription don't want the action", func() {
+			So(fn(context.Background(), subscription, SubscriptionTriggerUpdate, nil), ShouldBeNil)
+			So(called, ShouldBeFalse)
+		})
+	})
+}
+
+func TestSubscriptionDeliveryLimit(t *testing.T) {
+	Convey("Given a list of SubscriptionDeliveryLimit", t, func() {
+		tests := []struct {
+			title    string
+			limit    SubscriptionDeliveryLimit
+			enabled  bool
+			interval time.Duration
+		}{
+			{"Unlimited", SubscriptionDeliveryLimit{}, false, 0},
+			{"Only the in-flight", SubscriptionDeliveryLimit{MaxInFlight: 2}, true, 0},
+			{"Only the rate", SubscriptionDeliveryLimit{RequestsPerSecond: 4}, true, 250 * time.Millisecond},
+			{"Rate below one", SubscriptionDeliveryLimit{RequestsPerSecond: 0.5}, true, 2 * time.Second},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				So(tt.limit.Enabled(), ShouldEqual, tt.enabled)
+				So(tt.limit.Interval(), ShouldEqual, tt.interval)
+			})
+		}
+	})
+}
@@ -0,0 +1,145 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+
+	"github.com/diegobernardes/flare"
+)
+
+const (
+	// Max size of the response body stored at the delivery log.
+	proxyMaxResponseBody = 1024
+
+	// Max size of the response body read from the proxy.
+	proxyMaxResponseRead = 64 * 1024
+)
+
+// Proxy implements flare.SubscriptionTarget publishing the notifications through a Kafka REST
+// Proxy, API v2. The subscription endpoint URL is the proxy address and the message key is the
+// document id, this way, the changes of a document are kept in order at the same partition.
+type Proxy struct {
+	client *http.Client
+}
+
+type proxyRecords struct {
+	Records []proxyRecord `json:"records"`
+}
+
+type proxyRecord struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type proxyResponse struct {
+	Offsets []struct {
+		Partition int    `json:"partition"`
+		Offset    int64  `json:"offset"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// Deliver publish the notification at the endpoint topic.
+func (p *Proxy) Deliver(
+	ctx context.Context,
+	sub flare.Subscription,
+	content []byte,
+	header http.Header,
+	delivery *flare.Delivery,
+) error {
+	if sub.Endpoint.Topic == "" {
+		return errors.New("missing topic")
+	}
+
+	// The binary embedded format is used because the templates can generate any kind of content.
+	body, err := json.Marshal(&proxyRecords{
+		Records: []proxyRecord{{Key: []byte(delivery.Document.Id), Value: content}},
+	})
+	if err != nil {
+		return errors.Wrap(err, "error during records marshal")
+	}
+
+	endpoint := sub.Endpoint.URL
+	endpoint.Path = fmt.Sprintf("%s/topics/%s", endpoint.Path, url.PathEscape(sub.Endpoint.Topic))
+	req, err := http.NewRequest(http.MethodPost, endpoint.String(), bytes.NewBuffer(body))
+	if err != nil {
+		return errors.Wrap(err, "error during http request create")
+	}
+	req = req.WithContext(ctx)
+
+	// The headers are sent to the proxy because the API don't support record headers, this way,
+	// the endpoint headers can be used to authenticate at the proxy.
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.binary.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error during http request")
+	}
+	defer resp.Body.Close()
+
+	delivery.Status = resp.StatusCode
+	rawResponse, err := ioutil.ReadAll(io.LimitReader(resp.Body, proxyMaxResponseRead))
+	if err != nil {
+		return errors.Wrap(err, "error during response read")
+	}
+	delivery.ResponseBody = string(rawResponse)
+	if len(rawResponse) > proxyMaxResponseBody {
+		delivery.ResponseBody = string(rawResponse[:proxyMaxResponseBody])
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected proxy response status '%d'", resp.StatusCode)
+	}
+
+	var response proxyResponse
+	if err := json.Unmarshal(rawResponse, &response); err != nil {
+		return errors.Wrap(err, "error during response unmarshal")
+	}
+
+	if len(response.Offsets) != 1 {
+		return fmt.Errorf("expected one offset at the proxy response, got '%d'", len(response.Offsets))
+	}
+
+	offset := response.Offsets[0]
+	if offset.Error != "" {
+		return fmt.Errorf("error during message publish: %s", offset.Error)
+	}
+	delivery.MessageID = fmt.Sprintf("%d:%d", offset.Partition, offset.Offset)
+	return nil
+}
+
+// NewProxy returns a configured Kafka REST Proxy target.
+func NewProxy(options ...func(*Proxy)) (*Proxy, error) {
+	p := &Proxy{}
+
+	for _, option := range options {
+		option(p)
+	}
+
+	if p.client == nil {
+		return nil, errors.New("httpClient not found")
+	}
+
+	return p, nil
+}
+
+// ProxyHTTPClient set the HTTP client used to access the proxy.
+func ProxyHTTPClient(client *http.Client) func(*Proxy) {
+	return func(p *Proxy) { p.client = client }
+}
@@ -0,0 +1,94 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/diegobernardes/flare"
+)
+
+func TestProxyDeliver(t *testing.T) {
+	Convey("Given a Kafka REST Proxy", t, func() {
+		var (
+			path    string
+			records proxyRecords
+		)
+
+		tests := []struct {
+			title     string
+			status    int
+			response  string
+			hasErr    bool
+			messageID string
+		}{
+			{
+				"It should publish the message",
+				http.StatusOK,
+				`{"offsets":[{"partition":2,"offset":100,"error_code":null,"error":null}]}`,
+				false,
+				"2:100",
+			},
+			{
+				"It should fail when the offset has a error",
+				http.StatusOK,
+				`{"offsets":[{"partition":null,"offset":null,"error_code":1,"error":"failure"}]}`,
+				true,
+				"",
+			},
+			{
+				"It should fail when the topic is not found",
+				http.StatusNotFound,
+				`{"error_code":40401,"message":"Topic not found"}`,
+				true,
+				"",
+			},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.title, func() {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					path = r.URL.Path
+					if err := json.NewDecoder(r.Body).Decode(&records); err != nil {
+						w.WriteHeader(http.StatusBadRequest)
+						return
+					}
+					w.WriteHeader(tt.status)
+					w.Write([]byte(tt.response))
+				}))
+				defer server.Close()
+
+				endpoint, err := url.Parse(server.URL)
+				So(err, ShouldBeNil)
+
+				proxy, err := NewProxy(ProxyHTTPClient(http.DefaultClient))
+				So(err, ShouldBeNil)
+
+				sub := flare.Subscription{
+					Endpoint: flare.SubscriptionEndpoint{
+						Target: flare.SubscriptionTargetKafka, URL: *endpoint, Topic: "users",
+					},
+				}
+				delivery := &flare.Delivery{Document: flare.Document{Id: "http://app.com/users/1"}}
+
+				err = proxy.Deliver(context.Background(), sub, []byte(`{"id":1}`), nil, delivery)
+				So(err != nil, ShouldEqual, tt.hasErr)
+				So(delivery.Status, ShouldEqual, tt.status)
+				So(delivery.MessageID, ShouldEqual, tt.messageID)
+				So(path, ShouldEqual, "/topics/users")
+				So(records.Records, ShouldHaveLength, 1)
+				So(string(records.Records[0].Key), ShouldEqual, "http://app.com/users/1")
+				So(string(records.Records[0].Value), ShouldEqual, `{"id":1}`)
+			})
+		}
+	})
+}
@@ -0,0 +1,95 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/diegobernardes/flare"
+)
+
+// Message is a notification received by the Sink.
+type Message struct {
+	ID      string
+	Content []byte
+	Header  http.Header
+}
+
+// Sink implements flare.SubscriptionTarget keeping the notifications in memory, grouped by the
+// subscription endpoint topic. Only the newest messages of each topic are kept.
+type Sink struct {
+	mutex    sync.RWMutex
+	messages map[string][]Message
+	sequence int
+	size     int
+}
+
+// Deliver store the notification at the endpoint topic.
+func (s *Sink) Deliver(
+	_ context.Context,
+	sub flare.Subscription,
+	content []byte,
+	header http.Header,
+	delivery *flare.Delivery,
+) error {
+	if sub.Endpoint.Topic == "" {
+		return errors.New("missing topic")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sequence++
+	msg := Message{
+		ID:      strconv.Itoa(s.sequence),
+		Content: append([]byte{}, content...),
+		Header:  make(http.Header, len(header)),
+	}
+	for key, values := range header {
+		msg.Header[key] = append([]string{}, values...)
+	}
+
+	messages := append(s.messages[sub.Endpoint.Topic], msg)
+	if len(messages) > s.size {
+		messages = messages[len(messages)-s.size:]
+	}
+	s.messages[sub.Endpoint.Topic] = messages
+	delivery.MessageID = msg.ID
+	return nil
+}
+
+// Messages return the notifications received at the topic, the oldest first.
+func (s *Sink) Messages(topic string) []Message {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return append([]Message{}, s.messages[topic]...)
+}
+
+// NewSink returns a configured memory sink.
+func NewSink(options ...func(*Sink)) (*Sink, error) {
+	s := &Sink{messages: make(map[string][]Message)}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	if s.size == 0 {
+		s.size = 1000
+	} else if s.size < 0 {
+		return nil, errors.New("invalid size")
+	}
+
+	return s, nil
+}
+
+// SinkSize set the maximum quantity of messages kept per topic.
+func SinkSize(size int) func(*Sink) {
+	return func(s *Sink) { s.size = size }
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 Diego Bernardes. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/diegobernardes/flare"
+)
+
+func TestNewSink(t *testing.T) {
+	Convey("Given a list of invalid options", t, func() {
+		Convey("It should not create a sink with a negative size", func() {
+			_, err := NewSink(SinkSize(-1))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestSinkDeliver(t *testing.T) {
+	Convey("Given a Sink", t, func() {
+		sink, err := NewSink(SinkSize(2))
+		So(err, ShouldBeNil)
+
+		sub := flare.Subscription{
+			Endpoint: flare.SubscriptionEndpoint{Target: flare.SubscriptionTargetMemory, Topic: "users"},
+		}
+		header := http.Header{"X-Event": []string{"create"}}
+
+		Convey("It should keep the newest messages of the topic", func() {
+			for _, content := range []string{"1", "2", "3"} {
+				delivery := &flare.Delivery{}
+				So(sink.Deliver(context.Background(), sub, []byte(content), header, delivery), ShouldBeNil)
+				So(delivery.MessageID, ShouldEqual, content)
+			}
+
+			messages := sink.Messages("users")
+			So(messages, ShouldHaveLength, 2)
+			So(string(messages[0].Content), ShouldEqual, "2")
+			So(string(messages[1].Content), ShouldEqual, "3")
+			So(messages[1].Header.Get("X-Event"), ShouldEqual, "create")
+			So(sink.Messages("orders"), ShouldBeEmpty)
+		})
+
+		Convey("It should not deliver without a topic", func() {
+			sub.Endpoint.Topic = ""
+			err := sink.Deliver(context.Background(), sub, []byte("1"), header, &flare.Delivery{})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}